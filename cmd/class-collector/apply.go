@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"class-collector/internal/bundle"
+)
+
+// stringList is a repeatable string flag.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// runApply implements "class-collector apply -base full.zip [-delta d1.zip
+// ...] -out dir/": it materializes the tree bundle.ApplyDeltas reconstructs
+// under the -out directory, which must not exist yet or be empty, and
+// returns the process exit code.
+func runApply(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("class-collector apply", flag.ContinueOnError)
+	fs.SetOutput(new(bytes.Buffer))
+	baseFlag := fs.String("base", "", "FULL bundle built with -emit-src")
+	var deltas stringList
+	fs.Var(&deltas, "delta", "DELTA bundle to apply on top; repeat in the order they were built")
+	outFlag := fs.String("out", "", "directory to write the reconstructed tree to (must not exist or be empty)")
+	layoutFlag := fs.String("output-layout", "", "DELTA entry names the bundles were written with (as for the main command)")
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintln(stderr, "ERROR:", err)
+		return 1
+	}
+	if *baseFlag == "" || *outFlag == "" || fs.NArg() != 0 {
+		fmt.Fprintln(stderr, "ERROR:", errors.New("usage: class-collector apply -base <full bundle> [-delta <delta bundle> ...] -out <dir> [-output-layout <json>]"))
+		return 1
+	}
+	layout, err := resolveDeltaLayout(*layoutFlag)
+	if err != nil {
+		fmt.Fprintln(stderr, "ERROR:", err)
+		return 1
+	}
+	bundle.SetDeltaLayout(layout)
+
+	if ents, err := os.ReadDir(*outFlag); err == nil && len(ents) > 0 {
+		fmt.Fprintln(stderr, "ERROR:", fmt.Errorf("-out %s is not empty", *outFlag))
+		return 1
+	}
+	tree, err := bundle.ApplyDeltas(*baseFlag, deltas)
+	if err != nil {
+		fmt.Fprintln(stderr, "ERROR:", err)
+		return 1
+	}
+	if err := writeTree(*outFlag, tree); err != nil {
+		fmt.Fprintln(stderr, "ERROR:", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "Wrote %d files to %s (base %s + %d deltas)\n", len(tree), *outFlag, *baseFlag, len(deltas))
+	return 0
+}
+
+// writeTree writes every file of tree under dir, refusing paths that would
+// land outside it.
+func writeTree(dir string, tree map[string][]byte) error {
+	paths := make([]string, 0, len(tree))
+	for p := range tree {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		rel := filepath.FromSlash(p)
+		if !filepath.IsLocal(rel) {
+			return fmt.Errorf("%s: path escapes the output directory", p)
+		}
+		dst := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(dst, tree[p], 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
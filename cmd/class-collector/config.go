@@ -0,0 +1,311 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"class-collector/internal/textutil"
+)
+
+// configNames are the project config files looked up in <src_dir>, in
+// order; at most one of them may exist.
+var configNames = []string{".classcollector.yaml", ".classcollector.yml", ".classcollector.json"}
+
+// configNone disables config lookup via -config.
+const configNone = "none"
+
+// configSections group the flags written by -init, as section title and
+// flag names. They are also the only flags a project config found in
+// <src_dir> may set: a checkout is not trusted to pick output paths or
+// commands to run (-summarizer-cmd), so those need the command line or an
+// explicit -config.
+var configSections = []struct {
+	title string
+	flags []string
+}{
+	{"Files", []string{"ext", "exclude", "include", "max-bytes", "max-file-bytes", "use-gitignore"}},
+	{"Anchors", []string{"auto-anchors", "auto-anchors-min-lines", "auto-anchors-max-per-file", "auto-anchors-prefix"}},
+	{"Diff", []string{"diff-context", "diff-no-prefix", "max-diff-bytes", "rename-similarity", "rename-sim-thresh"}},
+}
+
+// projectConfigFlags returns the flags a project config found in <src_dir>
+// may set.
+func projectConfigFlags() map[string]bool {
+	allowed := map[string]bool{}
+	for _, sec := range configSections {
+		for _, name := range sec.flags {
+			allowed[name] = true
+		}
+	}
+	return allowed
+}
+
+// configModeFlags select the run mode. A mode given on the command line
+// replaces the config's, so "-delta x" works with a config that sets zip.
+var configModeFlags = []string{"zip", "delta", "chat", "serve"}
+
+// findConfig returns the project config in srcDir, or "" when there is none.
+func findConfig(srcDir string) (string, error) {
+	var found []string
+	for _, name := range configNames {
+		p := filepath.Join(srcDir, name)
+		if fi, err := os.Stat(p); err == nil && !fi.IsDir() {
+			found = append(found, p)
+		}
+	}
+	if len(found) > 1 {
+		return "", fmt.Errorf("both %s and %s exist; keep one", found[0], found[1])
+	}
+	if len(found) == 0 {
+		return "", nil
+	}
+	return found[0], nil
+}
+
+// loadConfig reads a config file into flag name → value. JSON (with
+// comments) is used for .json files and the YAML subset of parseConfigYAML
+// otherwise; lists become the comma-separated values the flags expect.
+func loadConfig(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]string
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		values, err = parseConfigJSON(data)
+	} else {
+		values, err = parseConfigYAML(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return values, nil
+}
+
+// parseConfigJSON reads a JSON object of flag values; string, number and
+// boolean values and lists of them are accepted.
+func parseConfigJSON(data []byte) (map[string]string, error) {
+	var raw map[string]any
+	if err := json.Unmarshal(textutil.StripJSONC(data), &raw); err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(raw))
+	for k, v := range raw {
+		items, isList := v.([]any)
+		if !isList {
+			items = []any{v}
+		}
+		parts := make([]string, 0, len(items))
+		for _, item := range items {
+			s, err := configScalar(item)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: %w", k, err)
+			}
+			parts = append(parts, s)
+		}
+		values[k] = strings.Join(parts, ",")
+	}
+	return values, nil
+}
+
+// configScalar formats a decoded JSON string, number or boolean.
+func configScalar(v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	}
+	return "", errors.New("value must be a string, number, boolean or list of them")
+}
+
+// parseConfigYAML reads the flat YAML subset a flag config needs:
+// "key: value" lines with plain, 'single' or "double" quoted scalars, lists
+// as "[a, b]" or as "- item" lines under an empty "key:", and "#" comments.
+// Nested mappings, anchors and multi-line scalars are rejected.
+func parseConfigYAML(data []byte) (map[string]string, error) {
+	values := map[string]string{}
+	listKey := ""
+	var list []string
+	flush := func() {
+		if listKey != "" {
+			values[listKey] = strings.Join(list, ",")
+		}
+		listKey, list = "", nil
+	}
+	for n, line := range strings.Split(strings.TrimPrefix(string(data), "\ufeff"), "\n") {
+		text := strings.TrimSpace(stripYAMLComment(line))
+		if text == "" || text == "---" {
+			continue
+		}
+		if item, ok := strings.CutPrefix(text, "-"); ok && (item == "" || item[0] == ' ') {
+			if listKey == "" {
+				return nil, fmt.Errorf("line %d: list item without a key", n+1)
+			}
+			s, err := yamlScalar(strings.TrimSpace(item))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n+1, err)
+			}
+			list = append(list, s)
+			continue
+		}
+		flush()
+		if line[0] == ' ' || line[0] == '\t' {
+			return nil, fmt.Errorf("line %d: nested mappings are not supported", n+1)
+		}
+		key, val, ok := strings.Cut(text, ":")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("line %d: expected \"key: value\"", n+1)
+		}
+		val = strings.TrimSpace(val)
+		if _, dup := values[key]; dup {
+			return nil, fmt.Errorf("line %d: duplicate key %q", n+1, key)
+		}
+		switch {
+		case val == "":
+			listKey = key
+			values[key] = ""
+		case strings.HasPrefix(val, "[") && strings.HasSuffix(val, "]"):
+			var items []string
+			for _, item := range strings.Split(val[1:len(val)-1], ",") {
+				if item = strings.TrimSpace(item); item == "" {
+					continue
+				}
+				s, err := yamlScalar(item)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", n+1, err)
+				}
+				items = append(items, s)
+			}
+			values[key] = strings.Join(items, ",")
+		default:
+			s, err := yamlScalar(val)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n+1, err)
+			}
+			values[key] = s
+		}
+	}
+	flush()
+	return values, nil
+}
+
+// stripYAMLComment drops a "#" comment that starts the line or follows
+// whitespace, outside quotes.
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// yamlScalar unquotes a YAML scalar; plain scalars are taken as written.
+func yamlScalar(s string) (string, error) {
+	switch {
+	case len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"':
+		return strconv.Unquote(s)
+	case len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'':
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	case s != "" && strings.ContainsAny(s[:1], "\"'&*!|>{"):
+		return "", fmt.Errorf("unsupported YAML value %q", s)
+	}
+	return s, nil
+}
+
+// applyConfig sets every config value whose flag was not given on the
+// command line, so explicit flags always win. A non-nil allowed rejects
+// keys outside it.
+func applyConfig(fs *flag.FlagSet, path string, values map[string]string, allowed map[string]bool) error {
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	for _, name := range configModeFlags {
+		if explicit[name] {
+			for _, m := range configModeFlags {
+				explicit[m] = true
+			}
+			break
+		}
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		name := strings.TrimLeft(k, "-")
+		if fs.Lookup(name) == nil || name == "config" || name == "init" {
+			return fmt.Errorf("%s: unknown key %q", path, k)
+		}
+		if allowed != nil && !allowed[name] {
+			return fmt.Errorf("%s: %q cannot be set by a project config found in <src_dir>; give it on the command line or load the file with -config", path, k)
+		}
+		if explicit[name] {
+			continue
+		}
+		if err := fs.Set(name, values[k]); err != nil {
+			return fmt.Errorf("%s: %s: %w", path, k, err)
+		}
+	}
+	return nil
+}
+
+// configTemplate renders the commented .classcollector.yaml written by
+// -init: every setting of configSections, commented out at its default.
+func configTemplate(fs *flag.FlagSet) string {
+	var b strings.Builder
+	b.WriteString("# class-collector project config. Values here are defaults for the\n")
+	b.WriteString("# flags of the same name; flags given on the command line win.\n")
+	b.WriteString("# Lists may be written as [a, b] or as \"- item\" lines.\n")
+	for _, sec := range configSections {
+		fmt.Fprintf(&b, "\n# --- %s ---\n", sec.title)
+		for _, name := range sec.flags {
+			f := fs.Lookup(name)
+			fmt.Fprintf(&b, "\n# %s\n", f.Usage)
+			val := f.DefValue
+			if val == "" || strings.ContainsAny(val, ":#'\"[]{}") || strings.TrimSpace(val) != val {
+				val = strconv.Quote(val)
+			}
+			fmt.Fprintf(&b, "# %s: %s\n", name, val)
+		}
+	}
+	return b.String()
+}
+
+// writeConfigTemplate writes the -init template to <srcDir>/.classcollector.yaml
+// unless a project config already exists.
+func writeConfigTemplate(srcDir, template string) (string, error) {
+	if existing, err := findConfig(srcDir); err != nil || existing != "" {
+		if err == nil {
+			err = fmt.Errorf("%s already exists", existing)
+		}
+		return "", err
+	}
+	path := filepath.Join(srcDir, configNames[0])
+	if err := os.WriteFile(path, []byte(template), 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
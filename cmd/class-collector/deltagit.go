@@ -0,0 +1,152 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"class-collector/internal/cache"
+)
+
+// parseGitRange splits a -delta-git "<rev1>..<rev2>" range.
+func parseGitRange(s string) (from, to string, err error) {
+	from, to, ok := strings.Cut(s, "..")
+	if !ok || from == "" || to == "" || strings.HasPrefix(to, ".") {
+		return "", "", fmt.Errorf("-delta-git must be <rev1>..<rev2>, got %q", s)
+	}
+	return from, to, nil
+}
+
+// exportGitRange writes the trees of both -delta-git revisions, limited to
+// the part of the repository under srcDir, into a fresh temporary directory.
+// Each tree lands in a directory named like srcDir so snapshot module names
+// match. The returned cleanup removes everything.
+func exportGitRange(srcDir, rng string) (fromDir, toDir string, cleanup func(), err error) {
+	from, to, err := parseGitRange(rng)
+	if err != nil {
+		return "", "", nil, err
+	}
+	out, err := gitOutput(srcDir, "rev-parse", "--show-toplevel", "--show-prefix")
+	if err != nil {
+		return "", "", nil, err
+	}
+	top, prefix, _ := strings.Cut(strings.TrimRight(string(out), "\n"), "\n")
+	abs, err := filepath.Abs(srcDir)
+	if err != nil {
+		return "", "", nil, err
+	}
+	tmp, err := os.MkdirTemp("", "class-collector-git-")
+	if err != nil {
+		return "", "", nil, err
+	}
+	cleanup = func() { _ = os.RemoveAll(tmp) }
+	name := filepath.Base(abs)
+	fromDir = filepath.Join(tmp, "from", name)
+	toDir = filepath.Join(tmp, "to", name)
+	for _, side := range []struct{ rev, dir string }{{from, fromDir}, {to, toDir}} {
+		if err := exportGitTree(top, side.rev+":"+prefix, side.dir); err != nil {
+			cleanup()
+			return "", "", nil, err
+		}
+	}
+	return fromDir, toDir, cleanup, nil
+}
+
+// exportGitTree extracts `git archive <treeish>` into dir. Regular files
+// and symlinks are written; entries that would escape dir are skipped.
+func exportGitTree(repo, treeish, dir string) error {
+	data, err := gitOutput(repo, "archive", "--format=tar", treeish)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tr := tar.NewReader(bytes.NewReader(data))
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read git archive %s: %w", treeish, err)
+		}
+		name := path.Clean(hdr.Name)
+		if name == "." || !fs.ValidPath(name) {
+			continue
+		}
+		dst := filepath.Join(dir, filepath.FromSlash(name))
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(dst, 0o755)
+		case tar.TypeSymlink:
+			if err = os.MkdirAll(filepath.Dir(dst), 0o755); err == nil {
+				err = os.Symlink(hdr.Linkname, dst)
+			}
+		case tar.TypeReg:
+			if err = os.MkdirAll(filepath.Dir(dst), 0o755); err == nil {
+				err = writeTarFile(dst, tr, hdr.FileInfo().Mode().Perm())
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("extract %s: %w", name, err)
+		}
+	}
+}
+
+func writeTarFile(dst string, r io.Reader, perm fs.FileMode) error {
+	f, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func gitOutput(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// gitBaseline snapshots the exported <rev1> tree with the same filters as
+// the current side; old content is read back from the export.
+func gitBaseline(cfg Config, fromDir string) (*cache.Snapshot, func(string) ([]byte, error), error) {
+	cfg.srcDir = fromDir
+	files, err := collectFiles(cfg, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("collect files at base revision: %w", err)
+	}
+	prev, err := buildSnapshot(cfg, files)
+	if err != nil {
+		return nil, nil, err
+	}
+	absOf := make(map[string]string, len(files))
+	for _, f := range files {
+		absOf[f.SHA256Hex] = f.AbsPath
+	}
+	readOld := func(hash string) ([]byte, error) {
+		abs, ok := absOf[hash]
+		if !ok {
+			return nil, fs.ErrNotExist
+		}
+		return os.ReadFile(abs)
+	}
+	return prev, readOld, nil
+}
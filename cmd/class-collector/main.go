@@ -10,10 +10,19 @@ import (
 	"class-collector/internal/graph"
 	"class-collector/internal/index"
 	"class-collector/internal/meta"
+	"class-collector/internal/parallel"
+	"class-collector/internal/progress"
 	"class-collector/internal/validate"
 	"class-collector/internal/walkwalk"
+	"class-collector/internal/warn"
+	"class-collector/internal/ziputil"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
@@ -27,25 +36,46 @@ type fileRef = struct {
 	AbsPath string
 }
 
+// dualFS reads snapshot paths for the rename similarity pass: current files
+// through the AbsPath the walk found them at (paths are NFC-normalized and
+// may not match the name on disk), old ones resolved under oldRoot.
 type dualFS struct {
-	oldRoot string
-	newRoot string
+	oldRoot  string
+	newFiles map[string]string
 }
 
 func (d dualFS) Read(p string, old bool) ([]byte, error) {
-	root := d.newRoot
 	if old {
-		root = d.oldRoot
+		return os.ReadFile(walkwalk.ResolvePath(d.oldRoot, p))
 	}
-	full := filepath.Join(root, filepath.FromSlash(p))
-	return os.ReadFile(full)
+	abs, ok := d.newFiles[p]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", p, fs.ErrNotExist)
+	}
+	return os.ReadFile(abs)
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "verify":
+			os.Exit(runVerify(os.Args[2:], os.Stdout, os.Stderr))
+		case "apply":
+			os.Exit(runApply(os.Args[2:], os.Stdout, os.Stderr))
+		}
+	}
 	cfg, err := parseFlags(os.Args[1:])
 	if err != nil {
 		logFatal(err)
 	}
+	if cfg.initConfig != "" {
+		path, err := writeConfigTemplate(cfg.srcDir, cfg.initConfig)
+		if err != nil {
+			logFatal(err)
+		}
+		fmt.Printf("Wrote config template %s\n", path)
+		return
+	}
 	opt, langs, err := buildOptions(cfg)
 	if err != nil {
 		logFatal(err)
@@ -54,6 +84,33 @@ func main() {
 	if err != nil {
 		logFatal(err)
 	}
+	parallel.SetLimit(cfg.maxWorkers)
+	ziputil.SetJSONCompact(cfg.jsonCompact)
+	bundle.SetDeltaLayout(cfg.deltaLayout)
+	bundle.SetSymbolsFormat(cfg.symbolsFormat)
+	bundle.SetSymbolsSort(cfg.symbolsSort)
+	bundle.SetChatRedactPaths(cfg.chatRedact)
+	bundle.SetChatSeparators(cfg.chatBetween, cfg.chatFooter)
+	bundle.SetChatSkipLargeFiles(cfg.chatMaxLines)
+	bundle.SetOutFormat(cfg.outFormat)
+	bundle.SetEmitHTML(cfg.emitHTML)
+	index.SetSlicesFromSymbols(cfg.symbolSlices)
+	index.SetSlicesPrefer(cfg.slicesPrefer)
+	index.SetParser(cfg.parser)
+	index.SetGoIncludePrivate(cfg.includePrivate)
+	index.SetExcludeTestSymbols(cfg.noTestSyms)
+	index.SetLangForExt(cfg.langForExt)
+	index.SetShebangDetection(cfg.shebangLang)
+	index.SetSymbolsMinConfidence(cfg.minSymConf)
+	index.SetMinFileSymbols(cfg.minFileSyms)
+	index.SetRegionLimits(cfg.regionDepth, cfg.regionMax)
+	index.SetSummarizer(index.CommandSummarizer(strings.Fields(cfg.summarizerCmd), summarizerTimeout))
+	validate.SetStrict(cfg.validateStrict)
+	bundle.SetWriteWarnings(cfg.warningsJSON)
+	warnings := &warn.Collector{}
+	warn.Use(warnings)
+	useBundleIDAlgo(cfg)
+	useProgress(cfg.progress)
 	var runErr error
 	switch mode {
 	case "full":
@@ -62,14 +119,51 @@ func main() {
 		runErr = runDelta(cfg, opt)
 	case "chat":
 		runErr = runChat(cfg, opt)
+	case "serve":
+		runErr = runServe(cfg, opt)
 	default:
 		runErr = fmt.Errorf("unknown mode %q", mode)
 	}
+	progress.Done()
+	if cfg.verbose {
+		printWarnings(warnings.List())
+	}
 	if runErr != nil {
 		logFatal(runErr)
 	}
 }
 
+// useProgress installs a stderr progress reporter when enabled, or none.
+func useProgress(enabled bool) {
+	if !enabled {
+		progress.Use(nil)
+		return
+	}
+	progress.Use(progress.New(os.Stderr, progress.DefaultEvery))
+}
+
+// stderrIsTerminal reports whether stderr is a character device (a TTY).
+func stderrIsTerminal() bool {
+	fi, err := os.Stderr.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+func printWarnings(list []warn.Warning) {
+	for _, w := range list {
+		if w.Path != "" {
+			fmt.Fprintf(os.Stderr, "WARN [%s] %s: %s\n", w.Kind, w.Path, w.Message)
+		} else {
+			fmt.Fprintf(os.Stderr, "WARN [%s] %s\n", w.Kind, w.Message)
+		}
+	}
+}
+
+func printRenameReport(list []cache.RenameCandidate) {
+	for _, rc := range list {
+		fmt.Fprintf(os.Stderr, "RENAME %s -> %s: %s %d (threshold %d) %s\n", rc.From, rc.To, rc.Metric, rc.Score, rc.Threshold, rc.Decision)
+	}
+}
+
 func logFatal(err error) {
 	if err == nil {
 		return
@@ -82,21 +176,60 @@ func logFatal(err error) {
 // existing flags to avoid behavior changes while enabling unit testing.
 type Config struct {
 	exts           string
+	extCaseSens    bool
 	exclude        string
 	include        string
 	maxBytes       int64
 	maxFileBytes   int64
+	maxWorkers     int
 	useGitignore   bool
+	globalIgnore   bool
+	modifiedSince  time.Time
+	summarizerCmd  string
+	dedupLinks     bool
+	dedupContent   bool
 	followSymlinks bool
+	keepSymlinks   bool
+	submodules     string
+	deltaLayout    bundle.DeltaLayout
+	langForExt     map[string]string
+	shebangLang    bool
+	minSymConf     int // -symbols-min-confidence threshold (0..100)
+	minFileSyms    int // -min-file-symbols api-surface threshold (0 = off)
+	excludeRoles   string
+	onlyRoles      string
 
 	zipOut         string
 	deltaOut       string
 	chatOut        string
+	serve          bool
+	servePort      int
 	chatMaxClasses int
 	chatMaxChars   int
+	chatMaxMsgs    int
+	chatOverflow   string
+	chatSysPrompt  string
+	chatGraph      bool
+	chatOverview   bool
+	chatRedact     bool
+	chatFooter     string
+	chatBetween    string
+	chatOrderFile  string
+	chatMaxLines   int
+	outFormat      string
+	readmeFirst    bool
+	failOnEmpty    bool
+	bundleIDAlgo   string
+	outNameTmpl    string
+	writeSHA256    bool
+	skipUnchanged  bool
+	warningsJSON   bool
+	verbose        bool
+	progress       bool
 
 	diffContext  int
 	diffNoPrefix bool
+	diffFuncOnly bool
 
 	benchPath string
 
@@ -104,15 +237,51 @@ type Config struct {
 	resetCache       bool
 	storeBlobs       bool
 	maxDiffBytes     int
+	maxDiffTotal     int
 	renameSimilarity bool
 	renameSimThresh  int
 	renameSimOldRoot string
+	deltaAgainstFull string
+	deltaGit         string
+	deltaModCheck    string
+	symbolsFormat    string
+	symbolsSort      string
+	renameSimPct     int
+	renameReport     bool
+	deltaLangs       string
+	deltaSummary     bool
+	deltaCurrMan     bool
 
 	emitSrc        bool
+	emitHTML       bool
+	emitSrcFilter  string
+	emitSrcSep     bool
 	maxFileLines   int
+	symbolSlices   bool
+	slicesPrefer   string
+	parser         string
+	includePrivate bool
+	noTestSyms     bool
 	langHints      string
 	validateJSON   bool
+	validateStrict bool
 	saveSnapOnFull bool
+	emitStats      bool
+	emitHygiene    bool
+	emitDeps       bool
+	symConflicts   int
+	emitClusters   bool
+	impact         bool
+	jsonCompact    bool
+	graphMaxNodes  int
+	graphReduce    string
+	graphKinds     bool
+	graphCalls     bool
+	graphCache     bool
+	artifactCache  bool
+	noGraph        bool
+	regionDepth    int
+	regionMax      int
 
 	autoAnchors        bool
 	autoAnchorsMin     int
@@ -120,8 +289,10 @@ type Config struct {
 	autoAnchorsImports bool
 	autoAnchorsTests   bool
 	autoAnchorsPrefix  string
+	autoAnchorsNoDup   bool
 
-	srcDir string
+	srcDir     string
+	initConfig string // -init: template to write instead of running a mode
 }
 
 // parseFlags parses CLI arguments into Config without side effects.
@@ -133,87 +304,394 @@ func parseFlags(args []string) (Config, error) {
 	extsFlag := fs.String("ext",
 		".go,.java,.kt,.cs,.ts,.tsx,.js,.json,.yaml,.yml,.xml,.proto,.gradle,.md,.txt,.cpp,.cc,.cxx,.hpp,.hh,.h",
 		"comma-separated extensions to include")
+	extCaseSensFlag := fs.Bool("follow-case-sensitive-ext", false, "match file extensions against -ext case-sensitively (e.g. .H is not .h)")
 	excludeFlag := fs.String("exclude",
 		".git,node_modules,dist,build,out,target,.idea,.vscode,.DS_Store",
-		"comma-separated dir/file prefixes to exclude")
-	includeFlag := fs.String("include", "", "comma-separated substrings to force include (anywhere in path)")
+		"comma-separated dir/file prefixes to exclude; entries with '/' are gitignore-style path patterns, '!' re-includes")
+	includeFlag := fs.String("include", "", "comma-separated substrings to force include (anywhere in path); '!pattern' re-includes an excluded path")
 	maxBytesFlag := fs.Int64("max-bytes", 25_000_000, "approximate max total bytes to include in FULL bundle (0 = no limit)")
+	maxWorkersFlag := fs.Int("max-concurrency", 0, "max parallel workers for file indexing (0 = GOMAXPROCS, 1 = fully serial)")
+	jobsFlag := fs.Int("jobs", 0, "worker pool size for hashing, symbol extraction and graph scanning; same as -max-concurrency (0 = GOMAXPROCS)")
 	maxFileBytesFlag := fs.Int64("max-file-bytes", 2_000_000, "max bytes per file (0 = no limit)")
 	useGitignoreFlag := fs.Bool("use-gitignore", true, "honor .gitignore patterns when walking files")
+	summarizerCmdFlag := fs.String("summarizer-cmd", "", "external program (split on spaces) that reads a file on stdin, with CLASS_COLLECTOR_PATH set, and prints a one-line manifest summary")
+	dedupContentFlag := fs.Bool("exclude-duplicate-content", false, "index only the first file (in walk order) of byte-identical non-empty files and list the others as manifest aliases")
+	dedupLinksFlag := fs.Bool("dedup-hardlinks", false, "collect hard-linked files once (first path in walk order) and list the other paths as manifest aliases; no effect where inodes are unavailable")
+	modifiedSinceFlag := fs.String("modified-since", "", "only collect files modified within a window (36h, 7d) or since a date (2006-01-02 or RFC 3339), by file mtime")
+	globalIgnoreFlag := fs.Bool("exclude-if-gitignored-anywhere", false, "also honor the global gitignore ($XDG_CONFIG_HOME/git/ignore or ~/.config/git/ignore)")
 	followSymlinksFlag := fs.Bool("follow-symlinks", false, "follow symlinks during file walk")
+	keepSymlinksFlag := fs.Bool("preserve-symlink-targets", false, "record unfollowed symlinks in the manifest (kind \"symlink\" with target) instead of dropping them")
+	layoutFlag := fs.String("output-layout", "", "JSON object (or path to a JSON file) overriding DELTA entry names: diffs, added, patch, index, summary, readme, currentFiles, renameReport")
+	submodulesFlag := fs.String("submodules", "skip", "how to treat submodule paths declared in .gitmodules: include|skip")
+	excludeRoleFlag := fs.String("exclude-role", "", "drop files with these roles (comma list of source,test,config,doc,generated)")
+	onlyRoleFlag := fs.String("only-role", "", "keep only files with these roles (comma list; mutually exclusive with -exclude-role)")
 
 	zipFlag := fs.String("zip", "", "path to FULL bundle output (mutually exclusive with -delta/-chat)")
 	deltaFlag := fs.String("delta", "", "path to DELTA bundle output (mutually exclusive with -zip/-chat)")
 	chatFlag := fs.String("chat", "", "path to CHAT bundle output (mutually exclusive with -zip/-delta)")
+	serveFlag := fs.Bool("serve", false, "serve manifest, symbols, graph, slices and diffs over HTTP on localhost, reindexing on change (mutually exclusive with -zip/-delta/-chat)")
+	portFlag := fs.Int("port", 8080, "TCP port for -serve (0 picks a free one)")
 	chatMaxClasses := fs.Int("chat-max-classes", 10, "max classes/entities per chat message")
 	chatMaxChars := fs.Int("chat-max-chars", 80_000, "max characters per chat message")
+	chatMaxMsgs := fs.Int("chat-max-messages", 0, "hard cap on chat file messages (0 = no limit)")
+	chatMaxLinesFlag := fs.Int("chat-skip-large-files", 0, "leave files with more lines than this out of CHAT messages and list them in the chat README (0 = no limit)")
+	chatOverflow := fs.String("chat-overflow", bundle.ChatOverflowPack, "when -chat-max-messages is exceeded: pack (more files per message) or drop (lowest-ranked files)")
+	chatOverviewFlag := fs.Bool("chat-manifest-message", false, "add a chat/0000-overview.md message with module, build system, file count, language breakdown and the message TOC")
+	chatRedactFlag := fs.Bool("chat-redact-paths", false, "replace file paths in the CHAT bundle with opaque file-<hash> tokens; the token→path map is written beside the archive as <name>.path-map.json")
+	chatGraphFlag := fs.Bool("chat-include-graph", false, "add a chat/0000-graph.md message with the dependency graph as an adjacency list")
+	chatOrderFlag := fs.String("chat-order-file", "", "file listing repo-relative paths (one per line, # comments) to send first in chat messages, in that order; other files follow the default ranking")
+	chatFooterFlag := fs.String("chat-footer", "", "add a final chat/zzzz-footer.md message with this text (e.g. a closing instruction)")
+	chatBetweenFlag := fs.String("chat-between", "", "text appended to every chat message except the last, as an explicit message delimiter")
+	chatSysPromptFlag := fs.String("chat-system-prompt", "", "leading chat/0000-system.md message: a file path, literal text, or \"default\"")
+	outNameTmplFlag := fs.String("out-name-template", "", "output file name template, e.g. {module}-{bundleid8}.zip (placed next to -zip/-delta/-chat)")
+	skipUnchangedFlag := fs.Bool("skip-if-unchanged", false, "FULL: leave the output zip untouched when its BUNDLE.ID matches the would-be bundle ID")
+	outFormatFlag := fs.String("out-format", bundle.OutFormatZip, "bundle output format: zip, tgz (gzip-compressed tar) or dir (plain directory tree)")
+	writeSHA256Flag := fs.Bool("write-sha256", false, "also write the archive SHA-256 to <out>.sha256")
+	warningsJSONFlag := fs.Bool("warnings-json", false, "write recorded warnings (skipped files, oversize diffs, truncations) to warnings.json in the bundle")
+	verboseFlag := fs.Bool("verbose", false, "print recorded warnings to stderr")
+	progressFlag := fs.Bool("progress", stderrIsTerminal(), "report phases and file counts on stderr (default on when stderr is a terminal)")
 
 	diffContextFlag := fs.Int("diff-context", 4, "lines of context in unified diffs")
 	diffNoPrefixFlag := fs.Bool("diff-no-prefix", true, "omit a/ and b/ prefixes in diffs")
+	diffFuncOnlyFlag := fs.Bool("diff-context-func-only", false, "zero-context hunks with the enclosing symbol appended to each @@ header")
 	benchFlag := fs.String("bench", "", "path to include as bench.txt in bundles")
 
 	tmpDirFlag := fs.String("tmp-dir", "tmp/.ccache", "base cache directory for snapshots and blobs")
 	newFlag := fs.Bool("new", false, "reset cache for this <src_dir> before building")
 	storeBlobsFlag := fs.Bool("store-blobs", false, "store source copies as content-addressed blobs for diffs")
 	maxDiffBytesFlag := fs.Int("max-diff-bytes", 2_000_000, "max bytes for per-file diffs in DELTA bundles (0 = no limit)")
+	maxDiffTotalFlag := fs.Int("diff-max-total-bytes", 0, "budget for the summed size of all diffs in DELTA bundles, changed files then added ones; later files (by path) get placeholders (0 = no limit)")
 	renameSimFlag := fs.Bool("rename-similarity", false, "enable similarity-based rename detection in DELTA mode")
 	renameSimThreshFlag := fs.Int("rename-sim-thresh", 8, "max Hamming distance for SimHash rename detection")
+	deltaGitFlag := fs.String("delta-git", "", "build the DELTA between two Git revisions (<rev1>..<rev2>) of the repository containing src_dir instead of using the cache")
+	deltaAgainstFullFlag := fs.String("delta-against-full", "", "build the DELTA against this FULL bundle's manifest (and src/, if present) instead of the cache")
+	deltaModCheckFlag := fs.String("delta-base-module-check", moduleCheckWarn, "when the DELTA baseline belongs to another module: off, warn (default) or error")
 	renameSimOldRootFlag := fs.String("rename-sim-oldroot", "", "optional root of previous snapshot files for rename similarity")
+	deltaCurrManFlag := fs.Bool("delta-include-unchanged-manifest", false, "embed current-files.json in the DELTA listing every current file (path, hash, lines), not just the changed ones")
+	deltaSummaryFlag := fs.Bool("delta-summary-only", false, "write only delta.index.json and SUMMARY.md into the DELTA (no diffs/, added/ or delta.patch; diff generation is skipped)")
+	renameReportFlag := fs.Bool("diff-rename-similarity-report", false, "write rename-report.json (renameReport in -output-layout; scored rename pairs, distances and decisions) into the DELTA; -verbose also prints it")
+	renameSimPctFlag := fs.Int("rename-sim-percent", 0, "min line similarity percent (1-100) for rename detection; overrides -rename-sim-thresh when > 0")
+	deltaLangsFlag := fs.String("delta-langs", "", "limit DELTA entries to specific languages (comma list, e.g. go,java)")
 
+	emitHTMLFlag := fs.Bool("emit-html", false, "add a self-contained index.html to the FULL bundle linking TOC, manifest, symbols and graph, with the file list, per-file symbols and graph adjacency")
 	emitSrcFlag := fs.Bool("emit-src", false, "include source copies in FULL bundle under src/")
+	emitSrcSepFlag := fs.Bool("emit-src-compressed", false, "write sources to a sibling <zip>.src.zip instead of src/ in the FULL bundle (implies -emit-src)")
+	bundleIDAlgoFlag := fs.String("bundle-id-algo", index.BundleIDContent, "what bundle_id hashes: content (paths+hashes), module (plus module name) or module+git (plus module name and HEAD commit)")
+	failOnEmptyFlag := fs.Bool("fail-on-empty", false, "exit with an error instead of 0 when no files match the filters")
+	readmeFirstFlag := fs.Bool("repo-readme-first", false, "FULL/CHAT: collect the top-level README even if filtered out, flag it at the top of TOC.md and send it first in chat messages")
+	emitSrcFilterFlag := fs.String("emit-src-filter", "", "glob over manifest paths limiting which files -emit-src copies (supports *, ?, **)")
 	maxFileLinesFlag := fs.Int("max-file-lines", 500, "max lines per file before slicing; anchors preferred")
+	includePrivateFlag := fs.Bool("symbols-include-private", false, "list unexported Go functions/methods in manifest exports (symbols always include them)")
+	noTestSymsFlag := fs.Bool("exclude-symbols-in-tests", false, "omit symbols of test files from symbols.json and pointers; the files stay in the manifest")
+	shebangLangFlag := fs.Bool("symbols-lang-override", true, "route extensionless and .txt files by their #! interpreter line (e.g. python3 → py) to the matching symbol extractor")
+	langForExtFlag := fs.String("lang-for-ext", "", "override the extractor language per extension (comma list, e.g. .h=objc,.m=objc)")
+	minSymConfFlag := fs.Int("symbols-min-confidence", 0, "drop regex-extracted methods/functions whose confidence score (0..100) is below this (0 = keep all)")
+	minFileSymsFlag := fs.Int("min-file-symbols", 0, "tag files with at least this many symbols as api-surface in manifest tags; chat ranks them first (0 = off)")
+	symbolsFormatFlag := fs.String("symbols-format", bundle.SymbolsFormatFlat, "symbols.json layout: flat (list) or tree (members nested under types)")
+	symbolsSortFlag := fs.String("symbols-sort", bundle.SymbolsSortPosition, "flat symbols.json order: position (path, start, end) or name; display only, the bundle ID is unaffected")
+	parserFlag := fs.String("parser", index.ParserRegex, "Java/Kotlin/TS symbol extraction: regex (default) or precise (structural parser: nested types, exact end lines, signatures)")
+	slicesPreferFlag := fs.String("slices-prefer", index.SlicesPreferAll, "nested anchor/symbol slices: all (keep), inner (drop redundant containers) or outer (drop slices nested in them)")
+	symbolSlicesFlag := fs.Bool("slices-from-symbols", false, "emit one slice per symbol (named by the symbol) for files that have symbols")
 	langHintFlag := fs.String("lang", "", "limit symbol extraction to specific languages (comma list)")
 	validateFlag := fs.Bool("validate", true, "validate manifest/symbols JSON output")
+	validateStrictFlag := fs.Bool("validate-strict", false, "fail on unsorted artifacts and cross-check symbols, slices and pointers against the manifest (implies -validate)")
 	saveSnapFlag := fs.Bool("save-snapshot", true, "save snapshot in cache after FULL bundle")
+	symConflictsFlag := fs.Int("max-symbols-global-dedup", 0, "write symbol-conflicts.json listing up to N fully-qualified symbols defined in more than one file (0 = off)")
+	emitDepsFlag := fs.Bool("emit-deps", false, "include a lockfile dependency summary (dependencies.json from go.sum, package-lock.json, requirements.txt) in FULL bundle")
+	emitHygieneFlag := fs.Bool("emit-hygiene", false, "include hygiene.json in FULL bundle: files with mixed CRLF/LF, trailing whitespace or mixed tab/space indentation")
+	emitStatsFlag := fs.Bool("emit-stats", false, "include per-language line statistics (stats.json) in FULL bundle")
+	impactFlag := fs.Bool("impact", false, "record each file's transitive dependents count in manifest.json (impact)")
+	emitClustersFlag := fs.Bool("emit-clusters", false, "include graph community clustering (graph.clusters.json) in FULL bundle")
+	jsonCompactFlag := fs.Bool("json-compact", false, "write JSON artifacts without indentation")
+	noGraphFlag := fs.Bool("no-graph", false, "FULL/CHAT: skip import scanning and write an empty graph.json (chat ranking falls back to exports and paths)")
+	artifactCacheFlag := fs.Bool("artifact-cache", false, "FULL: reuse per-file index results cached in the -tmp-dir cache for files whose hash is unchanged")
+	graphCacheFlag := fs.Bool("graph-cache", false, "reuse per-file graph imports cached in the -tmp-dir cache for files whose hash is unchanged")
+	graphMaxNodesFlag := fs.Int("graph-max-nodes", 0, "cap graph.json to the highest-degree nodes (0 = no limit)")
+	graphKindsFlag := fs.Bool("graph-node-kinds", false, "label graph.json nodes as internal, stdlib or external (nodeKinds)")
+	graphCallsFlag := fs.Bool("graph-calls", false, "FULL/CHAT: add call edges between symbols (calls) to graph.json")
+	graphReduceFlag := fs.String("graph-reduce", "", "transitively reduce the FULL graph: alongside (add graph.reduced.json) or replace (reduce graph.json); cycles are kept")
 
+	regionDepthFlag := fs.Int("regions-max-depth", index.DefaultRegionMaxDepth, "ignore explicit regions nested deeper than this, with a warning (0 = unlimited)")
+	regionMaxFlag := fs.Int("regions-max-per-file", index.DefaultRegionMaxPerFile, "keep at most this many explicit region anchors per file, with a warning (0 = unlimited)")
 	autoAnchorsFlag := fs.Bool("auto-anchors", true, "generate auto anchors from symbols/imports/tests")
 	autoAnchorsMinFlag := fs.Int("auto-anchors-min-lines", 8, "minimum region length for auto anchors")
 	autoAnchorsMaxFlag := fs.Int("auto-anchors-max-per-file", 64, "maximum number of auto anchors per file (0 = unlimited)")
 	autoAnchorsImportsFlag := fs.Bool("auto-anchors-imports", true, "add IMPORTS anchor when import block exists")
 	autoAnchorsTestsFlag := fs.Bool("auto-anchors-tests", true, "add anchors for tests (Go/TS patterns)")
 	autoAnchorsPrefixFlag := fs.String("auto-anchors-prefix", "auto:", "prefix for auto anchor names")
+	autoAnchorsNoDupFlag := fs.Bool("auto-anchors-suppress-contained", false, "drop auto anchors fully contained in an explicit region")
+	configFlag := fs.String("config", "", "project config file supplying flag defaults (default: <src_dir>/.classcollector.yaml, .yml or .json if present; \"none\" disables)")
+	initFlag := fs.Bool("init", false, "write a commented .classcollector.yaml template into <src_dir> (default .) and exit")
 
 	if err := fs.Parse(args); err != nil {
 		return cfg, err
 	}
+	if *initFlag {
+		cfg.srcDir = "."
+		if fs.NArg() > 0 {
+			cfg.srcDir = filepath.Clean(fs.Arg(0))
+		}
+		cfg.initConfig = configTemplate(fs)
+		return cfg, nil
+	}
 	if fs.NArg() < 1 {
 		return cfg, fmt.Errorf("missing <src_dir>")
 	}
+	configPath := *configFlag
+	var allowed map[string]bool // nil: an explicit -config may set any flag
+	if configPath == "" {
+		found, err := findConfig(fs.Arg(0))
+		if err != nil {
+			return cfg, err
+		}
+		configPath, allowed = found, projectConfigFlags()
+	}
+	if configPath != "" && configPath != configNone {
+		values, err := loadConfig(configPath)
+		if err != nil {
+			return cfg, err
+		}
+		if err := applyConfig(fs, configPath, values, allowed); err != nil {
+			return cfg, err
+		}
+	}
+	if *deltaGitFlag != "" {
+		if _, _, err := parseGitRange(*deltaGitFlag); err != nil {
+			return cfg, err
+		}
+		if *deltaAgainstFullFlag != "" {
+			return cfg, fmt.Errorf("-delta-git and -delta-against-full are mutually exclusive")
+		}
+	}
+	if *portFlag < 0 || *portFlag > 65535 {
+		return cfg, fmt.Errorf("-port must be between 0 and 65535, got %d", *portFlag)
+	}
+	if *maxWorkersFlag < 0 {
+		return cfg, fmt.Errorf("-max-concurrency must be >= 0, got %d", *maxWorkersFlag)
+	}
+	if *jobsFlag < 0 {
+		return cfg, fmt.Errorf("-jobs must be >= 0, got %d", *jobsFlag)
+	}
+	if *jobsFlag > 0 && *maxWorkersFlag > 0 && *jobsFlag != *maxWorkersFlag {
+		return cfg, fmt.Errorf("-jobs %d conflicts with -max-concurrency %d; set only one", *jobsFlag, *maxWorkersFlag)
+	}
+	if *jobsFlag > 0 {
+		*maxWorkersFlag = *jobsFlag
+	}
+	switch *outFormatFlag {
+	case bundle.OutFormatZip, bundle.OutFormatTgz, bundle.OutFormatDir:
+	default:
+		return cfg, fmt.Errorf("-out-format must be zip, tgz or dir, got %q", *outFormatFlag)
+	}
+	if *outFormatFlag == bundle.OutFormatDir && *writeSHA256Flag {
+		return cfg, errors.New("-write-sha256 cannot be used with -out-format dir")
+	}
+	if *chatMaxLinesFlag < 0 {
+		return cfg, fmt.Errorf("-chat-skip-large-files must be >= 0, got %d", *chatMaxLinesFlag)
+	}
+	switch *chatOverflow {
+	case bundle.ChatOverflowPack, bundle.ChatOverflowDrop:
+	default:
+		return cfg, fmt.Errorf("-chat-overflow must be pack or drop, got %q", *chatOverflow)
+	}
+	switch *parserFlag {
+	case index.ParserRegex, index.ParserPrecise:
+	default:
+		return cfg, fmt.Errorf("-parser must be regex or precise, got %q", *parserFlag)
+	}
+	switch *slicesPreferFlag {
+	case index.SlicesPreferAll, index.SlicesPreferInner, index.SlicesPreferOuter:
+	default:
+		return cfg, fmt.Errorf("-slices-prefer must be all, inner or outer, got %q", *slicesPreferFlag)
+	}
+	switch *deltaModCheckFlag {
+	case moduleCheckOff, moduleCheckWarn, moduleCheckError:
+	default:
+		return cfg, fmt.Errorf("-delta-base-module-check must be off, warn or error, got %q", *deltaModCheckFlag)
+	}
+	switch *symbolsSortFlag {
+	case bundle.SymbolsSortPosition, bundle.SymbolsSortName:
+	default:
+		return cfg, fmt.Errorf("-symbols-sort must be position or name, got %q", *symbolsSortFlag)
+	}
+	switch *symbolsFormatFlag {
+	case bundle.SymbolsFormatFlat, bundle.SymbolsFormatTree:
+	default:
+		return cfg, fmt.Errorf("-symbols-format must be flat or tree, got %q", *symbolsFormatFlag)
+	}
+	if *maxDiffTotalFlag < 0 {
+		return cfg, fmt.Errorf("-diff-max-total-bytes must be >= 0, got %d", *maxDiffTotalFlag)
+	}
+	if *regionDepthFlag < 0 || *regionMaxFlag < 0 {
+		return cfg, fmt.Errorf("-regions-max-depth and -regions-max-per-file must be >= 0")
+	}
+	if *minFileSymsFlag < 0 {
+		return cfg, fmt.Errorf("-min-file-symbols must be >= 0, got %d", *minFileSymsFlag)
+	}
+	if *minSymConfFlag < 0 || *minSymConfFlag > 100 {
+		return cfg, fmt.Errorf("-symbols-min-confidence must be within 0..100, got %d", *minSymConfFlag)
+	}
+	if *renameSimPctFlag < 0 || *renameSimPctFlag > 100 {
+		return cfg, fmt.Errorf("-rename-sim-percent must be within 0..100, got %d", *renameSimPctFlag)
+	}
+	modifiedSince, err := walkwalk.ParseModifiedSince(*modifiedSinceFlag, time.Now())
+	if err != nil {
+		return cfg, err
+	}
+	if !modifiedSince.IsZero() && *deltaFlag != "" {
+		return cfg, fmt.Errorf("-modified-since cannot be used with -delta (files outside the window would show as removed)")
+	}
+	if *noGraphFlag {
+		needsGraph := []struct {
+			name string
+			on   bool
+		}{{"-chat-include-graph", *chatGraphFlag}, {"-impact", *impactFlag}, {"-emit-clusters", *emitClustersFlag}, {"-graph-reduce", *graphReduceFlag != ""}, {"-graph-calls", *graphCallsFlag}}
+		for _, f := range needsGraph {
+			if f.on {
+				return cfg, fmt.Errorf("-no-graph cannot be combined with %s", f.name)
+			}
+		}
+	}
+	if *chatRedactFlag && *chatGraphFlag {
+		return cfg, fmt.Errorf("-chat-redact-paths cannot be combined with -chat-include-graph (graph nodes name packages and paths)")
+	}
+	switch *graphReduceFlag {
+	case "", graphReduceAlongside, graphReduceReplace:
+	default:
+		return cfg, fmt.Errorf("-graph-reduce must be alongside or replace, got %q", *graphReduceFlag)
+	}
+	switch *bundleIDAlgoFlag {
+	case index.BundleIDContent, index.BundleIDModule, index.BundleIDModuleGit:
+	default:
+		return cfg, fmt.Errorf("-bundle-id-algo must be content, module or module+git, got %q", *bundleIDAlgoFlag)
+	}
+	switch *submodulesFlag {
+	case "include", "skip":
+	default:
+		return cfg, fmt.Errorf("-submodules must be include or skip, got %q", *submodulesFlag)
+	}
+	if err := validateRoles(*excludeRoleFlag, *onlyRoleFlag); err != nil {
+		return cfg, err
+	}
+	layout, err := resolveDeltaLayout(*layoutFlag)
+	if err != nil {
+		return cfg, err
+	}
+	langForExt, err := index.ParseLangForExt(*langForExtFlag)
+	if err != nil {
+		return cfg, err
+	}
 
 	cfg = Config{
 		exts:               *extsFlag,
+		extCaseSens:        *extCaseSensFlag,
 		exclude:            *excludeFlag,
 		include:            *includeFlag,
 		maxBytes:           *maxBytesFlag,
 		maxFileBytes:       *maxFileBytesFlag,
+		maxWorkers:         *maxWorkersFlag,
 		useGitignore:       *useGitignoreFlag,
+		globalIgnore:       *globalIgnoreFlag,
+		modifiedSince:      modifiedSince,
+		summarizerCmd:      *summarizerCmdFlag,
+		dedupLinks:         *dedupLinksFlag,
+		dedupContent:       *dedupContentFlag,
 		followSymlinks:     *followSymlinksFlag,
+		keepSymlinks:       *keepSymlinksFlag,
+		submodules:         *submodulesFlag,
+		deltaLayout:        layout,
+		langForExt:         langForExt,
+		shebangLang:        *shebangLangFlag,
+		minSymConf:         *minSymConfFlag,
+		minFileSyms:        *minFileSymsFlag,
+		excludeRoles:       *excludeRoleFlag,
+		onlyRoles:          *onlyRoleFlag,
 		zipOut:             *zipFlag,
 		deltaOut:           *deltaFlag,
 		chatOut:            *chatFlag,
+		serve:              *serveFlag,
+		servePort:          *portFlag,
 		chatMaxClasses:     *chatMaxClasses,
 		chatMaxChars:       *chatMaxChars,
+		chatMaxMsgs:        *chatMaxMsgs,
+		chatOverflow:       *chatOverflow,
+		chatSysPrompt:      *chatSysPromptFlag,
+		chatGraph:          *chatGraphFlag,
+		chatRedact:         *chatRedactFlag,
+		chatFooter:         *chatFooterFlag,
+		chatBetween:        *chatBetweenFlag,
+		chatOrderFile:      *chatOrderFlag,
+		chatMaxLines:       *chatMaxLinesFlag,
+		outFormat:          *outFormatFlag,
+		chatOverview:       *chatOverviewFlag,
+		readmeFirst:        *readmeFirstFlag,
+		failOnEmpty:        *failOnEmptyFlag,
+		bundleIDAlgo:       *bundleIDAlgoFlag,
+		outNameTmpl:        *outNameTmplFlag,
+		writeSHA256:        *writeSHA256Flag,
+		skipUnchanged:      *skipUnchangedFlag,
+		warningsJSON:       *warningsJSONFlag,
+		verbose:            *verboseFlag,
+		progress:           *progressFlag,
 		diffContext:        *diffContextFlag,
 		diffNoPrefix:       *diffNoPrefixFlag,
+		diffFuncOnly:       *diffFuncOnlyFlag,
 		benchPath:          *benchFlag,
 		tmpDir:             *tmpDirFlag,
 		resetCache:         *newFlag,
 		storeBlobs:         *storeBlobsFlag,
 		maxDiffBytes:       *maxDiffBytesFlag,
+		maxDiffTotal:       *maxDiffTotalFlag,
 		renameSimilarity:   *renameSimFlag,
+		renameReport:       *renameReportFlag,
 		renameSimThresh:    *renameSimThreshFlag,
 		renameSimOldRoot:   *renameSimOldRootFlag,
-		emitSrc:            *emitSrcFlag,
+		deltaAgainstFull:   *deltaAgainstFullFlag,
+		deltaGit:           *deltaGitFlag,
+		deltaModCheck:      *deltaModCheckFlag,
+		symbolsFormat:      *symbolsFormatFlag,
+		symbolsSort:        *symbolsSortFlag,
+		renameSimPct:       *renameSimPctFlag,
+		deltaLangs:         *deltaLangsFlag,
+		deltaSummary:       *deltaSummaryFlag,
+		deltaCurrMan:       *deltaCurrManFlag,
+		emitSrc:            *emitSrcFlag || *emitSrcSepFlag,
+		emitHTML:           *emitHTMLFlag,
+		emitSrcSep:         *emitSrcSepFlag,
+		emitSrcFilter:      *emitSrcFilterFlag,
 		maxFileLines:       *maxFileLinesFlag,
+		symbolSlices:       *symbolSlicesFlag,
+		slicesPrefer:       *slicesPreferFlag,
+		parser:             *parserFlag,
+		includePrivate:     *includePrivateFlag,
+		noTestSyms:         *noTestSymsFlag,
 		langHints:          *langHintFlag,
-		validateJSON:       *validateFlag,
+		validateJSON:       *validateFlag || *validateStrictFlag,
+		validateStrict:     *validateStrictFlag,
 		saveSnapOnFull:     *saveSnapFlag,
+		emitStats:          *emitStatsFlag,
+		emitHygiene:        *emitHygieneFlag,
+		emitDeps:           *emitDepsFlag,
+		symConflicts:       *symConflictsFlag,
+		emitClusters:       *emitClustersFlag,
+		impact:             *impactFlag,
+		jsonCompact:        *jsonCompactFlag,
+		graphMaxNodes:      *graphMaxNodesFlag,
+		graphReduce:        *graphReduceFlag,
+		graphKinds:         *graphKindsFlag,
+		graphCalls:         *graphCallsFlag,
+		graphCache:         *graphCacheFlag,
+		artifactCache:      *artifactCacheFlag,
+		noGraph:            *noGraphFlag,
+		regionDepth:        *regionDepthFlag,
+		regionMax:          *regionMaxFlag,
 		autoAnchors:        *autoAnchorsFlag,
 		autoAnchorsMin:     *autoAnchorsMinFlag,
 		autoAnchorsMax:     *autoAnchorsMaxFlag,
 		autoAnchorsImports: *autoAnchorsImportsFlag,
 		autoAnchorsTests:   *autoAnchorsTestsFlag,
 		autoAnchorsPrefix:  *autoAnchorsPrefixFlag,
+		autoAnchorsNoDup:   *autoAnchorsNoDupFlag,
 		srcDir:             filepath.Clean(fs.Arg(0)),
 	}
 	return cfg, nil
@@ -222,12 +700,14 @@ func parseFlags(args []string) (Config, error) {
 func buildOptions(cfg Config) (diff.Options, []string, error) {
 	opt := diff.Options{
 		MaxBytes:       cfg.maxDiffBytes,
+		MaxTotalBytes:  cfg.maxDiffTotal,
 		TimeoutSeconds: 5.0,
 		Context:        cfg.diffContext,
 		NoPrefix:       cfg.diffNoPrefix,
 		LineMode:       true,
+		FuncOnly:       cfg.diffFuncOnly,
 	}
-	langs := []string{"cpp", "cs", "go", "java", "kt", "py", "ts", "tsx"}
+	langs := []string{"c", "cpp", "cs", "go", "java", "kt", "objc", "py", "ts", "tsx"}
 	sort.Strings(langs)
 	return opt, langs, nil
 }
@@ -239,7 +719,12 @@ func selectMode(cfg Config) (string, error) {
 	if (zipMode && deltaMode) || (zipMode && chatMode) || (deltaMode && chatMode) {
 		return "", fmt.Errorf("-zip, -delta and -chat are mutually exclusive")
 	}
+	if cfg.serve && (zipMode || deltaMode || chatMode) {
+		return "", fmt.Errorf("-serve cannot be combined with -zip, -delta or -chat")
+	}
 	switch {
+	case cfg.serve:
+		return "serve", nil
 	case zipMode:
 		return "full", nil
 	case deltaMode:
@@ -256,19 +741,42 @@ func runFull(cfg Config, opt diff.Options, _ []string) error {
 	if err != nil {
 		return fmt.Errorf("collect files: %w", err)
 	}
+	files = withRepoReadme(cfg, files)
 	if len(files) == 0 {
-		fmt.Fprintln(os.Stderr, "No files matched filters.")
-		return nil
+		return noFilesMatched(cfg)
 	}
 
 	langHints := toSet(splitCSV(cfg.langHints))
 	applyAutoAnchorsConfig(cfg)
 
+	if cfg.artifactCache {
+		cacheDir, err := cacheDirFor(cfg)
+		if err != nil {
+			return err
+		}
+		index.SetArtifactCache(filepath.Join(cacheDir, index.ArtifactCacheDir), cfg.summarizerCmd)
+		defer index.SetArtifactCache("", "")
+	}
+
+	progress.Phase("index")
 	man, syms, slices, pointers := index.BuildArtifacts(cfg.srcDir, files, cfg.maxFileLines, langHints)
-	graphFiles := toGraphFiles(files)
-	g := graph.BuildFrom(graphFiles)
+	goMods := multiGoModules(cfg.srcDir, files)
+	graphFiles := toGraphFiles(files, goMods)
+	progress.Phase("graph")
+	g0, err := buildGraph(cfg, graphFiles, syms.Symbols)
+	if err != nil {
+		return err
+	}
+	g := graph.Truncate(g0, cfg.graphMaxNodes)
+	if cfg.graphKinds {
+		g.NodeKinds = graph.ClassifyNodes(g, goModulePaths(cfg.srcDir))
+	}
+	if cfg.impact {
+		applyImpact(&man, graph.FileImpact(g0, graph.DefaultImpactLimit))
+	}
 
 	meta.ApplyToManifest(meta.Detect(cfg.srcDir), &man)
+	meta.ApplyGoModules(goMods, &man, &syms)
 	if cfg.validateJSON {
 		if err := validate.Manifest(man); err != nil {
 			return fmt.Errorf("validate manifest: %w", err)
@@ -276,12 +784,87 @@ func runFull(cfg Config, opt diff.Options, _ []string) error {
 		if err := validate.Symbols(syms); err != nil {
 			return fmt.Errorf("validate symbols: %w", err)
 		}
+		if cfg.validateStrict {
+			if err := validate.CrossCheck(man, syms, slices, pointers); err != nil {
+				return fmt.Errorf("validate strict: %w", err)
+			}
+		}
 	}
 
-	srcFiles := pickIndexedFiles(cfg.emitSrc, files, man)
-	if err := bundle.WriteFull(cfg.zipOut, cfg.srcDir, srcFiles, man, syms, slices, pointers, g, cfg.emitSrc, cfg.benchPath, opt.Context, opt.NoPrefix); err != nil {
+	var stats *index.Stats
+	if cfg.emitStats {
+		st := index.BuildStats(man)
+		stats = &st
+	}
+	var hygiene *index.Hygiene
+	if cfg.emitHygiene {
+		hy := index.BuildHygiene(man)
+		hygiene = &hy
+	}
+	var clusters *graph.Clusters
+	if cfg.emitClusters {
+		cl := graph.Cluster(g)
+		clusters = &cl
+	}
+	var deps *meta.Dependencies
+	if cfg.emitDeps {
+		deps = meta.DetectDependencies(cfg.srcDir)
+	}
+	var conflicts *index.SymbolConflicts
+	if cfg.symConflicts > 0 {
+		sc := index.FindSymbolConflicts(syms.Symbols, cfg.symConflicts)
+		conflicts = &sc
+	}
+	var reduced *graph.Graph
+	switch cfg.graphReduce {
+	case graphReduceAlongside:
+		rg := graph.TransitiveReduction(g)
+		reduced = &rg
+	case graphReduceReplace:
+		g = graph.TransitiveReduction(g)
+	}
+
+	cfg.zipOut = resolveOutPath(cfg.zipOut, cfg.outNameTmpl, man.Module, man.BundleID)
+	if cfg.skipUnchanged {
+		man.OptionsFingerprint = optionsFingerprint(cfg)
+	}
+	if cfg.skipUnchanged && bundleUnchanged(cfg.zipOut, man) {
+		fmt.Printf("Bundle %s unchanged (bundle_id=%s); not rewritten\n", cfg.zipOut, man.BundleID)
+		return nil
+	}
+	srcFiles := pickIndexedFiles(cfg.emitSrc, srcGlobFilter(cfg.emitSrcFilter), files, man)
+	srcArchive := ""
+	if cfg.emitSrcSep {
+		srcArchive = srcArchivePath(cfg.zipOut, cfg.outFormat)
+		man.SrcArchive = filepath.Base(srcArchive)
+	}
+	progress.Phase("write")
+	art := bundle.FullArtifacts{
+		Artifacts: index.Artifacts{Manifest: man, Symbols: syms, Slices: slices, Pointers: pointers, Graph: g},
+		Stats:     stats,
+		Clusters:  clusters,
+		Deps:      deps,
+		Conflicts: conflicts,
+		Reduced:   reduced,
+		Hygiene:   hygiene,
+	}
+	if !cfg.emitSrcSep {
+		art.Sources = srcFiles
+	}
+	if err := bundle.WriteFull(cfg.zipOut, art, cfg.benchPath, opt.Context, opt.NoPrefix); err != nil {
 		return fmt.Errorf("write full bundle: %w", err)
 	}
+	if err := reportArchiveHash(cfg.zipOut, cfg.writeSHA256); err != nil {
+		return err
+	}
+	if srcArchive != "" {
+		if err := bundle.WriteSources(srcArchive, srcFiles); err != nil {
+			return fmt.Errorf("write source archive: %w", err)
+		}
+		if err := reportArchiveHash(srcArchive, cfg.writeSHA256); err != nil {
+			return err
+		}
+	}
 	if err := persistSnapshotOnFull(cfg, man); err != nil {
 		return err
 	}
@@ -295,13 +878,23 @@ func runDelta(cfg Config, opt diff.Options) error {
 	if cfg.maxBytes > 0 {
 		fmt.Fprintln(os.Stderr, "Note: ignoring -max-bytes in -delta mode")
 	}
+	gitBase := ""
+	if cfg.deltaGit != "" {
+		// Both revisions are exported and walked like a working tree; the
+		// cache is neither read nor updated, and blobs are not stored.
+		from, to, cleanup, err := exportGitRange(cfg.srcDir, cfg.deltaGit)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		gitBase, cfg.srcDir, cfg.storeBlobs = from, to, false
+	}
 	files, err := collectFiles(cfg, 0)
 	if err != nil {
 		return fmt.Errorf("collect files: %w", err)
 	}
 	if len(files) == 0 {
-		fmt.Fprintln(os.Stderr, "No files matched filters.")
-		return nil
+		return noFilesMatched(cfg)
 	}
 
 	cacheDir, err := cacheDirFor(cfg)
@@ -314,43 +907,64 @@ func runDelta(cfg Config, opt diff.Options) error {
 		}
 	}
 
+	progress.Phase("snapshot")
 	curr, err := buildSnapshot(cfg, files)
 	if err != nil {
 		return err
 	}
 
-	prev, err := cache.Load(cacheDir)
+	var prev *cache.Snapshot
+	var readOld func(string) ([]byte, error)
+	if gitBase != "" {
+		prev, readOld, err = gitBaseline(cfg, gitBase)
+	} else {
+		prev, readOld, err = deltaBaseline(cfg, cacheDir, curr.Module)
+	}
 	if err != nil {
-		return fmt.Errorf("load snapshot: %w", err)
+		return err
 	}
-	if prev == nil {
-		prev = &cache.Snapshot{Module: curr.Module}
+	if err := checkBaseModule(cfg.deltaModCheck, prev.Module, curr.Module); err != nil {
+		return err
 	}
 
 	cache.SetRenameSimilarity(cfg.renameSimilarity, cfg.renameSimThresh)
+	cache.SetRenameSimilarityPercent(cfg.renameSimPct)
 	if cfg.renameSimilarity && cfg.renameSimOldRoot != "" {
-		cache.SetContentProvider(dualFS{oldRoot: cfg.renameSimOldRoot, newRoot: cfg.srcDir})
+		cache.SetContentProvider(dualFS{oldRoot: cfg.renameSimOldRoot, newFiles: absPaths(files)})
 	}
 
-	delta := cache.BuildDelta(prev, curr)
-	readOld := func(hash string) ([]byte, error) {
-		if len(hash) < 6 {
-			return nil, fs.ErrNotExist
-		}
-		return cache.ReadBlob(cacheDir, hash)
+	delta, filtered := cache.FilterDelta(cache.BuildDelta(prev, curr), langFilter(cfg.deltaLangs))
+	if cfg.deltaSummary {
+		return writeDeltaSummary(cfg, cacheDir, prev, curr, delta, filtered)
 	}
+	progress.Phase("diff")
 	diffs, err := bundle.MakeDiffs(delta, files, opt, readOld)
 	if err != nil {
 		return fmt.Errorf("build diffs: %w", err)
 	}
 
-	indexPayload := makeDeltaIndex(prev, curr, delta)
+	indexPayload := makeDeltaIndex(prev, curr, delta, filtered)
+	cfg.deltaOut = resolveOutPath(cfg.deltaOut, cfg.outNameTmpl, curr.Module, snapshotBundleID(curr))
 	addedFiles := gatherAddedFiles(files, delta.Added)
-	if err := bundle.WriteDelta(cfg.deltaOut, indexPayload, diffs, addedFiles, cfg.benchPath, opt.Context, opt.NoPrefix, opt.MaxBytes); err != nil {
+	progress.Phase("write")
+	var renameReport []cache.RenameCandidate
+	if cfg.renameReport {
+		renameReport = append([]cache.RenameCandidate{}, delta.RenameReport...)
+		if cfg.verbose {
+			printRenameReport(renameReport)
+		}
+	}
+	art := bundle.DeltaArtifacts{Index: indexPayload, Diffs: diffs, Added: addedFiles, RenameReport: renameReport, Current: deltaCurrent(cfg, curr)}
+	if err := bundle.WriteDelta(cfg.deltaOut, art, cfg.benchPath, opt); err != nil {
 		return fmt.Errorf("write delta bundle: %w", err)
 	}
-	if err := cache.Save(cacheDir, curr); err != nil {
-		return fmt.Errorf("save snapshot: %w", err)
+	if err := reportArchiveHash(cfg.deltaOut, cfg.writeSHA256); err != nil {
+		return err
+	}
+	if cfg.deltaAgainstFull == "" && cfg.deltaGit == "" {
+		if err := cache.Save(cacheDir, curr); err != nil {
+			return fmt.Errorf("save snapshot: %w", err)
+		}
 	}
 
 	fmt.Printf("Wrote delta bundle %s (added=%d, removed=%d, changed=%d, renamed=%d, oversize=%d)\n",
@@ -358,77 +972,382 @@ func runDelta(cfg Config, opt diff.Options) error {
 	return nil
 }
 
+// writeDeltaSummary finishes a -delta-summary-only run: the archive holds the
+// delta index and SUMMARY.md only, so no diffs are generated.
+func writeDeltaSummary(cfg Config, cacheDir string, prev, curr *cache.Snapshot, delta cache.Delta, filtered int) error {
+	indexPayload := makeDeltaIndex(prev, curr, delta, filtered)
+	cfg.deltaOut = resolveOutPath(cfg.deltaOut, cfg.outNameTmpl, curr.Module, snapshotBundleID(curr))
+	progress.Phase("write")
+	if err := bundle.WriteDeltaSummary(cfg.deltaOut, indexPayload, deltaCurrent(cfg, curr)); err != nil {
+		return fmt.Errorf("write delta summary: %w", err)
+	}
+	if err := reportArchiveHash(cfg.deltaOut, cfg.writeSHA256); err != nil {
+		return err
+	}
+	if cfg.deltaAgainstFull == "" && cfg.deltaGit == "" {
+		if err := cache.Save(cacheDir, curr); err != nil {
+			return fmt.Errorf("save snapshot: %w", err)
+		}
+	}
+	fmt.Printf("Wrote delta summary %s (added=%d, removed=%d, changed=%d, renamed=%d)\n",
+		cfg.deltaOut, len(delta.Added), len(delta.Removed), len(delta.Changed), len(delta.Renamed))
+	return nil
+}
+
+// applyImpact copies per-file impact scores into the manifest.
+func applyImpact(man *index.Manifest, scores map[string]int) {
+	for i := range man.Files {
+		man.Files[i].Impact = scores[man.Files[i].Path]
+	}
+}
+
+// deltaBaseline returns the "prev" snapshot and old-content reader for a
+// DELTA run: the FULL bundle given by -delta-against-full, or else the cached
+// snapshot and blobs (an empty snapshot when none exists yet).
+func deltaBaseline(cfg Config, cacheDir, module string) (*cache.Snapshot, func(string) ([]byte, error), error) {
+	if cfg.deltaAgainstFull != "" {
+		base, err := bundle.Open(cfg.deltaAgainstFull)
+		if err != nil {
+			return nil, nil, fmt.Errorf("open -delta-against-full bundle: %w", err)
+		}
+		return base.Snapshot(), base.ReadOld(), nil
+	}
+	prev, err := cache.Load(cacheDir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load snapshot: %w", err)
+	}
+	if prev == nil {
+		prev = &cache.Snapshot{Module: module}
+	}
+	readOld := func(hash string) ([]byte, error) {
+		if len(hash) < 6 {
+			return nil, fs.ErrNotExist
+		}
+		return cache.ReadBlob(cacheDir, hash)
+	}
+	return prev, readOld, nil
+}
+
+// -delta-base-module-check modes.
+const (
+	moduleCheckOff   = "off"
+	moduleCheckWarn  = "warn"  // record a config warning and go on
+	moduleCheckError = "error" // refuse to build the delta
+)
+
+// checkBaseModule compares the module of the delta baseline with the current
+// one: a mismatch means the cache (or -delta-against-full bundle) belongs to
+// another project or a renamed module, and the delta would be meaningless.
+func checkBaseModule(mode, prev, curr string) error {
+	if mode == moduleCheckOff || prev == "" || prev == curr {
+		return nil
+	}
+	msg := fmt.Sprintf("baseline snapshot is for module %q, current tree is %q; rerun with -new to reset the cache", prev, curr)
+	if mode == moduleCheckError {
+		return errors.New(msg)
+	}
+	warn.Add(warn.KindConfig, "", "%s", msg)
+	fmt.Fprintln(os.Stderr, "Warning: "+msg)
+	return nil
+}
+
 func runChat(cfg Config, _ diff.Options) error {
+	order, err := readChatOrder(cfg.chatOrderFile)
+	if err != nil {
+		return err
+	}
+	bundle.SetChatOrder(order)
 	files, err := collectFiles(cfg, cfg.maxBytes)
 	if err != nil {
 		return fmt.Errorf("collect files: %w", err)
 	}
+	files = withRepoReadme(cfg, files)
 	if len(files) == 0 {
-		fmt.Fprintln(os.Stderr, "No files matched filters.")
-		return nil
+		return noFilesMatched(cfg)
 	}
 
 	langHints := toSet(splitCSV(cfg.langHints))
 	applyAutoAnchorsConfig(cfg)
 
+	progress.Phase("index")
 	man, syms, _, _ := index.BuildArtifacts(cfg.srcDir, files, cfg.maxFileLines, langHints)
-	graphFiles := toGraphFiles(files)
-	g := graph.BuildFrom(graphFiles)
+	if cfg.chatOverview {
+		meta.ApplyToManifest(meta.Detect(cfg.srcDir), &man)
+	}
+	graphFiles := toGraphFiles(files, multiGoModules(cfg.srcDir, files))
+	progress.Phase("graph")
+	g, err := buildGraph(cfg, graphFiles, syms.Symbols)
+	if err != nil {
+		return err
+	}
 
-	srcFiles := pickIndexedFiles(true, files, man)
-	if err := bundle.WriteChat(cfg.chatOut, man, srcFiles, syms, g, cfg.chatMaxClasses, cfg.chatMaxChars, cfg.benchPath); err != nil {
+	cfg.chatOut = resolveOutPath(cfg.chatOut, cfg.outNameTmpl, man.Module, man.BundleID)
+	srcFiles := pickIndexedFiles(true, nil, files, man)
+	prompt, err := resolveChatPrompt(cfg.chatSysPrompt)
+	if err != nil {
+		return err
+	}
+	progress.Phase("write")
+	if err := bundle.WriteChat(cfg.chatOut, man, srcFiles, syms, g, bundle.ChatOptions{
+		MaxClasses:   cfg.chatMaxClasses,
+		MaxChars:     cfg.chatMaxChars,
+		BenchPath:    cfg.benchPath,
+		SystemPrompt: prompt,
+		Overview:     cfg.chatOverview,
+		Graph:        cfg.chatGraph,
+		MaxMessages:  cfg.chatMaxMsgs,
+		Overflow:     cfg.chatOverflow,
+	}); err != nil {
 		return fmt.Errorf("write chat bundle: %w", err)
 	}
+	if err := reportArchiveHash(cfg.chatOut, cfg.writeSHA256); err != nil {
+		return err
+	}
 	fmt.Printf("Wrote chat bundle %s (files=%d)\n", cfg.chatOut, len(man.Files))
+	if cfg.chatRedact {
+		fmt.Printf("Wrote path map %s (keep it out of shared copies)\n", bundle.PathMapFor(cfg.chatOut))
+	}
 	return nil
 }
 
 // ------------- helpers -------------
 
 func collectFiles(cfg Config, totalBudget int64) ([]walkwalk.FileInfo, error) {
-	exts := toSet(splitCSV(cfg.exts))
 	exclude := toSet(splitCSV(cfg.exclude))
-	includes := splitCSV(cfg.include)
-	files, _, err := walkwalk.CollectFiles(
-		cfg.srcDir,
-		exts,
-		exclude,
-		includes,
-		totalBudget,
-		cfg.maxFileBytes,
-		cfg.useGitignore,
-		cfg.followSymlinks,
-	)
+	for _, name := range configNames {
+		exclude["/"+name] = struct{}{} // project config, not project source
+	}
+	progress.Phase("collect")
+	files, _, err := walkwalk.CollectFiles(cfg.srcDir, walkwalk.Options{
+		Exts:             toSet(splitCSV(cfg.exts)),
+		Exclude:          exclude,
+		Includes:         splitCSV(cfg.include),
+		MaxBytes:         totalBudget,
+		MaxFileBytes:     cfg.maxFileBytes,
+		UseGitignore:     cfg.useGitignore,
+		GlobalGitignore:  cfg.globalIgnore,
+		FollowSymlinks:   cfg.followSymlinks,
+		SkipSubmodules:   cfg.submodules == "skip",
+		RecordSymlinks:   cfg.keepSymlinks,
+		CaseSensitiveExt: cfg.extCaseSens,
+		ModifiedSince:    cfg.modifiedSince,
+		DedupHardlinks:   cfg.dedupLinks,
+		DedupContent:     cfg.dedupContent,
+		Keep:             keepRoles(roleFilter(cfg.excludeRoles, cfg.onlyRoles)),
+	})
 	if err != nil {
 		return nil, err
 	}
 	return files, nil
 }
 
+// useBundleIDAlgo installs -bundle-id-algo, resolving the HEAD commit of
+// srcDir for module+git (with a warning when there is none).
+func useBundleIDAlgo(cfg Config) {
+	commit := ""
+	if cfg.bundleIDAlgo == index.BundleIDModuleGit {
+		if commit = meta.GitCommit(cfg.srcDir); commit == "" {
+			warn.Add(warn.KindConfig, "", "-bundle-id-algo module+git: no git commit found in the source tree")
+		}
+	}
+	index.SetBundleIDAlgo(cfg.bundleIDAlgo, commit)
+}
+
+// -graph-reduce modes.
+const (
+	graphReduceAlongside = "alongside" // write graph.reduced.json next to graph.json
+	graphReduceReplace   = "replace"   // write the reduced graph as graph.json
+)
+
+// summarizerTimeout bounds each -summarizer-cmd run; slower files get no
+// summary.
+const summarizerTimeout = 30 * time.Second
+
+// errNoFiles is returned under -fail-on-empty when the filters match nothing.
+var errNoFiles = errors.New("no files matched filters")
+
+// noFilesMatched reports an empty collection: a note on stderr and success by
+// default, errNoFiles under -fail-on-empty.
+func noFilesMatched(cfg Config) error {
+	if cfg.failOnEmpty {
+		return fmt.Errorf("%w (-fail-on-empty)", errNoFiles)
+	}
+	fmt.Fprintln(os.Stderr, "No files matched filters.")
+	return nil
+}
+
+// withRepoReadme, under -repo-readme-first, adds the top-level README to files
+// when the filters left it out and registers it with bundle.SetRepoReadme.
+func withRepoReadme(cfg Config, files []walkwalk.FileInfo) []walkwalk.FileInfo {
+	bundle.SetRepoReadme("")
+	if !cfg.readmeFirst {
+		return files
+	}
+	readme, ok := walkwalk.RepoReadme(cfg.srcDir)
+	if !ok {
+		return files
+	}
+	bundle.SetRepoReadme(readme.RelPath)
+	for _, f := range files {
+		if f.RelPath == readme.RelPath {
+			return files
+		}
+	}
+	files = append(files, readme)
+	sort.Slice(files, func(i, j int) bool { return files[i].RelPath < files[j].RelPath })
+	return files
+}
+
+// keepRoles adapts a role predicate to walkwalk.Options.Keep, so files are
+// classified while walking, before they count against -max-bytes.
+// Unreadable files are dropped; a nil predicate keeps everything.
+func keepRoles(keep func(string) bool) func(walkwalk.FileInfo) bool {
+	if keep == nil {
+		return nil
+	}
+	return func(f walkwalk.FileInfo) bool {
+		abs := f.AbsPath
+		if f.Symlink != "" {
+			abs = "" // never read through an unfollowed link
+		}
+		role, err := index.ClassifyFileRole(f.RelPath, abs)
+		return err == nil && keep(role)
+	}
+}
+
 func applyAutoAnchorsConfig(cfg Config) {
 	index.SetAutoAnchorsConfig(index.AutoAnchorConfig{
-		Enabled:        cfg.autoAnchors,
-		MinLines:       cfg.autoAnchorsMin,
-		MaxPerFile:     cfg.autoAnchorsMax,
-		IncludeImports: cfg.autoAnchorsImports,
-		IncludeTests:   cfg.autoAnchorsTests,
-		Prefix:         cfg.autoAnchorsPrefix,
+		Enabled:           cfg.autoAnchors,
+		MinLines:          cfg.autoAnchorsMin,
+		MaxPerFile:        cfg.autoAnchorsMax,
+		IncludeImports:    cfg.autoAnchorsImports,
+		IncludeTests:      cfg.autoAnchorsTests,
+		Prefix:            cfg.autoAnchorsPrefix,
+		SuppressContained: cfg.autoAnchorsNoDup,
 	})
 }
 
-func toGraphFiles(files []walkwalk.FileInfo) []graph.File {
+// toGraphFiles converts walker output for graph.BuildFrom. With goMods set,
+// Go graph nodes are labeled by their module-relative import path; the
+// manifest package and symbol names keep the Go package clause.
+func toGraphFiles(files []walkwalk.FileInfo, goMods []index.GoModule) []graph.File {
 	out := make([]graph.File, 0, len(files))
 	for _, f := range files {
-		out = append(out, graph.File{
+		if f.Symlink != "" {
+			continue
+		}
+		gf := graph.File{
 			RelPath: f.RelPath,
 			AbsPath: f.AbsPath,
 			Ext:     f.Ext,
-		})
+			Hash:    f.SHA256Hex,
+		}
+		if f.Ext == ".go" {
+			gf.GoPkg = index.GoImportPath(goMods, f.RelPath)
+		}
+		out = append(out, gf)
+	}
+	return out
+}
+
+// buildGraph builds the import graph, going through the on-disk import cache
+// when -graph-cache is set, and adds call edges between syms with
+// -graph-calls. With -no-graph nothing is scanned and the graph is empty
+// (non-nil slices, so graph.json reads {"nodes":[],"edges":[]}).
+func buildGraph(cfg Config, files []graph.File, syms []index.Symbol) (graph.Graph, error) {
+	if cfg.noGraph {
+		return graph.Graph{Nodes: []string{}, Edges: [][2]string{}}, nil
+	}
+	var g graph.Graph
+	if !cfg.graphCache {
+		g = graph.BuildFrom(files)
+	} else {
+		cacheDir, err := cacheDirFor(cfg)
+		if err != nil {
+			return graph.Graph{}, err
+		}
+		path := filepath.Join(cacheDir, graph.ImportCacheFile)
+		ic := graph.LoadImportCache(path)
+		g, _ = graph.BuildCached(files, ic)
+		if err := ic.Save(path); err != nil {
+			return graph.Graph{}, fmt.Errorf("save graph cache: %w", err)
+		}
+	}
+	if cfg.graphCalls {
+		g.Calls = graph.BuildCalls(files, callDefs(syms))
+	}
+	return g, nil
+}
+
+// callDefs returns the method, function and constructor symbols of syms.
+func callDefs(syms []index.Symbol) []graph.Def {
+	var defs []graph.Def
+	for _, s := range syms {
+		if s.Kind == "method" || s.Kind == "func" || s.Kind == "ctor" {
+			defs = append(defs, graph.Def{Symbol: s.Symbol, Path: s.Path, Start: s.Start, End: s.End})
+		}
+	}
+	return defs
+}
+
+// multiGoModules returns the go.mod boundaries under srcDir that are the
+// nearest module of at least one collected file, so modules in excluded or
+// ignored directories do not count, when there are at least two (a
+// multi-module repo); single-module repos keep package-name labels.
+func multiGoModules(srcDir string, files []walkwalk.FileInfo) []index.GoModule {
+	mods := meta.DetectGoModules(srcDir)
+	used := make(map[string]bool, len(mods))
+	for _, f := range files {
+		if m, ok := index.EnclosingGoModule(mods, f.RelPath); ok {
+			used[m.Dir] = true
+		}
+	}
+	var out []index.GoModule
+	for _, m := range mods {
+		if used[m.Dir] {
+			out = append(out, m)
+		}
+	}
+	if len(out) < 2 {
+		return nil
 	}
 	return out
 }
 
-func pickIndexedFiles(includeAll bool, files []walkwalk.FileInfo, man index.Manifest) []fileRef {
+// goModulePaths returns the module paths of every go.mod under srcDir, which
+// graph.ClassifyNodes treats as internal import path prefixes.
+func goModulePaths(srcDir string) []string {
+	var paths []string
+	for _, m := range meta.DetectGoModules(srcDir) {
+		if m.Path != "" {
+			paths = append(paths, m.Path)
+		}
+	}
+	return paths
+}
+
+// srcArchivePath returns the sibling sources archive for a FULL bundle path
+// in the given -out-format: "out/app.zip" becomes "out/app.src.zip",
+// "out/app.tar.gz" "out/app.src.tgz" and the directory "out/app" "out/app.src".
+func srcArchivePath(path, format string) string {
+	base := path
+	for _, ext := range []string{".zip", ".tgz", ".tar.gz"} {
+		if strings.HasSuffix(strings.ToLower(base), ext) {
+			base = base[:len(base)-len(ext)]
+			break
+		}
+	}
+	switch format {
+	case bundle.OutFormatTgz:
+		return base + ".src.tgz"
+	case bundle.OutFormatDir:
+		return base + ".src"
+	default:
+		return base + ".src.zip"
+	}
+}
+
+func pickIndexedFiles(includeAll bool, keep func(string) bool, files []walkwalk.FileInfo, man index.Manifest) []fileRef {
 	if !includeAll {
 		return nil
 	}
@@ -438,7 +1357,7 @@ func pickIndexedFiles(includeAll bool, files []walkwalk.FileInfo, man index.Mani
 	}
 	out := make([]fileRef, 0, len(indexed))
 	for _, f := range files {
-		if _, ok := indexed[f.RelPath]; ok {
+		if _, ok := indexed[f.RelPath]; ok && f.Symlink == "" && (keep == nil || keep(f.RelPath)) {
 			out = append(out, fileRef{RelPath: f.RelPath, AbsPath: f.AbsPath})
 		}
 	}
@@ -461,6 +1380,9 @@ func persistSnapshotOnFull(cfg Config, man index.Manifest) error {
 		Files:         make([]cache.SnapFile, 0, len(man.Files)),
 	}
 	for _, f := range man.Files {
+		if f.Kind == "symlink" {
+			continue // delta snapshots only track regular files
+		}
 		snap.Files = append(snap.Files, cache.SnapFile{
 			Path:  f.Path,
 			Hash:  f.Hash,
@@ -481,6 +1403,15 @@ func cacheDirFor(cfg Config) (string, error) {
 	return cache.CacheDir(cfg.tmpDir, srcAbs), nil
 }
 
+// deltaCurrent returns the snapshot to embed as the DELTA current file list, or
+// nil unless -delta-include-unchanged-manifest is set.
+func deltaCurrent(cfg Config, curr *cache.Snapshot) *cache.Snapshot {
+	if !cfg.deltaCurrMan {
+		return nil
+	}
+	return curr
+}
+
 func buildSnapshot(cfg Config, files []walkwalk.FileInfo) (*cache.Snapshot, error) {
 	snap := &cache.Snapshot{
 		Module:        filepath.Base(cfg.srcDir),
@@ -494,11 +1425,15 @@ func buildSnapshot(cfg Config, files []walkwalk.FileInfo) (*cache.Snapshot, erro
 		return nil, err
 	}
 	for _, f := range files {
+		progress.Tick()
+		if f.Symlink != "" {
+			continue
+		}
 		data, err := os.ReadFile(f.AbsPath)
 		if err != nil {
 			continue
 		}
-		lines := 1 + bytes.Count(data, []byte("\n"))
+		lines := index.CountLines(data)
 		snap.Files = append(snap.Files, cache.SnapFile{
 			Path:  f.RelPath,
 			Hash:  f.SHA256Hex,
@@ -514,11 +1449,12 @@ func buildSnapshot(cfg Config, files []walkwalk.FileInfo) (*cache.Snapshot, erro
 	return snap, nil
 }
 
-func makeDeltaIndex(prev, curr *cache.Snapshot, delta cache.Delta) any {
+func makeDeltaIndex(prev, curr *cache.Snapshot, delta cache.Delta, filtered int) any {
 	type renamedEntry struct {
-		From string `json:"from"`
-		To   string `json:"to"`
-		Hash string `json:"hash"`
+		From       string `json:"from"`
+		To         string `json:"to"`
+		Hash       string `json:"hash"`
+		HashBefore string `json:"hashBefore,omitempty"`
 	}
 	type changedEntry struct {
 		Path       string `json:"path"`
@@ -529,7 +1465,7 @@ func makeDeltaIndex(prev, curr *cache.Snapshot, delta cache.Delta) any {
 	}
 	renamed := make([]renamedEntry, 0, len(delta.Renamed))
 	for _, r := range delta.Renamed {
-		renamed = append(renamed, renamedEntry{From: r.From, To: r.To, Hash: r.Hash})
+		renamed = append(renamed, renamedEntry{From: r.From, To: r.To, Hash: r.Hash, HashBefore: r.HashBefore})
 	}
 	changed := make([]changedEntry, 0, len(delta.Changed))
 	for _, c := range delta.Changed {
@@ -549,6 +1485,7 @@ func makeDeltaIndex(prev, curr *cache.Snapshot, delta cache.Delta) any {
 		Removed      []cache.SnapFile `json:"removed"`
 		Renamed      []renamedEntry   `json:"renamed"`
 		Changed      []changedEntry   `json:"changed"`
+		FilteredOut  int              `json:"filteredOut,omitempty"`
 	}{
 		BaseModule:   curr.Module,
 		BaseSnapshot: prev.Created,
@@ -557,7 +1494,63 @@ func makeDeltaIndex(prev, curr *cache.Snapshot, delta cache.Delta) any {
 		Removed:      append([]cache.SnapFile{}, delta.Removed...),
 		Renamed:      renamed,
 		Changed:      changed,
+		FilteredOut:  filtered,
+	}
+}
+
+// langFilter returns a predicate that keeps paths whose inferred language is
+// listed in csv. An empty list disables filtering (nil predicate).
+func langFilter(csv string) func(string) bool {
+	langs := toSet(splitCSV(strings.ToLower(csv)))
+	if len(langs) == 0 {
+		return nil
+	}
+	return func(p string) bool {
+		_, ok := langs[index.InferLangByExt(filepath.Ext(p))]
+		return ok
+	}
+}
+
+// roleFilter returns a predicate over file roles built from -exclude-role or
+// -only-role. With neither set it returns nil (no filtering).
+func roleFilter(exclude, only string) func(string) bool {
+	if roles := toSet(splitCSV(strings.ToLower(only))); len(roles) > 0 {
+		return func(role string) bool {
+			_, ok := roles[role]
+			return ok
+		}
 	}
+	if roles := toSet(splitCSV(strings.ToLower(exclude))); len(roles) > 0 {
+		return func(role string) bool {
+			_, drop := roles[role]
+			return !drop
+		}
+	}
+	return nil
+}
+
+// validateRoles rejects combining -exclude-role with -only-role and unknown
+// role names.
+func validateRoles(exclude, only string) error {
+	ex, on := splitCSV(strings.ToLower(exclude)), splitCSV(strings.ToLower(only))
+	if len(ex) > 0 && len(on) > 0 {
+		return fmt.Errorf("-exclude-role and -only-role are mutually exclusive")
+	}
+	for _, r := range append(ex, on...) {
+		switch r {
+		case index.RoleSource, index.RoleTest, index.RoleConfig, index.RoleDoc, index.RoleGenerated:
+		default:
+			return fmt.Errorf("unknown role %q (want source, test, config, doc or generated)", r)
+		}
+	}
+	return nil
+}
+
+// srcGlobFilter returns a predicate matching manifest paths against glob
+// (see walkwalk.GlobMatcher). An empty glob disables filtering (nil
+// predicate).
+func srcGlobFilter(glob string) func(string) bool {
+	return walkwalk.GlobMatcher(glob)
 }
 
 func gatherAddedFiles(files []walkwalk.FileInfo, added []cache.SnapFile) []fileRef {
@@ -594,6 +1587,176 @@ func countOversize(changed []struct {
 	return n
 }
 
+// readChatOrder reads -chat-order-file: one project-relative path per line;
+// blank lines and lines starting with '#' are skipped, and "./" prefixes and
+// backslashes are normalized. An empty name yields nil.
+func readChatOrder(name string) ([]string, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("read chat order file: %w", err)
+	}
+	var paths []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		paths = append(paths, strings.TrimPrefix(strings.ReplaceAll(line, "\\", "/"), "./"))
+	}
+	return paths, nil
+}
+
+// resolveChatPrompt maps -chat-system-prompt to message text: "default" selects
+// the built-in prompt, an existing file is read, anything else is used as-is.
+func resolveChatPrompt(v string) (string, error) {
+	switch strings.TrimSpace(v) {
+	case "":
+		return "", nil
+	case "default":
+		return bundle.DefaultChatSystemPrompt, nil
+	}
+	if fi, err := os.Stat(v); err == nil && fi.Mode().IsRegular() {
+		data, err := os.ReadFile(v)
+		if err != nil {
+			return "", fmt.Errorf("read chat system prompt: %w", err)
+		}
+		return string(data), nil
+	}
+	return v, nil
+}
+
+// resolveDeltaLayout parses -output-layout: inline JSON when v starts with
+// '{', otherwise the path of a JSON file. Empty selects the default layout.
+func resolveDeltaLayout(v string) (bundle.DeltaLayout, error) {
+	text := strings.TrimSpace(v)
+	if text != "" && !strings.HasPrefix(text, "{") {
+		data, err := os.ReadFile(text)
+		if err != nil {
+			return bundle.DeltaLayout{}, fmt.Errorf("read output layout: %w", err)
+		}
+		text = string(data)
+	}
+	return bundle.ParseDeltaLayout(text)
+}
+
+// resolveOutPath expands tmpl (if set) and places the result in the directory
+// of out. Supported placeholders: {module}, {bundleid}, {bundleid8}. Values are
+// sanitized so the expansion cannot introduce path separators.
+func resolveOutPath(out, tmpl, module, bundleID string) string {
+	if strings.TrimSpace(tmpl) == "" {
+		return out
+	}
+	return filepath.Join(filepath.Dir(out), expandOutName(tmpl, module, bundleID))
+}
+
+func expandOutName(tmpl, module, bundleID string) string {
+	id8 := bundleID
+	if len(id8) > 8 {
+		id8 = id8[:8]
+	}
+	r := strings.NewReplacer(
+		"{module}", sanitizeNamePart(module),
+		"{bundleid8}", sanitizeNamePart(id8),
+		"{bundleid}", sanitizeNamePart(bundleID),
+	)
+	name := sanitizeNamePart(r.Replace(tmpl))
+	if name == "" {
+		name = "bundle.zip"
+	}
+	return name
+}
+
+// sanitizeNamePart keeps [A-Za-z0-9._-] and maps anything else to '_'.
+func sanitizeNamePart(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return strings.Trim(b.String(), ".")
+}
+
+// bundleUnchanged reports whether the FULL bundle at path (in any
+// -out-format) was built from the same content with the same options as
+// man: its manifest records the same bundle ID and options fingerprint. A
+// missing or unreadable bundle counts as changed.
+func bundleUnchanged(path string, man index.Manifest) bool {
+	if man.BundleID == "" {
+		return false
+	}
+	entries, err := bundle.ReadEntries(path)
+	if err != nil {
+		return false
+	}
+	var prev index.Manifest
+	if err := json.Unmarshal(entries["manifest.json"], &prev); err != nil {
+		return false
+	}
+	return prev.BundleID == man.BundleID && prev.OptionsFingerprint == man.OptionsFingerprint
+}
+
+// optionsFingerprint hashes the tool and manifest versions with every option
+// that can change what a FULL bundle contains. Output paths, worker counts,
+// caches and console output are left out: they do not change the content.
+func optionsFingerprint(cfg Config) string {
+	cfg.zipOut, cfg.writeSHA256, cfg.skipUnchanged = "", false, false
+	cfg.maxWorkers, cfg.verbose, cfg.progress = 0, false, false
+	cfg.tmpDir, cfg.resetCache, cfg.graphCache, cfg.artifactCache = "", false, false, false
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\n%d\n%+v", index.ToolVersion, index.ManifestVersion, cfg)))
+	return hex.EncodeToString(sum[:])
+}
+
+// reportArchiveHash prints "sha256:<hex>  <path>" for the written archive to
+// stderr and, when sidecar is set, writes "<hex>  <name>" (sha256sum format)
+// to <path>.sha256.
+func reportArchiveHash(path string, sidecar bool) error {
+	if st, err := os.Stat(path); err == nil && st.IsDir() {
+		return nil // -out-format dir: there is no single archive to hash
+	}
+	sum, err := archiveSHA256(path)
+	if err != nil {
+		return fmt.Errorf("hash archive: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "sha256:%s  %s\n", sum, path)
+	if !sidecar {
+		return nil
+	}
+	line := sum + "  " + filepath.Base(path) + "\n"
+	if err := os.WriteFile(path+".sha256", []byte(line), 0o644); err != nil {
+		return fmt.Errorf("write sha256 file: %w", err)
+	}
+	return nil
+}
+
+func archiveSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// snapshotBundleID computes the manifest-style bundle ID for a snapshot.
+func snapshotBundleID(s *cache.Snapshot) string {
+	man := index.Manifest{Module: s.Module, Files: make([]index.ManFile, 0, len(s.Files))}
+	for _, f := range s.Files {
+		man.Files = append(man.Files, index.ManFile{Path: f.Path, Hash: f.Hash})
+	}
+	return index.ComputeBundleID(man)
+}
+
 func splitCSV(s string) []string {
 	if strings.TrimSpace(s) == "" {
 		return nil
@@ -608,6 +1771,15 @@ func splitCSV(s string) []string {
 	return out
 }
 
+// absPaths maps each file's project path to the path it was read from.
+func absPaths(files []walkwalk.FileInfo) map[string]string {
+	m := make(map[string]string, len(files))
+	for _, f := range files {
+		m[f.RelPath] = f.AbsPath
+	}
+	return m
+}
+
 func toSet(list []string) map[string]struct{} {
 	if len(list) == 0 {
 		return nil
@@ -1,8 +1,33 @@
 package main
 
 import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	"class-collector/internal/bundle"
+	"class-collector/internal/cache"
+	"class-collector/internal/diff"
+	"class-collector/internal/index"
+	"class-collector/internal/parallel"
+	"class-collector/internal/progress"
+	"class-collector/internal/walkwalk"
+	"class-collector/internal/warn"
 )
 
 func TestParseFlagsBasic(t *testing.T) {
@@ -32,6 +57,31 @@ func TestParseFlagsMissingSrcDir(t *testing.T) {
 	}
 }
 
+func TestParseFlagsSubmodules(t *testing.T) {
+	cfg, err := parseFlags([]string{"-zip", "out.zip", "."})
+	if err != nil {
+		t.Fatalf("parseFlags error: %v", err)
+	}
+	if cfg.submodules != "skip" {
+		t.Fatalf("default submodules got %q", cfg.submodules)
+	}
+	if _, err := parseFlags([]string{"-zip", "out.zip", "-submodules", "follow", "."}); err == nil {
+		t.Fatalf("expected error for invalid -submodules value")
+	}
+}
+
+func TestParseFlagsRenameSimPercentRange(t *testing.T) {
+	cfg, err := parseFlags([]string{"-delta", "d.zip", "-rename-sim-percent", "60", "."})
+	if err != nil || cfg.renameSimPct != 60 {
+		t.Fatalf("parseFlags = %d, %v; want 60", cfg.renameSimPct, err)
+	}
+	for _, bad := range []string{"-1", "101"} {
+		if _, err := parseFlags([]string{"-delta", "d.zip", "-rename-sim-percent", bad, "."}); err == nil {
+			t.Fatalf("expected error for -rename-sim-percent %s", bad)
+		}
+	}
+}
+
 func TestParseFlagsExtWithSpaces(t *testing.T) {
 	args := []string{"-zip", "out.zip", "-ext", ".go, .java , .py", "."}
 	cfg, err := parseFlags(args)
@@ -43,6 +93,145 @@ func TestParseFlagsExtWithSpaces(t *testing.T) {
 	}
 }
 
+func TestParseFlagsProjectConfig(t *testing.T) {
+	src := t.TempDir()
+	yaml := "# project defaults\n" +
+		"ext: [.go, .java]\n" +
+		"exclude:\n" +
+		"  - vendor\n" +
+		"  - 'gen' # generated\n" +
+		"diff-context: 9\n" +
+		"auto-anchors-prefix: \"a:\"\n"
+	projectConfig := filepath.Join(src, ".classcollector.yaml")
+	if err := os.WriteFile(projectConfig, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "a.go"), []byte("package a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := parseFlags([]string{"-diff-context", "2", "-zip", "x.zip", src})
+	if err != nil {
+		t.Fatalf("parseFlags error: %v", err)
+	}
+	if cfg.exts != ".go,.java" || cfg.exclude != "vendor,gen" || cfg.autoAnchorsPrefix != "a:" {
+		t.Fatalf("config values not applied: exts=%q exclude=%q prefix=%q", cfg.exts, cfg.exclude, cfg.autoAnchorsPrefix)
+	}
+	if cfg.diffContext != 2 {
+		t.Fatalf("-diff-context flag should win over config, got %d", cfg.diffContext)
+	}
+	// The config is not project source, even when its extension is collected.
+	cfg.exts += ",.yaml"
+	files, err := collectFiles(cfg, 0)
+	if err != nil {
+		t.Fatalf("collectFiles error: %v", err)
+	}
+	for _, f := range files {
+		if f.RelPath == ".classcollector.yaml" {
+			t.Fatalf("project config was collected: %+v", files)
+		}
+	}
+	if cfg, err = parseFlags([]string{"-config", "none", "-zip", "x.zip", src}); err != nil || cfg.diffContext != 4 {
+		t.Fatalf("-config none: diffContext = %d, err = %v", cfg.diffContext, err)
+	}
+
+	// A project config found in <src_dir> may not pick outputs or commands;
+	// the same file passed with -config may.
+	if err := os.WriteFile(projectConfig, []byte("summarizer-cmd: touch PWNED\nzip: full.zip\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseFlags([]string{"-zip", "x.zip", src}); err == nil || !strings.Contains(err.Error(), "summarizer-cmd") {
+		t.Fatalf("expected summarizer-cmd to be rejected from the project config, got %v", err)
+	}
+	cfg, err = parseFlags([]string{"-config", projectConfig, src})
+	if err != nil || cfg.zipOut != "full.zip" || cfg.summarizerCmd != "touch PWNED" {
+		t.Fatalf("-config: zip=%q summarizer=%q err=%v", cfg.zipOut, cfg.summarizerCmd, err)
+	}
+	// A mode flag replaces the configured mode instead of conflicting.
+	cfg, err = parseFlags([]string{"-config", projectConfig, "-delta", "d.zip", src})
+	if err != nil {
+		t.Fatalf("parseFlags -delta error: %v", err)
+	}
+	if mode, err := selectMode(cfg); err != nil || mode != "delta" || cfg.zipOut != "" {
+		t.Fatalf("mode = %q (%v), zipOut = %q", mode, err, cfg.zipOut)
+	}
+
+	jsonPath := filepath.Join(t.TempDir(), "cc.json")
+	jsonc := `{
+  // JSONC like tsconfig.json
+  "exclude": ["a", "b",],
+  "max-bytes": 1000,
+  "use-gitignore": false,
+}`
+	if err := os.WriteFile(jsonPath, []byte(jsonc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = parseFlags([]string{"-config", jsonPath, src})
+	if err != nil {
+		t.Fatalf("parseFlags -config json error: %v", err)
+	}
+	if cfg.exclude != "a,b" || cfg.maxBytes != 1000 || cfg.useGitignore || cfg.zipOut != "" {
+		t.Fatalf("json config: exclude=%q maxBytes=%d useGitignore=%v zip=%q", cfg.exclude, cfg.maxBytes, cfg.useGitignore, cfg.zipOut)
+	}
+
+	if err := os.WriteFile(jsonPath, []byte(`{"no-such-flag": 1}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseFlags([]string{"-config", jsonPath, src}); err == nil || !strings.Contains(err.Error(), "no-such-flag") {
+		t.Fatalf("expected unknown key error, got %v", err)
+	}
+}
+
+func TestInitWritesParsableTemplate(t *testing.T) {
+	src := t.TempDir()
+	cfg, err := parseFlags([]string{"-init", src})
+	if err != nil {
+		t.Fatalf("parseFlags -init error: %v", err)
+	}
+	path, err := writeConfigTemplate(cfg.srcDir, cfg.initConfig)
+	if err != nil {
+		t.Fatalf("writeConfigTemplate error: %v", err)
+	}
+	if _, err := writeConfigTemplate(cfg.srcDir, cfg.initConfig); err == nil {
+		t.Fatalf("expected -init to refuse overwriting %s", path)
+	}
+	// Uncommenting every setting must reproduce the defaults.
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if body, ok := strings.CutPrefix(line, "# "); ok && !strings.HasPrefix(body, "zip:") &&
+			!strings.HasPrefix(body, "delta:") && !strings.HasPrefix(body, "chat:") {
+			if key, _, ok := strings.Cut(body, ": "); ok && !strings.Contains(key, " ") {
+				line = body
+			}
+		}
+		lines = append(lines, line)
+	}
+	values, err := parseConfigYAML([]byte(strings.Join(lines, "\n")))
+	if err != nil {
+		t.Fatalf("template does not parse: %v", err)
+	}
+	if len(values) < 15 {
+		t.Fatalf("expected the template settings to parse, got %v", values)
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := parseFlags([]string{"-zip", "out.zip", src})
+	if err != nil {
+		t.Fatalf("parseFlags with uncommented template error: %v", err)
+	}
+	want, err := parseFlags([]string{"-zip", "out.zip", "-config", "none", src})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("uncommented template changed defaults:\n got %+v\nwant %+v", got, want)
+	}
+}
+
 func TestBuildOptionsAndLangs(t *testing.T) {
 	cfg := Config{maxDiffBytes: 123, diffContext: 5, diffNoPrefix: true}
 	opt, langs, err := buildOptions(cfg)
@@ -52,7 +241,7 @@ func TestBuildOptionsAndLangs(t *testing.T) {
 	if opt.MaxBytes != 123 || opt.Context != 5 || !opt.NoPrefix {
 		t.Fatalf("unexpected options: %+v", opt)
 	}
-	want := []string{"cpp", "cs", "go", "java", "kt", "py", "ts", "tsx"}
+	want := []string{"c", "cpp", "cs", "go", "java", "kt", "objc", "py", "ts", "tsx"}
 	if !reflect.DeepEqual(langs, want) {
 		t.Fatalf("langs mismatch: got %v want %v", langs, want)
 	}
@@ -78,3 +267,1371 @@ func TestSelectModeNoMode(t *testing.T) {
 		t.Fatalf("expected error when no mode is selected")
 	}
 }
+
+func TestExpandOutName(t *testing.T) {
+	got := expandOutName("{module}-{bundleid8}.zip", "github.com/acme/app", "0123456789abcdef")
+	if got != "github.com_acme_app-01234567.zip" {
+		t.Fatalf("expandOutName = %q", got)
+	}
+	if got := expandOutName("../{module}.zip", "x", ""); got != "_x.zip" {
+		t.Fatalf("traversal not sanitized: %q", got)
+	}
+	if got := resolveOutPath("out/full.zip", "", "m", "id"); got != "out/full.zip" {
+		t.Fatalf("empty template should keep path, got %q", got)
+	}
+}
+
+func TestRunFullWritesTemplatedPath(t *testing.T) {
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "a.go"), []byte("package a\n"), 0o644); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+	outDir := t.TempDir()
+	cfg, err := parseFlags([]string{"-zip", filepath.Join(outDir, "ignored.zip"), "-save-snapshot=false",
+		"-out-name-template", "{module}-{bundleid8}.zip", src})
+	if err != nil {
+		t.Fatalf("parseFlags error: %v", err)
+	}
+	opt, langs, _ := buildOptions(cfg)
+	if err := runFull(cfg, opt, langs); err != nil {
+		t.Fatalf("runFull error: %v", err)
+	}
+	matches, _ := filepath.Glob(filepath.Join(outDir, filepath.Base(src)+"-*.zip"))
+	if len(matches) != 1 {
+		t.Fatalf("expected templated bundle, got %v", matches)
+	}
+	if len(strings.TrimSuffix(strings.TrimPrefix(filepath.Base(matches[0]), filepath.Base(src)+"-"), ".zip")) != 8 {
+		t.Fatalf("unexpected bundle id part in %s", matches[0])
+	}
+}
+
+func TestSrcGlobFilter(t *testing.T) {
+	keep := srcGlobFilter("src/**/*.go")
+	for path, want := range map[string]bool{
+		"src/main.go":       true,
+		"src/pkg/util.go":   true,
+		"src/pkg/util.java": false,
+		"tools/gen.go":      false,
+	} {
+		if got := keep(path); got != want {
+			t.Fatalf("keep(%q) = %v, want %v", path, got, want)
+		}
+	}
+	if srcGlobFilter("  ") != nil {
+		t.Fatalf("empty glob should disable filtering")
+	}
+}
+
+func TestRunFullEmitSrcFilter(t *testing.T) {
+	src := t.TempDir()
+	for _, rel := range []string{"src/main.go", "src/pkg/util.go", "tools/gen.go"} {
+		p := filepath.Join(src, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(p, []byte("package x\n"), 0o644); err != nil {
+			t.Fatalf("write source: %v", err)
+		}
+	}
+	out := filepath.Join(t.TempDir(), "full.zip")
+	cfg, err := parseFlags([]string{"-zip", out, "-save-snapshot=false", "-emit-src", "-emit-src-filter", "src/**/*.go", src})
+	if err != nil {
+		t.Fatalf("parseFlags error: %v", err)
+	}
+	opt, langs, _ := buildOptions(cfg)
+	if err := runFull(cfg, opt, langs); err != nil {
+		t.Fatalf("runFull error: %v", err)
+	}
+
+	zr, err := zip.OpenReader(out)
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	defer zr.Close()
+	var copied []string
+	for _, f := range zr.File {
+		if strings.HasPrefix(f.Name, "src/") {
+			copied = append(copied, f.Name)
+		}
+	}
+	sort.Strings(copied)
+	if want := []string{"src/src/main.go", "src/src/pkg/util.go"}; !reflect.DeepEqual(copied, want) {
+		t.Fatalf("copied = %v, want %v", copied, want)
+	}
+	if man := readZipManifest(t, out); len(man.Files) != 3 {
+		t.Fatalf("manifest should list all files, got %d", len(man.Files))
+	}
+}
+
+func TestParseFlagsRoles(t *testing.T) {
+	if _, err := parseFlags([]string{"-zip", "out.zip", "-exclude-role", "test", "-only-role", "source", "."}); err == nil {
+		t.Fatalf("expected error for -exclude-role with -only-role")
+	}
+	if _, err := parseFlags([]string{"-zip", "out.zip", "-only-role", "sources", "."}); err == nil {
+		t.Fatalf("expected error for unknown role")
+	}
+}
+
+func TestRunFullOnlySourceRole(t *testing.T) {
+	src := t.TempDir()
+	for rel, body := range map[string]string{
+		"main.go":      "package main\n",
+		"main_test.go": "package main\n",
+		"config.yaml":  "key: value\n",
+	} {
+		if err := os.WriteFile(filepath.Join(src, rel), []byte(body), 0o644); err != nil {
+			t.Fatalf("write source: %v", err)
+		}
+	}
+	out := filepath.Join(t.TempDir(), "full.zip")
+	cfg, err := parseFlags([]string{"-zip", out, "-save-snapshot=false", "-only-role", "source", src})
+	if err != nil {
+		t.Fatalf("parseFlags error: %v", err)
+	}
+	opt, langs, _ := buildOptions(cfg)
+	if err := runFull(cfg, opt, langs); err != nil {
+		t.Fatalf("runFull error: %v", err)
+	}
+
+	man := readZipManifest(t, out)
+	if len(man.Files) != 1 || man.Files[0].Path != "main.go" || man.Files[0].Role != index.RoleSource {
+		t.Fatalf("expected source-only manifest, got %+v", man.Files)
+	}
+}
+
+func readZipManifest(t *testing.T, zipPath string) index.Manifest {
+	t.Helper()
+	zr, err := zip.OpenReader(zipPath)
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	defer zr.Close()
+	var man index.Manifest
+	for _, f := range zr.File {
+		if f.Name != "manifest.json" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open manifest: %v", err)
+		}
+		err = json.NewDecoder(rc).Decode(&man)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("decode manifest: %v", err)
+		}
+	}
+	return man
+}
+
+func TestRunFullWritesSHA256(t *testing.T) {
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "a.go"), []byte("package a\n"), 0o644); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+	out := filepath.Join(t.TempDir(), "full.zip")
+	cfg, err := parseFlags([]string{"-zip", out, "-save-snapshot=false", "-write-sha256", src})
+	if err != nil {
+		t.Fatalf("parseFlags error: %v", err)
+	}
+	opt, langs, _ := buildOptions(cfg)
+	if err := runFull(cfg, opt, langs); err != nil {
+		t.Fatalf("runFull error: %v", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("read archive: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	want := hex.EncodeToString(sum[:]) + "  full.zip\n"
+	got, err := os.ReadFile(out + ".sha256")
+	if err != nil {
+		t.Fatalf("read sha256 file: %v", err)
+	}
+	if string(got) != want {
+		t.Fatalf("sha256 file = %q, want %q", got, want)
+	}
+}
+
+func TestRunFullSkipIfUnchanged(t *testing.T) {
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "a.go"), []byte("package a\n"), 0o644); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+	out := filepath.Join(t.TempDir(), "full.tgz")
+	args := []string{"-zip", out, "-out-format", "tgz", "-save-snapshot=false", "-skip-if-unchanged", src}
+	run := func() string {
+		t.Helper()
+		cfg, err := parseFlags(args)
+		if err != nil {
+			t.Fatalf("parseFlags error: %v", err)
+		}
+		opt, langs, _ := buildOptions(cfg)
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		saved := os.Stdout
+		os.Stdout = w
+		runErr := runFull(cfg, opt, langs)
+		os.Stdout = saved
+		w.Close()
+		data, _ := io.ReadAll(r)
+		if runErr != nil {
+			t.Fatalf("runFull error: %v", runErr)
+		}
+		return string(data)
+	}
+
+	if got := run(); strings.Contains(got, "unchanged") {
+		t.Fatalf("first run reported unchanged: %q", got)
+	}
+	old := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(out, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if got := run(); !strings.Contains(got, "unchanged") {
+		t.Fatalf("second run output = %q, want unchanged", got)
+	}
+	if st, err := os.Stat(out); err != nil || !st.ModTime().Equal(old) {
+		t.Fatalf("archive was rewritten (mtime %v, err %v)", st.ModTime(), err)
+	}
+
+	// Same content, but an option that changes the bundle: rewrite.
+	args = append([]string{"-emit-src"}, args...)
+	if got := run(); strings.Contains(got, "unchanged") {
+		t.Fatalf("changed options reported unchanged: %q", got)
+	}
+	if st, _ := os.Stat(out); st.ModTime().Equal(old) {
+		t.Fatal("archive not rewritten after an option change")
+	}
+	if err := os.Chtimes(out, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if got := run(); !strings.Contains(got, "unchanged") {
+		t.Fatalf("rerun with the new options = %q, want unchanged", got)
+	}
+
+	if err := os.WriteFile(filepath.Join(src, "a.go"), []byte("package a\n\nfunc A() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := run(); strings.Contains(got, "unchanged") {
+		t.Fatalf("changed input reported unchanged: %q", got)
+	}
+	if st, _ := os.Stat(out); st.ModTime().Equal(old) {
+		t.Fatal("archive not rewritten after input change")
+	}
+}
+
+// runFullCapturingStderr runs a FULL build with args and returns what was
+// written to stderr, installing the progress reporter the way main does.
+func runFullCapturingStderr(t *testing.T, args ...string) string {
+	t.Helper()
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "a.go"), []byte("package a\n"), 0o644); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+	out := filepath.Join(t.TempDir(), "full.zip")
+	cfg, err := parseFlags(append(append([]string{"-zip", out, "-save-snapshot=false"}, args...), src))
+	if err != nil {
+		t.Fatalf("parseFlags error: %v", err)
+	}
+	opt, langs, _ := buildOptions(cfg)
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := os.Stderr
+	os.Stderr = w
+	useProgress(cfg.progress)
+	runErr := runFull(cfg, opt, langs)
+	progress.Done()
+	progress.Use(nil)
+	os.Stderr = saved
+	w.Close()
+	data, _ := io.ReadAll(r)
+	if runErr != nil {
+		t.Fatalf("runFull error: %v", runErr)
+	}
+	return string(data)
+}
+
+func TestRunFullProgressOnStderr(t *testing.T) {
+	got := runFullCapturingStderr(t, "-progress")
+	for _, want := range []string{"progress: collect\n", "progress: collect 1 files\n", "progress: index\n", "progress: graph\n", "progress: write\n", "progress: done\n"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("stderr missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Index(got, "progress: collect") > strings.Index(got, "progress: write") {
+		t.Fatalf("phases out of order:\n%s", got)
+	}
+
+	if got := runFullCapturingStderr(t, "-progress=false"); strings.Contains(got, "progress:") {
+		t.Fatalf("unexpected progress output without the flag:\n%s", got)
+	}
+	if got := runFullCapturingStderr(t); strings.Contains(got, "progress:") {
+		t.Fatalf("progress must be off by default when stderr is not a terminal:\n%s", got)
+	}
+}
+
+func TestRunDeltaAgainstFullBundle(t *testing.T) {
+	for _, emitSrc := range []bool{true, false} {
+		src := t.TempDir()
+		write := func(name, body string) {
+			t.Helper()
+			if err := os.WriteFile(filepath.Join(src, name), []byte(body), 0o644); err != nil {
+				t.Fatalf("write %s: %v", name, err)
+			}
+		}
+		write("a.go", "package a\n\nfunc A() int { return 1 }\n")
+		write("b.go", "package a\n\nfunc B() {}\n")
+
+		out := t.TempDir()
+		full := filepath.Join(out, "full.zip")
+		cfg, err := parseFlags([]string{"-zip", full, "-save-snapshot=false", "-emit-src=" + strconv.FormatBool(emitSrc), src})
+		if err != nil {
+			t.Fatalf("parseFlags error: %v", err)
+		}
+		opt, langs, _ := buildOptions(cfg)
+		if err := runFull(cfg, opt, langs); err != nil {
+			t.Fatalf("runFull error: %v", err)
+		}
+
+		write("a.go", "package a\n\nfunc A() int { return 2 }\n")
+		write("c.go", "package a\n\nfunc C() {}\n")
+		if err := os.Remove(filepath.Join(src, "b.go")); err != nil {
+			t.Fatal(err)
+		}
+
+		delta := filepath.Join(out, "delta.zip")
+		cfg, err = parseFlags([]string{"-delta", delta, "-delta-against-full", full, "-tmp-dir", filepath.Join(out, "cache"), src})
+		if err != nil {
+			t.Fatalf("parseFlags error: %v", err)
+		}
+		opt, _, _ = buildOptions(cfg)
+		if err := runDelta(cfg, opt); err != nil {
+			t.Fatalf("runDelta error: %v", err)
+		}
+
+		var idx struct {
+			Added   []struct{ Path string } `json:"added"`
+			Removed []struct{ Path string } `json:"removed"`
+			Changed []struct {
+				Path     string `json:"path"`
+				Diff     string `json:"diff"`
+				Oversize bool   `json:"oversize"`
+			} `json:"changed"`
+		}
+		if err := json.Unmarshal([]byte(readZipEntryString(t, delta, "delta.index.json")), &idx); err != nil {
+			t.Fatalf("decode delta index: %v", err)
+		}
+		if len(idx.Added) != 1 || idx.Added[0].Path != "c.go" || len(idx.Removed) != 1 || idx.Removed[0].Path != "b.go" {
+			t.Fatalf("emitSrc=%v: unexpected added/removed: %+v", emitSrc, idx)
+		}
+		if len(idx.Changed) != 1 || idx.Changed[0].Path != "a.go" || idx.Changed[0].Oversize == emitSrc {
+			t.Fatalf("emitSrc=%v: unexpected changed: %+v", emitSrc, idx.Changed)
+		}
+		patch := readZipEntryString(t, delta, idx.Changed[0].Diff)
+		if emitSrc && (!strings.Contains(patch, "-func A() int { return 1 }") || !strings.Contains(patch, "+func A() int { return 2 }")) {
+			t.Fatalf("expected a real diff against bundle src/, got:\n%s", patch)
+		}
+		if !emitSrc && !strings.Contains(patch, "diff omitted") {
+			t.Fatalf("expected oversize placeholder without src/, got:\n%s", patch)
+		}
+		if snap, _ := cache.Load(cache.CacheDir(filepath.Join(out, "cache"), mustAbs(t, src))); snap != nil {
+			t.Fatalf("-delta-against-full must not update the cache snapshot")
+		}
+	}
+}
+
+func readZipEntryString(t *testing.T, zipPath, name string) string {
+	t.Helper()
+	zr, err := zip.OpenReader(zipPath)
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", name, err)
+		}
+		defer rc.Close()
+		data, err := io.ReadAll(rc)
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		return string(data)
+	}
+	t.Fatalf("%s missing from %s", name, zipPath)
+	return ""
+}
+
+func mustAbs(t *testing.T, p string) string {
+	t.Helper()
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return abs
+}
+
+func TestRunFullEmitSrcCompressedWritesSiblingArchive(t *testing.T) {
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "a.go"), []byte("package a\n"), 0o644); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+	out := filepath.Join(t.TempDir(), "full.zip")
+	cfg, err := parseFlags([]string{"-zip", out, "-save-snapshot=false", "-emit-src-compressed", src})
+	if err != nil {
+		t.Fatalf("parseFlags error: %v", err)
+	}
+	opt, langs, _ := buildOptions(cfg)
+	if err := runFull(cfg, opt, langs); err != nil {
+		t.Fatalf("runFull error: %v", err)
+	}
+
+	entries := func(path string) []string {
+		t.Helper()
+		zr, err := zip.OpenReader(path)
+		if err != nil {
+			t.Fatalf("open %s: %v", path, err)
+		}
+		defer zr.Close()
+		var names []string
+		for _, f := range zr.File {
+			names = append(names, f.Name)
+		}
+		return names
+	}
+	for _, name := range entries(out) {
+		if strings.HasPrefix(name, "src/") {
+			t.Fatalf("main archive must not carry sources, found %s", name)
+		}
+	}
+	if man := readZipManifest(t, out); man.SrcArchive != "full.src.zip" {
+		t.Fatalf("manifest srcArchive = %q, want full.src.zip", man.SrcArchive)
+	}
+	if got := entries(filepath.Join(filepath.Dir(out), "full.src.zip")); !reflect.DeepEqual(got, []string{"src/a.go"}) {
+		t.Fatalf("source archive entries = %v, want [src/a.go]", got)
+	}
+}
+
+func TestSrcArchivePathFollowsOutFormat(t *testing.T) {
+	for _, tc := range []struct{ path, format, want string }{
+		{"out/app.zip", bundle.OutFormatZip, "out/app.src.zip"},
+		{"out/app.tar.gz", bundle.OutFormatTgz, "out/app.src.tgz"},
+		{"out/app.tgz", bundle.OutFormatTgz, "out/app.src.tgz"},
+		{"out/app", bundle.OutFormatDir, "out/app.src"},
+	} {
+		if got := srcArchivePath(tc.path, tc.format); got != tc.want {
+			t.Errorf("srcArchivePath(%q, %q) = %q, want %q", tc.path, tc.format, got, tc.want)
+		}
+	}
+}
+
+func TestRepoReadmeFirst(t *testing.T) {
+	src := t.TempDir()
+	for name, body := range map[string]string{
+		"README.md": "# Demo\n\nStart with app.\n",
+		"a.go":      "package a\n\nfunc A() {}\n",
+		"b.go":      "package a\n\nfunc B() {}\n",
+	} {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(body), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	defer bundle.SetRepoReadme("")
+
+	// -ext go filters README.md out; the option brings it back.
+	full := filepath.Join(t.TempDir(), "full.zip")
+	cfg, err := parseFlags([]string{"-zip", full, "-save-snapshot=false", "-ext", ".go", "-repo-readme-first", src})
+	if err != nil {
+		t.Fatalf("parseFlags error: %v", err)
+	}
+	opt, langs, _ := buildOptions(cfg)
+	if err := runFull(cfg, opt, langs); err != nil {
+		t.Fatalf("runFull error: %v", err)
+	}
+	if toc := readZipEntryString(t, full, "TOC.md"); !strings.HasPrefix(toc, "# TOC\n\n> **Start here:** `README.md` (row 1)") {
+		t.Fatalf("TOC does not lead with the repo README:\n%s", toc)
+	}
+
+	chat := filepath.Join(t.TempDir(), "chat.zip")
+	cfg, err = parseFlags([]string{"-chat", chat, "-ext", ".go", "-repo-readme-first", src})
+	if err != nil {
+		t.Fatalf("parseFlags error: %v", err)
+	}
+	opt, _, _ = buildOptions(cfg)
+	if err := runChat(cfg, opt); err != nil {
+		t.Fatalf("runChat error: %v", err)
+	}
+	if msg := readZipEntryString(t, chat, "chat/msg-0001.md"); !strings.HasPrefix(msg, "# README.md") {
+		t.Fatalf("first chat message does not start with README.md:\n%s", msg)
+	}
+
+	cfg, err = parseFlags([]string{"-chat", chat, "-ext", ".go", src})
+	if err != nil {
+		t.Fatalf("parseFlags error: %v", err)
+	}
+	if err := runChat(cfg, opt); err != nil {
+		t.Fatalf("runChat error: %v", err)
+	}
+	if msg := readZipEntryString(t, chat, "chat/msg-0001.md"); strings.Contains(msg, "README") {
+		t.Fatalf("README included without the option:\n%s", msg)
+	}
+}
+
+func TestFailOnEmpty(t *testing.T) {
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "a.go"), []byte("package a\n"), 0o644); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+	out := t.TempDir()
+	run := func(args ...string) error {
+		t.Helper()
+		cfg, err := parseFlags(append(args, src))
+		if err != nil {
+			t.Fatalf("parseFlags error: %v", err)
+		}
+		opt, langs, _ := buildOptions(cfg)
+		switch {
+		case cfg.zipOut != "":
+			return runFull(cfg, opt, langs)
+		case cfg.deltaOut != "":
+			return runDelta(cfg, opt)
+		default:
+			return runChat(cfg, opt)
+		}
+	}
+	modes := [][]string{
+		{"-zip", filepath.Join(out, "full.zip"), "-save-snapshot=false"},
+		{"-delta", filepath.Join(out, "delta.zip"), "-tmp-dir", filepath.Join(out, "cache")},
+		{"-chat", filepath.Join(out, "chat.zip")},
+	}
+	for _, mode := range modes {
+		if err := run(append(mode, "-ext", ".java")...); err != nil {
+			t.Fatalf("%s without -fail-on-empty: %v", mode[0], err)
+		}
+		err := run(append(mode, "-ext", ".java", "-fail-on-empty")...)
+		if !errors.Is(err, errNoFiles) {
+			t.Fatalf("%s -fail-on-empty: got %v, want errNoFiles", mode[0], err)
+		}
+		if err := run(append(mode, "-fail-on-empty")...); err != nil {
+			t.Fatalf("%s -fail-on-empty with matches: %v", mode[0], err)
+		}
+	}
+}
+
+func TestRunDeltaSummaryOnly(t *testing.T) {
+	src := t.TempDir()
+	write := func(name, body string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(src, name), []byte(body), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	write("a.go", "package a\n\nfunc A() int { return 1 }\n")
+	out := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cfg, err := parseFlags(append(args, "-tmp-dir", filepath.Join(out, "cache"), src))
+		if err != nil {
+			t.Fatalf("parseFlags error: %v", err)
+		}
+		opt, _, _ := buildOptions(cfg)
+		if err := runDelta(cfg, opt); err != nil {
+			t.Fatalf("runDelta error: %v", err)
+		}
+	}
+	run("-delta", filepath.Join(out, "base.zip"))
+
+	write("a.go", "package a\n\nfunc A() int { return 2 }\n")
+	write("b.go", "package a\n\nfunc B() {}\n")
+	delta := filepath.Join(out, "delta.zip")
+	run("-delta", delta, "-delta-summary-only")
+
+	zr, err := zip.OpenReader(delta)
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	zr.Close()
+	sort.Strings(names)
+	if strings.Join(names, ",") != "SUMMARY.md,delta.index.json" {
+		t.Fatalf("summary-only archive should hold only the index and summary, got %v", names)
+	}
+
+	var idx struct {
+		Added   []struct{ Path string } `json:"added"`
+		Changed []struct {
+			Path string `json:"path"`
+			Diff string `json:"diff"`
+		} `json:"changed"`
+	}
+	if err := json.Unmarshal([]byte(readZipEntryString(t, delta, "delta.index.json")), &idx); err != nil {
+		t.Fatalf("decode delta index: %v", err)
+	}
+	if len(idx.Added) != 1 || idx.Added[0].Path != "b.go" || len(idx.Changed) != 1 || idx.Changed[0].Diff != "" {
+		t.Fatalf("unexpected index: %+v", idx)
+	}
+	summary := readZipEntryString(t, delta, "SUMMARY.md")
+	if !strings.Contains(summary, "- a.go\n") || !strings.Contains(summary, "- b.go\n") || strings.Contains(summary, "->") {
+		t.Fatalf("summary should list bare paths, got:\n%s", summary)
+	}
+}
+
+func TestModifiedSinceFlag(t *testing.T) {
+	src := t.TempDir()
+	cfg, err := parseFlags([]string{"-zip", "out.zip", "-modified-since", "2d", src})
+	if err != nil {
+		t.Fatalf("parseFlags error: %v", err)
+	}
+	if age := time.Since(cfg.modifiedSince); age < 47*time.Hour || age > 49*time.Hour {
+		t.Fatalf("modifiedSince = %v (age %v), want about 48h ago", cfg.modifiedSince, age)
+	}
+	if _, err := parseFlags([]string{"-delta", "d.zip", "-modified-since", "2d", src}); err == nil {
+		t.Fatalf("expected -modified-since to be rejected with -delta")
+	}
+	if _, err := parseFlags([]string{"-zip", "out.zip", "-modified-since", "soon", src}); err == nil {
+		t.Fatalf("expected an invalid -modified-since value to be rejected")
+	}
+}
+
+func TestVerifySubcommand(t *testing.T) {
+	src := t.TempDir()
+	write := func(name, body string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(src, name), []byte(body), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	write("a.go", "package a\n\nfunc A() int { return 1 }\n")
+	write("notes.txt", "first\r\nlast")
+	write("latin.txt", "caf\xe9\n")
+
+	out := t.TempDir()
+	full := filepath.Join(out, "full.zip")
+	cfg, err := parseFlags([]string{"-zip", full, "-save-snapshot=false", "-emit-src", src})
+	if err != nil {
+		t.Fatalf("parseFlags error: %v", err)
+	}
+	opt, langs, _ := buildOptions(cfg)
+	if err := runFull(cfg, opt, langs); err != nil {
+		t.Fatalf("runFull error: %v", err)
+	}
+
+	write("a.go", "package a\n\nfunc A() int { return 2 }\n")
+	write("notes.txt", "first\r\nlast!")
+	write("latin.txt", "caf\xe9 cr\xe8me\n")
+	write("c.go", "package a\n\nfunc C() {}\n")
+	delta := filepath.Join(out, "delta.zip")
+	cfg, err = parseFlags([]string{"-delta", delta, "-delta-against-full", full, "-tmp-dir", filepath.Join(out, "cache"), src})
+	if err != nil {
+		t.Fatalf("parseFlags error: %v", err)
+	}
+	opt, _, _ = buildOptions(cfg)
+	if err := runDelta(cfg, opt); err != nil {
+		t.Fatalf("runDelta error: %v", err)
+	}
+
+	verify := func(args ...string) (int, bundle.VerifyReport) {
+		t.Helper()
+		var stdout, stderr bytes.Buffer
+		code := runVerify(args, &stdout, &stderr)
+		var report bundle.VerifyReport
+		if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+			t.Fatalf("verify %v: decode report: %v (stderr %q)", args, err, stderr.String())
+		}
+		return code, report
+	}
+	if code, report := verify(full); code != 0 || !report.OK || report.Kind != "full" {
+		t.Fatalf("verify FULL: code %d, report %+v", code, report)
+	}
+	code, report := verify("-base", full, delta)
+	if code != 0 || report.Kind != "delta" {
+		t.Fatalf("verify DELTA: code %d, report %+v", code, report)
+	}
+	if last := report.Checks[len(report.Checks)-1]; last.Name != "patch" || last.Detail != "4 applied" {
+		t.Fatalf("patch check = %+v, want all 4 sections applied", last)
+	}
+
+	// A tampered source no longer matches its manifest hash.
+	tampered := filepath.Join(out, "tampered.zip")
+	zr, err := zip.OpenReader(full)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(tampered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for _, zf := range zr.File {
+		w, err := zw.Create(zf.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if zf.Name == "src/a.go" {
+			io.WriteString(w, "package a\n")
+			continue
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(w, rc)
+		rc.Close()
+	}
+	zr.Close()
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	code, report = verify(tampered)
+	if code != 1 || report.OK {
+		t.Fatalf("verify tampered: code %d, report %+v", code, report)
+	}
+	for _, c := range report.Checks {
+		if c.Name == "sources" && (c.Status != bundle.CheckFailed || len(c.Problems) != 1 || !strings.HasPrefix(c.Problems[0], "a.go: content hash")) {
+			t.Fatalf("sources check = %+v", c)
+		}
+	}
+}
+
+func TestApplySubcommandReconstructsTree(t *testing.T) {
+	src := t.TempDir()
+	write := func(name, body string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(src, name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(src, name), []byte(body), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	out := t.TempDir()
+	cacheDir := filepath.Join(out, "cache")
+	run := func(args ...string) {
+		t.Helper()
+		cfg, err := parseFlags(append(args, "-store-blobs", "-tmp-dir", cacheDir, src))
+		if err != nil {
+			t.Fatalf("parseFlags error: %v", err)
+		}
+		opt, langs, _ := buildOptions(cfg)
+		if cfg.zipOut != "" {
+			err = runFull(cfg, opt, langs)
+		} else {
+			err = runDelta(cfg, opt)
+		}
+		if err != nil {
+			t.Fatalf("run %v: %v", args, err)
+		}
+	}
+	write("a.go", "package a\n\nfunc A() int { return 1 }\n")
+	write("b.go", "package a\n\nfunc B() {}\n")
+	write("notes.txt", "first\r\nlast")
+	write("latin.txt", "caf\xe9\n")
+	write("long.go", "package a\n\nfunc L1() {}\n\nfunc L2() {}\n\nfunc L3() {}\n")
+	full := filepath.Join(out, "full.zip")
+	run("-zip", full, "-emit-src")
+
+	write("a.go", "package a\n\nfunc A() int { return 2 }\n")
+	write("notes.txt", "first\r\nlast!")
+	write("latin.txt", "caf\xe9 cr\xe8me\n")
+	write("pkg/c.go", "package pkg\n")
+	d1 := filepath.Join(out, "d1.zip")
+	run("-delta", d1)
+
+	if err := os.Rename(filepath.Join(src, "b.go"), filepath.Join(src, "pkg", "b.go")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(src, "a.go")); err != nil {
+		t.Fatal(err)
+	}
+	write("pkg/c.go", "package pkg\n\nfunc C() {}\n")
+	write("latin.txt", "caf\xe9 cr\xe8me br\xfbl\xe9e\n")
+	if err := os.Remove(filepath.Join(src, "long.go")); err != nil {
+		t.Fatal(err)
+	}
+	write("pkg/long.go", "package pkg\n\nfunc L1() {}\n\nfunc L2() {}\n\nfunc L3() {}\n")
+	oldRoot := filepath.Join(out, "old")
+	if err := os.MkdirAll(oldRoot, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(oldRoot, "long.go"), []byte("package a\n\nfunc L1() {}\n\nfunc L2() {}\n\nfunc L3() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	d2 := filepath.Join(out, "d2.zip")
+	run("-delta", d2, "-rename-similarity", "-rename-sim-percent", "50", "-rename-sim-oldroot", oldRoot)
+
+	dst := filepath.Join(out, "tree")
+	var stdout, stderr bytes.Buffer
+	if code := runApply([]string{"-base", full, "-delta", d1, "-delta", d2, "-out", dst}, &stdout, &stderr); code != 0 {
+		t.Fatalf("apply: code %d, stderr %q", code, stderr.String())
+	}
+	want := map[string]string{
+		"latin.txt":   "caf\xe9 cr\xe8me br\xfbl\xe9e\n",
+		"notes.txt":   "first\r\nlast!",
+		"pkg/b.go":    "package a\n\nfunc B() {}\n",
+		"pkg/c.go":    "package pkg\n\nfunc C() {}\n",
+		"pkg/long.go": "package pkg\n\nfunc L1() {}\n\nfunc L2() {}\n\nfunc L3() {}\n",
+	}
+	got := map[string]string{}
+	filepath.WalkDir(dst, func(p string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			data, _ := os.ReadFile(p)
+			rel, _ := filepath.Rel(dst, p)
+			got[filepath.ToSlash(rel)] = string(data)
+		}
+		return err
+	})
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("reconstructed tree = %q, want %q", got, want)
+	}
+
+	// Out of order, d2 finds the tree it expects missing.
+	stderr.Reset()
+	if code := runApply([]string{"-base", full, "-delta", d2, "-out", filepath.Join(out, "bad")}, &stdout, &stderr); code != 1 || !strings.Contains(stderr.String(), d2) {
+		t.Fatalf("apply out of order: code %d, stderr %q", code, stderr.String())
+	}
+}
+
+func TestDeltaLangFilterKeepsOnlyGoDiffs(t *testing.T) {
+	dir := t.TempDir()
+	goPath := filepath.Join(dir, "main.go")
+	tsPath := filepath.Join(dir, "app.ts")
+	if err := os.WriteFile(goPath, []byte("package main\n\nfunc main() {}\n"), 0o644); err != nil {
+		t.Fatalf("write go: %v", err)
+	}
+	if err := os.WriteFile(tsPath, []byte("export const x = 2;\n"), 0o644); err != nil {
+		t.Fatalf("write ts: %v", err)
+	}
+	var d cache.Delta
+	d.Changed = append(d.Changed, struct {
+		Path       string `json:"path"`
+		HashBefore string `json:"hashBefore"`
+		HashAfter  string `json:"hashAfter"`
+		DiffPath   string `json:"diff"`
+		Oversize   bool   `json:"oversize"`
+	}{Path: "app.ts", HashBefore: "aaaaaa", HashAfter: "bbbbbb"}, struct {
+		Path       string `json:"path"`
+		HashBefore string `json:"hashBefore"`
+		HashAfter  string `json:"hashAfter"`
+		DiffPath   string `json:"diff"`
+		Oversize   bool   `json:"oversize"`
+	}{Path: "main.go", HashBefore: "cccccc", HashAfter: "dddddd"})
+	d.Added = []cache.SnapFile{{Path: "extra.ts"}, {Path: "extra.go"}}
+
+	filtered, dropped := cache.FilterDelta(d, langFilter("go"))
+	if dropped != 2 {
+		t.Fatalf("dropped = %d, want 2", dropped)
+	}
+	if len(filtered.Added) != 1 || filtered.Added[0].Path != "extra.go" {
+		t.Fatalf("unexpected added: %#v", filtered.Added)
+	}
+	files := []walkwalk.FileInfo{
+		{RelPath: "app.ts", AbsPath: tsPath},
+		{RelPath: "main.go", AbsPath: goPath},
+	}
+	patches, err := bundle.MakeDiffs(filtered, files, diff.Options{Context: 3, NoPrefix: true}, nil)
+	if err != nil {
+		t.Fatalf("MakeDiffs error: %v", err)
+	}
+	if len(patches) != 1 {
+		t.Fatalf("expected 1 patch, got %d", len(patches))
+	}
+	if _, ok := patches["main.go.patch"]; !ok {
+		t.Fatalf("expected main.go.patch, got %v", patches)
+	}
+}
+
+func TestRunDeltaIncludeUnchangedManifest(t *testing.T) {
+	src := t.TempDir()
+	write := func(name, body string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(src, name), []byte(body), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	write("a.go", "package a\n\nfunc A() int { return 1 }\n")
+	write("c.go", "package a\n\nfunc C() {}\n")
+	out := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cfg, err := parseFlags(append(args, "-tmp-dir", filepath.Join(out, "cache"), src))
+		if err != nil {
+			t.Fatalf("parseFlags error: %v", err)
+		}
+		opt, _, _ := buildOptions(cfg)
+		if err := runDelta(cfg, opt); err != nil {
+			t.Fatalf("runDelta error: %v", err)
+		}
+	}
+	run("-delta", filepath.Join(out, "base.zip"))
+	zr, err := zip.OpenReader(filepath.Join(out, "base.zip"))
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	for _, f := range zr.File {
+		if f.Name == "current-files.json" {
+			t.Fatalf("current-files.json should be opt-in")
+		}
+	}
+	zr.Close()
+
+	write("b.go", "package a\n\nfunc B() {}\n")
+	delta := filepath.Join(out, "delta.zip")
+	run("-delta", delta, "-delta-include-unchanged-manifest")
+
+	var man bundle.CurrentManifest
+	if err := json.Unmarshal([]byte(readZipEntryString(t, delta, "current-files.json")), &man); err != nil {
+		t.Fatalf("decode manifest: %v", err)
+	}
+	var paths []string
+	for _, f := range man.Files {
+		if f.Hash == "" || f.Lines != 4 {
+			t.Fatalf("incomplete entry: %+v", f)
+		}
+		paths = append(paths, f.Path)
+	}
+	if strings.Join(paths, ",") != "a.go,b.go,c.go" {
+		t.Fatalf("manifest should list every current file, got %v", paths)
+	}
+}
+
+func TestReadChatOrder(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "order.txt")
+	if err := os.WriteFile(name, []byte("# read first\n./README.md\r\n\n  src\\app\\main.go  \n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readChatOrder(name)
+	if err != nil {
+		t.Fatalf("readChatOrder error: %v", err)
+	}
+	if want := []string{"README.md", "src/app/main.go"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("order = %v, want %v", got, want)
+	}
+	if _, err := readChatOrder(name + ".missing"); err == nil {
+		t.Fatalf("expected error for a missing order file")
+	}
+}
+
+func TestMaxConcurrencySerialMatchesParallel(t *testing.T) {
+	src := t.TempDir()
+	for i := 0; i < 40; i++ {
+		body := fmt.Sprintf("package p%d\n\nimport \"fmt\"\n\n// F%d prints.\nfunc F%d() { fmt.Println(%d) }\n", i%4, i, i, i)
+		dir := filepath.Join(src, fmt.Sprintf("p%d", i%4))
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("f%02d.go", i)), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer parallel.SetLimit(0)
+	build := func(workers ...string) []byte {
+		t.Helper()
+		out := filepath.Join(t.TempDir(), "full.zip")
+		cfg, err := parseFlags(append([]string{"-zip", out, "-save-snapshot=false", "-emit-src", "-graph-calls"}, append(workers, src)...))
+		if err != nil {
+			t.Fatalf("parseFlags error: %v", err)
+		}
+		parallel.SetLimit(cfg.maxWorkers)
+		opt, langs, _ := buildOptions(cfg)
+		if err := runFull(cfg, opt, langs); err != nil {
+			t.Fatalf("runFull error: %v", err)
+		}
+		data, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	if serial, par := build("-max-concurrency", "1"), build("-jobs", "8"); !bytes.Equal(serial, par) {
+		t.Fatalf("-max-concurrency 1 and -jobs 8 produced different archives")
+	}
+	if _, err := parseFlags([]string{"-zip", "x.zip", "-max-concurrency", "-1", src}); err == nil {
+		t.Fatalf("expected error for negative -max-concurrency")
+	}
+	if _, err := parseFlags([]string{"-zip", "x.zip", "-jobs", "2", "-max-concurrency", "4", src}); err == nil {
+		t.Fatalf("expected error for conflicting -jobs and -max-concurrency")
+	}
+}
+
+func TestRunNoGraph(t *testing.T) {
+	src := t.TempDir()
+	write := func(name, body string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(src, name), []byte(body), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	write("a.go", "package a\n\nimport \"fmt\"\n\nfunc A() { fmt.Println() }\n")
+	write("b.go", "package a\n\nfunc B() {}\n")
+
+	full := filepath.Join(t.TempDir(), "full.zip")
+	cfg, err := parseFlags([]string{"-zip", full, "-save-snapshot=false", "-no-graph", src})
+	if err != nil {
+		t.Fatalf("parseFlags error: %v", err)
+	}
+	opt, langs, _ := buildOptions(cfg)
+	if err := runFull(cfg, opt, langs); err != nil {
+		t.Fatalf("runFull error: %v", err)
+	}
+	var g struct {
+		Nodes []string    `json:"nodes"`
+		Edges [][2]string `json:"edges"`
+	}
+	if err := json.Unmarshal([]byte(readZipEntryString(t, full, "graph.json")), &g); err != nil {
+		t.Fatalf("decode graph.json: %v", err)
+	}
+	if g.Nodes == nil || g.Edges == nil || len(g.Nodes)+len(g.Edges) != 0 {
+		t.Fatalf("graph.json = %+v, want empty lists", g)
+	}
+	if man := readZipManifest(t, full); len(man.Files) != 2 {
+		t.Fatalf("manifest files = %+v, want a.go and b.go", man.Files)
+	}
+	if syms := readZipEntryString(t, full, "symbols.json"); !strings.Contains(syms, "a.A") || !strings.Contains(syms, "a.B") {
+		t.Fatalf("symbols incomplete without the graph:\n%s", syms)
+	}
+
+	chat := filepath.Join(t.TempDir(), "chat.zip")
+	cfg, err = parseFlags([]string{"-chat", chat, "-no-graph", src})
+	if err != nil {
+		t.Fatalf("parseFlags error: %v", err)
+	}
+	opt, _, _ = buildOptions(cfg)
+	if err := runChat(cfg, opt); err != nil {
+		t.Fatalf("runChat error: %v", err)
+	}
+	if msg := readZipEntryString(t, chat, "chat/msg-0001.md"); !strings.Contains(msg, "a.go") {
+		t.Fatalf("chat message missing files:\n%s", msg)
+	}
+
+	if _, err := parseFlags([]string{"-no-graph", "-impact", src}); err == nil {
+		t.Fatal("-no-graph -impact accepted")
+	}
+}
+
+func TestRunDeltaBaseModuleCheck(t *testing.T) {
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "a.go"), []byte("package a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out := t.TempDir()
+	cacheRoot := filepath.Join(out, "cache")
+	run := func(args ...string) error {
+		t.Helper()
+		cfg, err := parseFlags(append(append([]string{"-delta", filepath.Join(out, "delta.zip"), "-tmp-dir", cacheRoot}, args...), src))
+		if err != nil {
+			t.Fatalf("parseFlags error: %v", err)
+		}
+		opt, _, _ := buildOptions(cfg)
+		return runDelta(cfg, opt)
+	}
+	if err := run(); err != nil {
+		t.Fatalf("first runDelta error: %v", err)
+	}
+
+	// Simulate a stale cache left by another project.
+	cfg, _ := parseFlags([]string{"-tmp-dir", cacheRoot, src})
+	dir, err := cacheDirFor(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap, err := cache.Load(dir)
+	if err != nil || snap == nil {
+		t.Fatalf("load snapshot: %v, %v", snap, err)
+	}
+	snap.Module = "other-project"
+	if err := cache.Save(dir, snap); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := run("-delta-base-module-check", "error"); err == nil || !strings.Contains(err.Error(), `"other-project"`) || !strings.Contains(err.Error(), "-new") {
+		t.Fatalf("error mode: err = %v", err)
+	}
+
+	c := &warn.Collector{}
+	warn.Use(c)
+	err = run()
+	warn.Use(nil)
+	if err != nil {
+		t.Fatalf("warn mode runDelta error: %v", err)
+	}
+	if ws := c.List(); len(ws) != 1 || ws[0].Kind != warn.KindConfig || !strings.Contains(ws[0].Message, "other-project") {
+		t.Fatalf("warnings = %+v", ws)
+	}
+
+	// The warn-mode run saved a fresh snapshot; break it again and reset.
+	snap.Module = "other-project"
+	if err := cache.Save(dir, snap); err != nil {
+		t.Fatal(err)
+	}
+	if err := run("-new", "-delta-base-module-check", "error"); err != nil {
+		t.Fatalf("-new should reset the mismatched cache: %v", err)
+	}
+}
+
+func TestServeStateDiffsAgainstBaseline(t *testing.T) {
+	src := t.TempDir()
+	file := filepath.Join(src, "a.go")
+	if err := os.WriteFile(file, []byte("package a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out := t.TempDir()
+	cacheRoot := filepath.Join(out, "cache")
+	cfg, err := parseFlags([]string{"-delta", filepath.Join(out, "delta.zip"), "-tmp-dir", cacheRoot, "-store-blobs", src})
+	if err != nil {
+		t.Fatalf("parseFlags error: %v", err)
+	}
+	opt, _, _ := buildOptions(cfg)
+	if err := runDelta(cfg, opt); err != nil {
+		t.Fatalf("runDelta error: %v", err)
+	}
+
+	cfg, err = parseFlags([]string{"-serve", "-port", "0", "-tmp-dir", cacheRoot, src})
+	if err != nil {
+		t.Fatalf("parseFlags error: %v", err)
+	}
+	if mode, err := selectMode(cfg); err != nil || mode != "serve" {
+		t.Fatalf("selectMode = %q, %v", mode, err)
+	}
+	files, err := serveFiles(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := filesFingerprint(files)
+	st, err := serveState(cfg, opt, files)
+	if err != nil {
+		t.Fatalf("serveState error: %v", err)
+	}
+	if st.Diff == nil || st.Diff("a.go") != "" {
+		t.Fatal("unchanged file should have an empty diff")
+	}
+
+	if err := os.WriteFile(file, []byte("package a\n\nfunc F() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if files, err = serveFiles(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if filesFingerprint(files) == before {
+		t.Fatal("fingerprint should change with file content")
+	}
+	if st, err = serveState(cfg, opt, files); err != nil {
+		t.Fatalf("serveState error: %v", err)
+	}
+	if d := st.Diff("a.go"); !strings.Contains(d, "+func F() {}") || !strings.Contains(d, "--- a.go") {
+		t.Fatalf("diff = %q", d)
+	}
+	if len(st.Symbols.Symbols) == 0 {
+		t.Fatal("symbols should be rebuilt from the new content")
+	}
+
+	if _, err := selectMode(Config{serve: true, zipOut: "x.zip"}); err == nil {
+		t.Fatal("-serve with -zip should be rejected")
+	}
+}
+
+func TestRunDeltaGitRange(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	repo := t.TempDir()
+	src := filepath.Join(repo, "svc")
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", repo, "-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(name, body string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(repo, name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(repo, name), []byte(body), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	git("init", "-q")
+	write("svc/a.go", "package a\n\nfunc A() int { return 1 }\n")
+	write("svc/b.go", "package a\n\nfunc B() {}\n")
+	write("other/x.go", "package x\n")
+	git("add", ".")
+	git("commit", "-q", "-m", "one")
+	write("svc/a.go", "package a\n\nfunc A() int { return 2 }\n")
+	write("svc/c.go", "package a\n\nfunc C() {}\n")
+	write("other/x.go", "package x\n\nvar X int\n")
+	git("rm", "-q", "svc/b.go")
+	git("add", ".")
+	git("commit", "-q", "-m", "two")
+	// Uncommitted edits must not leak into the delta.
+	write("svc/a.go", "package a\n\nfunc A() int { return 3 }\n")
+
+	out := t.TempDir()
+	delta := filepath.Join(out, "delta.zip")
+	cacheRoot := filepath.Join(out, "cache")
+	cfg, err := parseFlags([]string{"-delta", delta, "-delta-git", "HEAD~1..HEAD", "-tmp-dir", cacheRoot, src})
+	if err != nil {
+		t.Fatalf("parseFlags error: %v", err)
+	}
+	opt, _, _ := buildOptions(cfg)
+	if err := runDelta(cfg, opt); err != nil {
+		t.Fatalf("runDelta error: %v", err)
+	}
+
+	var idx struct {
+		Added   []struct{ Path string } `json:"added"`
+		Removed []struct{ Path string } `json:"removed"`
+		Changed []struct {
+			Path string `json:"path"`
+			Diff string `json:"diff"`
+		} `json:"changed"`
+	}
+	if err := json.Unmarshal([]byte(readZipEntryString(t, delta, "delta.index.json")), &idx); err != nil {
+		t.Fatalf("decode delta index: %v", err)
+	}
+	if len(idx.Added) != 1 || idx.Added[0].Path != "c.go" || len(idx.Removed) != 1 || idx.Removed[0].Path != "b.go" {
+		t.Fatalf("unexpected added/removed: %+v", idx)
+	}
+	if len(idx.Changed) != 1 || idx.Changed[0].Path != "a.go" {
+		t.Fatalf("unexpected changed: %+v", idx.Changed)
+	}
+	patch := readZipEntryString(t, delta, idx.Changed[0].Diff)
+	if !strings.Contains(patch, "-func A() int { return 1 }") || !strings.Contains(patch, "+func A() int { return 2 }") {
+		t.Fatalf("expected a diff between the two commits, got:\n%s", patch)
+	}
+	if snap, _ := cache.Load(cache.CacheDir(cacheRoot, mustAbs(t, src))); snap != nil {
+		t.Fatal("-delta-git must not update the cache snapshot")
+	}
+
+	for _, bad := range []string{"HEAD", "HEAD...main", "..HEAD"} {
+		if _, err := parseFlags([]string{"-delta", delta, "-delta-git", bad, src}); err == nil {
+			t.Fatalf("-delta-git %q should be rejected", bad)
+		}
+	}
+}
+
+func TestRunFullArtifactCacheSameBundle(t *testing.T) {
+	src := t.TempDir()
+	for name, body := range map[string]string{
+		"a.go":      "package a\n\n// region: setup\nfunc A() {}\n// endregion\n",
+		"b/b.ts":    "export function b() {}\nexport { x as y } from './x'\n",
+		"README.md": "# demo\n",
+	} {
+		p := filepath.Join(src, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := t.TempDir()
+	build := func(name string, extra ...string) string {
+		t.Helper()
+		zipPath := filepath.Join(out, name)
+		args := append([]string{"-zip", zipPath, "-save-snapshot=false", "-tmp-dir", filepath.Join(out, "cache")}, extra...)
+		cfg, err := parseFlags(append(args, src))
+		if err != nil {
+			t.Fatalf("parseFlags error: %v", err)
+		}
+		opt, langs, _ := buildOptions(cfg)
+		if err := runFull(cfg, opt, langs); err != nil {
+			t.Fatalf("runFull error: %v", err)
+		}
+		sum, err := archiveSHA256(zipPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sum
+	}
+	plain := build("plain.zip")
+	cold := build("cold.zip", "-artifact-cache")
+	warm := build("warm.zip", "-artifact-cache")
+	if plain != cold || cold != warm {
+		t.Fatalf("bundles differ: plain=%s cold=%s warm=%s", plain, cold, warm)
+	}
+	dir := filepath.Join(out, "cache")
+	var entries int
+	_ = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && filepath.Base(filepath.Dir(p)) == index.ArtifactCacheDir {
+			entries++
+		}
+		return nil
+	})
+	if entries != 3 {
+		t.Fatalf("artifact cache entries = %d, want 3", entries)
+	}
+}
+
+func TestDualFSReadsDecomposedNames(t *testing.T) {
+	oldRoot, newRoot := t.TempDir(), t.TempDir()
+	// "café" decomposed (e + U+0301) on disk in both trees.
+	for _, root := range []string{oldRoot, newRoot} {
+		if err := os.WriteFile(filepath.Join(root, "café.go"), []byte("package x\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg, err := parseFlags([]string{"-ext", ".go", newRoot})
+	if err != nil {
+		t.Fatalf("parseFlags: %v", err)
+	}
+	files, err := collectFiles(cfg, 0)
+	if err != nil || len(files) != 1 || files[0].RelPath != "caf\u00e9.go" {
+		t.Fatalf("collectFiles = %+v, %v", files, err)
+	}
+	d := dualFS{oldRoot: oldRoot, newFiles: absPaths(files)}
+	for _, old := range []bool{false, true} {
+		if data, err := d.Read("caf\u00e9.go", old); err != nil || string(data) != "package x\n" {
+			t.Fatalf("Read(old=%v) = %q, %v", old, data, err)
+		}
+	}
+}
+
+func TestMultiGoModulesIgnoresExcludedModules(t *testing.T) {
+	src := t.TempDir()
+	for rel, body := range map[string]string{
+		"go.mod":            "module example.com/app\n",
+		"main.go":           "package main\n",
+		"tools/go.mod":      "module example.com/tools\n",
+		"tools/gen.go":      "package tools\n",
+		"scratch/go.mod":    "module example.com/scratch\n",
+		"scratch/try.go":    "package scratch\n",
+		"onlymod/go.mod":    "module example.com/onlymod\n",
+		"onlymod/README.md": "notes\n",
+	} {
+		p := filepath.Join(src, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg, err := parseFlags([]string{"-ext", ".go", "-exclude", "scratch", src})
+	if err != nil {
+		t.Fatalf("parseFlags: %v", err)
+	}
+	files, err := collectFiles(cfg, 0)
+	if err != nil {
+		t.Fatalf("collectFiles: %v", err)
+	}
+	want := []index.GoModule{{Dir: ".", Path: "example.com/app"}, {Dir: "tools", Path: "example.com/tools"}}
+	if got := multiGoModules(src, files); !reflect.DeepEqual(got, want) {
+		t.Fatalf("multiGoModules = %+v, want %+v", got, want)
+	}
+}
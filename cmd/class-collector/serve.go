@@ -0,0 +1,180 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"class-collector/internal/bundle"
+	"class-collector/internal/diff"
+	"class-collector/internal/graph"
+	"class-collector/internal/index"
+	"class-collector/internal/meta"
+	"class-collector/internal/serve"
+	"class-collector/internal/walkwalk"
+	"class-collector/internal/warn"
+)
+
+// serveInterval is how often -serve re-walks the tree looking for changes.
+const serveInterval = 2 * time.Second
+
+// runServe indexes the tree, serves it on localhost:<port> and rebuilds the
+// served state whenever a re-walk finds added, removed or modified files.
+// It only returns when the listener fails.
+func runServe(cfg Config, opt diff.Options) error {
+	applyAutoAnchorsConfig(cfg)
+	files, err := serveFiles(cfg)
+	if err != nil {
+		return err
+	}
+	st, err := serveState(cfg, opt, files)
+	if err != nil {
+		return err
+	}
+	ln, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(cfg.servePort)))
+	if err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+	srv := serve.New(ln.Addr().(*net.TCPAddr).Port)
+	srv.Update(st)
+
+	fmt.Printf("Serving %s on http://%s (files=%d, symbols=%d)\n", cfg.srcDir, ln.Addr(), len(st.Manifest.Files), len(st.Symbols.Symbols))
+	go watchServe(cfg, opt, srv, filesFingerprint(files))
+	return http.Serve(ln, srv)
+}
+
+// watchServe polls the tree every serveInterval and swaps in a new state
+// when its fingerprint changes. Failed rebuilds keep the previous state.
+func watchServe(cfg Config, opt diff.Options, srv *serve.Server, fp string) {
+	for range time.Tick(serveInterval) {
+		// A fresh collector per pass keeps a long-running server from
+		// accumulating warnings forever.
+		warn.Use(&warn.Collector{})
+		files, err := serveFiles(cfg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Warning:", err)
+			continue
+		}
+		next := filesFingerprint(files)
+		if next == fp {
+			continue
+		}
+		st, err := serveState(cfg, opt, files)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Warning: reindex:", err)
+			continue
+		}
+		srv.Update(st)
+		fp = next
+		fmt.Printf("Reindexed %s (files=%d, symbols=%d)\n", cfg.srcDir, len(st.Manifest.Files), len(st.Symbols.Symbols))
+	}
+}
+
+func serveFiles(cfg Config) ([]walkwalk.FileInfo, error) {
+	files, err := collectFiles(cfg, cfg.maxBytes)
+	if err != nil {
+		return nil, fmt.Errorf("collect files: %w", err)
+	}
+	return withRepoReadme(cfg, files), nil
+}
+
+// filesFingerprint hashes the walked path→content pairs; walks are sorted,
+// so equal trees give equal fingerprints.
+func filesFingerprint(files []walkwalk.FileInfo) string {
+	h := sha256.New()
+	for _, f := range files {
+		fmt.Fprintf(h, "%s\x00%s\x00%s\n", f.RelPath, f.SHA256Hex, f.Symlink)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// serveState builds what a FULL bundle would hold for files, plus a diff
+// function against the DELTA baseline (the cached snapshot).
+func serveState(cfg Config, opt diff.Options, files []walkwalk.FileInfo) (serve.State, error) {
+	langHints := toSet(splitCSV(cfg.langHints))
+	man, syms, slices, _ := index.BuildArtifacts(cfg.srcDir, files, cfg.maxFileLines, langHints)
+	goMods := multiGoModules(cfg.srcDir, files)
+	g0, err := buildGraph(cfg, toGraphFiles(files, goMods), syms.Symbols)
+	if err != nil {
+		return serve.State{}, err
+	}
+	g := graph.Truncate(g0, cfg.graphMaxNodes)
+	if cfg.graphKinds {
+		g.NodeKinds = graph.ClassifyNodes(g, goModulePaths(cfg.srcDir))
+	}
+	meta.ApplyToManifest(meta.Detect(cfg.srcDir), &man)
+	meta.ApplyGoModules(goMods, &man, &syms)
+	contents := readContents(files)
+	diffs, err := serveDiffs(cfg, opt, files, contents, man.Module)
+	if err != nil {
+		return serve.State{}, err
+	}
+	return serve.State{Contents: contents, Manifest: man, Symbols: syms, Slices: slices, Graph: g, Diff: diffs}, nil
+}
+
+// readContents snapshots the content of every readable regular file by
+// RelPath, so requests never see files edited after the state was built.
+func readContents(files []walkwalk.FileInfo) map[string][]byte {
+	out := make(map[string][]byte, len(files))
+	for _, f := range files {
+		if f.Symlink != "" {
+			continue
+		}
+		if data, err := os.ReadFile(f.AbsPath); err == nil {
+			out[f.RelPath] = data
+		}
+	}
+	return out
+}
+
+// serveDiffs returns a per-file diff against the baseline -delta would use,
+// or nil when there is none yet. Files unchanged since the baseline diff
+// to ""; changes whose old content is not cached get the oversize
+// placeholder.
+func serveDiffs(cfg Config, opt diff.Options, files []walkwalk.FileInfo, contents map[string][]byte, module string) (func(string) string, error) {
+	cacheDir, err := cacheDirFor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	prev, readOld, err := deltaBaseline(cfg, cacheDir, module)
+	if err != nil {
+		return nil, err
+	}
+	if len(prev.Files) == 0 {
+		return nil, nil
+	}
+	before := make(map[string]string, len(prev.Files))
+	for _, f := range prev.Files {
+		before[f.Path] = f.Hash
+	}
+	byPath := make(map[string]walkwalk.FileInfo, len(files))
+	for _, f := range files {
+		byPath[f.RelPath] = f
+	}
+	return func(p string) string {
+		f, ok := byPath[p]
+		if !ok || before[p] == f.SHA256Hex {
+			return ""
+		}
+		newData, ok := contents[p]
+		if !ok {
+			return ""
+		}
+		var oldData []byte
+		var err error
+		if h := before[p]; h != "" {
+			if oldData, err = readOld(h); err != nil {
+				if opt.NoPrefix {
+					return diff.Omitted(p, p)
+				}
+				return diff.Omitted("a/"+p, "b/"+p)
+			}
+		}
+		return bundle.FileDiff(p, opt, oldData, newData)
+	}, nil
+}
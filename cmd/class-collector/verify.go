@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"class-collector/internal/bundle"
+)
+
+// runVerify implements "class-collector verify [-base full.zip]
+// [-output-layout ...] <bundle>": it prints the bundle.Verify report as JSON
+// to stdout and returns the process exit code, 1 when a check failed or the
+// bundle could not be read.
+func runVerify(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("class-collector verify", flag.ContinueOnError)
+	fs.SetOutput(new(bytes.Buffer))
+	baseFlag := fs.String("base", "", "FULL bundle with sources (-emit-src) holding the before-content of DELTA changes, so their diffs are applied too")
+	layoutFlag := fs.String("output-layout", "", "DELTA entry names the bundle was written with (as for the main command)")
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintln(stderr, "ERROR:", err)
+		return 1
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "ERROR:", errors.New("usage: class-collector verify [-base <full bundle>] [-output-layout <json>] <bundle>"))
+		return 1
+	}
+	layout, err := resolveDeltaLayout(*layoutFlag)
+	if err != nil {
+		fmt.Fprintln(stderr, "ERROR:", err)
+		return 1
+	}
+	bundle.SetDeltaLayout(layout)
+
+	var base *bundle.Bundle
+	if *baseFlag != "" {
+		if base, err = bundle.Open(*baseFlag); err != nil {
+			fmt.Fprintln(stderr, "ERROR:", fmt.Errorf("open -base: %w", err))
+			return 1
+		}
+	}
+	report, err := bundle.Verify(fs.Arg(0), base)
+	if err != nil {
+		fmt.Fprintln(stderr, "ERROR:", err)
+		return 1
+	}
+	out, _ := json.MarshalIndent(report, "", "  ")
+	fmt.Fprintln(stdout, string(out))
+	if !report.OK {
+		return 1
+	}
+	return 0
+}
@@ -2,4 +2,7 @@ module class-collector
 
 go 1.24
 
-require github.com/pmezard/go-difflib v1.0.0
+require (
+	github.com/pmezard/go-difflib v1.0.0
+	golang.org/x/text v0.21.0
+)
@@ -0,0 +1,237 @@
+package bundle
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"class-collector/internal/cache"
+	"class-collector/internal/diff"
+	"class-collector/internal/textutil"
+	"class-collector/internal/ziputil"
+)
+
+// ApplyDeltas reconstructs the source tree a chain of bundles describes: the
+// files of the FULL bundle at base (which must carry sources, in src/ or the
+// srcArchive sibling) with the DELTA bundles at deltas applied in order. Each
+// DELTA drops its removed files, moves its renamed ones, patches its changed
+// files and renames with changes with their delta.patch sections and adds
+// the added/ contents (layout as set by SetDeltaLayout). Every step is
+// checked against the hashes of the delta index, so a DELTA applied to the
+// wrong tree or an omitted (oversize) diff is an error. The result maps
+// project paths to their content; symlink entries are not part of it.
+func ApplyDeltas(base string, deltas []string) (map[string][]byte, error) {
+	tree, err := fullSources(base)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range deltas {
+		if err := applyDelta(tree, path); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return tree, nil
+}
+
+// fullSources returns the content of every manifest file of the FULL bundle
+// at path by project path.
+func fullSources(path string) (map[string][]byte, error) {
+	b, err := Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	sources := b.Sources
+	if b.Manifest.SrcArchive != "" {
+		archive := filepath.Join(filepath.Dir(path), filepath.FromSlash(b.Manifest.SrcArchive))
+		entries, err := ReadEntries(archive)
+		if err != nil {
+			return nil, fmt.Errorf("%s: srcArchive: %w", path, err)
+		}
+		for name, data := range entries {
+			if rel, ok := strings.CutPrefix(name, "src/"); ok {
+				sources[rel] = data
+			}
+		}
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("%s: no src/ entries (build the FULL bundle with -emit-src)", path)
+	}
+	tree := make(map[string][]byte, len(b.Manifest.Files))
+	for _, f := range b.Manifest.Files {
+		if f.Kind == "symlink" {
+			continue
+		}
+		data, ok := sources[strings.TrimPrefix(ziputil.SanitizePath("src/"+f.Path), "src/")]
+		if !ok {
+			return nil, fmt.Errorf("%s: %s has no src/ entry", path, f.Path)
+		}
+		tree[f.Path] = data
+	}
+	return tree, nil
+}
+
+func applyDelta(tree map[string][]byte, path string) error {
+	entries, err := ReadEntries(path)
+	if err != nil {
+		return err
+	}
+	index, ok := entries[deltaLayout.Index]
+	if !ok {
+		return fmt.Errorf("%s not found (not a DELTA bundle?)", deltaLayout.Index)
+	}
+	var d cache.Delta
+	if err := json.Unmarshal(index, &d); err != nil {
+		return fmt.Errorf("%s: %w", deltaLayout.Index, err)
+	}
+	// take removes p from the tree after checking it holds content with hash.
+	take := func(p, hash string) ([]byte, error) {
+		data, ok := tree[p]
+		if !ok {
+			return nil, fmt.Errorf("%s: not in the tree the DELTA is applied to", p)
+		}
+		if hash != "" && !strings.EqualFold(sha256Hex(data), hash) {
+			return nil, fmt.Errorf("%s: content hash %s does not match the DELTA's %s (wrong base or order?)", p, sha256Hex(data), hash)
+		}
+		delete(tree, p)
+		return data, nil
+	}
+	put := func(p string, data []byte) error {
+		if _, exists := tree[p]; exists {
+			return fmt.Errorf("%s: already in the tree the DELTA is applied to", p)
+		}
+		tree[p] = data
+		return nil
+	}
+
+	for _, r := range d.Removed {
+		if _, err := take(r.Path, r.Hash); err != nil {
+			return err
+		}
+	}
+	sections := map[string]diff.FilePatch{}
+	patched := make(map[string]bool, len(d.Changed)+len(d.Renamed))
+	for _, c := range d.Changed {
+		patched[c.Path] = true
+	}
+	for _, r := range d.Renamed {
+		if r.HashBefore != "" && !strings.EqualFold(r.HashBefore, r.Hash) {
+			patched[r.To] = true
+		}
+	}
+	for _, fp := range diff.SplitFiles(string(entries[deltaLayout.Patch])) {
+		if p := sectionPath(fp.NewName, func(p string) bool { return patched[p] }); patched[p] {
+			sections[p] = fp
+		}
+	}
+
+	moved := make([][]byte, len(d.Renamed))
+	for i, r := range d.Renamed {
+		data, err := take(r.From, r.HashBefore)
+		if err != nil {
+			return err
+		}
+		if !patched[r.To] {
+			if r.Hash != "" && !strings.EqualFold(sha256Hex(data), r.Hash) {
+				return fmt.Errorf("%s -> %s: renamed with changes but the DELTA records no hashBefore", r.From, r.To)
+			}
+		} else if data, err = patchFile(r.To, data, sections, r.Hash); err != nil {
+			return err
+		}
+		moved[i] = data
+	}
+	for i, r := range d.Renamed {
+		if err := put(r.To, moved[i]); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range d.Changed {
+		data, err := take(c.Path, c.HashBefore)
+		if err != nil {
+			return err
+		}
+		if tree[c.Path], err = patchFile(c.Path, data, sections, c.HashAfter); err != nil {
+			return err
+		}
+	}
+
+	for _, a := range d.Added {
+		data, ok := entries[ziputil.SanitizePath(deltaLayout.Added+"/"+a.Path)]
+		if !ok {
+			return fmt.Errorf("%s: no %s/ entry", a.Path, deltaLayout.Added)
+		}
+		if a.Hash != "" && !strings.EqualFold(sha256Hex(data), a.Hash) {
+			return fmt.Errorf("%s: content hash %s does not match %s", a.Path, sha256Hex(data), a.Hash)
+		}
+		if err := put(a.Path, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// patchFile applies the delta.patch section for path to data, the file's
+// content before the change, and checks the result against hashAfter.
+func patchFile(path string, data []byte, sections map[string]diff.FilePatch, hashAfter string) ([]byte, error) {
+	fp, ok := sections[path]
+	if !ok {
+		return nil, fmt.Errorf("%s: no section in %s", path, deltaLayout.Patch)
+	}
+	// An added-file patch stands in for a change whose base was unknown
+	// to the builder; its result is still re-encoded like the base.
+	old, enc := textutil.ToUTF8(data)
+	if fp.OldName == "/dev/null" {
+		old = nil
+	}
+	got, err := diff.Apply(old, fp.Body)
+	switch {
+	case errors.Is(err, diff.ErrOmitted):
+		return nil, fmt.Errorf("%s: diff omitted (oversize); rebuild the DELTA with a larger -max-diff-bytes", path)
+	case err != nil:
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if got, ok = withHash(got, enc, hashAfter); !ok {
+		return nil, fmt.Errorf("%s: patched content hash %s does not match hashAfter %s", path, sha256Hex(got), hashAfter)
+	}
+	return got, nil
+}
+
+// sectionPath returns the project path a delta.patch section patches: its
+// "+++" name, without the "b/" prefix unless known has the name as-is.
+func sectionPath(name string, known func(string) bool) string {
+	if known(name) {
+		return name
+	}
+	return strings.TrimPrefix(name, "b/")
+}
+
+// withHash returns patched content in the rendition with hash want; ok is
+// false when none has it. Patches carry UTF-8 with LF line endings, so the
+// renditions tried are content as-is, with CRLF line endings, and both
+// re-encoded to enc, the encoding the base content was decoded from. An
+// empty want accepts content as-is.
+func withHash(content []byte, enc, want string) (out []byte, ok bool) {
+	if want == "" {
+		return content, true
+	}
+	renditions := [][]byte{content}
+	if !bytes.Contains(content, []byte("\r")) {
+		renditions = append(renditions, bytes.ReplaceAll(content, []byte("\n"), []byte("\r\n")))
+	}
+	if enc != "" {
+		for _, r := range renditions {
+			if b, ok := textutil.FromUTF8(r, enc); ok {
+				renditions = append(renditions, b)
+			}
+		}
+	}
+	for _, r := range renditions {
+		if strings.EqualFold(sha256Hex(r), want) {
+			return r, true
+		}
+	}
+	return content, false
+}
@@ -0,0 +1,45 @@
+package bundle
+
+import (
+	"fmt"
+	"strings"
+
+	"class-collector/internal/textutil"
+	"class-collector/internal/ziputil"
+)
+
+// chatFooterName is the optional closing message; it sorts after every
+// chat/msg-NNNN.md, so it is always the last message.
+const chatFooterName = "chat/zzzz-footer.md"
+
+var chatBetween, chatFooter string
+
+// SetChatSeparators configures scripted-ingestion boundaries for WriteChat:
+// a non-blank between is appended to every message that is followed by
+// another one (not to the last), and a non-blank footer is written as the
+// final message chat/zzzz-footer.md. Both are outside the per-message
+// character budget.
+func SetChatSeparators(between, footer string) {
+	chatBetween, chatFooter = between, footer
+}
+
+// chatSeparator returns the text appended to a message; it is empty for the
+// last message or when no between text is set.
+func chatSeparator(hasNext bool) string {
+	if !hasNext || strings.TrimSpace(chatBetween) == "" {
+		return ""
+	}
+	return "\n" + string(textutil.EnsureTrailingLF(textutil.NormalizeUTF8LF([]byte(chatBetween))))
+}
+
+// writeChatFooter writes the optional footer message and returns its TOC entry.
+func writeChatFooter(zw Writer) ([]chatMessageMeta, error) {
+	if strings.TrimSpace(chatFooter) == "" {
+		return nil, nil
+	}
+	text := textutil.EnsureTrailingLF(textutil.NormalizeUTF8LF([]byte(chatFooter)))
+	if err := ziputil.WriteText(zw, chatFooterName, text); err != nil {
+		return nil, fmt.Errorf("write %s: %w", chatFooterName, err)
+	}
+	return []chatMessageMeta{{Name: chatFooterName}}, nil
+}
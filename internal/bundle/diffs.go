@@ -8,7 +8,8 @@
 // Highlights:
 //   - Windows-safe patch filenames (sanitization + uniqueness).
 //   - Determinism: names are constructed identically for identical input.
-//   - Diff size limit is controlled by diff.Options (see internal/diff).
+//   - Diff size limits are controlled by diff.Options (see internal/diff): MaxBytes
+//     per file and MaxTotalBytes across the delta.
 //
 // Note: the order of writing patches into the ZIP must be sorted at the archive-writing stage.
 // We return a map here; determinism is ensured by sorting in the writer.
@@ -17,6 +18,7 @@ package bundle
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -25,9 +27,19 @@ import (
 
 	"class-collector/internal/cache"
 	"class-collector/internal/diff"
+	"class-collector/internal/index"
+	"class-collector/internal/progress"
+	"class-collector/internal/textutil"
 	"class-collector/internal/walkwalk"
+	"class-collector/internal/warn"
 )
 
+// ErrOldContentUnavailable may be returned by a MakeDiffs readOld callback
+// when the previous content is known to be missing (e.g. a FULL bundle built
+// without -emit-src). The change then gets an oversize placeholder instead of
+// an "added-only" patch that would misrepresent it.
+var ErrOldContentUnavailable = errors.New("old content unavailable")
+
 // invalidFileCharsRe contains characters that are invalid in Windows filenames.
 var invalidFileCharsRe = regexp.MustCompile(`[\\:*?"<>|]`)
 
@@ -77,13 +89,18 @@ func uniquePatchName(base, hashHint string, used map[string]struct{}) string {
 	return name
 }
 
-// MakeDiffs generates patches for d.Changed.
+// MakeDiffs generates patches for d.Changed and for the d.Renamed entries
+// whose content changed (HashBefore set and different from Hash), the latter
+// diffed from the From path to the To path.
 //   - files: current files (to read the "b" content).
 //   - opt: options like size limits (see internal/diff.Options).
 //   - readOld: function to obtain the "a" content by old hash (may be nil).
 //
 // Returns map[patch_name]patch_text. Fields d.Changed[i].Oversize and .DiffPath
-// are filled during generation.
+// are filled during generation. Changes are diffed in path order; once
+// opt.MaxTotalBytes would be exceeded, that change and every later one get
+// oversize placeholders without being diffed, so the kept diffs depend only
+// on the input. WriteDelta charges the added-file patches to what is left.
 func MakeDiffs(
 	d cache.Delta,
 	files []walkwalk.FileInfo,
@@ -95,39 +112,76 @@ func MakeDiffs(
 		byPath[f.RelPath] = f
 	}
 
-	patches := make([]generatedPatch, 0, len(d.Changed))
-	usedNames := make(map[string]struct{}, len(d.Changed))
+	jobs := make([]diffJob, 0, len(d.Changed)+len(d.Renamed))
+	for i, c := range d.Changed {
+		jobs = append(jobs, diffJob{changed: i, from: c.Path, path: c.Path, hashBefore: c.HashBefore, hashAfter: c.HashAfter})
+	}
+	for _, r := range d.Renamed {
+		if r.HashBefore != "" && r.HashBefore != r.Hash {
+			jobs = append(jobs, diffJob{changed: -1, from: r.From, path: r.To, hashBefore: r.HashBefore, hashAfter: r.Hash})
+		}
+	}
+	sort.SliceStable(jobs, func(a, b int) bool { return jobs[a].path < jobs[b].path })
+
+	patches := make([]generatedPatch, 0, len(jobs))
+	usedNames := make(map[string]struct{}, len(jobs))
+	total, budgetSpent := 0, false
 
-	for i := range d.Changed {
-		chg := &d.Changed[i]
+	for _, job := range jobs {
+		progress.Tick()
 
 		var oldData []byte
-		if readOld != nil && chg.HashBefore != "" {
-			if data, err := readOld(chg.HashBefore); err == nil && len(data) > 0 {
-				oldData = data
+		oldMissing := false
+		if readOld != nil && job.hashBefore != "" && !budgetSpent {
+			data, err := readOld(job.hashBefore)
+			switch {
+			case errors.Is(err, ErrOldContentUnavailable):
+				oldMissing = true
+			case err == nil && len(data) > 0:
+				oldData, _ = textutil.ToUTF8(data)
 			}
 		}
 
 		var newData []byte
-		if fi, ok := byPath[chg.Path]; ok {
+		if fi, ok := byPath[job.path]; ok && !budgetSpent {
 			if data, err := os.ReadFile(fi.AbsPath); err == nil {
-				newData = data
+				newData, _ = textutil.ToUTF8(data)
 			}
 		}
 
-		base := safeDiffBase(chg.Path)
-		hashHint := chg.HashAfter
+		base := safeDiffBase(job.path)
+		hashHint := job.hashAfter
 		if hashHint == "" {
-			hashHint = shortHash(chg.Path)
+			hashHint = shortHash(job.path)
 		}
 		patchName := uniquePatchName(base, hashHint[:min(len(hashHint), 8)], usedNames)
-		body, oversize := diffFile(chg.Path, opt, oldData, newData)
+		var (
+			body     string
+			oversize bool
+		)
+		if oldMissing {
+			body, oversize = omittedPatch(job.from, job.path, opt), true
+			warn.Add(warn.KindOversize, job.path, "diff omitted: previous content not available")
+		} else if budgetSpent {
+			body, oversize = omittedPatch(job.from, job.path, opt), true
+			warn.Add(warn.KindOversize, job.path, "diff omitted: delta diffs exceed the %d-byte total budget", opt.MaxTotalBytes)
+		} else if body, oversize = diffFile(job.from, job.path, opt, oldData, newData); oversize {
+			warn.Add(warn.KindOversize, job.path, "diff omitted: old+new exceed %d bytes", opt.MaxBytes)
+		} else if opt.MaxTotalBytes > 0 && total+len(body) > opt.MaxTotalBytes {
+			budgetSpent = true
+			body, oversize = omittedPatch(job.from, job.path, opt), true
+			warn.Add(warn.KindOversize, job.path, "diff omitted: delta diffs exceed the %d-byte total budget", opt.MaxTotalBytes)
+		} else {
+			total += len(body)
+		}
 
 		patches = append(patches, generatedPatch{name: patchName, body: body, oversize: oversize})
 
-		summary := summarizePatch(patchName, oversize)
-		chg.Oversize = summary.oversize
-		chg.DiffPath = summary.diffPath
+		if job.changed >= 0 {
+			summary := summarizePatch(patchName, oversize)
+			d.Changed[job.changed].Oversize = summary.oversize
+			d.Changed[job.changed].DiffPath = summary.diffPath
+		}
 	}
 
 	sorted := sortAndPackage(patches)
@@ -138,6 +192,14 @@ func MakeDiffs(
 	return out, nil
 }
 
+// diffJob is one file MakeDiffs diffs: a change (changed indexes d.Changed)
+// or a rename with changes (changed is -1), read from path from.
+type diffJob struct {
+	changed               int
+	from, path            string
+	hashBefore, hashAfter string
+}
+
 type generatedPatch struct {
 	name     string
 	body     string
@@ -149,11 +211,12 @@ type patchSummary struct {
 	oversize bool
 }
 
-func diffFile(path string, opt diff.Options, oldData, newData []byte) (string, bool) {
-	aName := "a/" + path
+// diffFile diffs a file that was at path from before the change.
+func diffFile(from, path string, opt diff.Options, oldData, newData []byte) (string, bool) {
+	aName := "a/" + from
 	bName := "b/" + path
 	if opt.NoPrefix {
-		aName = path
+		aName = from
 		bName = path
 	}
 	if len(oldData) == 0 {
@@ -163,11 +226,49 @@ func diffFile(path string, opt diff.Options, oldData, newData []byte) (string, b
 	if tooShortOrNoHunks(body) {
 		return diff.Added(bName, newData, opt)
 	}
+	if opt.FuncOnly && !oversize {
+		body = diff.AnnotateHunks(body, enclosingSymbol(index.FileSymbols(path, newData)))
+	}
 	return body, oversize
 }
 
+// FileDiff renders one file change the way MakeDiffs does (an added-only
+// patch when oldData is empty); both sides are converted to UTF-8 first.
+func FileDiff(path string, opt diff.Options, oldData, newData []byte) string {
+	oldData, _ = textutil.ToUTF8(oldData)
+	newData, _ = textutil.ToUTF8(newData)
+	body, _ := diffFile(path, path, opt, oldData, newData)
+	return body
+}
+
+// omittedPatch returns the oversize placeholder for a file at path that was
+// at from before the change.
+func omittedPatch(from, path string, opt diff.Options) string {
+	if opt.NoPrefix {
+		return diff.Omitted(from, path)
+	}
+	return diff.Omitted("a/"+from, "b/"+path)
+}
+
+// enclosingSymbol returns a lookup of the innermost symbol covering a line
+// (the latest-starting one, since syms are sorted by Start).
+func enclosingSymbol(syms []index.Symbol) func(line int) string {
+	return func(line int) string {
+		name := ""
+		for _, s := range syms {
+			if s.Start > line {
+				break
+			}
+			if line <= s.End {
+				name = s.Symbol
+			}
+		}
+		return name
+	}
+}
+
 func summarizePatch(patchName string, oversize bool) patchSummary {
-	diffPath := filepath.ToSlash(filepath.Join("diffs", patchName))
+	diffPath := filepath.ToSlash(filepath.Join(deltaLayout.Diffs, patchName))
 	return patchSummary{diffPath: diffPath, oversize: oversize}
 }
 
@@ -1,23 +1,51 @@
 package bundle
 
 import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
+	"class-collector/internal/cache"
 	"class-collector/internal/diff"
+	"class-collector/internal/walkwalk"
+	"class-collector/internal/warn"
 )
 
 func TestDiffFileProducesUnifiedDiff(t *testing.T) {
 	old := []byte("line1\nline2\n")
 	new := []byte("line1\nline3\n")
-	body, oversize := diffFile("sample.txt", diff.Options{Context: 3}, old, new)
+	body, oversize := diffFile("sample.txt", "sample.txt", diff.Options{Context: 3}, old, new)
 	if oversize {
 		t.Fatalf("unexpected oversize")
 	}
-	if len(body) == 0 || body[0] != '@' && body[0] != 'd' {
+	if !strings.HasPrefix(body, "--- ") || !strings.Contains(body, "@@") {
 		t.Fatalf("unexpected diff body: %q", body)
 	}
 }
 
+func TestDiffFileFuncOnlyZeroContext(t *testing.T) {
+	old := []byte("package demo\n\nfunc First() {\n\ta := 1\n\t_ = a\n}\n\nfunc Second() {\n\tb := 2\n\t_ = b\n}\n")
+	new := []byte("package demo\n\nfunc First() {\n\ta := 1\n\t_ = a\n}\n\nfunc Second() {\n\tb := 3\n\t_ = b\n}\n")
+	body, _ := diffFile("demo.go", "demo.go", diff.Options{Context: 3, NoPrefix: true, FuncOnly: true}, old, new)
+	if !strings.Contains(body, "@@ -9 +9 @@ demo.Second\n-\tb := 2\n+\tb := 3\n") {
+		t.Fatalf("unexpected func-only diff: %q", body)
+	}
+	for _, ln := range strings.Split(body, "\n") {
+		if strings.HasPrefix(ln, " ") {
+			t.Fatalf("expected zero context lines, got %q", ln)
+		}
+	}
+}
+
+func TestDiffFileEmptyAddedIsHeaderOnly(t *testing.T) {
+	body, oversize := diffFile("empty.go", "empty.go", diff.Options{Context: 3}, nil, nil)
+	if oversize || body != "--- /dev/null\n+++ empty.go\n" {
+		t.Fatalf("unexpected empty added patch: %q (oversize=%v)", body, oversize)
+	}
+}
+
 func TestSortAndPackageOrdersByName(t *testing.T) {
 	patches := []generatedPatch{
 		{name: "b.patch", body: "b"},
@@ -28,3 +56,133 @@ func TestSortAndPackageOrdersByName(t *testing.T) {
 		t.Fatalf("patches not sorted: %#v", out)
 	}
 }
+
+func TestMakeDiffsWarnsOnOversize(t *testing.T) {
+	c := &warn.Collector{}
+	warn.Use(c)
+	defer warn.Use(nil)
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "big.go")
+	if err := os.WriteFile(src, []byte("package big\n\nvar x = 2\n"), 0o644); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+	var d cache.Delta
+	d.Changed = append(d.Changed, struct {
+		Path       string `json:"path"`
+		HashBefore string `json:"hashBefore"`
+		HashAfter  string `json:"hashAfter"`
+		DiffPath   string `json:"diff"`
+		Oversize   bool   `json:"oversize"`
+	}{Path: "big.go", HashBefore: "aaaaaa", HashAfter: "bbbbbb"})
+	readOld := func(string) ([]byte, error) { return []byte("package big\n\nvar x = 1\n"), nil }
+	files := []walkwalk.FileInfo{{RelPath: "big.go", AbsPath: src}}
+	if _, err := MakeDiffs(d, files, diff.Options{MaxBytes: 8, NoPrefix: true}, readOld); err != nil {
+		t.Fatalf("MakeDiffs error: %v", err)
+	}
+	got := c.List()
+	if len(got) != 1 || got[0].Kind != warn.KindOversize || got[0].Path != "big.go" {
+		t.Fatalf("expected oversize warning, got %#v", got)
+	}
+}
+
+func TestMakeDiffsTotalBudget(t *testing.T) {
+	c := &warn.Collector{}
+	warn.Use(c)
+	defer warn.Use(nil)
+
+	dir := t.TempDir()
+	var d cache.Delta
+	var files []walkwalk.FileInfo
+	// Listed out of order: the budget must be spent in path order.
+	for _, name := range []string{"c.go", "d.go", "a.go", "b.go"} {
+		src := filepath.Join(dir, name)
+		if err := os.WriteFile(src, []byte("package p\n\nvar x = 2\n"), 0o644); err != nil {
+			t.Fatalf("write source: %v", err)
+		}
+		files = append(files, walkwalk.FileInfo{RelPath: name, AbsPath: src})
+		d.Changed = append(d.Changed, struct {
+			Path       string `json:"path"`
+			HashBefore string `json:"hashBefore"`
+			HashAfter  string `json:"hashAfter"`
+			DiffPath   string `json:"diff"`
+			Oversize   bool   `json:"oversize"`
+		}{Path: name, HashBefore: "aaaaaa", HashAfter: "bbbbbb"})
+	}
+	readOld := func(string) ([]byte, error) { return []byte("package p\n\nvar x = 1\n"), nil }
+
+	full, err := MakeDiffs(d, files, diff.Options{NoPrefix: true}, readOld)
+	if err != nil {
+		t.Fatalf("MakeDiffs error: %v", err)
+	}
+	one := len(full["a.go.patch"])
+	if one == 0 {
+		t.Fatalf("no diff for a.go: %v", full)
+	}
+
+	budget := 2*one + one/2
+	reads := 0
+	countingReadOld := func(h string) ([]byte, error) { reads++; return readOld(h) }
+	patches, err := MakeDiffs(d, files, diff.Options{NoPrefix: true, MaxTotalBytes: budget}, countingReadOld)
+	if err != nil {
+		t.Fatalf("MakeDiffs error: %v", err)
+	}
+	oversize := map[string]bool{}
+	for _, chg := range d.Changed {
+		oversize[chg.Path] = chg.Oversize
+	}
+	if want := map[string]bool{"a.go": false, "b.go": false, "c.go": true, "d.go": true}; !reflect.DeepEqual(oversize, want) {
+		t.Fatalf("oversize = %v, want %v", oversize, want)
+	}
+	if patches["a.go.patch"] != full["a.go.patch"] || !strings.Contains(patches["c.go.patch"], "omitted") {
+		t.Fatalf("patches = %v", patches)
+	}
+	// d.go comes after the budget ran out and is not diffed at all.
+	if reads != 3 {
+		t.Fatalf("readOld called %d times, want 3", reads)
+	}
+	if ws := c.List(); len(ws) != 2 || ws[0].Path != "c.go" || ws[1].Path != "d.go" || ws[0].Kind != warn.KindOversize {
+		t.Fatalf("warnings = %+v", ws)
+	}
+}
+
+func TestSynthesizeAddedPatchesChargesBudget(t *testing.T) {
+	c := &warn.Collector{}
+	warn.Use(c)
+	defer warn.Use(nil)
+
+	dir := t.TempDir()
+	var files []struct{ RelPath, AbsPath string }
+	for _, name := range []string{"z.go", "x.go", "y.go"} {
+		src := filepath.Join(dir, name)
+		if err := os.WriteFile(src, []byte("package p\n\nvar x = 1\n"), 0o644); err != nil {
+			t.Fatalf("write source: %v", err)
+		}
+		files = append(files, struct{ RelPath, AbsPath string }{name, src})
+	}
+	opt := diff.Options{NoPrefix: true}
+	full, err := synthesizeAddedPatches(files, opt, 0)
+	if err != nil {
+		t.Fatalf("synthesizeAddedPatches error: %v", err)
+	}
+	one := len(full[0].body)
+
+	// The changed-file diffs already spent all but room for one patch.
+	opt.MaxTotalBytes = 100 + one + one/2
+	patches, err := synthesizeAddedPatches(files, opt, 100)
+	if err != nil {
+		t.Fatalf("synthesizeAddedPatches error: %v", err)
+	}
+	var omitted []string
+	for _, p := range patches {
+		if diff.IsOmitted(string(p.body)) {
+			omitted = append(omitted, p.name)
+		}
+	}
+	if want := []string{"added/y.go", "added/z.go"}; !reflect.DeepEqual(omitted, want) {
+		t.Fatalf("omitted = %v, want %v", omitted, want)
+	}
+	if ws := c.List(); len(ws) != 2 || ws[0].Path != "y.go" || ws[1].Path != "z.go" {
+		t.Fatalf("warnings = %+v", ws)
+	}
+}
@@ -0,0 +1,113 @@
+package bundle
+
+import (
+	"fmt"
+	"html"
+	"path"
+	"strconv"
+	"strings"
+
+	"class-collector/internal/graph"
+	"class-collector/internal/index"
+	"class-collector/internal/ziputil"
+)
+
+// indexHTMLName is the optional browsable FULL bundle index.
+const indexHTMLName = "index.html"
+
+var emitHTML bool
+
+// SetEmitHTML toggles writing index.html into FULL bundles: a self-contained
+// page (inline CSS and JS, no external resources) linking the JSON artifacts
+// and listing files, their symbols and the graph's adjacency list. It holds
+// no timestamps, so it is as deterministic as the artifacts it renders.
+func SetEmitHTML(enable bool) { emitHTML = enable }
+
+// htmlStyle and htmlScript are inlined into index.html; the script only
+// filters the file list and graph by a search string.
+const (
+	htmlStyle = `body{font:14px/1.4 system-ui,sans-serif;margin:2em;color:#222}
+h1{font-size:1.4em}h2{font-size:1.15em;margin-top:2em}
+table{border-collapse:collapse;width:100%}th,td{text-align:left;padding:2px 8px;border-bottom:1px solid #eee;vertical-align:top}
+td.n{text-align:right}code{font:12px ui-monospace,monospace}
+details summary{cursor:pointer}ul.syms{margin:4px 0 4px 1em;padding:0}
+#q{width:24em;padding:4px;margin:1em 0}.hidden{display:none}`
+	htmlScript = `document.getElementById("q").addEventListener("input",function(e){
+var q=e.target.value.toLowerCase();
+document.querySelectorAll("[data-key]").forEach(function(el){
+el.classList.toggle("hidden",q!==""&&el.getAttribute("data-key").indexOf(q)<0);});});`
+)
+
+// writeIndexHTML renders index.html for a FULL bundle. srcLinked lists the
+// paths stored under src/, which the file list links to.
+func writeIndexHTML(zw Writer, man index.Manifest, syms index.Symbols, g graph.Graph, srcLinked map[string]bool) error {
+	esc := html.EscapeString
+	title := strings.TrimSpace(man.Module)
+	if title == "" {
+		title = "bundle"
+	}
+
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&b, "<title>%s</title>\n<style>\n%s\n</style>\n</head>\n<body>\n", esc(title), htmlStyle)
+	fmt.Fprintf(&b, "<h1>%s</h1>\n<p>", esc(title))
+	if man.BundleID != "" {
+		fmt.Fprintf(&b, "Bundle <code>%s</code> · ", esc(man.BundleID))
+	}
+	fmt.Fprintf(&b, "%d files · %d symbols · %d graph nodes</p>\n", len(man.Files), len(syms.Symbols), len(g.Nodes))
+	b.WriteString("<p>")
+	for i, name := range []string{"README.md", "TOC.md", "manifest.json", "symbols.json", "graph.json"} {
+		if i > 0 {
+			b.WriteString(" · ")
+		}
+		fmt.Fprintf(&b, "<a href=\"%s\">%s</a>", esc(name), esc(name))
+	}
+	b.WriteString("</p>\n<input id=\"q\" type=\"search\" placeholder=\"Filter files and graph nodes\">\n")
+
+	bySrc := make(map[string][]index.Symbol, len(man.Files))
+	for _, s := range syms.Symbols {
+		bySrc[s.Path] = append(bySrc[s.Path], s)
+	}
+	b.WriteString("<h2>Files</h2>\n<table>\n<tr><th>#</th><th>Path</th><th>Kind</th><th>Lines</th></tr>\n")
+	for i, f := range man.Files {
+		name := esc(f.Path)
+		if srcLinked[f.Path] {
+			name = fmt.Sprintf("<a href=\"%s\">%s</a>", esc(ziputil.SanitizePath(path.Join("src", f.Path))), name)
+		}
+		if fs := bySrc[f.Path]; len(fs) > 0 {
+			var list strings.Builder
+			for _, s := range fs {
+				fmt.Fprintf(&list, "<li><code>%s</code> %s %d–%d</li>", esc(s.Symbol), esc(s.Kind), s.Start, s.End)
+			}
+			name = fmt.Sprintf("<details><summary>%s (%d symbols)</summary><ul class=\"syms\">%s</ul></details>", name, len(fs), list.String())
+		}
+		fmt.Fprintf(&b, "<tr data-key=\"%s\"><td class=\"n\">%d</td><td>%s</td><td>%s</td><td class=\"n\">%s</td></tr>\n",
+			esc(strings.ToLower(f.Path)), i+1, name, esc(f.Kind), strconv.Itoa(f.Lines))
+	}
+	b.WriteString("</table>\n")
+
+	targets := make(map[string][]string, len(g.Nodes))
+	for _, e := range g.Edges {
+		targets[e[0]] = append(targets[e[0]], e[1])
+	}
+	b.WriteString("<h2>Graph</h2>\n<table>\n<tr><th>Node</th><th>Imports</th></tr>\n")
+	for _, n := range g.Nodes {
+		to := targets[n]
+		if len(to) == 0 {
+			continue
+		}
+		quoted := make([]string, len(to))
+		for i, t := range to {
+			quoted[i] = "<code>" + esc(t) + "</code>"
+		}
+		fmt.Fprintf(&b, "<tr data-key=\"%s\"><td><code>%s</code></td><td>%s</td></tr>\n",
+			esc(strings.ToLower(n)), esc(n), strings.Join(quoted, ", "))
+	}
+	b.WriteString("</table>\n")
+	fmt.Fprintf(&b, "<script>\n%s\n</script>\n</body>\n</html>\n", htmlScript)
+
+	if err := ziputil.WriteText(zw, indexHTMLName, []byte(b.String())); err != nil {
+		return fmt.Errorf("write %s: %w", indexHTMLName, err)
+	}
+	return nil
+}
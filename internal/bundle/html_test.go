@@ -0,0 +1,82 @@
+package bundle
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"class-collector/internal/graph"
+	"class-collector/internal/index"
+)
+
+func TestWriteFullEmitsIndexHTML(t *testing.T) {
+	SetEmitHTML(true)
+	defer SetEmitHTML(false)
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(src, []byte("package main\n\nfunc main() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	man := index.Manifest{
+		Module: "demo<x>",
+		Files:  []index.ManFile{{Path: "main.go", Kind: "file", Lines: 3}},
+	}
+	man.BundleID = index.ComputeBundleID(man)
+	syms := index.Symbols{Version: 1, Symbols: []index.Symbol{{Symbol: "main.main", Kind: "func", Path: "main.go", Start: 3, End: 3}}}
+	g := graph.Graph{Nodes: []string{"go:main", "go:fmt"}, Edges: [][2]string{{"go:main", "go:fmt"}}}
+	files := []struct{ RelPath, AbsPath string }{{RelPath: "main.go", AbsPath: src}}
+
+	var pages []string
+	for i := 0; i < 2; i++ {
+		out := filepath.Join(dir, "full.zip")
+		if err := WriteFull(out, FullArtifacts{Artifacts: index.Artifacts{Manifest: man, Symbols: syms, Graph: g}, Sources: files}, "", 3, false); err != nil {
+			t.Fatalf("WriteFull error: %v", err)
+		}
+		pages = append(pages, indexHTML(t, out))
+	}
+	page := pages[0]
+	if page != pages[1] {
+		t.Fatalf("index.html differs between runs")
+	}
+	for _, want := range []string{
+		`href="TOC.md"`, `href="manifest.json"`, `href="symbols.json"`, `href="graph.json"`,
+		`<a href="src/main.go">main.go</a>`, "<code>main.main</code> func 3–3",
+		"<code>go:main</code></td><td><code>go:fmt</code>", "demo&lt;x&gt;", man.BundleID,
+	} {
+		if !strings.Contains(page, want) {
+			t.Fatalf("index.html missing %q:\n%s", want, page)
+		}
+	}
+	if strings.Contains(page, "http://") || strings.Contains(page, "https://") {
+		t.Fatalf("index.html must not load external resources")
+	}
+	today := time.Now().UTC().Format("2006-01-02")
+	if regexp.MustCompile(`\d{4}-\d{2}-\d{2}|\d{2}:\d{2}:\d{2}`).MatchString(page) || strings.Contains(page, today) {
+		t.Fatalf("index.html contains wall-clock data:\n%s", page)
+	}
+}
+
+func indexHTML(t *testing.T, path string) string {
+	t.Helper()
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		if f.Name == "index.html" {
+			data, err := readZipEntry(f)
+			if err != nil {
+				t.Fatalf("read index.html: %v", err)
+			}
+			return string(data)
+		}
+	}
+	t.Fatalf("index.html missing")
+	return ""
+}
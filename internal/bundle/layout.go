@@ -0,0 +1,78 @@
+package bundle
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"class-collector/internal/textutil"
+	"class-collector/internal/ziputil"
+)
+
+// DeltaLayout maps logical DELTA artifacts to ZIP entry names. Diffs and Added
+// are directory prefixes; the others are file names.
+type DeltaLayout struct {
+	Diffs   string `json:"diffs"`
+	Added   string `json:"added"`
+	Patch   string `json:"patch"`
+	Index   string `json:"index"`
+	Summary string `json:"summary"`
+	Readme  string `json:"readme"`
+	// CurrentFiles lists every file of the current tree
+	// (-delta-include-unchanged-manifest, see CurrentManifest).
+	CurrentFiles string `json:"currentFiles"`
+	// RenameReport holds the scored rename pairs
+	// (-diff-rename-similarity-report).
+	RenameReport string `json:"renameReport"`
+}
+
+// DefaultDeltaLayout returns the built-in DELTA layout.
+func DefaultDeltaLayout() DeltaLayout {
+	return DeltaLayout{
+		Diffs:   "diffs",
+		Added:   "added",
+		Patch:   "delta.patch",
+		Index:   "delta.index.json",
+		Summary: "SUMMARY.md",
+		Readme:  "README.md",
+
+		CurrentFiles: "current-files.json",
+		RenameReport: "rename-report.json",
+	}
+}
+
+var deltaLayout = DefaultDeltaLayout()
+
+// SetDeltaLayout overrides the global DELTA layout used by MakeDiffs and
+// WriteDelta.
+func SetDeltaLayout(l DeltaLayout) { deltaLayout = l }
+
+// ParseDeltaLayout decodes a JSON object of overrides on top of
+// DefaultDeltaLayout; comments and trailing commas are allowed. Unknown keys
+// are rejected, and every name is sanitized into a relative ZIP path;
+// prefixes lose any trailing slash.
+func ParseDeltaLayout(text string) (DeltaLayout, error) {
+	l := DefaultDeltaLayout()
+	if strings.TrimSpace(text) == "" {
+		return l, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(textutil.StripJSONC([]byte(text))))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&l); err != nil {
+		return DeltaLayout{}, fmt.Errorf("parse output layout: %w", err)
+	}
+	fields := []*string{&l.Diffs, &l.Added, &l.Patch, &l.Index, &l.Summary, &l.Readme, &l.CurrentFiles, &l.RenameReport}
+	seen := make(map[string]struct{}, len(fields))
+	for _, f := range fields {
+		if strings.TrimSpace(*f) == "" {
+			return DeltaLayout{}, fmt.Errorf("output layout: names must be non-empty")
+		}
+		*f = ziputil.SanitizePath(*f)
+		if _, dup := seen[*f]; dup {
+			return DeltaLayout{}, fmt.Errorf("output layout: duplicate name %q", *f)
+		}
+		seen[*f] = struct{}{}
+	}
+	return l, nil
+}
@@ -0,0 +1,91 @@
+package bundle
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"class-collector/internal/cache"
+	"class-collector/internal/diff"
+	"class-collector/internal/walkwalk"
+)
+
+func TestWriteDeltaCustomLayout(t *testing.T) {
+	layout, err := ParseDeltaLayout(`{"diffs": "patches/", "renameReport": "renames.json"}`)
+	if err != nil {
+		t.Fatalf("ParseDeltaLayout error: %v", err)
+	}
+	SetDeltaLayout(layout)
+	defer SetDeltaLayout(DefaultDeltaLayout())
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(src, []byte("package main\n\nfunc main() { println(2) }\n"), 0o644); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+	var d cache.Delta
+	d.Changed = append(d.Changed, struct {
+		Path       string `json:"path"`
+		HashBefore string `json:"hashBefore"`
+		HashAfter  string `json:"hashAfter"`
+		DiffPath   string `json:"diff"`
+		Oversize   bool   `json:"oversize"`
+	}{Path: "main.go", HashBefore: "aaaaaa", HashAfter: "bbbbbb"})
+	readOld := func(string) ([]byte, error) { return []byte("package main\n\nfunc main() { println(1) }\n"), nil }
+	files := []walkwalk.FileInfo{{RelPath: "main.go", AbsPath: src}}
+	patches, err := MakeDiffs(d, files, diff.Options{Context: 3, NoPrefix: true}, readOld)
+	if err != nil {
+		t.Fatalf("MakeDiffs error: %v", err)
+	}
+
+	out := filepath.Join(dir, "delta.zip")
+	if err := WriteDelta(out, DeltaArtifacts{Index: d, Diffs: patches, RenameReport: []cache.RenameCandidate{}}, "", diff.Options{Context: 3, NoPrefix: true}); err != nil {
+		t.Fatalf("WriteDelta error: %v", err)
+	}
+	zr, err := zip.OpenReader(out)
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	defer zr.Close()
+	var idx cache.Delta
+	seen := map[string]bool{}
+	for _, f := range zr.File {
+		seen[f.Name] = true
+		if f.Name != "delta.index.json" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open index: %v", err)
+		}
+		body, _ := io.ReadAll(rc)
+		_ = rc.Close()
+		if err := json.Unmarshal(body, &idx); err != nil {
+			t.Fatalf("decode index: %v", err)
+		}
+	}
+	if !seen["patches/main.go.patch"] || seen["diffs/main.go.patch"] {
+		t.Fatalf("expected patch under patches/, got %v", seen)
+	}
+	if !seen["renames.json"] || seen["rename-report.json"] {
+		t.Fatalf("expected the rename report as renames.json, got %v", seen)
+	}
+	if len(idx.Changed) != 1 || idx.Changed[0].DiffPath != "patches/main.go.patch" {
+		t.Fatalf("index should reference patches/: %#v", idx.Changed)
+	}
+}
+
+func TestParseDeltaLayoutRejectsBadNames(t *testing.T) {
+	for _, text := range []string{`{"diff": "x"}`, `{"added": ""}`, `{"patch": "SUMMARY.md"}`, `{"index": "rename-report.json"}`} {
+		if _, err := ParseDeltaLayout(text); err == nil {
+			t.Fatalf("ParseDeltaLayout(%s) should fail", text)
+		}
+	}
+	l, err := ParseDeltaLayout(`{"added": "../../new/"}`)
+	if err != nil || l.Added != "new" {
+		t.Fatalf("expected sanitized prefix, got %q (%v)", l.Added, err)
+	}
+}
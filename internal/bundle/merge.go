@@ -0,0 +1,354 @@
+// Package bundle — merging of existing FULL bundles.
+//
+// Merge combines several FULL archives into one. Every input gets a unique
+// path prefix (its manifest module, sanitized; "-2", "-3", … on collision) so
+// files from different services never clash:
+//
+//	manifest/symbols/slices paths   <prefix>/<path>
+//	manifest aliases, goModules     <prefix>/<path>
+//	pointer IDs                     <prefix>-<id>
+//	src/ copies                     src/<prefix>/<path>
+//	project graph nodes, calls      <lang>:<prefix>/<name> (py:<prefix>.<name>)
+//
+// Graph nodes of an input's own files (see localNodes) are namespaced so two
+// services' "go:internal/db" stay apart and js: nodes keep matching the
+// prefixed paths; dependencies such as npm:<pkg> or go:fmt are shared and
+// merge. NodeKinds follow their nodes. The bundle ID is recomputed over the
+// prefixed manifest and all outputs are sorted.
+package bundle
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"class-collector/internal/graph"
+	"class-collector/internal/index"
+	"class-collector/internal/ziputil"
+)
+
+// mergeInput holds the artifacts read from one FULL bundle.
+type mergeInput struct {
+	prefix   string
+	man      index.Manifest
+	syms     index.Symbols
+	slices   []index.Slice
+	pointers []index.Pointer
+	graph    graph.Graph
+	src      map[string][]byte // path (without "src/") -> content
+}
+
+// Merge reads the FULL bundles in inputs and writes their union to
+// outputPath in the current output format (see SetOutFormat).
+func Merge(outputPath string, inputs []string) (err error) {
+	if len(inputs) == 0 {
+		return fmt.Errorf("merge: no input bundles")
+	}
+	used := make(map[string]struct{}, len(inputs))
+	parts := make([]mergeInput, 0, len(inputs))
+	for _, in := range inputs {
+		mi, err := readFullBundle(in)
+		if err != nil {
+			return fmt.Errorf("merge: read %s: %w", in, err)
+		}
+		name := mi.man.Module
+		if strings.TrimSpace(name) == "" {
+			name = strings.TrimSuffix(filepath.Base(in), filepath.Ext(in))
+		}
+		mi.prefix = uniquePrefix(mergePrefix(name), used)
+		parts = append(parts, mi)
+	}
+
+	var (
+		art     index.Artifacts
+		modules []string
+		nodes   = map[string]struct{}{}
+		kinds   = map[string]string{}
+		edges   = map[[2]string]struct{}{}
+		calls   = map[[2]string]struct{}{}
+		srcs    []struct {
+			name string
+			data []byte
+		}
+	)
+	art.Symbols.Version = 1
+	for _, p := range parts {
+		modules = append(modules, p.prefix)
+		for _, f := range p.man.Files {
+			f.Path = p.prefix + "/" + f.Path
+			if len(f.Aliases) > 0 {
+				aliases := make([]string, len(f.Aliases))
+				for i, a := range f.Aliases {
+					aliases[i] = p.prefix + "/" + a
+				}
+				f.Aliases = aliases
+			}
+			art.Manifest.Files = append(art.Manifest.Files, f)
+		}
+		for _, m := range p.man.GoModules {
+			m.Dir = path.Join(p.prefix, m.Dir)
+			art.Manifest.GoModules = append(art.Manifest.GoModules, m)
+		}
+		for _, s := range p.syms.Symbols {
+			s.Path = p.prefix + "/" + s.Path
+			art.Symbols.Symbols = append(art.Symbols.Symbols, s)
+		}
+		for _, s := range p.slices {
+			s.Path = p.prefix + "/" + s.Path
+			art.Slices = append(art.Slices, s)
+		}
+		for _, ptr := range p.pointers {
+			ptr.ID = p.prefix + "-" + ptr.ID
+			ptr.Path = p.prefix + "/" + ptr.Path
+			art.Pointers = append(art.Pointers, ptr)
+		}
+		local := localNodes(p)
+		node := func(n string) string {
+			if local[n] {
+				return prefixNode(p.prefix, n)
+			}
+			return n
+		}
+		for _, n := range p.graph.Nodes {
+			nodes[node(n)] = struct{}{}
+		}
+		for n, k := range p.graph.NodeKinds {
+			kinds[node(n)] = k
+		}
+		for _, e := range p.graph.Edges {
+			edges[[2]string{node(e[0]), node(e[1])}] = struct{}{}
+		}
+		for _, e := range p.graph.Calls {
+			// Calls only link project symbols.
+			calls[[2]string{prefixNode(p.prefix, e[0]), prefixNode(p.prefix, e[1])}] = struct{}{}
+		}
+		art.Graph.Dropped += p.graph.Dropped
+		for rel, data := range p.src {
+			srcs = append(srcs, struct {
+				name string
+				data []byte
+			}{name: "src/" + p.prefix + "/" + rel, data: data})
+		}
+	}
+
+	sort.Slice(art.Manifest.Files, func(i, j int) bool { return art.Manifest.Files[i].Path < art.Manifest.Files[j].Path })
+	sort.Slice(art.Symbols.Symbols, func(i, j int) bool {
+		a, b := art.Symbols.Symbols[i], art.Symbols.Symbols[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Start != b.Start {
+			return a.Start < b.Start
+		}
+		if a.End != b.End {
+			return a.End < b.End
+		}
+		return a.Symbol < b.Symbol
+	})
+	for n := range nodes {
+		art.Graph.Nodes = append(art.Graph.Nodes, n)
+	}
+	sort.Strings(art.Graph.Nodes)
+	if len(kinds) > 0 {
+		art.Graph.NodeKinds = kinds
+	}
+	sort.Slice(art.Manifest.GoModules, func(i, j int) bool { return art.Manifest.GoModules[i].Dir < art.Manifest.GoModules[j].Dir })
+	for e := range edges {
+		art.Graph.Edges = append(art.Graph.Edges, e)
+	}
+	sort.Slice(art.Graph.Edges, func(i, j int) bool {
+		if art.Graph.Edges[i][0] == art.Graph.Edges[j][0] {
+			return art.Graph.Edges[i][1] < art.Graph.Edges[j][1]
+		}
+		return art.Graph.Edges[i][0] < art.Graph.Edges[j][0]
+	})
+	for e := range calls {
+		art.Graph.Calls = append(art.Graph.Calls, e)
+	}
+	sort.Slice(art.Graph.Calls, func(i, j int) bool {
+		if art.Graph.Calls[i][0] == art.Graph.Calls[j][0] {
+			return art.Graph.Calls[i][1] < art.Graph.Calls[j][1]
+		}
+		return art.Graph.Calls[i][0] < art.Graph.Calls[j][0]
+	})
+	sortSlices(art.Slices)
+	sortPointers(art.Pointers)
+	sort.Slice(srcs, func(i, j int) bool { return srcs[i].name < srcs[j].name })
+
+	art.Manifest.Module = strings.Join(modules, "+")
+	art.Manifest.BundleID = index.ComputeBundleID(art.Manifest)
+	art.Manifest.BundleIDAlgo = index.BundleIDAlgo()
+	art.Manifest.ToolVersion, art.Manifest.ManifestVersion = index.ToolVersion, index.ManifestVersion
+
+	zw, err := createWriter(outputPath)
+	if err != nil {
+		return err
+	}
+	defer closeWriter(zw, &err)
+
+	if err := writeCoreJson(zw, art); err != nil {
+		return err
+	}
+	if err := writeReadmeFull(zw, ReadmeOptions{
+		ModuleName:       art.Manifest.Module,
+		SupportedLangs:   supportedLangs(),
+		PresentLangs:     presentLangsFromManifest(art.Manifest),
+		IncludeFullNotes: true,
+	}); err != nil {
+		return err
+	}
+	if err := writeToc(zw, art.Manifest); err != nil {
+		return err
+	}
+	for _, s := range srcs {
+		if err := ziputil.WriteFile(zw, s.name, s.data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// localNodes returns the graph nodes that stand for in's own files: those
+// labelled internal when the graph carries NodeKinds; otherwise every js:
+// node (always a project path), the nodes of manifest files, go: packages
+// under the manifest's module paths, and any node that imports something or
+// is imported by nothing (only scanned files add such nodes).
+func localNodes(in mergeInput) map[string]bool {
+	g := in.graph
+	local := make(map[string]bool, len(g.Nodes))
+	if g.NodeKinds != nil {
+		for n, k := range g.NodeKinds {
+			if k == graph.NodeInternal {
+				local[n] = true
+			}
+		}
+		return local
+	}
+	var modules []string
+	if in.man.Module != "" {
+		modules = append(modules, in.man.Module)
+	}
+	for _, m := range in.man.GoModules {
+		modules = append(modules, m.Path)
+	}
+	for _, f := range in.man.Files {
+		switch strings.ToLower(path.Ext(f.Path)) {
+		case ".py":
+			local[graph.PyNode(f.Path)] = true
+		case ".rs":
+			local[graph.RsNode(f.Path)] = true
+		}
+	}
+	targets := make(map[string]bool, len(g.Edges))
+	for _, e := range g.Edges {
+		local[e[0]] = true
+		targets[e[1]] = true
+	}
+	for _, n := range g.Nodes {
+		if !targets[n] || strings.HasPrefix(n, "js:") {
+			local[n] = true
+			continue
+		}
+		if pkg, ok := strings.CutPrefix(n, "go:"); ok {
+			for _, m := range modules {
+				if pkg == m || strings.HasPrefix(pkg, m+"/") {
+					local[n] = true
+				}
+			}
+		}
+	}
+	return local
+}
+
+// prefixNode namespaces the graph node n with prefix behind its language
+// tag, the way the node of the prefixed file would read: js:<prefix>/<path>,
+// py:<prefix>.<module>, and <lang>:<prefix>/<name> otherwise.
+func prefixNode(prefix, n string) string {
+	lang, rest, ok := strings.Cut(n, ":")
+	switch {
+	case !ok:
+		return prefix + "/" + n
+	case lang == "py":
+		return lang + ":" + prefix + "." + rest
+	}
+	return lang + ":" + prefix + "/" + rest
+}
+
+// readFullBundle loads the artifacts of one FULL bundle.
+func readFullBundle(path string) (mergeInput, error) {
+	b, err := Open(path)
+	if err != nil {
+		return mergeInput{}, err
+	}
+	return mergeInput{
+		man:      b.Manifest,
+		syms:     b.Symbols,
+		slices:   b.Slices,
+		pointers: b.Pointers,
+		graph:    b.Graph,
+		src:      b.Sources,
+	}, nil
+}
+
+func readZipEntry(zf *zip.File) ([]byte, error) {
+	rc, err := zf.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+func decodeJSONL[T any](data []byte) ([]T, error) {
+	var out []T
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var v T
+		if err := json.Unmarshal(line, &v); err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, sc.Err()
+}
+
+// mergePrefix turns a module name into a single safe path segment.
+func mergePrefix(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	s := strings.Trim(b.String(), ".")
+	if s == "" {
+		s = "bundle"
+	}
+	return s
+}
+
+func uniquePrefix(p string, used map[string]struct{}) string {
+	cand := p
+	for n := 2; ; n++ {
+		if _, ok := used[cand]; !ok {
+			used[cand] = struct{}{}
+			return cand
+		}
+		cand = fmt.Sprintf("%s-%d", p, n)
+	}
+}
@@ -0,0 +1,169 @@
+package bundle
+
+import (
+	"archive/zip"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"class-collector/internal/graph"
+	"class-collector/internal/index"
+)
+
+func writeTestFull(t *testing.T, path, module string, files ...string) {
+	t.Helper()
+	man := index.Manifest{Module: module}
+	var syms index.Symbols
+	for _, f := range files {
+		man.Files = append(man.Files, index.ManFile{Path: f, Lines: 1})
+		syms.Symbols = append(syms.Symbols, index.Symbol{Symbol: module + "." + f, Path: f, Start: 1, End: 1})
+	}
+	syms.Version = 1
+	man.BundleID = index.ComputeBundleID(man)
+	g := graph.Graph{Nodes: []string{"go:" + module, "go:fmt"}, Edges: [][2]string{{"go:" + module, "go:fmt"}}}
+	ptrs := []index.Pointer{{ID: "p", Path: files[0], Start: 1, End: 1}}
+	if err := WriteFull(path, FullArtifacts{Artifacts: index.Artifacts{Manifest: man, Symbols: syms, Pointers: ptrs, Graph: g}}, "", 3, true); err != nil {
+		t.Fatalf("WriteFull error: %v", err)
+	}
+}
+
+func TestMergeBundlesPrefixesPaths(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.zip"), filepath.Join(dir, "b.zip")
+	writeTestFull(t, a, "billing", "main.go", "api/api.go")
+	writeTestFull(t, b, "users", "main.go")
+
+	out := filepath.Join(dir, "merged.zip")
+	if err := Merge(out, []string{a, b}); err != nil {
+		t.Fatalf("Merge error: %v", err)
+	}
+	mi, err := readFullBundle(out)
+	if err != nil {
+		t.Fatalf("read merged: %v", err)
+	}
+
+	var paths []string
+	for _, f := range mi.man.Files {
+		paths = append(paths, f.Path)
+	}
+	if want := []string{"billing/api/api.go", "billing/main.go", "users/main.go"}; !reflect.DeepEqual(paths, want) {
+		t.Fatalf("merged paths = %v, want %v", paths, want)
+	}
+	if mi.man.Module != "billing+users" || mi.man.BundleID != index.ComputeBundleID(mi.man) {
+		t.Fatalf("unexpected merged manifest header: %+v", mi.man)
+	}
+	if len(mi.syms.Symbols) != 3 || mi.syms.Symbols[0].Path != "billing/api/api.go" {
+		t.Fatalf("symbols not merged: %+v", mi.syms.Symbols)
+	}
+	if len(mi.pointers) != 2 || mi.pointers[0].ID != "billing-p" || mi.pointers[1].Path != "users/main.go" {
+		t.Fatalf("pointers not merged: %+v", mi.pointers)
+	}
+	if want := []string{"go:billing/billing", "go:fmt", "go:users/users"}; !reflect.DeepEqual(mi.graph.Nodes, want) {
+		t.Fatalf("graph nodes = %v, want %v", mi.graph.Nodes, want)
+	}
+
+	// Same inputs must yield identical bytes.
+	out2 := filepath.Join(dir, "merged2.zip")
+	if err := Merge(out2, []string{a, b}); err != nil {
+		t.Fatalf("Merge error: %v", err)
+	}
+	if manifestJSON(t, out) != manifestJSON(t, out2) {
+		t.Fatalf("merge not deterministic")
+	}
+}
+
+func TestMergeNamespacesInternalGraphNodes(t *testing.T) {
+	dir := t.TempDir()
+	write := func(path, module string) {
+		t.Helper()
+		man := index.Manifest{
+			Module:    module,
+			Files:     []index.ManFile{{Path: "src/app.ts", Lines: 1, Aliases: []string{"src/copy.ts"}}, {Path: "src/util.ts", Lines: 1}},
+			GoModules: []index.GoModule{{Dir: ".", Path: "example.com/" + module}},
+		}
+		man.BundleID = index.ComputeBundleID(man)
+		g := graph.Graph{
+			Nodes:     []string{"js:src/app", "js:src/util", "npm:react"},
+			Edges:     [][2]string{{"js:src/app", "js:src/util"}, {"js:src/app", "npm:react"}},
+			NodeKinds: map[string]string{"js:src/app": graph.NodeInternal, "js:src/util": graph.NodeInternal, "npm:react": graph.NodeExternal},
+		}
+		if err := WriteFull(path, FullArtifacts{Artifacts: index.Artifacts{Manifest: man, Graph: g}}, "", 3, true); err != nil {
+			t.Fatalf("WriteFull error: %v", err)
+		}
+	}
+	a, b := filepath.Join(dir, "a.zip"), filepath.Join(dir, "b.zip")
+	write(a, "billing")
+	write(b, "users")
+
+	out := filepath.Join(dir, "merged.zip")
+	if err := Merge(out, []string{a, b}); err != nil {
+		t.Fatalf("Merge error: %v", err)
+	}
+	mi, err := readFullBundle(out)
+	if err != nil {
+		t.Fatalf("read merged: %v", err)
+	}
+	wantNodes := []string{"js:billing/src/app", "js:billing/src/util", "js:users/src/app", "js:users/src/util", "npm:react"}
+	if !reflect.DeepEqual(mi.graph.Nodes, wantNodes) {
+		t.Fatalf("graph nodes = %v, want %v", mi.graph.Nodes, wantNodes)
+	}
+	if len(mi.graph.Edges) != 4 || mi.graph.Edges[0] != [2]string{"js:billing/src/app", "js:billing/src/util"} {
+		t.Fatalf("graph edges = %v", mi.graph.Edges)
+	}
+	if mi.graph.NodeKinds["js:users/src/util"] != graph.NodeInternal || mi.graph.NodeKinds["npm:react"] != graph.NodeExternal {
+		t.Fatalf("node kinds = %v", mi.graph.NodeKinds)
+	}
+	if graph.JSNode(mi.man.Files[0].Path) != "js:billing/src/app" {
+		t.Fatalf("js: node does not match the prefixed path %s", mi.man.Files[0].Path)
+	}
+	if got := mi.man.Files[0].Aliases; !reflect.DeepEqual(got, []string{"billing/src/copy.ts"}) {
+		t.Fatalf("aliases = %v", got)
+	}
+	wantMods := []index.GoModule{{Dir: "billing", Path: "example.com/billing"}, {Dir: "users", Path: "example.com/users"}}
+	if !reflect.DeepEqual(mi.man.GoModules, wantMods) {
+		t.Fatalf("goModules = %+v, want %+v", mi.man.GoModules, wantMods)
+	}
+}
+
+func manifestJSON(t *testing.T, path string) string {
+	t.Helper()
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		if f.Name == "manifest.json" {
+			data, err := readZipEntry(f)
+			if err != nil {
+				t.Fatalf("read manifest: %v", err)
+			}
+			return string(data)
+		}
+	}
+	t.Fatalf("manifest.json missing")
+	return ""
+}
+
+func TestMergeFollowsOutFormat(t *testing.T) {
+	defer SetOutFormat("")
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.zip"), filepath.Join(dir, "b.zip")
+	writeTestFull(t, a, "billing", "main.go")
+	writeTestFull(t, b, "users", "main.go")
+
+	SetOutFormat(OutFormatTgz)
+	out := filepath.Join(dir, "merged.tgz")
+	if err := Merge(out, []string{a, b}); err != nil {
+		t.Fatalf("Merge error: %v", err)
+	}
+	entries, err := ReadEntries(out)
+	if err != nil {
+		t.Fatalf("read merged tgz: %v", err)
+	}
+	for _, name := range []string{"manifest.json", "symbols.json", "pointers.jsonl"} {
+		if _, ok := entries[name]; !ok {
+			t.Fatalf("%s missing from tgz merge", name)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package bundle
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+
+	"class-collector/internal/cache"
+	"class-collector/internal/graph"
+	"class-collector/internal/index"
+	"class-collector/internal/ziputil"
+)
+
+// Bundle is the parsed content of a FULL archive, as returned by Open.
+// Optional entries that are absent leave their field at the zero value.
+type Bundle struct {
+	Manifest index.Manifest
+	Symbols  index.Symbols
+	Graph    graph.Graph
+	Slices   []index.Slice
+	Pointers []index.Pointer
+	Sources  map[string][]byte // src/ entries keyed by sanitized project path; empty unless -emit-src
+}
+
+// Open reads the FULL bundle at path (in any -out-format, see ReadEntries)
+// back into typed structures. Only manifest.json is required; symbols,
+// graph, slices, pointers and sources are parsed when present. A DELTA
+// bundle (one holding the delta index of the current layout) is rejected.
+func Open(path string) (*Bundle, error) {
+	entries, err := ReadEntries(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := entries[deltaLayout.Index]; ok {
+		return nil, fmt.Errorf("%s found: a DELTA bundle, not a FULL one", deltaLayout.Index)
+	}
+	b := &Bundle{Sources: map[string][]byte{}}
+	seenManifest := false
+	for _, name := range sortedNames(entries) {
+		data := entries[name]
+		switch {
+		case name == "manifest.json":
+			seenManifest = true
+			err = json.Unmarshal(data, &b.Manifest)
+		case name == "symbols.json":
+			b.Symbols, err = decodeSymbols(data)
+		case name == "graph.json":
+			err = json.Unmarshal(data, &b.Graph)
+		case name == "slices.jsonl":
+			b.Slices, err = decodeJSONL[index.Slice](data)
+		case name == "pointers.jsonl":
+			b.Pointers, err = decodeJSONL[index.Pointer](data)
+		case strings.HasPrefix(name, "src/"):
+			b.Sources[strings.TrimPrefix(name, "src/")] = data
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	if !seenManifest {
+		return nil, fmt.Errorf("manifest.json not found (not a FULL bundle?)")
+	}
+	return b, nil
+}
+
+// ReadEntries returns the entries of the bundle at path by name, whatever
+// format it was written in: a directory tree (OutFormatDir), a
+// gzip-compressed tar archive (OutFormatTgz, recognized by its magic bytes)
+// or a ZIP archive.
+func ReadEntries(path string) (map[string][]byte, error) {
+	st, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	entries := map[string][]byte{}
+	if st.IsDir() {
+		root := os.DirFS(path)
+		err := fs.WalkDir(root, ".", func(name string, d fs.DirEntry, err error) error {
+			if err != nil || !d.Type().IsRegular() {
+				return err
+			}
+			data, err := fs.ReadFile(root, name)
+			entries[name] = data
+			return err
+		})
+		return entries, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	magic := make([]byte, 2)
+	if n, _ := io.ReadFull(f, magic); n == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		tr := tar.NewReader(gz)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				return entries, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			if hdr.Typeflag != tar.TypeReg {
+				continue
+			}
+			if entries[hdr.Name], err = io.ReadAll(tr); err != nil {
+				return nil, fmt.Errorf("%s: %w", hdr.Name, err)
+			}
+		}
+	}
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	for _, zf := range zr.File {
+		if strings.HasSuffix(zf.Name, "/") {
+			continue
+		}
+		if entries[zf.Name], err = readZipEntry(zf); err != nil {
+			return nil, fmt.Errorf("%s: %w", zf.Name, err)
+		}
+	}
+	return entries, nil
+}
+
+// sortedNames returns the entry names in lexical order.
+func sortedNames(entries map[string][]byte) []string {
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Snapshot converts the manifest into a cache snapshot (path, hash, lines) so
+// the bundle can serve as the "prev" side of cache.BuildDelta. Symlink
+// entries are skipped, as they are in snapshots built from a tree.
+func (b *Bundle) Snapshot() *cache.Snapshot {
+	snap := &cache.Snapshot{
+		Module:        b.Manifest.Module,
+		FormatVersion: "1",
+		Files:         make([]cache.SnapFile, 0, len(b.Manifest.Files)),
+	}
+	for _, f := range b.Manifest.Files {
+		if f.Kind == "symlink" {
+			continue
+		}
+		snap.Files = append(snap.Files, cache.SnapFile{Path: f.Path, Hash: f.Hash, Lines: f.Lines})
+	}
+	sort.Slice(snap.Files, func(i, j int) bool { return snap.Files[i].Path < snap.Files[j].Path })
+	return snap
+}
+
+// ReadOld returns a MakeDiffs content callback backed by the bundle's src/
+// entries. Hashes without a stored copy yield ErrOldContentUnavailable, so
+// their diffs become oversize placeholders.
+func (b *Bundle) ReadOld() func(hash string) ([]byte, error) {
+	byHash := make(map[string][]byte, len(b.Sources))
+	for _, f := range b.Manifest.Files {
+		key := strings.TrimPrefix(ziputil.SanitizePath("src/"+f.Path), "src/")
+		if data, ok := b.Sources[key]; ok && f.Hash != "" {
+			byHash[f.Hash] = data
+		}
+	}
+	return func(hash string) ([]byte, error) {
+		if data, ok := byHash[hash]; ok {
+			return data, nil
+		}
+		return nil, ErrOldContentUnavailable
+	}
+}
+
+// decodeSymbols accepts both symbols.json layouts; a tree is flattened.
+func decodeSymbols(data []byte) (index.Symbols, error) {
+	var head struct {
+		Format string `json:"format"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return index.Symbols{}, err
+	}
+	if head.Format == SymbolsFormatTree {
+		var t index.SymbolTree
+		if err := json.Unmarshal(data, &t); err != nil {
+			return index.Symbols{}, err
+		}
+		return index.FlattenSymbolTree(t), nil
+	}
+	var s index.Symbols
+	err := json.Unmarshal(data, &s)
+	return s, err
+}
@@ -0,0 +1,172 @@
+package bundle
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"class-collector/internal/graph"
+	"class-collector/internal/index"
+	"class-collector/internal/ziputil"
+)
+
+func TestOpenRoundTripsFullBundle(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(src, []byte("package main\n\nfunc main() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	man := index.Manifest{
+		Module: "demo",
+		Build:  "go",
+		Files:  []index.ManFile{{Path: "main.go", Package: "main", Lines: 3, Exports: []string{"main()"}}},
+	}
+	man.BundleID = index.ComputeBundleID(man)
+	syms := index.Symbols{Version: 1, Symbols: []index.Symbol{{Symbol: "main.main", Kind: "func", Path: "main.go", Start: 3, End: 3}}}
+	slices := []index.Slice{{Path: "main.go", Slice: "chunk_1", Start: 1, End: 3}}
+	ptrs := []index.Pointer{{ID: "main.main", Path: "main.go", Sym: "main.main", Start: 3, End: 3}}
+	g := graph.Graph{Nodes: []string{"go:main"}, Edges: [][2]string{}}
+	files := []struct{ RelPath, AbsPath string }{{RelPath: "main.go", AbsPath: src}}
+
+	out := filepath.Join(dir, "full.zip")
+	if err := WriteFull(out, FullArtifacts{Artifacts: index.Artifacts{Manifest: man, Symbols: syms, Slices: slices, Pointers: ptrs, Graph: g}, Sources: files}, "", 3, false); err != nil {
+		t.Fatalf("WriteFull error: %v", err)
+	}
+
+	b, err := Open(out)
+	if err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	if !reflect.DeepEqual(b.Manifest, man) {
+		t.Fatalf("manifest = %+v, want %+v", b.Manifest, man)
+	}
+	if !reflect.DeepEqual(b.Symbols, syms) {
+		t.Fatalf("symbols = %+v, want %+v", b.Symbols, syms)
+	}
+	if !reflect.DeepEqual(b.Slices, slices) || !reflect.DeepEqual(b.Pointers, ptrs) {
+		t.Fatalf("slices/pointers = %+v / %+v", b.Slices, b.Pointers)
+	}
+	if !reflect.DeepEqual(b.Graph.Nodes, g.Nodes) {
+		t.Fatalf("graph nodes = %v, want %v", b.Graph.Nodes, g.Nodes)
+	}
+	if got := string(b.Sources["main.go"]); !strings.Contains(got, "func main()") {
+		t.Fatalf("source not read back: %q", got)
+	}
+}
+
+func TestOpenToleratesMissingOptionalEntries(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "min.zip")
+	writeTestZip(t, out, map[string]string{"manifest.json": `{"module":"demo","files":[]}`})
+
+	b, err := Open(out)
+	if err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	if b.Manifest.Module != "demo" || b.Slices != nil || b.Pointers != nil || len(b.Sources) != 0 {
+		t.Fatalf("unexpected bundle: %+v", b)
+	}
+
+	bad := filepath.Join(dir, "bad.zip")
+	writeTestZip(t, bad, map[string]string{"README.md": "x\n"})
+	if _, err := Open(bad); err == nil || !strings.Contains(err.Error(), "manifest.json not found") {
+		t.Fatalf("expected missing manifest error, got %v", err)
+	}
+
+	// A DELTA is never taken for a FULL base, whatever else it holds.
+	delta := filepath.Join(dir, "delta.zip")
+	writeTestZip(t, delta, map[string]string{"delta.index.json": `{}`, "manifest.json": `{"module":"demo","files":[]}`})
+	if _, err := Open(delta); err == nil || !strings.Contains(err.Error(), "DELTA") {
+		t.Fatalf("expected a DELTA bundle to be rejected, got %v", err)
+	}
+}
+
+func writeTestZip(t *testing.T, path string, entries map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for name, body := range entries {
+		if err := ziputil.WriteText(zw, name, []byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpenReadsTreeSymbols(t *testing.T) {
+	SetSymbolsFormat(SymbolsFormatTree)
+	defer SetSymbolsFormat(SymbolsFormatFlat)
+
+	man := index.Manifest{Module: "demo", Files: []index.ManFile{{Path: "s.go", Lines: 9}}}
+	syms := index.Symbols{Version: 1, Symbols: []index.Symbol{
+		{Symbol: "demo.Server", Kind: "type", Path: "s.go", Start: 1, End: 2},
+		{Symbol: "demo.Server.Start", Kind: "method", Path: "s.go", Start: 3, End: 9},
+	}}
+	out := filepath.Join(t.TempDir(), "full.zip")
+	if err := WriteFull(out, FullArtifacts{Artifacts: index.Artifacts{Manifest: man, Symbols: syms}}, "", 3, false); err != nil {
+		t.Fatalf("WriteFull error: %v", err)
+	}
+	b, err := Open(out)
+	if err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	if len(b.Symbols.Symbols) != 2 || b.Symbols.Symbols[0].End != 9 || b.Symbols.Symbols[1].Symbol != "demo.Server.Start" {
+		t.Fatalf("tree symbols not flattened on read: %+v", b.Symbols)
+	}
+}
+
+func TestWriteFullSymbolsSortByName(t *testing.T) {
+	man := index.Manifest{Module: "demo", Files: []index.ManFile{{Path: "a.go", Hash: "aa", Lines: 9}, {Path: "b.go", Hash: "bb", Lines: 9}}}
+	man.BundleID = index.ComputeBundleID(man)
+	syms := index.Symbols{Version: 1, Symbols: []index.Symbol{
+		{Symbol: "demo.Zeta", Kind: "func", Path: "a.go", Start: 1, End: 4},
+		{Symbol: "demo.Alpha", Kind: "func", Path: "a.go", Start: 5, End: 9},
+		{Symbol: "demo.Mid", Kind: "func", Path: "b.go", Start: 1, End: 9},
+	}}
+	write := func(order string) (*Bundle, string) {
+		t.Helper()
+		SetSymbolsSort(order)
+		defer SetSymbolsSort(SymbolsSortPosition)
+		out := filepath.Join(t.TempDir(), "full.zip")
+		if err := WriteFull(out, FullArtifacts{Artifacts: index.Artifacts{Manifest: man, Symbols: syms}}, "", 3, false); err != nil {
+			t.Fatalf("WriteFull error: %v", err)
+		}
+		b, err := Open(out)
+		if err != nil {
+			t.Fatalf("Open error: %v", err)
+		}
+		entries, err := ReadEntries(out)
+		if err != nil {
+			t.Fatalf("ReadEntries error: %v", err)
+		}
+		return b, strings.TrimSpace(string(entries["BUNDLE.ID"]))
+	}
+
+	byPos, posID := write(SymbolsSortPosition)
+	byName, nameID := write(SymbolsSortName)
+	var got []string
+	for _, s := range byName.Symbols.Symbols {
+		got = append(got, s.Symbol)
+	}
+	if want := []string{"demo.Alpha", "demo.Mid", "demo.Zeta"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("name-sorted symbols = %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(byPos.Symbols, syms) {
+		t.Fatalf("position-sorted symbols = %+v, want %+v", byPos.Symbols, syms)
+	}
+	if posID == "" || posID != nameID || byName.Manifest.BundleID != man.BundleID {
+		t.Fatalf("bundle IDs differ by display sort: %q vs %q", posID, nameID)
+	}
+	if syms.Symbols[0].Symbol != "demo.Zeta" {
+		t.Fatal("WriteFull reordered the caller's symbols")
+	}
+}
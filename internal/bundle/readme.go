@@ -5,6 +5,9 @@ import (
 	"sort"
 	"strings"
 	"text/template"
+
+	"class-collector/internal/index"
+	"class-collector/internal/meta"
 )
 
 // ReadmeOptions configures README generation for FULL and DELTA bundles.
@@ -18,6 +21,8 @@ type ReadmeOptions struct {
 	IncludeBenchNote  bool
 	IncludeDeltaNotes bool
 	IncludeFullNotes  bool
+	Stats             []index.LangStats // optional per-language line counts (FULL only)
+	Deps              []meta.DepSet     // optional lockfile summaries (FULL only)
 }
 
 type rdCtx struct {
@@ -27,6 +32,13 @@ type rdCtx struct {
 	DiffNoPrefix      bool
 	ContextLines      int
 	IncludeBenchNote  bool
+	Stats             []index.LangStats
+	Deps              []depRow
+}
+
+type depRow struct {
+	Ecosystem, Lockfile string
+	Direct, Transitive  int
 }
 
 const fullReadmeTemplate = `
@@ -61,7 +73,21 @@ This archive is a **FULL bundle** produced by *class-collector*. It contains a p
 - Git-style prefixes **a/** and **b/** are {{if .DiffNoPrefix}}**omitted**{{else}}**present**{{end}}.
 - Supported languages: {{.SupportedLangsCSV}}.
 - Present in this bundle: {{.PresentLangsCSV}}.
-
+{{if .Stats}}
+## Code statistics
+See **stats.json** for the machine-readable version.
+
+| Language | Files | Lines | Blank | Comment | Code |
+|:---------|------:|------:|------:|--------:|-----:|
+{{range .Stats}}| {{.Lang}} | {{.Files}} | {{.Lines}} | {{.Blank}} | {{.Comment}} | {{.Code}} |
+{{end}}{{end}}{{if .Deps}}
+## Dependencies
+See **dependencies.json** for names and versions.
+
+| Ecosystem | Lockfile | Direct | Transitive |
+|:----------|:---------|-------:|-----------:|
+{{range .Deps}}| {{.Ecosystem}} | {{.Lockfile}} | {{.Direct}} | {{.Transitive}} |
+{{end}}{{end}}
 {{if .IncludeBenchNote -}}
 ## Benchmarks
 If provided via ` + "`-bench <path>`" + `, a plain-text **bench.txt** is included at the bundle root.
@@ -151,6 +177,11 @@ func renderReadme(tpl string, opts ReadmeOptions) []byte {
 		DiffNoPrefix:      opts.DiffNoPrefix,
 		ContextLines:      opts.ContextLines,
 		IncludeBenchNote:  opts.IncludeBenchNote,
+		Stats:             opts.Stats,
+	}
+	for _, s := range opts.Deps {
+		direct, transitive := s.Counts()
+		ctx.Deps = append(ctx.Deps, depRow{Ecosystem: s.Ecosystem, Lockfile: s.Lockfile, Direct: direct, Transitive: transitive})
 	}
 
 	t, _ := template.New("readme").Parse(tpl)
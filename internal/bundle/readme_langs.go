@@ -1,6 +1,6 @@
 package bundle
 
-var fullSupportedLangs = []string{"cs", "cpp", "go", "java", "kt", "py", "ts", "tsx"}
+var fullSupportedLangs = []string{"c", "cs", "cpp", "go", "java", "kt", "objc", "py", "shell", "sql", "ts", "tsx"}
 
 func supportedLangs() []string {
 	out := make([]string, len(fullSupportedLangs))
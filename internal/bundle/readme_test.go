@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"strings"
 	"testing"
+
+	"class-collector/internal/index"
+	"class-collector/internal/meta"
 )
 
 func TestGenerateFullReadmeDeterminism(t *testing.T) {
@@ -73,3 +76,27 @@ func TestDeltaReadmeBenchAndLangs(t *testing.T) {
 		t.Fatalf("supported languages not sorted or missing cpp: %s", out)
 	}
 }
+
+func TestFullReadmeStatsTable(t *testing.T) {
+	opts := ReadmeOptions{Stats: []index.LangStats{{Lang: "go", Files: 2, Lines: 10, Blank: 1, Comment: 2, Code: 7}}}
+	out := string(GenerateFullReadme(opts))
+	if !strings.Contains(out, "## Code statistics") || !strings.Contains(out, "| go | 2 | 10 | 1 | 2 | 7 |") {
+		t.Fatalf("stats table missing: %s", out)
+	}
+	if strings.Contains(string(GenerateFullReadme(ReadmeOptions{})), "Code statistics") {
+		t.Fatalf("stats section should be omitted without stats")
+	}
+}
+
+func TestFullReadmeDependenciesTable(t *testing.T) {
+	opts := ReadmeOptions{Deps: []meta.DepSet{{Ecosystem: "go", Lockfile: "go.sum", Dependencies: []meta.Dependency{
+		{Name: "a", Version: "v1", Direct: true}, {Name: "b", Version: "v2"}, {Name: "c", Version: "v3"},
+	}}}}
+	out := string(GenerateFullReadme(opts))
+	if !strings.Contains(out, "## Dependencies") || !strings.Contains(out, "| go | go.sum | 1 | 2 |") {
+		t.Fatalf("dependencies table missing: %s", out)
+	}
+	if strings.Contains(string(GenerateFullReadme(ReadmeOptions{})), "## Dependencies") {
+		t.Fatalf("dependencies section should be omitted without lockfiles")
+	}
+}
@@ -0,0 +1,138 @@
+package bundle
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"class-collector/internal/index"
+	"class-collector/internal/warn"
+)
+
+// PathMapSuffix is appended to the chat archive name (minus its extension)
+// for the sidecar written by -chat-redact-paths: chat.zip → chat.path-map.json.
+const PathMapSuffix = ".path-map.json"
+
+var chatRedactPaths bool
+
+// SetChatRedactPaths makes WriteChat replace every file path in headers, the
+// TOC, the overview, README.md and warnings.json with an opaque token
+// ("file-<hash><ext>"). The token → path mapping is written next to the
+// archive (see PathMapFor), never into it.
+func SetChatRedactPaths(enable bool) { chatRedactPaths = enable }
+
+// PathMap is the path-map.json payload: token → original path.
+type PathMap struct {
+	Version int               `json:"version"`
+	Paths   map[string]string `json:"paths"`
+}
+
+// PathMapFor returns where the path map of the chat archive zipPath goes.
+func PathMapFor(zipPath string) string {
+	return strings.TrimSuffix(zipPath, filepath.Ext(zipPath)) + PathMapSuffix
+}
+
+// redactTokens assigns each path a token derived from its SHA-256, keeping
+// the extension so code fences and language counts still work. Paths are
+// processed in sorted order and a clashing token takes more hash digits, so
+// the result depends only on the set of paths.
+func redactTokens(paths []string) map[string]string {
+	sorted := append([]string(nil), paths...)
+	sort.Strings(sorted)
+	tokens := make(map[string]string, len(sorted))
+	used := make(map[string]struct{}, len(sorted))
+	for _, p := range sorted {
+		if _, ok := tokens[p]; ok {
+			continue
+		}
+		sum := sha256.Sum256([]byte(p))
+		digest := hex.EncodeToString(sum[:])
+		ext := strings.ToLower(filepath.Ext(p))
+		tok := ""
+		for n := 8; n <= len(digest); n += 4 {
+			tok = "file-" + digest[:n] + ext
+			if _, clash := used[tok]; !clash {
+				break
+			}
+		}
+		used[tok] = struct{}{}
+		tokens[p] = tok
+	}
+	return tokens
+}
+
+// redactChat rewrites the ranked files, manifest and content index of a chat
+// bundle to use tokens, rewrites recorded warnings the same way, and returns
+// the reverse mapping.
+func redactChat(order []index.ManFile, man index.Manifest, absOf map[string]string) ([]index.ManFile, index.Manifest, map[string]string, PathMap) {
+	paths := make([]string, 0, len(man.Files))
+	for _, mf := range man.Files {
+		paths = append(paths, mf.Path)
+	}
+	tokens := redactTokens(paths)
+	rename := func(files []index.ManFile) []index.ManFile {
+		out := make([]index.ManFile, len(files))
+		for i, mf := range files {
+			mf.Path = tokens[mf.Path]
+			out[i] = mf
+		}
+		return out
+	}
+
+	pm := PathMap{Version: 1, Paths: make(map[string]string, len(tokens))}
+	redactedAbs := make(map[string]string, len(absOf))
+	for p, tok := range tokens {
+		pm.Paths[tok] = p
+		if abs, ok := absOf[p]; ok {
+			redactedAbs[tok] = abs
+		}
+	}
+	man.Files = rename(man.Files)
+	redactWarnings(tokens)
+	return rename(order), man, redactedAbs, pm
+}
+
+// redactWarnings replaces known paths in the Path and Message of the
+// warnings recorded so far.
+func redactWarnings(tokens map[string]string) {
+	paths := make([]string, 0, len(tokens))
+	for p := range tokens {
+		paths = append(paths, p)
+	}
+	// Longer paths first so "a/b.go" is not rewritten inside "x/a/b.go".
+	sort.Slice(paths, func(i, j int) bool {
+		if len(paths[i]) != len(paths[j]) {
+			return len(paths[i]) > len(paths[j])
+		}
+		return paths[i] < paths[j]
+	})
+	pairs := make([]string, 0, 2*len(paths))
+	for _, p := range paths {
+		pairs = append(pairs, p, tokens[p])
+	}
+	r := strings.NewReplacer(pairs...)
+	warn.Active().Rewrite(func(w warn.Warning) warn.Warning {
+		if tok, ok := tokens[w.Path]; ok {
+			w.Path = tok
+		}
+		w.Message = r.Replace(w.Message)
+		return w
+	})
+}
+
+func writePathMap(zipPath string, pm PathMap) error {
+	data, err := json.MarshalIndent(pm, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode path map: %w", err)
+	}
+	name := PathMapFor(zipPath)
+	if err := os.WriteFile(name, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,331 @@
+package bundle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"class-collector/internal/cache"
+	"class-collector/internal/diff"
+	"class-collector/internal/index"
+	"class-collector/internal/textutil"
+	"class-collector/internal/validate"
+	"class-collector/internal/ziputil"
+)
+
+// Verify check statuses.
+const (
+	CheckOK      = "ok"
+	CheckFailed  = "failed"
+	CheckSkipped = "skipped"
+)
+
+// VerifyReport is the outcome of Verify: the bundle kind ("full" or
+// "delta") and its checks in the order they ran. OK is false when any check
+// failed.
+type VerifyReport struct {
+	Bundle string        `json:"bundle"`
+	Kind   string        `json:"kind"`
+	OK     bool          `json:"ok"`
+	Checks []VerifyCheck `json:"checks"`
+}
+
+// VerifyCheck is one verification step. Detail explains a skipped check or
+// qualifies a passed one; Problems lists what failed.
+type VerifyCheck struct {
+	Name     string   `json:"name"`
+	Status   string   `json:"status"`
+	Detail   string   `json:"detail,omitempty"`
+	Problems []string `json:"problems,omitempty"`
+}
+
+// add appends a check whose status follows from problems (skipped when
+// skip is non-empty).
+func (r *VerifyReport) add(name, skip, detail string, problems []string) {
+	c := VerifyCheck{Name: name, Status: CheckOK, Detail: detail, Problems: problems}
+	switch {
+	case len(problems) > 0:
+		c.Status = CheckFailed
+		r.OK = false
+	case skip != "":
+		c.Status, c.Detail = CheckSkipped, skip
+	}
+	r.Checks = append(r.Checks, c)
+}
+
+// Verify re-checks the FULL or DELTA bundle at path (any -out-format). A
+// FULL bundle is validated like WriteFull does, its BUNDLE.ID recomputed
+// from the manifest and, when sources were emitted (in src/ or the
+// srcArchive sibling), every manifest file checked against its hash. A
+// DELTA bundle is cross-checked against its delta index (layout as set by
+// SetDeltaLayout), added/ contents against their hashes, and every section
+// of the combined patch applied: added files to empty content, changed
+// files to the before-content found in base (a FULL bundle with sources;
+// nil skips them), with the result hashed against hashAfter. The error is
+// reserved for unreadable or unrecognized bundles.
+func Verify(path string, base *Bundle) (*VerifyReport, error) {
+	entries, err := ReadEntries(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &VerifyReport{Bundle: path, OK: true}
+	switch {
+	case entries[deltaLayout.Index] != nil:
+		r.Kind = "delta"
+		verifyDelta(r, entries, base)
+	case entries["manifest.json"] != nil:
+		r.Kind = "full"
+		verifyFull(r, path, entries)
+	default:
+		return nil, fmt.Errorf("%s: neither manifest.json nor %s found (not a FULL or DELTA bundle?)", path, deltaLayout.Index)
+	}
+	return r, nil
+}
+
+func verifyFull(r *VerifyReport, path string, entries map[string][]byte) {
+	var man index.Manifest
+	if err := json.Unmarshal(entries["manifest.json"], &man); err != nil {
+		r.add("manifest", "", "", []string{"manifest.json: " + err.Error()})
+		return
+	}
+	r.add("manifest", "", "", errLines(validate.Manifest(man)))
+
+	if data, ok := entries["symbols.json"]; ok {
+		syms, err := decodeSymbols(data)
+		if err == nil {
+			err = validate.Symbols(syms)
+		}
+		r.add("symbols", "", "", errLines(err))
+	} else {
+		r.add("symbols", "symbols.json not present", "", nil)
+	}
+
+	var problems []string
+	recorded, hasID := entries["BUNDLE.ID"]
+	id := strings.TrimSpace(string(recorded))
+	switch {
+	case man.BundleIDAlgo == index.BundleIDModuleGit:
+		r.add("bundle-id", "bundle_id_algo module+git: the commit is not recorded in the bundle", "", nil)
+	default:
+		want := index.BundleIDFor(man, man.BundleIDAlgo, "")
+		if hasID && id != want {
+			problems = append(problems, fmt.Sprintf("BUNDLE.ID %s does not match the recomputed %s", id, want))
+		}
+		if man.BundleID != "" && man.BundleID != want {
+			problems = append(problems, fmt.Sprintf("manifest bundle_id %s does not match the recomputed %s", man.BundleID, want))
+		}
+		skip := ""
+		if !hasID && man.BundleID == "" {
+			skip = "no BUNDLE.ID or bundle_id recorded"
+		}
+		r.add("bundle-id", skip, "", problems)
+	}
+
+	sources := map[string][]byte{}
+	for name, data := range entries {
+		if strings.HasPrefix(name, "src/") {
+			sources[name] = data
+		}
+	}
+	detail := ""
+	if man.SrcArchive != "" {
+		archive := filepath.Join(filepath.Dir(path), filepath.FromSlash(man.SrcArchive))
+		more, err := ReadEntries(archive)
+		if err != nil {
+			r.add("sources", "", "", []string{"srcArchive: " + err.Error()})
+			return
+		}
+		for name, data := range more {
+			if strings.HasPrefix(name, "src/") {
+				sources[name] = data
+			}
+		}
+		detail = "sources read from " + man.SrcArchive
+	}
+	if len(sources) == 0 {
+		r.add("sources", "no src/ entries (bundle built without -emit-src)", "", nil)
+		return
+	}
+	problems = nil
+	for _, f := range man.Files {
+		if f.Kind == "symlink" {
+			continue
+		}
+		name := ziputil.SanitizePath("src/" + f.Path)
+		data, ok := sources[name]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("%s: missing %s", f.Path, name))
+		case f.Hash != "" && !strings.EqualFold(sha256Hex(data), f.Hash):
+			problems = append(problems, fmt.Sprintf("%s: content hash %s does not match manifest hash %s", f.Path, sha256Hex(data), f.Hash))
+		}
+	}
+	r.add("sources", "", detail, problems)
+}
+
+// verifyRenameReport checks that the rename report, when present, decodes
+// as a list of scored rename pairs.
+func verifyRenameReport(r *VerifyReport, entries map[string][]byte) {
+	data, ok := entries[deltaLayout.RenameReport]
+	if !ok {
+		r.add("rename-report", deltaLayout.RenameReport+" not present", "", nil)
+		return
+	}
+	var report []cache.RenameCandidate
+	if err := json.Unmarshal(data, &report); err != nil {
+		r.add("rename-report", "", "", []string{deltaLayout.RenameReport + ": " + err.Error()})
+		return
+	}
+	r.add("rename-report", "", fmt.Sprintf("%d pairs", len(report)), nil)
+}
+
+func verifyDelta(r *VerifyReport, entries map[string][]byte, base *Bundle) {
+	var d cache.Delta
+	if err := json.Unmarshal(entries[deltaLayout.Index], &d); err != nil {
+		r.add("delta-index", "", "", []string{deltaLayout.Index + ": " + err.Error()})
+		return
+	}
+	r.add("delta-index", "", "", errLines(validate.Delta(d, deltaLayout.Added, sortedNames(entries))))
+	verifyRenameReport(r, entries)
+
+	var problems []string
+	added := make(map[string][]byte, len(d.Added))
+	for _, a := range d.Added {
+		name := ziputil.SanitizePath(deltaLayout.Added + "/" + a.Path)
+		data, ok := entries[name]
+		if !ok {
+			continue // reported by delta-index
+		}
+		added[a.Path] = data
+		if a.Hash != "" && !strings.EqualFold(sha256Hex(data), a.Hash) {
+			problems = append(problems, fmt.Sprintf("%s: content hash %s does not match %s", a.Path, sha256Hex(data), a.Hash))
+		}
+	}
+	skip := ""
+	if len(d.Added) == 0 {
+		skip = "no added files"
+	}
+	r.add("added", skip, "", problems)
+
+	patch, ok := entries[deltaLayout.Patch]
+	if !ok {
+		skip := deltaLayout.Patch + " not present"
+		if len(d.Added) == 0 && len(d.Changed) == 0 {
+			skip = "no added or changed files"
+		}
+		r.add("patch", skip, "", nil)
+		return
+	}
+	type changed struct{ before, after string }
+	changes := make(map[string]changed, len(d.Changed))
+	for _, c := range d.Changed {
+		changes[c.Path] = changed{c.HashBefore, c.HashAfter}
+	}
+	for _, r := range d.Renamed {
+		if r.HashBefore != "" && !strings.EqualFold(r.HashBefore, r.Hash) {
+			changes[r.To] = changed{r.HashBefore, r.Hash}
+		}
+	}
+	var readOld func(string) ([]byte, error)
+	if base != nil {
+		readOld = base.ReadOld()
+	}
+	problems = nil
+	applied, omitted, noBase := 0, 0, 0
+	for _, fp := range diff.SplitFiles(string(patch)) {
+		p := sectionPath(fp.NewName, func(p string) bool {
+			_, isAdded := added[p]
+			_, isChanged := changes[p]
+			return isAdded || isChanged
+		})
+		if content, isAdded := added[p]; isAdded && fp.OldName == "/dev/null" {
+			got, err := diff.Apply(nil, fp.Body)
+			switch {
+			case errors.Is(err, diff.ErrOmitted):
+				omitted++
+			case err != nil:
+				problems = append(problems, fmt.Sprintf("%s: %v", p, err))
+			case !bytes.Equal(got, normalizedText(content)):
+				problems = append(problems, fmt.Sprintf("%s: patch does not reproduce %s/%s", p, deltaLayout.Added, p))
+			default:
+				applied++
+			}
+			continue
+		}
+		c, isChanged := changes[p]
+		if !isChanged {
+			problems = append(problems, fmt.Sprintf("%s: patch section for a path that is neither added nor changed", fp.NewName))
+			continue
+		}
+		var data []byte
+		haveBase := false
+		if readOld != nil {
+			var err error
+			data, err = readOld(c.before)
+			haveBase = err == nil
+		}
+		// An added-file patch stands in for a change whose base was unknown
+		// to the builder; the base, when at hand, still gives the encoding.
+		old, enc := textutil.ToUTF8(data)
+		if fp.OldName == "/dev/null" {
+			old = nil
+		} else if !haveBase {
+			noBase++
+			continue
+		}
+		got, err := diff.Apply(old, fp.Body)
+		switch {
+		case errors.Is(err, diff.ErrOmitted):
+			omitted++
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("%s: %v", p, err))
+		case c.after != "" && !matchesHash(got, enc, c.after):
+			problems = append(problems, fmt.Sprintf("%s: patched content hash %s does not match hashAfter %s", p, sha256Hex(got), c.after))
+		default:
+			applied++
+		}
+	}
+	var notes []string
+	notes = append(notes, fmt.Sprintf("%d applied", applied))
+	if omitted > 0 {
+		notes = append(notes, fmt.Sprintf("%d omitted (oversize)", omitted))
+	}
+	if noBase > 0 {
+		notes = append(notes, fmt.Sprintf("%d changed not checked (no base content; pass a FULL bundle with sources)", noBase))
+	}
+	sort.Strings(problems)
+	r.add("patch", "", strings.Join(notes, ", "), problems)
+}
+
+// normalizedText is content as an added-file patch carries it: UTF-8 with
+// LF line endings.
+func normalizedText(content []byte) []byte {
+	text, _ := textutil.ToUTF8(content)
+	return textutil.NormalizeUTF8LF(text)
+}
+
+// matchesHash reports whether patched content has hash want in one of the
+// renditions withHash tries.
+func matchesHash(content []byte, enc, want string) bool {
+	_, ok := withHash(content, enc, want)
+	return ok
+}
+
+// errLines splits a validate error into its messages.
+func errLines(err error) []string {
+	if err == nil {
+		return nil
+	}
+	return strings.Split(err.Error(), "\n")
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
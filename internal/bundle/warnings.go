@@ -0,0 +1,31 @@
+package bundle
+
+import (
+	"fmt"
+
+	"class-collector/internal/warn"
+	"class-collector/internal/ziputil"
+)
+
+// warningsName is the optional bundle entry listing recorded warnings.
+const warningsName = "warnings.json"
+
+var emitWarnings bool
+
+// SetWriteWarnings toggles writing warnings.json into FULL, DELTA and CHAT
+// bundles. The file is only written when at least one warning was recorded.
+func SetWriteWarnings(enable bool) { emitWarnings = enable }
+
+func writeWarnings(zw Writer) error {
+	if !emitWarnings {
+		return nil
+	}
+	list := warn.Active().List()
+	if len(list) == 0 {
+		return nil
+	}
+	if err := ziputil.WriteJSON(zw, warningsName, list); err != nil {
+		return fmt.Errorf("write %s: %w", warningsName, err)
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+package bundle
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"class-collector/internal/ziputil"
+)
+
+// Output formats for SetOutFormat.
+const (
+	OutFormatZip = "zip" // a ZIP archive (default)
+	OutFormatTgz = "tgz" // a gzip-compressed tar archive
+	OutFormatDir = "dir" // a plain directory tree
+)
+
+var outFormat = OutFormatZip
+
+// SetOutFormat selects how WriteFull, WriteDelta, WriteChat, WriteSources
+// and Merge store their entries at the output path. Every format keeps the
+// entry order and the fixed ziputil.FixedZipTime timestamp, so output stays
+// deterministic. "" restores OutFormatZip.
+func SetOutFormat(format string) {
+	if format == "" {
+		format = OutFormatZip
+	}
+	outFormat = format
+}
+
+// Writer receives the entries of one bundle in order; Close finishes the
+// output. *zip.Writer is one. The writers created here tolerate repeated
+// Close calls.
+type Writer interface {
+	ziputil.EntryWriter
+	Close() error
+}
+
+// createWriter creates the parent directory of path and opens a Writer for
+// the SetOutFormat format there.
+func createWriter(path string) (Writer, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("mkdir output: %w", err)
+	}
+	if outFormat == OutFormatDir {
+		return newDirWriter(path)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("create output: %w", err)
+	}
+	if outFormat == OutFormatTgz {
+		gz := gzip.NewWriter(f)
+		return &tgzWriter{f: f, gz: gz, tw: tar.NewWriter(gz)}, nil
+	}
+	return &zipFileWriter{Writer: zip.NewWriter(f), f: f}, nil
+}
+
+// closeWriter closes w and reports its error through err unless err already
+// holds one; use it deferred with a named result.
+func closeWriter(w Writer, err *error) {
+	if cerr := w.Close(); *err == nil {
+		*err = cerr
+	}
+}
+
+// zipFileWriter is a *zip.Writer owning its file.
+type zipFileWriter struct {
+	*zip.Writer
+	f      *os.File
+	closed bool
+}
+
+func (z *zipFileWriter) Close() error {
+	if z.closed {
+		return nil
+	}
+	z.closed = true
+	return errors.Join(z.Writer.Close(), z.f.Close())
+}
+
+// tgzWriter buffers each entry so its size is known when the tar header is
+// written. Headers carry only name, mode, size and the fixed timestamp.
+type tgzWriter struct {
+	f   *os.File
+	gz  *gzip.Writer
+	tw  *tar.Writer
+	cur *zip.FileHeader
+	buf bytes.Buffer
+
+	closed bool
+}
+
+func (t *tgzWriter) CreateHeader(fh *zip.FileHeader) (io.Writer, error) {
+	if err := t.flush(); err != nil {
+		return nil, err
+	}
+	t.cur = fh
+	t.buf.Reset()
+	return &t.buf, nil
+}
+
+func (t *tgzWriter) flush() error {
+	if t.cur == nil {
+		return nil
+	}
+	hdr := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     t.cur.Name,
+		Mode:     int64(t.cur.Mode().Perm()),
+		Size:     int64(t.buf.Len()),
+		ModTime:  t.cur.Modified,
+	}
+	t.cur = nil
+	if err := t.tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("tar header %s: %w", hdr.Name, err)
+	}
+	_, err := t.tw.Write(t.buf.Bytes())
+	return err
+}
+
+func (t *tgzWriter) Close() error {
+	if t.closed {
+		return nil
+	}
+	t.closed = true
+	return errors.Join(t.flush(), t.tw.Close(), t.gz.Close(), t.f.Close())
+}
+
+// dirWriter writes entries as files under root, which must not exist, be
+// empty or hold a bundle written earlier (see ownBundleDir), which is removed
+// first so no stale files from an earlier run survive. Files and, on Close,
+// directories get the fixed timestamp.
+type dirWriter struct {
+	root string
+	cur  *os.File
+	hdr  *zip.FileHeader
+
+	closed bool
+}
+
+func newDirWriter(root string) (*dirWriter, error) {
+	if st, err := os.Stat(root); err == nil {
+		if !st.IsDir() {
+			return nil, fmt.Errorf("create output: %s exists and is not a directory", root)
+		}
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			return nil, fmt.Errorf("create output: %w", err)
+		}
+		if len(entries) > 0 {
+			if !ownBundleDir(root) {
+				return nil, fmt.Errorf("create output: directory %s is not empty and holds no bundle", root)
+			}
+			if err := os.RemoveAll(root); err != nil {
+				return nil, fmt.Errorf("create output: %w", err)
+			}
+		}
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("create output: %w", err)
+	}
+	return &dirWriter{root: root}, nil
+}
+
+// ownBundleDir reports whether the directory root holds a bundle written
+// here: the entries a FULL (or merged), DELTA or CHAT bundle always has.
+func ownBundleDir(root string) bool {
+	markers := [][]string{
+		{"manifest.json", "symbols.json"},
+		{"BUNDLE.ID"},
+		{deltaLayout.Index},
+		{"README.md", "TOC.md", "chat"},
+	}
+	for _, set := range markers {
+		all := true
+		for _, name := range set {
+			if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(name))); err != nil {
+				all = false
+				break
+			}
+		}
+		if all {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *dirWriter) CreateHeader(fh *zip.FileHeader) (io.Writer, error) {
+	if err := d.finish(); err != nil {
+		return nil, err
+	}
+	if !fs.ValidPath(fh.Name) {
+		return nil, fmt.Errorf("create %s: invalid entry name", fh.Name)
+	}
+	dst := filepath.Join(d.root, filepath.FromSlash(fh.Name))
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, fh.Mode().Perm())
+	if err != nil {
+		return nil, err
+	}
+	d.cur, d.hdr = f, fh
+	return f, nil
+}
+
+// finish closes the open entry and stamps its timestamp.
+func (d *dirWriter) finish() error {
+	if d.cur == nil {
+		return nil
+	}
+	f, mod := d.cur, d.hdr.Modified
+	d.cur, d.hdr = nil, nil
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(f.Name(), mod, mod)
+}
+
+func (d *dirWriter) Close() error {
+	if d.closed {
+		return nil
+	}
+	d.closed = true
+	if err := d.finish(); err != nil {
+		return err
+	}
+	// Directory times change as files are added, so they are set last.
+	return filepath.WalkDir(d.root, func(p string, e fs.DirEntry, err error) error {
+		if err != nil || !e.IsDir() {
+			return err
+		}
+		return os.Chtimes(p, ziputil.FixedZipTime, ziputil.FixedZipTime)
+	})
+}
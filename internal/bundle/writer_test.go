@@ -0,0 +1,151 @@
+package bundle
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"class-collector/internal/graph"
+	"class-collector/internal/index"
+	"class-collector/internal/ziputil"
+)
+
+// writeTestChat writes a small CHAT bundle to out in the current format.
+func writeTestChat(t *testing.T, dir, out string) error {
+	t.Helper()
+	src := filepath.Join(dir, "foo.ts")
+	if err := os.WriteFile(src, []byte("export function bar() {}\n"), 0o644); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+	man := index.Manifest{Files: []index.ManFile{{Path: "foo.ts", Package: "pkg", Class: "Foo"}}}
+	files := []struct{ RelPath, AbsPath string }{{RelPath: "foo.ts", AbsPath: src}}
+	syms := index.Symbols{Symbols: []index.Symbol{{Symbol: "Foo.bar"}}}
+	return WriteChat(out, man, files, syms, graph.Graph{}, ChatOptions{MaxClasses: 2, MaxChars: 1024})
+}
+
+func TestOutFormatsKeepEntries(t *testing.T) {
+	defer SetOutFormat("")
+	dir := t.TempDir()
+
+	zipOut := filepath.Join(dir, "chat.zip")
+	if err := writeTestChat(t, dir, zipOut); err != nil {
+		t.Fatalf("zip: %v", err)
+	}
+	zr, err := zip.OpenReader(zipOut)
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	defer zr.Close()
+	want := map[string]string{}
+	var order []string
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		body, _ := io.ReadAll(rc)
+		_ = rc.Close()
+		want[f.Name] = string(body)
+		order = append(order, f.Name)
+	}
+
+	SetOutFormat(OutFormatTgz)
+	tgzOut := filepath.Join(dir, "chat.tgz")
+	if err := writeTestChat(t, dir, tgzOut); err != nil {
+		t.Fatalf("tgz: %v", err)
+	}
+	f, err := os.Open(tgzOut)
+	if err != nil {
+		t.Fatalf("open tgz: %v", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	tr := tar.NewReader(gz)
+	var tarOrder []string
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("tar: %v", err)
+		}
+		body, _ := io.ReadAll(tr)
+		if string(body) != want[hdr.Name] {
+			t.Errorf("tgz %s differs from zip entry", hdr.Name)
+		}
+		if !hdr.ModTime.Equal(ziputil.FixedZipTime) {
+			t.Errorf("tgz %s mtime = %v, want %v", hdr.Name, hdr.ModTime, ziputil.FixedZipTime)
+		}
+		tarOrder = append(tarOrder, hdr.Name)
+	}
+	if !reflect.DeepEqual(tarOrder, order) {
+		t.Fatalf("tgz order = %v, want %v", tarOrder, order)
+	}
+
+	SetOutFormat(OutFormatDir)
+	dirOut := filepath.Join(dir, "chat")
+	if err := writeTestChat(t, dir, dirOut); err != nil {
+		t.Fatalf("dir: %v", err)
+	}
+	for name, body := range want {
+		p := filepath.Join(dirOut, filepath.FromSlash(name))
+		got, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		if string(got) != body {
+			t.Errorf("dir %s differs from zip entry", name)
+		}
+		st, _ := os.Stat(p)
+		if !st.ModTime().Equal(ziputil.FixedZipTime) {
+			t.Errorf("dir %s mtime = %v, want %v", name, st.ModTime(), ziputil.FixedZipTime)
+		}
+	}
+}
+
+func TestOutFormatDirRejectsNonEmpty(t *testing.T) {
+	defer SetOutFormat("")
+	SetOutFormat(OutFormatDir)
+	dir := t.TempDir()
+	out := filepath.Join(dir, "chat")
+	if err := os.MkdirAll(out, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(out, "stale.md"), []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeTestChat(t, dir, out); err == nil {
+		t.Fatal("expected an error for a non-empty output directory")
+	}
+}
+
+func TestOutFormatDirReplacesOwnBundle(t *testing.T) {
+	defer SetOutFormat("")
+	SetOutFormat(OutFormatDir)
+	dir := t.TempDir()
+	out := filepath.Join(dir, "chat")
+	if err := writeTestChat(t, dir, out); err != nil {
+		t.Fatalf("first write: %v", err)
+	}
+	stale := filepath.Join(out, "chat", "msg-9999.md")
+	if err := os.WriteFile(stale, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeTestChat(t, dir, out); err != nil {
+		t.Fatalf("rewrite: %v", err)
+	}
+	if _, err := os.Stat(stale); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("stale entry survived the rewrite: %v", err)
+	}
+}
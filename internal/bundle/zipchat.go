@@ -13,6 +13,7 @@ import (
 	"class-collector/internal/graph"
 	"class-collector/internal/index"
 	"class-collector/internal/textutil"
+	"class-collector/internal/warn"
 	"class-collector/internal/ziputil"
 )
 
@@ -21,48 +22,164 @@ type chatMessageMeta struct {
 	Files []string
 }
 
+// Overflow modes for WriteChat's maxMessages cap.
+const (
+	ChatOverflowPack = "pack" // raise files per message so all files fit
+	ChatOverflowDrop = "drop" // keep files per message; drop the lowest-ranked files
+)
+
+// chatCap records how a message cap was applied, for the README.
+type chatCap struct {
+	max      int
+	overflow string
+	packedTo int      // effective files per message when packing raised it
+	dropped  []string // files left out, in rank order
+
+	maxLines int      // SetChatSkipLargeFiles cap, 0 when off
+	tooLarge []string // files over maxLines, in rank order
+}
+
+// chatSystemName is the leading system message; it sorts before msg-0001.md.
+const chatSystemName = "chat/0000-system.md"
+
+// chatGraphName is the optional dependency graph message, written after the
+// system message and before file messages.
+const chatGraphName = "chat/0000-graph.md"
+
+// chatOverviewName is the optional repository overview message, listed after
+// the system message and before the graph and file messages.
+const chatOverviewName = "chat/0000-overview.md"
+
+// DefaultChatSystemPrompt primes a model for the chat bundle layout.
+const DefaultChatSystemPrompt = `You are reviewing a source code bundle split into numbered chat messages.
+Each following message contains one or more project files; every file starts
+with a "# <path>" header (plus package/class when known) followed by the file
+content in a fenced code block. Large files may be truncated at the message
+size limit. Wait until all messages are received before answering, and cite
+files by their path.
+`
+
+// ChatOptions configures WriteChat: the per-message limits (see
+// normalizeChatLimits), the bench report path, and the optional system
+// prompt, overview and graph messages and message cap.
+type ChatOptions struct {
+	MaxClasses   int
+	MaxChars     int
+	BenchPath    string
+	SystemPrompt string
+	Overview     bool
+	Graph        bool
+	MaxMessages  int
+	Overflow     string
+}
+
 // WriteChat creates a deterministic ZIP archive with Markdown chat messages under chat/msg-XXXX.md.
+// A non-empty opt.SystemPrompt is written first as chat/0000-system.md; with
+// opt.Overview, chat/0000-overview.md summarizes the module, build system,
+// file count, language breakdown and message plan; with opt.Graph, an
+// adjacency-list rendering of g follows as chat/0000-graph.md.
+// SetChatSeparators adds text between messages and a closing
+// chat/zzzz-footer.md message.
+// With SetChatRedactPaths, file paths are replaced by opaque tokens after
+// ranking and the mapping is written beside zipPath (see PathMapFor).
+// opt.MaxMessages > 0 caps the number of file messages; opt.Overflow selects
+// whether files are packed more densely (ChatOverflowPack) or the
+// lowest-ranked ones are dropped (ChatOverflowDrop). Files that still do not
+// fit are dropped and listed in README.md. With SetChatSkipLargeFiles, files
+// over the line cap are left out before messages are planned and listed in
+// README.md as well.
 func WriteChat(
 	zipPath string,
 	man index.Manifest,
 	files []struct{ RelPath, AbsPath string },
 	syms index.Symbols,
 	g graph.Graph,
-	maxClasses int,
-	maxChars int,
-	benchPath string,
-) error {
-	maxClasses, maxChars = normalizeChatLimits(maxClasses, maxChars)
-
-	if err := os.MkdirAll(filepath.Dir(zipPath), 0o755); err != nil {
-		return fmt.Errorf("mkdir output: %w", err)
+	opt ChatOptions,
+) (err error) {
+	maxClasses, maxChars := normalizeChatLimits(opt.MaxClasses, opt.MaxChars)
+	benchPath, systemPrompt := opt.BenchPath, opt.SystemPrompt
+	includeOverview, includeGraph := opt.Overview, opt.Graph
+	maxMessages, overflow := opt.MaxMessages, opt.Overflow
+	if overflow == "" {
+		overflow = ChatOverflowPack
+	}
+	if overflow != ChatOverflowPack && overflow != ChatOverflowDrop {
+		return fmt.Errorf("unknown chat overflow mode %q", overflow)
 	}
-	f, err := os.Create(zipPath)
+
+	zw, err := createWriter(zipPath)
 	if err != nil {
-		return fmt.Errorf("create output: %w", err)
+		return err
 	}
-	defer f.Close()
-
-	zw := zip.NewWriter(f)
-	defer zw.Close()
+	defer closeWriter(zw, &err)
 
 	order := rankChatOrder(man, g)
 	absOf := buildAbsIndex(files)
+	if chatRedactPaths {
+		var pm PathMap
+		order, man, absOf, pm = redactChat(order, man, absOf)
+		if err := writePathMap(zipPath, pm); err != nil {
+			return err
+		}
+	}
+	capInfo := chatCap{max: maxMessages, overflow: overflow, maxLines: chatMaxFileLines}
+	order, capInfo.tooLarge = skipLargeChatFiles(order)
+	if maxMessages > 0 && overflow == ChatOverflowPack {
+		if need := (len(order) + maxMessages - 1) / maxMessages; need > maxClasses {
+			maxClasses = need
+			capInfo.packedTo = need
+		}
+	}
 
-	metas, err := writeChatMessages(zw, order, absOf, maxClasses, maxChars)
+	// Separators go after every message but the last; the preamble is
+	// followed by file messages whenever there are files, or by the footer.
+	hasFooter := strings.TrimSpace(chatFooter) != ""
+	tail := len(order) > 0 || hasFooter
+	sysMeta, err := writeChatSystem(zw, systemPrompt, chatSeparator(includeOverview || includeGraph || tail))
 	if err != nil {
 		return err
 	}
+	if includeGraph {
+		graphMeta, err := writeChatGraph(zw, g, maxChars, chatSeparator(tail))
+		if err != nil {
+			return err
+		}
+		sysMeta = append(sysMeta, graphMeta)
+	}
+	metas, dropped, err := writeChatMessages(zw, order, absOf, maxClasses, maxChars, maxMessages, hasFooter)
+	if err != nil {
+		return err
+	}
+	capInfo.dropped = dropped
+	footer, err := writeChatFooter(zw)
+	if err != nil {
+		return err
+	}
+	metas = append(metas, footer...)
+	if includeOverview {
+		// The overview lists the message plan, so it is written once the
+		// file messages are known but ordered right after the system message.
+		overview, err := writeChatOverview(zw, man, append(append([]chatMessageMeta(nil), sysMeta...), metas...), maxChars, chatSeparator(includeGraph || tail))
+		if err != nil {
+			return err
+		}
+		at := 0
+		if len(sysMeta) > 0 && sysMeta[0].Name == chatSystemName {
+			at = 1
+		}
+		sysMeta = append(sysMeta[:at], append([]chatMessageMeta{overview}, sysMeta[at:]...)...)
+	}
+	metas = append(sysMeta, metas...)
 	if err := writeChatToc(zw, metas); err != nil {
 		return err
 	}
-	if err := writeChatReadme(zw, man, syms, metas, maxClasses, maxChars); err != nil {
+	if err := writeChatReadme(zw, man, syms, metas, maxClasses, maxChars, capInfo); err != nil {
 		return err
 	}
 	if err := writeChatBench(zw, benchPath); err != nil {
 		return err
 	}
-	return nil
+	return writeWarnings(zw)
 }
 
 func normalizeChatLimits(maxClasses, maxChars int) (int, int) {
@@ -75,6 +192,18 @@ func normalizeChatLimits(maxClasses, maxChars int) (int, int) {
 	return maxClasses, maxChars
 }
 
+var chatOrder []string
+
+// SetChatOrder imposes a curated order on chat file messages: the listed
+// project-relative paths come first, in list order, and every other file
+// follows in the heuristic order. Paths not in the bundle are ignored with a
+// warning. nil restores the heuristic order alone.
+func SetChatOrder(paths []string) { chatOrder = paths }
+
+// rankChatOrder sorts files for chat messages: SetChatOrder paths first, then
+// the repository README (SetRepoReadme), then api-surface files
+// (index.SetMinFileSymbols), then by graph degree, exports, non-test before
+// test, and path.
 func rankChatOrder(man index.Manifest, g graph.Graph) []index.ManFile {
 	order := make([]index.ManFile, len(man.Files))
 	copy(order, man.Files)
@@ -84,8 +213,7 @@ func rankChatOrder(man index.Manifest, g graph.Graph) []index.ManFile {
 		p := order[i].Path
 		ext := strings.ToLower(filepath.Ext(p))
 		if ext == ".ts" || ext == ".tsx" || ext == ".js" || ext == ".jsx" || ext == ".mjs" || ext == ".cjs" {
-			noext := strings.TrimSuffix(filepath.ToSlash(p), filepath.Ext(p))
-			node := "js:" + noext
+			node := graph.JSNode(p)
 			count := 0
 			for _, e := range g.Edges {
 				if e[0] == node || e[1] == node {
@@ -98,6 +226,12 @@ func rankChatOrder(man index.Manifest, g graph.Graph) []index.ManFile {
 
 	sort.Slice(order, func(i, j int) bool {
 		a, b := order[i], order[j]
+		if ra, rb := a.Path == repoReadme, b.Path == repoReadme; repoReadme != "" && ra != rb {
+			return ra
+		}
+		if sa, sb := index.HasTag(a, index.TagAPISurface), index.HasTag(b, index.TagAPISurface); sa != sb {
+			return sa
+		}
 		if da, db := deg[a.Path], deg[b.Path]; da != db {
 			return da > db
 		}
@@ -105,13 +239,69 @@ func rankChatOrder(man index.Manifest, g graph.Graph) []index.ManFile {
 		if hasExportsA != hasExportsB {
 			return hasExportsA && !hasExportsB
 		}
-		ta, tb := isTestPath(a.Path), isTestPath(b.Path)
+		ta, tb := index.IsTestPath(a.Path), index.IsTestPath(b.Path)
 		if ta != tb {
 			return !ta && tb
 		}
 		return a.Path < b.Path
 	})
-	return order
+	return applyChatOrder(order)
+}
+
+var chatMaxFileLines int
+
+// SetChatSkipLargeFiles leaves files with more than maxLines lines out of
+// chat messages so a single giant file cannot fill a message on its own;
+// they are listed in the chat README.md instead. 0 disables the cap.
+func SetChatSkipLargeFiles(maxLines int) { chatMaxFileLines = maxLines }
+
+// skipLargeChatFiles removes files over the SetChatSkipLargeFiles cap from
+// order and returns them separately, both in rank order.
+func skipLargeChatFiles(order []index.ManFile) ([]index.ManFile, []string) {
+	if chatMaxFileLines <= 0 {
+		return order, nil
+	}
+	kept := order[:0:0]
+	var omitted []string
+	for _, mf := range order {
+		if mf.Lines > chatMaxFileLines {
+			omitted = append(omitted, mf.Path)
+			warn.Add(warn.KindTruncated, mf.Path, "omitted: %d lines exceeds the %d-line chat cap", mf.Lines, chatMaxFileLines)
+			continue
+		}
+		kept = append(kept, mf)
+	}
+	return kept, omitted
+}
+
+// applyChatOrder moves the SetChatOrder paths to the front of order.
+func applyChatOrder(order []index.ManFile) []index.ManFile {
+	if len(chatOrder) == 0 {
+		return order
+	}
+	at := make(map[string]int, len(order))
+	for i, mf := range order {
+		at[mf.Path] = i
+	}
+	out := make([]index.ManFile, 0, len(order))
+	taken := make(map[int]bool, len(chatOrder))
+	for _, p := range chatOrder {
+		i, ok := at[p]
+		if !ok {
+			warn.Add(warn.KindConfig, p, "chat order: not in the bundle; ignored")
+			continue
+		}
+		if !taken[i] {
+			taken[i] = true
+			out = append(out, order[i])
+		}
+	}
+	for i, mf := range order {
+		if !taken[i] {
+			out = append(out, mf)
+		}
+	}
+	return out
 }
 
 func buildAbsIndex(files []struct{ RelPath, AbsPath string }) map[string]string {
@@ -122,24 +312,147 @@ func buildAbsIndex(files []struct{ RelPath, AbsPath string }) map[string]string
 	return out
 }
 
+// writeChatSystem writes the optional system message, followed by sep, and
+// returns its TOC entry.
+func writeChatSystem(zw Writer, prompt, sep string) ([]chatMessageMeta, error) {
+	if strings.TrimSpace(prompt) == "" {
+		return nil, nil
+	}
+	text := append(textutil.EnsureTrailingLF(textutil.NormalizeUTF8LF([]byte(prompt))), sep...)
+	if err := ziputil.WriteText(zw, chatSystemName, text); err != nil {
+		return nil, fmt.Errorf("write %s: %w", chatSystemName, err)
+	}
+	return []chatMessageMeta{{Name: chatSystemName}}, nil
+}
+
+// writeChatGraph renders g as "from -> to, to" lines grouped by source node,
+// stopping (with a note) before the message would exceed maxChars. sep is
+// appended after the limit check.
+func writeChatGraph(zw Writer, g graph.Graph, maxChars int, sep string) (chatMessageMeta, error) {
+	targets := make(map[string][]string, len(g.Nodes))
+	var sources []string
+	for _, e := range g.Edges {
+		if _, ok := targets[e[0]]; !ok {
+			sources = append(sources, e[0])
+		}
+		targets[e[0]] = append(targets[e[0]], e[1])
+	}
+	sort.Strings(sources)
+
+	var b strings.Builder
+	b.WriteString("# Dependency graph\n\n")
+	fmt.Fprintf(&b, "Nodes: %d, edges: %d. Each line lists a node and the nodes it imports.\n\n", len(g.Nodes), len(g.Edges))
+	b.WriteString("```text\n")
+	const closing = "```\n"
+	for i, src := range sources {
+		to := append([]string(nil), targets[src]...)
+		sort.Strings(to)
+		line := src + " -> " + strings.Join(to, ", ") + "\n"
+		more := fmt.Sprintf("... %d more nodes omitted\n", len(sources)-i)
+		if b.Len()+len(line)+len(more)+len(closing) > maxChars {
+			b.WriteString(more)
+			break
+		}
+		b.WriteString(line)
+	}
+	b.WriteString(closing)
+
+	text := append(textutil.EnsureTrailingLF(textutil.NormalizeUTF8LF([]byte(b.String()))), sep...)
+	if err := ziputil.WriteText(zw, chatGraphName, text); err != nil {
+		return chatMessageMeta{}, fmt.Errorf("write %s: %w", chatGraphName, err)
+	}
+	return chatMessageMeta{Name: chatGraphName}, nil
+}
+
+// writeChatOverview renders the repository overview message: module, build
+// system, file count, per-language file counts (from the extractor language of
+// each manifest path extension) and the message plan, stopping (with a note) before the
+// message would exceed maxChars; sep is appended after the limit check.
+func writeChatOverview(zw Writer, man index.Manifest, plan []chatMessageMeta, maxChars int, sep string) (chatMessageMeta, error) {
+	counts := map[string]int{}
+	for _, mf := range man.Files {
+		lang := index.InferLangByExt(filepath.Ext(mf.Path))
+		if lang == "" {
+			lang = "other"
+		}
+		counts[lang]++
+	}
+	langs := make([]string, 0, len(counts))
+	for l := range counts {
+		langs = append(langs, l)
+	}
+	sort.Slice(langs, func(i, j int) bool {
+		if counts[langs[i]] != counts[langs[j]] {
+			return counts[langs[i]] > counts[langs[j]]
+		}
+		return langs[i] < langs[j]
+	})
+
+	var b strings.Builder
+	b.WriteString("# Repository overview\n\n")
+	module := man.Module
+	if module == "" {
+		module = "(unknown)"
+	}
+	build := man.Build
+	if build == "" {
+		build = "(unknown)"
+	}
+	fmt.Fprintf(&b, "- Module: %s\n", module)
+	fmt.Fprintf(&b, "- Build: %s\n", build)
+	fmt.Fprintf(&b, "- Files: %d\n", len(man.Files))
+	parts := make([]string, 0, len(langs))
+	for _, l := range langs {
+		parts = append(parts, fmt.Sprintf("%s (%d)", l, counts[l]))
+	}
+	if len(parts) > 0 {
+		fmt.Fprintf(&b, "- Languages: %s\n", strings.Join(parts, ", "))
+	}
+	b.WriteString("\n## Messages\n\n")
+	for i, m := range plan {
+		line := "- " + m.Name
+		if len(m.Files) > 0 {
+			line += ": " + strings.Join(m.Files, ", ")
+		}
+		line += "\n"
+		more := fmt.Sprintf("- ... %d more messages omitted\n", len(plan)-i)
+		if b.Len()+len(line)+len(more) > maxChars {
+			b.WriteString(more)
+			break
+		}
+		b.WriteString(line)
+	}
+
+	text := append(textutil.EnsureTrailingLF(textutil.NormalizeUTF8LF([]byte(b.String()))), sep...)
+	if err := ziputil.WriteText(zw, chatOverviewName, text); err != nil {
+		return chatMessageMeta{}, fmt.Errorf("write %s: %w", chatOverviewName, err)
+	}
+	return chatMessageMeta{Name: chatOverviewName}, nil
+}
+
+// writeChatMessages renders order into chat/msg-NNNN.md messages. With
+// maxMessages > 0 it stops after that many and returns the paths of the
+// remaining (lowest-ranked) files as dropped. The between separator ends
+// every message except the last one, unless footer says a footer follows.
 func writeChatMessages(
-	zw *zip.Writer,
+	zw Writer,
 	order []index.ManFile,
 	absOf map[string]string,
-	maxClasses, maxChars int,
-) ([]chatMessageMeta, error) {
+	maxClasses, maxChars, maxMessages int,
+	footer bool,
+) ([]chatMessageMeta, []string, error) {
 	metas := make([]chatMessageMeta, 0, (len(order)+maxClasses-1)/maxClasses)
 	msgIdx := 0
 	i := 0
-	for i < len(order) {
+	for i < len(order) && (maxMessages <= 0 || msgIdx < maxMessages) {
 		msgIdx++
-		name := filepath.ToSlash(filepath.Join("chat", pad4(msgIdx)+".md"))
+		name := filepath.ToSlash(filepath.Join("chat", "msg-"+pad4(msgIdx)+".md"))
 		h := &zip.FileHeader{Name: ziputil.SanitizePath(name), Method: zip.Deflate}
 		h.SetMode(0o644)
 		h.Modified = ziputil.FixedZipTime
 		w, err := zw.CreateHeader(h)
 		if err != nil {
-			return nil, fmt.Errorf("create %s: %w", name, err)
+			return nil, nil, fmt.Errorf("create %s: %w", name, err)
 		}
 
 		written := 0
@@ -155,16 +468,28 @@ func writeChatMessages(
 			var truncated bool
 			written, truncated, err = writeChatEntry(w, mf, absOf, maxChars, written)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 			if truncated {
+				warn.Add(warn.KindTruncated, mf.Path, "reached the %d-char chat message budget; content may be cut", maxChars)
 				break
 			}
 		}
+		more := i < len(order) && (maxMessages <= 0 || msgIdx < maxMessages)
+		if sep := chatSeparator(more || footer); sep != "" {
+			if _, err := io.WriteString(w, sep); err != nil {
+				return nil, nil, fmt.Errorf("write %s: %w", name, err)
+			}
+		}
 
 		metas = append(metas, meta)
 	}
-	return metas, nil
+	var dropped []string
+	for _, mf := range order[i:] {
+		dropped = append(dropped, mf.Path)
+		warn.Add(warn.KindTruncated, mf.Path, "dropped: over the %d-message chat cap", maxMessages)
+	}
+	return metas, dropped, nil
 }
 
 func writeChatEntry(
@@ -196,16 +521,19 @@ func writeChatEntry(
 	}
 
 	if abs := absOf[mf.Path]; abs != "" {
-		if err := writeFileBounded(w, abs, maxChars-written); err != nil {
+		n, err = writeFileBounded(w, abs, maxChars-written)
+		written += n
+		if err != nil {
 			return written, true, err
 		}
-		if written < maxChars {
-			written = maxChars - 1
-		}
 	}
 
+	closing := "\n```\n\n"
+	if mf.Lines == 0 {
+		closing = "```\n\n" // empty file: keep the fenced block empty
+	}
 	if written < maxChars {
-		n, err = writeBounded(w, []byte("\n```\n\n"), maxChars-written)
+		n, err = writeBounded(w, []byte(closing), maxChars-written)
 		written += n
 		if err != nil {
 			return written, written >= maxChars, err
@@ -216,7 +544,7 @@ func writeChatEntry(
 	return written, written >= maxChars, nil
 }
 
-func writeChatToc(zw *zip.Writer, metas []chatMessageMeta) error {
+func writeChatToc(zw Writer, metas []chatMessageMeta) error {
 	var b strings.Builder
 	b.WriteString("# CHAT TOC\n\n")
 	b.WriteString("| Message | Files |\n|:--------|:------|\n")
@@ -240,18 +568,35 @@ func writeChatToc(zw *zip.Writer, metas []chatMessageMeta) error {
 }
 
 func writeChatReadme(
-	zw *zip.Writer,
+	zw Writer,
 	man index.Manifest,
 	syms index.Symbols,
 	metas []chatMessageMeta,
 	maxClasses, maxChars int,
+	capInfo chatCap,
 ) error {
 	var b strings.Builder
 	b.WriteString("# Chat Bundle\n\n")
 	fmt.Fprintf(&b, "- Module: %s\n", strings.TrimSpace(man.Module))
 	fmt.Fprintf(&b, "- Files indexed: %d\n", len(man.Files))
 	fmt.Fprintf(&b, "- Symbols extracted: %d\n", len(syms.Symbols))
-	fmt.Fprintf(&b, "- Messages: %d (up to %d files per message, %d chars each)\n\n", len(metas), maxClasses, maxChars)
+	fmt.Fprintf(&b, "- Messages: %d (up to %d files per message, %d chars each)\n", len(metas), maxClasses, maxChars)
+	if capInfo.max > 0 {
+		fmt.Fprintf(&b, "- Message cap: %d (overflow: %s)\n", capInfo.max, capInfo.overflow)
+		if capInfo.packedTo > 0 {
+			fmt.Fprintf(&b, "- Packed: files per message raised to %d to fit the cap\n", capInfo.packedTo)
+		}
+		if len(capInfo.dropped) > 0 {
+			fmt.Fprintf(&b, "- Dropped (lowest-ranked, over the cap): %d files: %s\n", len(capInfo.dropped), strings.Join(capInfo.dropped, ", "))
+		}
+	}
+	if len(capInfo.tooLarge) > 0 {
+		fmt.Fprintf(&b, "- Omitted (too large, over %d lines): %d files: %s\n", capInfo.maxLines, len(capInfo.tooLarge), strings.Join(capInfo.tooLarge, ", "))
+	}
+	b.WriteString("\n")
+	if len(chatOrder) > 0 {
+		b.WriteString("Files from the chat order file come first, in the listed order; the rest follow.\n")
+	}
 	b.WriteString("Messages are sorted by heuristics (graph degree, exports, tests, path).\n")
 	b.WriteString("Each message contains one or more files rendered inside fenced code blocks.\n")
 	text := textutil.EnsureTrailingLF(textutil.NormalizeUTF8LF([]byte(b.String())))
@@ -261,7 +606,7 @@ func writeChatReadme(
 	return nil
 }
 
-func writeChatBench(zw *zip.Writer, benchPath string) error {
+func writeChatBench(zw Writer, benchPath string) error {
 	if strings.TrimSpace(benchPath) == "" {
 		return nil
 	}
@@ -275,12 +620,6 @@ func writeChatBench(zw *zip.Writer, benchPath string) error {
 	return nil
 }
 
-// isTestPath reports whether a path belongs to a tests folder or ends with _test.go.
-func isTestPath(p string) bool {
-	pp := strings.ReplaceAll(p, "\\", "/")
-	return strings.Contains(pp, "/test/") || strings.HasSuffix(pp, "_test.go")
-}
-
 func buildHeader(mf index.ManFile) string {
 	var b strings.Builder
 	b.WriteString("# ")
@@ -326,34 +665,19 @@ func writeBounded(w io.Writer, data []byte, remain int) (int, error) {
 	return n, err
 }
 
-func writeFileBounded(w io.Writer, absPath string, remain int) error {
+// writeFileBounded writes at most remain bytes of absPath, transcoded to
+// UTF-8, to w and reports how many were written. Unreadable files are skipped
+// silently.
+func writeFileBounded(w io.Writer, absPath string, remain int) (int, error) {
 	if remain <= 0 {
-		return nil
+		return 0, nil
 	}
-	f, err := os.Open(absPath)
+	data, err := os.ReadFile(absPath)
 	if err != nil {
-		return nil
-	}
-	defer f.Close()
-	buf := make([]byte, 32*1024)
-	left := remain
-	for left > 0 {
-		n := left
-		if n > len(buf) {
-			n = len(buf)
-		}
-		k, er := f.Read(buf[:n])
-		if k > 0 {
-			if _, ew := w.Write(buf[:k]); ew != nil {
-				return ew
-			}
-			left -= k
-		}
-		if er != nil {
-			break
-		}
+		return 0, nil
 	}
-	return nil
+	data, _ = textutil.ToUTF8(data)
+	return writeBounded(w, data, remain)
 }
 
 func pad4(n int) string {
@@ -378,6 +702,14 @@ func langFromExt(ext string) string {
 		return "csharp"
 	case ".py":
 		return "python"
+	case ".vue":
+		return "vue"
+	case ".svelte":
+		return "svelte"
+	case ".sh", ".bash":
+		return "bash"
+	case ".sql":
+		return "sql"
 	case ".md":
 		return "markdown"
 	default:
@@ -2,14 +2,18 @@ package bundle
 
 import (
 	"archive/zip"
+	"encoding/json"
 	"io"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
 
 	"class-collector/internal/graph"
 	"class-collector/internal/index"
+	"class-collector/internal/warn"
 )
 
 func TestWriteChatCreatesArtifacts(t *testing.T) {
@@ -28,7 +32,7 @@ func TestWriteChatCreatesArtifacts(t *testing.T) {
 		{RelPath: "foo.ts", AbsPath: src},
 	}
 	syms := index.Symbols{Symbols: []index.Symbol{{Symbol: "Foo.bar"}}}
-	if err := WriteChat(out, man, files, syms, graph.Graph{}, 2, 1024, ""); err != nil {
+	if err := WriteChat(out, man, files, syms, graph.Graph{}, ChatOptions{MaxClasses: 2, MaxChars: 1024}); err != nil {
 		t.Fatalf("WriteChat error: %v", err)
 	}
 	zr, err := zip.OpenReader(out)
@@ -58,3 +62,513 @@ func TestWriteChatCreatesArtifacts(t *testing.T) {
 		}
 	}
 }
+
+func TestWriteChatSystemPromptFirst(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "foo.go")
+	if err := os.WriteFile(src, []byte("package foo\n"), 0o644); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+	out := filepath.Join(dir, "chat.zip")
+	man := index.Manifest{Files: []index.ManFile{{Path: "foo.go"}}}
+	files := []struct{ RelPath, AbsPath string }{{RelPath: "foo.go", AbsPath: src}}
+	if err := WriteChat(out, man, files, index.Symbols{}, graph.Graph{}, ChatOptions{MaxClasses: 2, MaxChars: 1024, SystemPrompt: "Be concise."}); err != nil {
+		t.Fatalf("WriteChat error: %v", err)
+	}
+	zr, err := zip.OpenReader(out)
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	defer zr.Close()
+	if zr.File[0].Name != "chat/0000-system.md" {
+		t.Fatalf("first entry = %s, want system message", zr.File[0].Name)
+	}
+	var toc string
+	for _, f := range zr.File {
+		if f.Name != "TOC.md" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open TOC: %v", err)
+		}
+		body, _ := io.ReadAll(rc)
+		_ = rc.Close()
+		toc = string(body)
+	}
+	sys, msg := strings.Index(toc, "chat/0000-system.md"), strings.Index(toc, "chat/msg-0001.md")
+	if sys < 0 || msg < 0 || sys > msg {
+		t.Fatalf("TOC should list system message first:\n%s", toc)
+	}
+}
+
+func TestWriteChatIncludeGraph(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "foo.go")
+	if err := os.WriteFile(src, []byte("package foo\n"), 0o644); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+	out := filepath.Join(dir, "chat.zip")
+	man := index.Manifest{Files: []index.ManFile{{Path: "foo.go"}}}
+	files := []struct{ RelPath, AbsPath string }{{RelPath: "foo.go", AbsPath: src}}
+	g := graph.Graph{
+		Nodes: []string{"go:fmt", "go:foo", "go:os"},
+		Edges: [][2]string{{"go:foo", "go:os"}, {"go:foo", "go:fmt"}},
+	}
+	if err := WriteChat(out, man, files, index.Symbols{}, g, ChatOptions{MaxClasses: 2, MaxChars: 1024, Graph: true}); err != nil {
+		t.Fatalf("WriteChat error: %v", err)
+	}
+	zr, err := zip.OpenReader(out)
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	defer zr.Close()
+	if zr.File[0].Name != "chat/0000-graph.md" {
+		t.Fatalf("first entry = %s, want graph message", zr.File[0].Name)
+	}
+	rc, err := zr.File[0].Open()
+	if err != nil {
+		t.Fatalf("open graph message: %v", err)
+	}
+	body, _ := io.ReadAll(rc)
+	_ = rc.Close()
+	text := string(body)
+	if !strings.HasPrefix(text, "# Dependency graph\n") || !strings.Contains(text, "go:foo -> go:fmt, go:os\n") {
+		t.Fatalf("unexpected graph message:\n%s", text)
+	}
+	if strings.Count(text, "```") != 2 || !strings.HasSuffix(text, "```\n") {
+		t.Fatalf("graph message fence not closed:\n%s", text)
+	}
+}
+
+func TestWriteChatOverviewMessage(t *testing.T) {
+	dir := t.TempDir()
+	var files []struct{ RelPath, AbsPath string }
+	man := index.Manifest{Module: "acme-server", Build: "go"}
+	for _, p := range []string{"main.go", "util.go", "web/app.ts"} {
+		abs := filepath.Join(dir, filepath.Base(p))
+		if err := os.WriteFile(abs, []byte("x\n"), 0o644); err != nil {
+			t.Fatalf("write source: %v", err)
+		}
+		files = append(files, struct{ RelPath, AbsPath string }{p, abs})
+		man.Files = append(man.Files, index.ManFile{Path: p})
+	}
+	out := filepath.Join(dir, "chat.zip")
+	if err := WriteChat(out, man, files, index.Symbols{}, graph.Graph{}, ChatOptions{MaxClasses: 2, MaxChars: 1024, SystemPrompt: "Be concise.", Overview: true}); err != nil {
+		t.Fatalf("WriteChat error: %v", err)
+	}
+	zr, err := zip.OpenReader(out)
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	defer zr.Close()
+	entries := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		body, _ := io.ReadAll(rc)
+		_ = rc.Close()
+		entries[f.Name] = string(body)
+	}
+	text, ok := entries["chat/0000-overview.md"]
+	if !ok {
+		t.Fatalf("missing overview message")
+	}
+	for _, want := range []string{"- Module: acme-server\n", "- Build: go\n", "- Files: 3\n", "- Languages: go (2), ts (1)\n", "- chat/msg-0002.md: web/app.ts\n"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("overview missing %q:\n%s", want, text)
+		}
+	}
+	toc := entries["TOC.md"]
+	sys, ov, msg := strings.Index(toc, "0000-system.md"), strings.Index(toc, "0000-overview.md"), strings.Index(toc, "msg-0001.md")
+	if sys < 0 || ov < sys || msg < ov {
+		t.Fatalf("TOC should list system, overview, then messages:\n%s", toc)
+	}
+}
+
+func TestWriteChatEmptyFileEmptyFence(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty.go")
+	if err := os.WriteFile(empty, nil, 0o644); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+	out := filepath.Join(dir, "chat.zip")
+	man := index.Manifest{Files: []index.ManFile{{Path: "empty.go", Lines: 0}}}
+	files := []struct{ RelPath, AbsPath string }{{RelPath: "empty.go", AbsPath: empty}}
+	if err := WriteChat(out, man, files, index.Symbols{}, graph.Graph{}, ChatOptions{MaxClasses: 2, MaxChars: 1024}); err != nil {
+		t.Fatalf("WriteChat error: %v", err)
+	}
+	zr, err := zip.OpenReader(out)
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	defer zr.Close()
+	rc, err := zr.File[0].Open()
+	if err != nil {
+		t.Fatalf("open message: %v", err)
+	}
+	body, _ := io.ReadAll(rc)
+	_ = rc.Close()
+	if !strings.Contains(string(body), "```go\n```\n") {
+		t.Fatalf("expected empty fenced block:\n%s", body)
+	}
+}
+
+// writeChatEntry charges only the bytes it wrote: a file well within the
+// budget keeps its closing fence and leaves room for the next file, instead
+// of using up the message (the old maxChars-1 clamp).
+func TestWriteChatEntryChargesWrittenBytes(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "a.go")
+	if err := os.WriteFile(src, []byte("package x\n"), 0o644); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+	var b strings.Builder
+	mf := index.ManFile{Path: "a.go", Lines: 1}
+	written, full, err := writeChatEntry(&b, mf, map[string]string{"a.go": src}, 1024, 0)
+	if err != nil {
+		t.Fatalf("writeChatEntry error: %v", err)
+	}
+	if full || written != b.Len() {
+		t.Fatalf("written = %d, full = %v; want %d, false", written, full, b.Len())
+	}
+	if !strings.HasSuffix(b.String(), "package x\n\n```\n\n") {
+		t.Fatalf("closing fence missing:\n%s", b.String())
+	}
+}
+
+func TestWriteChatMaxMessages(t *testing.T) {
+	dir := t.TempDir()
+	var man index.Manifest
+	var files []struct{ RelPath, AbsPath string }
+	for _, name := range []string{"a.go", "b.go", "c.go", "d.go", "e.go"} {
+		abs := filepath.Join(dir, name)
+		if err := os.WriteFile(abs, []byte("package x\n"), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		man.Files = append(man.Files, index.ManFile{Path: name, Lines: 2})
+		files = append(files, struct{ RelPath, AbsPath string }{name, abs})
+	}
+	read := func(zr *zip.ReadCloser, name string) string {
+		for _, f := range zr.File {
+			if f.Name == name {
+				rc, err := f.Open()
+				if err != nil {
+					t.Fatalf("open %s: %v", name, err)
+				}
+				body, _ := io.ReadAll(rc)
+				_ = rc.Close()
+				return string(body)
+			}
+		}
+		return ""
+	}
+	countMsgs := func(zr *zip.ReadCloser) int {
+		n := 0
+		for _, f := range zr.File {
+			if strings.HasPrefix(f.Name, "chat/msg-") {
+				n++
+			}
+		}
+		return n
+	}
+
+	for _, tc := range []struct {
+		overflow, note string
+		inMsgs         []string
+	}{
+		{ChatOverflowPack, "files per message raised to 3", []string{"a.go", "e.go"}},
+		{ChatOverflowDrop, "Dropped (lowest-ranked, over the cap): 1 files: e.go", []string{"a.go", "d.go"}},
+	} {
+		out := filepath.Join(dir, tc.overflow+".zip")
+		if err := WriteChat(out, man, files, index.Symbols{}, graph.Graph{}, ChatOptions{MaxClasses: 2, MaxChars: 1024, MaxMessages: 2, Overflow: tc.overflow}); err != nil {
+			t.Fatalf("%s: WriteChat error: %v", tc.overflow, err)
+		}
+		zr, err := zip.OpenReader(out)
+		if err != nil {
+			t.Fatalf("open zip: %v", err)
+		}
+		if n := countMsgs(zr); n != 2 {
+			t.Fatalf("%s: %d messages, want 2", tc.overflow, n)
+		}
+		if readme := read(zr, "README.md"); !strings.Contains(readme, tc.note) {
+			t.Fatalf("%s: README missing %q:\n%s", tc.overflow, tc.note, readme)
+		}
+		toc := read(zr, "TOC.md")
+		for _, p := range tc.inMsgs {
+			if !strings.Contains(toc, p) {
+				t.Fatalf("%s: TOC missing %s:\n%s", tc.overflow, p, toc)
+			}
+		}
+		if tc.overflow == ChatOverflowDrop && strings.Contains(toc, "e.go") {
+			t.Fatalf("drop: e.go should not be in any message:\n%s", toc)
+		}
+		_ = zr.Close()
+	}
+}
+
+func TestWriteChatSkipLargeFiles(t *testing.T) {
+	SetChatSkipLargeFiles(2000)
+	defer SetChatSkipLargeFiles(0)
+	c := &warn.Collector{}
+	warn.Use(c)
+	defer warn.Use(nil)
+
+	dir := t.TempDir()
+	var man index.Manifest
+	var files []struct{ RelPath, AbsPath string }
+	for name, lines := range map[string]int{"big.go": 5000, "small.go": 3} {
+		abs := filepath.Join(dir, name)
+		body := "package x\n" + strings.Repeat("// line\n", lines-1)
+		if err := os.WriteFile(abs, []byte(body), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		man.Files = append(man.Files, index.ManFile{Path: name, Lines: lines})
+		files = append(files, struct{ RelPath, AbsPath string }{name, abs})
+	}
+	sort.Slice(man.Files, func(i, j int) bool { return man.Files[i].Path < man.Files[j].Path })
+
+	out := filepath.Join(dir, "chat.zip")
+	if err := WriteChat(out, man, files, index.Symbols{}, graph.Graph{}, ChatOptions{MaxClasses: 10, MaxChars: 1 << 20}); err != nil {
+		t.Fatalf("WriteChat error: %v", err)
+	}
+	zr, err := zip.OpenReader(out)
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	defer zr.Close()
+	contents := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		body, _ := io.ReadAll(rc)
+		_ = rc.Close()
+		contents[f.Name] = string(body)
+	}
+	msg := contents["chat/msg-0001.md"]
+	if !strings.Contains(msg, "small.go") || strings.Contains(msg, "big.go") {
+		t.Fatalf("message should hold small.go only:\n%s", msg)
+	}
+	if _, ok := contents["chat/msg-0002.md"]; ok {
+		t.Fatal("big.go should not get a message of its own")
+	}
+	if want := "- Omitted (too large, over 2000 lines): 1 files: big.go"; !strings.Contains(contents["README.md"], want) {
+		t.Fatalf("README missing %q:\n%s", want, contents["README.md"])
+	}
+	if ws := c.List(); len(ws) != 1 || ws[0].Path != "big.go" || ws[0].Kind != warn.KindTruncated {
+		t.Fatalf("warnings = %+v", ws)
+	}
+}
+
+func TestWriteChatRedactPaths(t *testing.T) {
+	SetChatRedactPaths(true)
+	defer SetChatRedactPaths(false)
+	SetWriteWarnings(true)
+	defer SetWriteWarnings(false)
+	defer warn.Use(nil)
+
+	dir := t.TempDir()
+	var files []struct{ RelPath, AbsPath string }
+	man := index.Manifest{Module: "acme"}
+	for _, p := range []string{"internal/billing/invoice.go", "web/admin/panel.ts"} {
+		abs := filepath.Join(dir, filepath.Base(p))
+		if err := os.WriteFile(abs, []byte("x\n"), 0o644); err != nil {
+			t.Fatalf("write source: %v", err)
+		}
+		files = append(files, struct{ RelPath, AbsPath string }{p, abs})
+		man.Files = append(man.Files, index.ManFile{Path: p, Lines: 1})
+	}
+
+	read := func(out string) map[string]string {
+		zr, err := zip.OpenReader(out)
+		if err != nil {
+			t.Fatalf("open zip: %v", err)
+		}
+		defer zr.Close()
+		entries := map[string]string{}
+		for _, f := range zr.File {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatalf("open %s: %v", f.Name, err)
+			}
+			body, _ := io.ReadAll(rc)
+			_ = rc.Close()
+			entries[f.Name] = string(body)
+		}
+		return entries
+	}
+	var first map[string]string
+	for run := 0; run < 2; run++ {
+		warn.Use(&warn.Collector{})
+		warn.Add(warn.KindEncoding, "internal/billing/invoice.go", "internal/billing/invoice.go: replaced bytes")
+		out := filepath.Join(dir, "chat.zip")
+		if err := WriteChat(out, man, files, index.Symbols{}, graph.Graph{}, ChatOptions{MaxClasses: 1, MaxChars: 1024, Overview: true}); err != nil {
+			t.Fatalf("WriteChat error: %v", err)
+		}
+		entries := read(out)
+		if first == nil {
+			first = entries
+		} else if !reflect.DeepEqual(first, entries) {
+			t.Fatalf("redacted chat bundle is not deterministic")
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "chat.path-map.json")); err != nil {
+		t.Fatalf("path map not written beside the archive: %v", err)
+	}
+	data, _ := os.ReadFile(PathMapFor(filepath.Join(dir, "chat.zip")))
+	var pm PathMap
+	if err := json.Unmarshal(data, &pm); err != nil {
+		t.Fatalf("decode path map: %v", err)
+	}
+	if len(pm.Paths) != 2 {
+		t.Fatalf("path map = %+v", pm)
+	}
+	for name, body := range first {
+		for _, leak := range []string{"billing", "admin", "invoice", "panel"} {
+			if strings.Contains(body, leak) {
+				t.Fatalf("%s leaks %q:\n%s", name, leak, body)
+			}
+		}
+		if strings.Contains(name, "path-map") {
+			t.Fatalf("path map must stay out of the archive")
+		}
+	}
+	for tok, orig := range pm.Paths {
+		if !strings.HasPrefix(tok, "file-") || filepath.Ext(tok) != filepath.Ext(orig) {
+			t.Fatalf("unexpected token %q for %q", tok, orig)
+		}
+		for _, name := range []string{"TOC.md", "chat/0000-overview.md"} {
+			if !strings.Contains(first[name], tok) {
+				t.Fatalf("%s does not mention token %s:\n%s", name, tok, first[name])
+			}
+		}
+		msgs := first["chat/msg-0001.md"] + first["chat/msg-0002.md"]
+		if !strings.Contains(msgs, "# "+tok+"\n") {
+			t.Fatalf("no header for %s in messages:\n%s", tok, msgs)
+		}
+	}
+	if !strings.Contains(first["warnings.json"], "file-") {
+		t.Fatalf("warnings should use tokens:\n%s", first["warnings.json"])
+	}
+}
+
+func TestWriteChatSeparators(t *testing.T) {
+	dir := t.TempDir()
+	var man index.Manifest
+	var files []struct{ RelPath, AbsPath string }
+	for _, name := range []string{"a.go", "b.go", "c.go"} {
+		abs := filepath.Join(dir, name)
+		if err := os.WriteFile(abs, []byte("package x\n"), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		man.Files = append(man.Files, index.ManFile{Path: name, Lines: 1})
+		files = append(files, struct{ RelPath, AbsPath string }{name, abs})
+	}
+	read := func(out string) ([]string, map[string]string) {
+		zr, err := zip.OpenReader(out)
+		if err != nil {
+			t.Fatalf("open zip: %v", err)
+		}
+		defer zr.Close()
+		var names []string
+		bodies := map[string]string{}
+		for _, f := range zr.File {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatalf("open %s: %v", f.Name, err)
+			}
+			body, _ := io.ReadAll(rc)
+			_ = rc.Close()
+			if strings.HasPrefix(f.Name, "chat/") {
+				names = append(names, f.Name)
+			}
+			bodies[f.Name] = string(body)
+		}
+		sort.Strings(names)
+		return names, bodies
+	}
+	defer SetChatSeparators("", "")
+
+	for _, tc := range []struct {
+		name, footer string
+		wantLast     string
+		withSep      []string
+	}{
+		{"footer", "Now answer.", "chat/zzzz-footer.md", []string{"chat/0000-system.md", "chat/msg-0001.md", "chat/msg-0002.md"}},
+		{"no-footer", "", "chat/msg-0002.md", []string{"chat/0000-system.md", "chat/msg-0001.md"}},
+	} {
+		SetChatSeparators("--- next message ---", tc.footer)
+		out := filepath.Join(dir, tc.name+".zip")
+		if err := WriteChat(out, man, files, index.Symbols{}, graph.Graph{}, ChatOptions{MaxClasses: 2, MaxChars: 1024, SystemPrompt: "Be concise."}); err != nil {
+			t.Fatalf("%s: WriteChat error: %v", tc.name, err)
+		}
+		names, bodies := read(out)
+		if last := names[len(names)-1]; last != tc.wantLast {
+			t.Fatalf("%s: last message = %s, want %s (%v)", tc.name, last, tc.wantLast, names)
+		}
+		if tc.footer != "" {
+			if bodies[tc.wantLast] != tc.footer+"\n" {
+				t.Fatalf("%s: footer = %q", tc.name, bodies[tc.wantLast])
+			}
+			toc := bodies["TOC.md"]
+			if strings.Index(toc, "chat/zzzz-footer.md") < strings.Index(toc, "chat/msg-0002.md") {
+				t.Fatalf("%s: TOC should list the footer last:\n%s", tc.name, toc)
+			}
+		}
+		for _, name := range names {
+			want := false
+			for _, s := range tc.withSep {
+				want = want || s == name
+			}
+			if got := strings.HasSuffix(bodies[name], "\n--- next message ---\n"); got != want {
+				t.Fatalf("%s: %s has separator = %v, want %v:\n%s", tc.name, name, got, want, bodies[name])
+			}
+		}
+	}
+}
+
+func TestRankChatOrderCustom(t *testing.T) {
+	SetChatOrder([]string{"z/last.go", "missing.go", "b.go", "z/last.go"})
+	defer SetChatOrder(nil)
+	c := &warn.Collector{}
+	warn.Use(c)
+	defer warn.Use(nil)
+
+	man := index.Manifest{Files: []index.ManFile{
+		{Path: "a.go", Exports: []string{"A"}},
+		{Path: "b.go"},
+		{Path: "c_test.go"},
+		{Path: "d.go", Exports: []string{"D"}},
+		{Path: "z/last.go"},
+	}}
+	var got []string
+	for _, mf := range rankChatOrder(man, graph.Graph{}) {
+		got = append(got, mf.Path)
+	}
+	if want := []string{"z/last.go", "b.go", "a.go", "d.go", "c_test.go"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("order = %v, want %v", got, want)
+	}
+	if ws := c.List(); len(ws) != 1 || ws[0].Path != "missing.go" || ws[0].Kind != warn.KindConfig {
+		t.Fatalf("warnings = %+v", ws)
+	}
+}
+
+func TestRankChatOrderAPISurfaceFirst(t *testing.T) {
+	man := index.Manifest{Files: []index.ManFile{
+		{Path: "a.go", Exports: []string{"A"}},
+		{Path: "b.go", Tags: []string{index.TagAPISurface}},
+		{Path: "c.go"},
+	}}
+	var got []string
+	for _, mf := range rankChatOrder(man, graph.Graph{}) {
+		got = append(got, mf.Path)
+	}
+	if want := []string{"b.go", "a.go", "c.go"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("order = %v, want %v", got, want)
+	}
+}
@@ -3,7 +3,6 @@
 package bundle
 
 import (
-	"archive/zip"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -11,9 +10,12 @@ import (
 	"sort"
 	"strings"
 
+	"class-collector/internal/cache"
 	"class-collector/internal/diff"
 	"class-collector/internal/sortutil"
 	"class-collector/internal/textutil"
+	"class-collector/internal/validate"
+	"class-collector/internal/warn"
 	"class-collector/internal/ziputil"
 )
 
@@ -99,7 +101,7 @@ func prepareDeltaView(deltaIndex any) deltaView {
 	return view
 }
 
-func writePerFileDiffs(zw *zip.Writer, diffs map[string]string) ([]zipPatch, error) {
+func writePerFileDiffs(zw Writer, diffs map[string]string) ([]zipPatch, error) {
 	if len(diffs) == 0 {
 		return nil, nil
 	}
@@ -112,7 +114,7 @@ func writePerFileDiffs(zw *zip.Writer, diffs map[string]string) ([]zipPatch, err
 	used := make(map[string]struct{}, len(names))
 	out := make([]zipPatch, 0, len(names))
 	for _, name := range names {
-		raw := filepath.ToSlash(filepath.Join("diffs", name))
+		raw := filepath.ToSlash(filepath.Join(deltaLayout.Diffs, name))
 		zname := ziputil.EnsureUniqueName(ziputil.SanitizePath(raw), used)
 		body := []byte(diffs[name])
 		norm := textutil.EnsureTrailingLF(textutil.NormalizeUTF8LF(body))
@@ -124,30 +126,50 @@ func writePerFileDiffs(zw *zip.Writer, diffs map[string]string) ([]zipPatch, err
 	return out, nil
 }
 
-func synthesizeAddedPatches(files []struct{ RelPath, AbsPath string }, maxBytes, diffContext int, diffNoPrefix bool) ([]zipPatch, error) {
+// synthesizeAddedPatches renders the delta.patch sections of the added
+// files in path order. With opt.MaxTotalBytes set they are charged to what
+// is left of it after spent (the changed-file diffs): once the next patch
+// would exceed the budget, it and every later one become placeholders.
+func synthesizeAddedPatches(files []struct{ RelPath, AbsPath string }, opt diff.Options, spent int) ([]zipPatch, error) {
 	if len(files) == 0 {
 		return nil, nil
 	}
-	opt := diff.Options{
-		MaxBytes: maxBytes,
-		Context:  diffContext,
-		NoPrefix: diffNoPrefix,
-		LineMode: true,
-	}
-	out := make([]zipPatch, 0, len(files))
-	for _, f := range files {
-		data, err := os.ReadFile(f.AbsPath)
-		if err != nil {
-			continue
-		}
+	opt.LineMode = true
+	sorted := make([]struct{ RelPath, AbsPath string }, len(files))
+	copy(sorted, files)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].RelPath < sorted[j].RelPath })
+	budgetSpent := false
+	out := make([]zipPatch, 0, len(sorted))
+	for _, f := range sorted {
 		bName := filepath.ToSlash(f.RelPath)
-		if !diffNoPrefix {
+		if !opt.NoPrefix {
 			bName = "b/" + bName
 		}
-		body, _ := diff.Added(bName, data, opt)
+		var body string
+		if budgetSpent {
+			body = diff.Omitted("/dev/null", bName)
+		} else {
+			data, err := os.ReadFile(f.AbsPath)
+			if err != nil {
+				continue
+			}
+			data, _ = textutil.ToUTF8(data)
+			body, _ = diff.Added(bName, data, opt)
+			if opt.MaxTotalBytes > 0 && !diff.IsOmitted(body) {
+				if spent+len(body) > opt.MaxTotalBytes {
+					budgetSpent = true
+					body = diff.Omitted("/dev/null", bName)
+				} else {
+					spent += len(body)
+				}
+			}
+		}
+		if budgetSpent {
+			warn.Add(warn.KindOversize, f.RelPath, "diff omitted: delta diffs exceed the %d-byte total budget", opt.MaxTotalBytes)
+		}
 		norm := textutil.EnsureTrailingLF(textutil.NormalizeUTF8LF([]byte(body)))
 		out = append(out, zipPatch{
-			name: filepath.ToSlash(filepath.Join("added", f.RelPath)),
+			name: filepath.ToSlash(filepath.Join(deltaLayout.Added, f.RelPath)),
 			body: norm,
 		})
 	}
@@ -172,14 +194,20 @@ func buildDeltaPatch(perFile, added []zipPatch) []byte {
 	return textutil.EnsureTrailingLF(textutil.NormalizeUTF8LF(joined))
 }
 
-func writeSummary(zw *zip.Writer, view deltaView) error {
+// writeSummary writes SUMMARY.md. With targets, changed and added paths point
+// at their patch or added/ entry; summary-only archives list bare paths.
+func writeSummary(zw Writer, view deltaView, targets bool) error {
 	var b strings.Builder
 	b.WriteString("# SUMMARY\n\n")
 	fmt.Fprintf(&b, "Changed (%d):\n", len(view.Changed))
 	for _, c := range view.Changed {
+		if !targets {
+			fmt.Fprintf(&b, "- %s\n", c.Path)
+			continue
+		}
 		target := c.DiffPath
 		if target == "" {
-			target = "diffs/"
+			target = deltaLayout.Diffs + "/"
 		}
 		fmt.Fprintf(&b, "- %s -> %s\n", c.Path, target)
 	}
@@ -187,7 +215,11 @@ func writeSummary(zw *zip.Writer, view deltaView) error {
 
 	fmt.Fprintf(&b, "Added (%d):\n", len(view.Added))
 	for _, path := range view.Added {
-		fmt.Fprintf(&b, "- %s -> added/%s\n", path, path)
+		if !targets {
+			fmt.Fprintf(&b, "- %s\n", path)
+			continue
+		}
+		fmt.Fprintf(&b, "- %s -> %s/%s\n", path, deltaLayout.Added, path)
 	}
 	b.WriteString("\n")
 
@@ -209,16 +241,18 @@ func writeSummary(zw *zip.Writer, view deltaView) error {
 			oversize++
 		}
 	}
-	fmt.Fprintf(&b, "Oversize diffs (%d)\n", oversize)
+	if targets {
+		fmt.Fprintf(&b, "Oversize diffs (%d)\n", oversize)
+	}
 
 	text := textutil.EnsureTrailingLF(textutil.NormalizeUTF8LF([]byte(b.String())))
-	if err := ziputil.WriteText(zw, "SUMMARY.md", text); err != nil {
-		return fmt.Errorf("write SUMMARY.md: %w", err)
+	if err := ziputil.WriteText(zw, deltaLayout.Summary, text); err != nil {
+		return fmt.Errorf("write %s: %w", deltaLayout.Summary, err)
 	}
 	return nil
 }
 
-func writeReadme(zw *zip.Writer, view deltaView, benchPath string, diffContext int, diffNoPrefix bool, present []string) error {
+func writeReadme(zw Writer, view deltaView, benchPath string, diffContext int, diffNoPrefix bool, present []string) error {
 	readme := GenerateDeltaReadme(ReadmeOptions{
 		ModuleName:        view.BaseModule,
 		SupportedLangs:    supportedLangs(),
@@ -229,13 +263,13 @@ func writeReadme(zw *zip.Writer, view deltaView, benchPath string, diffContext i
 		IncludeDeltaNotes: true,
 	})
 	readme = textutil.EnsureTrailingLF(textutil.NormalizeUTF8LF(readme))
-	if err := ziputil.WriteText(zw, "README.md", readme); err != nil {
-		return fmt.Errorf("write README.md: %w", err)
+	if err := ziputil.WriteText(zw, deltaLayout.Readme, readme); err != nil {
+		return fmt.Errorf("write %s: %w", deltaLayout.Readme, err)
 	}
 	return nil
 }
 
-func maybeWriteBench(zw *zip.Writer, benchPath string) error {
+func maybeWriteBench(zw Writer, benchPath string) error {
 	if strings.TrimSpace(benchPath) == "" {
 		return nil
 	}
@@ -249,47 +283,94 @@ func maybeWriteBench(zw *zip.Writer, benchPath string) error {
 	return nil
 }
 
-// WriteDelta writes a delta ZIP archive with deterministic layout.
-func WriteDelta(
-	zipPath string,
-	deltaIndex any,
-	diffs map[string]string,
-	addedFiles []struct{ RelPath, AbsPath string },
-	benchPath string,
-	diffContext int,
-	diffNoPrefix bool,
-	maxDiffBytes int,
-) error {
-	if err := os.MkdirAll(filepath.Dir(zipPath), 0o755); err != nil {
-		return fmt.Errorf("mkdir output: %w", err)
-	}
-	f, err := os.Create(zipPath)
-	if err != nil {
-		return fmt.Errorf("create output: %w", err)
+// CurrentManifest is the file list embedded in DELTA bundles on request
+// (DeltaLayout.CurrentFiles):
+// every file of the current tree (not just changed ones) with its hash and
+// line count, so consumers can resolve renames and context. It holds no
+// symbols or timestamps.
+type CurrentManifest struct {
+	Module string           `json:"module"`
+	Files  []cache.SnapFile `json:"files"`
+}
+
+// writeCurrentManifest writes the current file list from current; nil skips
+// it.
+func writeCurrentManifest(zw Writer, current *cache.Snapshot) error {
+	if current == nil {
+		return nil
 	}
-	defer f.Close()
+	man := CurrentManifest{Module: current.Module, Files: current.Files}
+	if man.Files == nil {
+		man.Files = []cache.SnapFile{}
+	}
+	if err := ziputil.WriteJSON(zw, deltaLayout.CurrentFiles, man); err != nil {
+		return fmt.Errorf("write %s: %w", deltaLayout.CurrentFiles, err)
+	}
+	return nil
+}
+
+// DeltaArtifacts is the content of a DELTA bundle: the delta index, the
+// per-file diffs by path, the added files to copy, and optionally the
+// rename report and the current snapshot.
+type DeltaArtifacts struct {
+	Index        any
+	Diffs        map[string]string
+	Added        []struct{ RelPath, AbsPath string }
+	RenameReport []cache.RenameCandidate
+	Current      *cache.Snapshot
+}
 
-	zw := zip.NewWriter(f)
-	defer zw.Close()
+// WriteDelta writes a delta ZIP archive with deterministic layout. Entry
+// names follow the layout set via SetDeltaLayout. A non-nil RenameReport is
+// written as the layout's RenameReport entry, and a non-nil Current snapshot as the
+// current file list (see CurrentManifest). opt is what MakeDiffs was given;
+// the added-file patches of delta.patch are rendered with it and charged to
+// the opt.MaxTotalBytes left after Diffs.
+func WriteDelta(zipPath string, art DeltaArtifacts, benchPath string, opt diff.Options) (err error) {
+	deltaIndex, diffs, addedFiles := art.Index, art.Diffs, art.Added
+	renameReport, current := art.RenameReport, art.Current
+	zw, err := createWriter(zipPath)
+	if err != nil {
+		return err
+	}
+	defer closeWriter(zw, &err)
 
-	if err := ziputil.WriteJSON(zw, "delta.index.json", deltaIndex); err != nil {
-		return fmt.Errorf("write delta.index.json: %w", err)
+	if err := ziputil.WriteJSON(zw, deltaLayout.Index, deltaIndex); err != nil {
+		return fmt.Errorf("write %s: %w", deltaLayout.Index, err)
+	}
+	if renameReport != nil {
+		if err := ziputil.WriteJSON(zw, deltaLayout.RenameReport, renameReport); err != nil {
+			return fmt.Errorf("write %s: %w", deltaLayout.RenameReport, err)
+		}
+	}
+	if err := writeCurrentManifest(zw, current); err != nil {
+		return err
 	}
 
 	perFile, err := writePerFileDiffs(zw, diffs)
 	if err != nil {
 		return err
 	}
-	addedPatches, err := synthesizeAddedPatches(addedFiles, maxDiffBytes, diffContext, diffNoPrefix)
+	spent := 0
+	for _, body := range diffs {
+		if !diff.IsOmitted(body) {
+			spent += len(body)
+		}
+	}
+	addedPatches, err := synthesizeAddedPatches(addedFiles, opt, spent)
 	if err != nil {
 		return err
 	}
 	if patch := buildDeltaPatch(perFile, addedPatches); len(patch) > 0 {
-		if err := ziputil.WriteText(zw, "delta.patch", patch); err != nil {
-			return fmt.Errorf("write delta.patch: %w", err)
+		if err := ziputil.WriteText(zw, deltaLayout.Patch, patch); err != nil {
+			return fmt.Errorf("write %s: %w", deltaLayout.Patch, err)
 		}
 	}
 
+	written := make([]string, 0, len(perFile)+len(addedFiles))
+	for _, p := range perFile {
+		written = append(written, p.name)
+	}
 	if len(addedFiles) > 0 {
 		sorted := make([]struct{ RelPath, AbsPath string }, len(addedFiles))
 		copy(sorted, addedFiles)
@@ -298,7 +379,7 @@ func WriteDelta(
 		})
 		used := make(map[string]struct{}, len(sorted))
 		for _, f := range sorted {
-			raw := filepath.ToSlash(filepath.Join("added", f.RelPath))
+			raw := filepath.ToSlash(filepath.Join(deltaLayout.Added, f.RelPath))
 			zname := ziputil.EnsureUniqueName(ziputil.SanitizePath(raw), used)
 			data, err := os.ReadFile(f.AbsPath)
 			if err != nil {
@@ -307,11 +388,15 @@ func WriteDelta(
 			if err := ziputil.WriteFile(zw, zname, data); err != nil {
 				return fmt.Errorf("write %s: %w", zname, err)
 			}
+			written = append(written, zname)
 		}
 	}
+	if err := validate.Delta(deltaIndex, deltaLayout.Added, written); err != nil {
+		return fmt.Errorf("validate delta: %w", err)
+	}
 
 	view := prepareDeltaView(deltaIndex)
-	if err := writeSummary(zw, view); err != nil {
+	if err := writeSummary(zw, view, true); err != nil {
 		return err
 	}
 
@@ -321,13 +406,13 @@ func WriteDelta(
 		present = presentLangsFromAddedAndDiffs(addedFiles, perFile)
 	}
 
-	if err := writeReadme(zw, view, benchPath, diffContext, diffNoPrefix, present); err != nil {
+	if err := writeReadme(zw, view, benchPath, opt.Context, opt.NoPrefix, present); err != nil {
 		return err
 	}
 	if err := maybeWriteBench(zw, benchPath); err != nil {
 		return err
 	}
-	return nil
+	return writeWarnings(zw)
 }
 
 func presentLangsFromDelta(view deltaView) []string {
@@ -434,3 +519,23 @@ func presentLangsFromAddedAndDiffs(added []struct{ RelPath, AbsPath string }, pe
 	sort.Strings(out)
 	return out
 }
+
+// WriteDeltaSummary writes a minimal DELTA archive holding only the delta
+// index and SUMMARY.md: no per-file patches, added/ copies or combined patch.
+// Changed entries of deltaIndex should carry no diff path. A non-nil current
+// snapshot is written as the layout's CurrentFiles entry, as in WriteDelta.
+func WriteDeltaSummary(zipPath string, deltaIndex any, current *cache.Snapshot) (err error) {
+	zw, err := createWriter(zipPath)
+	if err != nil {
+		return err
+	}
+	defer closeWriter(zw, &err)
+
+	if err := ziputil.WriteJSON(zw, deltaLayout.Index, deltaIndex); err != nil {
+		return fmt.Errorf("write %s: %w", deltaLayout.Index, err)
+	}
+	if err := writeCurrentManifest(zw, current); err != nil {
+		return err
+	}
+	return writeSummary(zw, prepareDeltaView(deltaIndex), false)
+}
@@ -4,12 +4,15 @@
 // archive with the following layout:
 //
 //	manifest.json
-//	symbols.json
+//	symbols.json # flat list, or a nested tree with -symbols-format tree
 //	graph.json # placeholder or actual graph
 //	slices.jsonl # optional, line-delimited JSON
 //	pointers.jsonl # optional, line-delimited JSON
 //	README.md # stable (no wall-clock timestamps)
-//	src/<project files> # optional, if emitSrc=true
+//	stats.json # optional per-language line counts, if stats != nil
+//	hygiene.json # optional mixed line endings/indentation report, if hygiene != nil
+//	graph.clusters.json # optional node -> cluster mapping, if clusters != nil
+//	src/<project files> # optional, if emitSrc=true (see WriteSources for a separate archive)
 //
 // Design goals:
 //   - Deterministic output (fixed timestamps, sorted entries)
@@ -20,6 +23,7 @@ package bundle
 import (
 	"archive/zip"
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -29,49 +33,100 @@ import (
 
 	"class-collector/internal/graph"
 	"class-collector/internal/index"
+	"class-collector/internal/meta"
 	"class-collector/internal/textutil"
 	"class-collector/internal/ziputil"
 )
 
+// symbols.json layouts selectable via SetSymbolsFormat.
+const (
+	SymbolsFormatFlat = "flat" // index.Symbols: one sorted list
+	SymbolsFormatTree = "tree" // index.SymbolTree: members nested under their types
+)
+
+var symbolsFormat = SymbolsFormatFlat
+
+// SetSymbolsFormat selects the symbols.json layout written by WriteFull and
+// Merge (SymbolsFormatFlat or SymbolsFormatTree).
+func SetSymbolsFormat(format string) { symbolsFormat = format }
+
+// Flat symbols.json orderings selectable via SetSymbolsSort.
+const (
+	SymbolsSortPosition = "position" // canonical (path, start, end, symbol)
+	SymbolsSortName     = "name"     // by symbol name, then position
+)
+
+var symbolsSort = SymbolsSortPosition
+
+// SetSymbolsSort selects the order of the flat symbols.json list. It is a
+// display order only: the bundle ID and validation use the canonical
+// position order, and the tree layout keeps its own.
+func SetSymbolsSort(order string) { symbolsSort = order }
+
+var repoReadme string
+
+// SetRepoReadme names the manifest path of the repository's own README to
+// surface first: at the top of the FULL TOC.md and as the first file of the
+// chat messages. Empty disables it.
+func SetRepoReadme(relPath string) { repoReadme = relPath }
+
+// FullArtifacts is the content of a FULL bundle: the core index artifacts,
+// the optional extras (each written only when non-nil) and the files copied
+// under src/ (none when Sources is empty).
+type FullArtifacts struct {
+	index.Artifacts
+	Stats     *index.Stats
+	Clusters  *graph.Clusters
+	Deps      *meta.Dependencies
+	Conflicts *index.SymbolConflicts
+	Reduced   *graph.Graph
+	Hygiene   *index.Hygiene
+	Sources   []struct{ RelPath, AbsPath string }
+}
+
 // WriteFull writes the full bundle zip.
-func WriteFull(
-	zipPath, root string,
-	files []struct{ RelPath, AbsPath string },
-	man index.Manifest,
-	syms index.Symbols,
-	slices []index.Slice,
-	pointers []index.Pointer,
-	g graph.Graph,
-	emitSrc bool,
-	benchPath string,
-	diffContext int,
-	diffNoPrefix bool,
-) error {
-	_ = root
-	if err := os.MkdirAll(filepath.Dir(zipPath), 0o755); err != nil {
-		return err
-	}
-	f, err := os.Create(zipPath)
+func WriteFull(zipPath string, art FullArtifacts, benchPath string, diffContext int, diffNoPrefix bool) (err error) {
+	zw, err := createWriter(zipPath)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-
-	zw := zip.NewWriter(f)
-	defer zw.Close()
-
-	art := index.Artifacts{
-		Manifest: man,
-		Symbols:  syms,
-		Slices:   slices,
-		Pointers: pointers,
-		Graph:    g,
-	}
+	defer closeWriter(zw, &err)
 
-	if err := writeCoreJson(zw, art); err != nil {
+	if err := writeCoreJson(zw, art.Artifacts); err != nil {
 		return err
 	}
+	if art.Stats != nil {
+		if err := ziputil.WriteJSON(zw, "stats.json", art.Stats); err != nil {
+			return err
+		}
+	}
+	if art.Clusters != nil {
+		if err := ziputil.WriteJSON(zw, "graph.clusters.json", art.Clusters); err != nil {
+			return err
+		}
+	}
+	if art.Deps != nil {
+		if err := ziputil.WriteJSON(zw, "dependencies.json", art.Deps); err != nil {
+			return err
+		}
+	}
+	if art.Conflicts != nil {
+		if err := ziputil.WriteJSON(zw, "symbol-conflicts.json", art.Conflicts); err != nil {
+			return err
+		}
+	}
+	if art.Reduced != nil {
+		if err := ziputil.WriteJSON(zw, "graph.reduced.json", art.Reduced); err != nil {
+			return err
+		}
+	}
+	if art.Hygiene != nil {
+		if err := ziputil.WriteJSON(zw, "hygiene.json", art.Hygiene); err != nil {
+			return err
+		}
+	}
 
+	man, stats, deps := art.Manifest, art.Stats, art.Deps
 	fullLangs := supportedLangs()
 	presentLangs := presentLangsFromManifest(man)
 
@@ -84,6 +139,14 @@ func WriteFull(
 		IncludeBenchNote: strings.TrimSpace(benchPath) != "",
 		IncludeFullNotes: true,
 	}
+	if stats != nil {
+		rows := make([]index.LangStats, 0, len(stats.Languages)+1)
+		rows = append(rows, stats.Languages...)
+		readmeOpts.Stats = append(rows, stats.Total)
+	}
+	if deps != nil {
+		readmeOpts.Deps = deps.Sets
+	}
 
 	if err := writeReadmeFull(zw, readmeOpts); err != nil {
 		return err
@@ -91,20 +154,36 @@ func WriteFull(
 	if err := writeToc(zw, man); err != nil {
 		return err
 	}
-	if err := writeSourcesIfEnabled(zw, files, emitSrc); err != nil {
+	if emitHTML {
+		srcLinked := make(map[string]bool, len(art.Sources))
+		for _, fi := range art.Sources {
+			srcLinked[fi.RelPath] = true
+		}
+		if err := writeIndexHTML(zw, man, art.Symbols, art.Graph, srcLinked); err != nil {
+			return err
+		}
+	}
+	if err := writeSourcesIfEnabled(zw, art.Sources, true); err != nil {
 		return err
 	}
 	if err := writeBenchIfPresent(zw, benchPath); err != nil {
 		return err
 	}
-	return nil
+	return writeWarnings(zw)
 }
 
-func writeCoreJson(zw *zip.Writer, art index.Artifacts) error {
+func writeCoreJson(zw Writer, art index.Artifacts) error {
 	if err := ziputil.WriteJSON(zw, "manifest.json", art.Manifest); err != nil {
 		return err
 	}
-	if err := ziputil.WriteJSON(zw, "symbols.json", art.Symbols); err != nil {
+	var symbols any = art.Symbols
+	switch {
+	case symbolsFormat == SymbolsFormatTree:
+		symbols = index.BuildSymbolTree(art.Symbols)
+	case symbolsSort == SymbolsSortName:
+		symbols = sortSymbolsByName(art.Symbols)
+	}
+	if err := ziputil.WriteJSON(zw, "symbols.json", symbols); err != nil {
 		return err
 	}
 	if art.Manifest.BundleID != "" {
@@ -120,15 +199,7 @@ func writeCoreJson(zw *zip.Writer, art index.Artifacts) error {
 	if len(art.Slices) > 0 {
 		sorted := make([]index.Slice, len(art.Slices))
 		copy(sorted, art.Slices)
-		sort.Slice(sorted, func(i, j int) bool {
-			if sorted[i].Path == sorted[j].Path {
-				if sorted[i].Start == sorted[j].Start {
-					return sorted[i].End < sorted[j].End
-				}
-				return sorted[i].Start < sorted[j].Start
-			}
-			return sorted[i].Path < sorted[j].Path
-		})
+		sortSlices(sorted)
 		if err := writeJSONLEntry(zw, "slices.jsonl", sorted, func(it any) ([]byte, error) {
 			return json.Marshal(it)
 		}); err != nil {
@@ -139,15 +210,7 @@ func writeCoreJson(zw *zip.Writer, art index.Artifacts) error {
 	if len(art.Pointers) > 0 {
 		sorted := make([]index.Pointer, len(art.Pointers))
 		copy(sorted, art.Pointers)
-		sort.Slice(sorted, func(i, j int) bool {
-			if sorted[i].ID == sorted[j].ID {
-				if sorted[i].Path == sorted[j].Path {
-					return sorted[i].Start < sorted[j].Start
-				}
-				return sorted[i].Path < sorted[j].Path
-			}
-			return sorted[i].ID < sorted[j].ID
-		})
+		sortPointers(sorted)
 		if err := writeJSONLEntry(zw, "pointers.jsonl", sorted, func(it any) ([]byte, error) {
 			return json.Marshal(it)
 		}); err != nil {
@@ -157,15 +220,57 @@ func writeCoreJson(zw *zip.Writer, art index.Artifacts) error {
 	return nil
 }
 
-func writeReadmeFull(zw *zip.Writer, opts ReadmeOptions) error {
+// sortSlices orders slices by path, then line range.
+func sortSlices(s []index.Slice) {
+	sort.Slice(s, func(i, j int) bool {
+		if s[i].Path == s[j].Path {
+			if s[i].Start == s[j].Start {
+				return s[i].End < s[j].End
+			}
+			return s[i].Start < s[j].Start
+		}
+		return s[i].Path < s[j].Path
+	})
+}
+
+// sortPointers orders pointers by ID, then path and start line.
+func sortPointers(p []index.Pointer) {
+	sort.Slice(p, func(i, j int) bool {
+		if p[i].ID == p[j].ID {
+			if p[i].Path == p[j].Path {
+				return p[i].Start < p[j].Start
+			}
+			return p[i].Path < p[j].Path
+		}
+		return p[i].ID < p[j].ID
+	})
+}
+
+// sortSymbolsByName returns a copy of s ordered by symbol name; equal names
+// keep their canonical position order.
+func sortSymbolsByName(s index.Symbols) index.Symbols {
+	out := s
+	out.Symbols = append([]index.Symbol(nil), s.Symbols...)
+	sort.SliceStable(out.Symbols, func(i, j int) bool { return out.Symbols[i].Symbol < out.Symbols[j].Symbol })
+	return out
+}
+
+func writeReadmeFull(zw Writer, opts ReadmeOptions) error {
 	readme := GenerateFullReadme(opts)
 	readme = textutil.EnsureTrailingLF(textutil.NormalizeUTF8LF(readme))
 	return ziputil.WriteText(zw, "README.md", readme)
 }
 
-func writeToc(zw *zip.Writer, man index.Manifest) error {
+func writeToc(zw Writer, man index.Manifest) error {
 	var b strings.Builder
-	b.WriteString("# TOC\n\n| # | Path | Lines |\n|---:|:-----|-----:|\n")
+	b.WriteString("# TOC\n\n")
+	for i, f := range man.Files {
+		if repoReadme != "" && f.Path == repoReadme {
+			fmt.Fprintf(&b, "> **Start here:** `%s` (row %d) is the repository's own README.\n\n", f.Path, i+1)
+			break
+		}
+	}
+	b.WriteString("| # | Path | Lines |\n|---:|:-----|-----:|\n")
 	for i, f := range man.Files {
 		b.WriteString("| ")
 		b.WriteString(strconv.Itoa(i + 1))
@@ -179,7 +284,19 @@ func writeToc(zw *zip.Writer, man index.Manifest) error {
 	return ziputil.WriteText(zw, "TOC.md", text)
 }
 
-func writeSourcesIfEnabled(zw *zip.Writer, files []struct{ RelPath, AbsPath string }, emit bool) error {
+// WriteSources writes a sources-only archive holding src/<path> for files,
+// with the same deterministic entry order and timestamps as WriteFull's src/,
+// in the current output format.
+func WriteSources(path string, files []struct{ RelPath, AbsPath string }) (err error) {
+	zw, err := createWriter(path)
+	if err != nil {
+		return err
+	}
+	defer closeWriter(zw, &err)
+	return writeSourcesIfEnabled(zw, files, true)
+}
+
+func writeSourcesIfEnabled(zw Writer, files []struct{ RelPath, AbsPath string }, emit bool) error {
 	if !emit || len(files) == 0 {
 		return nil
 	}
@@ -200,7 +317,7 @@ func writeSourcesIfEnabled(zw *zip.Writer, files []struct{ RelPath, AbsPath stri
 	return nil
 }
 
-func writeBenchIfPresent(zw *zip.Writer, benchPath string) error {
+func writeBenchIfPresent(zw Writer, benchPath string) error {
 	if strings.TrimSpace(benchPath) == "" {
 		return nil
 	}
@@ -211,7 +328,7 @@ func writeBenchIfPresent(zw *zip.Writer, benchPath string) error {
 	return ziputil.WriteFile(zw, "bench.txt", data)
 }
 
-func writeJSONLEntry(zw *zip.Writer, name string, items any, marshalEach func(it any) ([]byte, error)) error {
+func writeJSONLEntry(zw Writer, name string, items any, marshalEach func(it any) ([]byte, error)) error {
 	h := &zip.FileHeader{Name: ziputil.SanitizePath(name), Method: zip.Deflate}
 	h.SetMode(0o644)
 	h.Modified = ziputil.FixedZipTime
@@ -257,6 +374,8 @@ func presentLangsFromManifest(man index.Manifest) []string {
 			seen["py"] = struct{}{}
 		case ".cpp", ".cc", ".cxx", ".hpp", ".hh", ".h":
 			seen["cpp"] = struct{}{}
+		case ".m":
+			seen["objc"] = struct{}{}
 		}
 	}
 	for _, f := range man.Files {
@@ -15,14 +15,16 @@ type deltaChange = struct {
 }
 
 type deltaRename = struct {
-	From string `json:"from"`
-	To   string `json:"to"`
-	Hash string `json:"hash"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+	Hash       string `json:"hash"`
+	HashBefore string `json:"hashBefore,omitempty"`
 }
 
 var (
 	enableSimRename bool
 	simThresh       = 8
+	simPercent      int
 )
 
 // SetRenameSimilarity configures the optional similarity-based rename pass.
@@ -33,6 +35,20 @@ func SetRenameSimilarity(enable bool, thresh int) {
 	}
 }
 
+// SetRenameSimilarityPercent switches the similarity pass to a git-style
+// percentage (akin to git -M<n>%): a removed/added pair is reported as a rename
+// only when at least pct percent of lines are shared. Zero restores the
+// SimHash Hamming threshold configured via SetRenameSimilarity.
+func SetRenameSimilarityPercent(pct int) {
+	if pct < 0 {
+		pct = 0
+	}
+	if pct > 100 {
+		pct = 100
+	}
+	simPercent = pct
+}
+
 // BuildDelta computes the change set between two snapshots.
 func BuildDelta(prev *Snapshot, curr *Snapshot) Delta {
 	if delta, ok := handleTrivialDelta(prev, curr); ok {
@@ -203,11 +219,9 @@ func applySimilarityRenames(d *Delta) {
 	if len(pairs) == 0 {
 		return
 	}
-	scored := scoreRenameCandidates(d, pairs, prov)
-	if len(scored) == 0 {
-		return
-	}
+	scored, report := scoreRenameCandidates(d, pairs, prov)
 	renames, usedRemoved, usedAdded := pickScoredRenames(d, scored)
+	d.RenameReport = decideRenameReport(report, renames)
 	if len(renames) == 0 {
 		return
 	}
@@ -239,17 +253,38 @@ func collectRenameCandidates(d *Delta) []renameCandidate {
 	return pairs
 }
 
-func scoreRenameCandidates(d *Delta, pairs []renameCandidate, prov ContentProvider) []scoredRename {
+// scoreRenameCandidates returns the pairs within the threshold, best first,
+// plus a report entry for every pair (decisions other than unreadable and
+// over-threshold are settled by decideRenameReport).
+func scoreRenameCandidates(d *Delta, pairs []renameCandidate, prov ContentProvider) ([]scoredRename, []RenameCandidate) {
+	if simPercent > 0 {
+		scored, report := scoreByLineOverlap(d, pairs, prov)
+		return sortScored(d, scored), report
+	}
 	remCache := make(map[int]hashEntry)
 	addCache := make(map[int]hashEntry)
 	scored := make([]scoredRename, 0, len(pairs))
+	report := make([]RenameCandidate, 0, len(pairs))
 	for _, pair := range pairs {
+		rc := RenameCandidate{
+			From:      d.Removed[pair.removedIdx].Path,
+			To:        d.Added[pair.addedIdx].Path,
+			Metric:    "simhash",
+			Threshold: simThresh,
+		}
 		ha, oka := loadSimHash(pair.removedIdx, d.Removed, true, prov, remCache)
 		hb, okb := loadSimHash(pair.addedIdx, d.Added, false, prov, addCache)
 		if !oka || !okb {
+			rc.Decision = RenameUnreadable
+			report = append(report, rc)
 			continue
 		}
 		dist := hamming64(ha, hb)
+		rc.Score = dist
+		if dist > simThresh {
+			rc.Decision = RenameOverThreshold
+		}
+		report = append(report, rc)
 		if dist <= simThresh {
 			scored = append(scored, scoredRename{
 				removedIdx: pair.removedIdx,
@@ -259,6 +294,76 @@ func scoreRenameCandidates(d *Delta, pairs []renameCandidate, prov ContentProvid
 			})
 		}
 	}
+	return sortScored(d, scored), report
+}
+
+// scoreByLineOverlap scores pairs by the share of normalized lines they have in
+// common (multiset overlap over the larger file). The score is 100-similarity
+// so that lower remains better, matching the SimHash distance ordering.
+func scoreByLineOverlap(d *Delta, pairs []renameCandidate, prov ContentProvider) ([]scoredRename, []RenameCandidate) {
+	remCache := make(map[int]linesEntry)
+	addCache := make(map[int]linesEntry)
+	scored := make([]scoredRename, 0, len(pairs))
+	report := make([]RenameCandidate, 0, len(pairs))
+	for _, pair := range pairs {
+		rc := RenameCandidate{
+			From:      d.Removed[pair.removedIdx].Path,
+			To:        d.Added[pair.addedIdx].Path,
+			Metric:    "lines",
+			Threshold: simPercent,
+		}
+		la, oka := loadSimLines(pair.removedIdx, d.Removed, true, prov, remCache)
+		lb, okb := loadSimLines(pair.addedIdx, d.Added, false, prov, addCache)
+		if !oka || !okb {
+			rc.Decision = RenameUnreadable
+			report = append(report, rc)
+			continue
+		}
+		sim := lineSimilarity(la, lb)
+		rc.Score = sim
+		if sim < simPercent {
+			rc.Decision = RenameOverThreshold
+		}
+		report = append(report, rc)
+		if sim >= simPercent {
+			scored = append(scored, scoredRename{
+				removedIdx: pair.removedIdx,
+				addedIdx:   pair.addedIdx,
+				score:      100 - sim,
+				toPath:     d.Added[pair.addedIdx].Path,
+			})
+		}
+	}
+	return scored, report
+}
+
+// decideRenameReport marks the picked renames as accepted and the remaining
+// within-threshold pairs as paired elsewhere, sorted by (from, to).
+func decideRenameReport(report []RenameCandidate, renames []deltaRename) []RenameCandidate {
+	picked := make(map[[2]string]bool, len(renames))
+	for _, r := range renames {
+		picked[[2]string{r.From, r.To}] = true
+	}
+	for i := range report {
+		if report[i].Decision != "" {
+			continue
+		}
+		if picked[[2]string{report[i].From, report[i].To}] {
+			report[i].Decision = RenameAccepted
+		} else {
+			report[i].Decision = RenamePairedElsewhere
+		}
+	}
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].From != report[j].From {
+			return report[i].From < report[j].From
+		}
+		return report[i].To < report[j].To
+	})
+	return report
+}
+
+func sortScored(d *Delta, scored []scoredRename) []scoredRename {
 	sort.Slice(scored, func(i, j int) bool {
 		if scored[i].toPath != scored[j].toPath {
 			return scored[i].toPath < scored[j].toPath
@@ -285,6 +390,49 @@ func loadSimHash(idx int, files []SnapFile, old bool, prov ContentProvider, cach
 	return hash, true
 }
 
+type linesEntry struct {
+	lines []string
+	ok    bool
+}
+
+func loadSimLines(idx int, files []SnapFile, old bool, prov ContentProvider, cache map[int]linesEntry) ([]string, bool) {
+	if entry, ok := cache[idx]; ok {
+		return entry.lines, entry.ok
+	}
+	data, err := prov.Read(files[idx].Path, old)
+	if err != nil {
+		cache[idx] = linesEntry{ok: false}
+		return nil, false
+	}
+	lines := normalizeForSim(string(data))
+	cache[idx] = linesEntry{lines: lines, ok: true}
+	return lines, true
+}
+
+// lineSimilarity returns the percentage (0..100) of lines shared by a and b,
+// counted as a multiset intersection relative to the longer side.
+func lineSimilarity(a, b []string) int {
+	longest := len(a)
+	if len(b) > longest {
+		longest = len(b)
+	}
+	if longest == 0 {
+		return 100
+	}
+	counts := make(map[string]int, len(a))
+	for _, ln := range a {
+		counts[ln]++
+	}
+	common := 0
+	for _, ln := range b {
+		if counts[ln] > 0 {
+			counts[ln]--
+			common++
+		}
+	}
+	return common * 100 / longest
+}
+
 func pickScoredRenames(d *Delta, scored []scoredRename) ([]deltaRename, map[int]bool, map[int]bool) {
 	usedRemoved := make(map[int]bool)
 	usedAdded := make(map[int]bool)
@@ -296,9 +444,10 @@ func pickScoredRenames(d *Delta, scored []scoredRename) ([]deltaRename, map[int]
 		usedRemoved[s.removedIdx] = true
 		usedAdded[s.addedIdx] = true
 		renames = append(renames, deltaRename{
-			From: d.Removed[s.removedIdx].Path,
-			To:   d.Added[s.addedIdx].Path,
-			Hash: d.Added[s.addedIdx].Hash,
+			From:       d.Removed[s.removedIdx].Path,
+			To:         d.Added[s.addedIdx].Path,
+			Hash:       d.Added[s.addedIdx].Hash,
+			HashBefore: d.Removed[s.removedIdx].Hash,
 		})
 	}
 	return renames, usedRemoved, usedAdded
@@ -400,3 +549,48 @@ func fnv64(s string) uint64 {
 	}
 	return h
 }
+
+// FilterDelta keeps only the entries whose path satisfies keep and returns the
+// filtered delta together with the number of entries that were dropped.
+// Renamed entries are kept when either side matches. A nil keep returns d as-is.
+func FilterDelta(d Delta, keep func(path string) bool) (Delta, int) {
+	if keep == nil {
+		return d, 0
+	}
+	var out Delta
+	dropped := 0
+	for _, f := range d.Added {
+		if keep(f.Path) {
+			out.Added = append(out.Added, f)
+		} else {
+			dropped++
+		}
+	}
+	for _, f := range d.Removed {
+		if keep(f.Path) {
+			out.Removed = append(out.Removed, f)
+		} else {
+			dropped++
+		}
+	}
+	for _, r := range d.Renamed {
+		if keep(r.From) || keep(r.To) {
+			out.Renamed = append(out.Renamed, r)
+		} else {
+			dropped++
+		}
+	}
+	for _, c := range d.Changed {
+		if keep(c.Path) {
+			out.Changed = append(out.Changed, c)
+		} else {
+			dropped++
+		}
+	}
+	for _, rc := range d.RenameReport {
+		if keep(rc.From) || keep(rc.To) {
+			out.RenameReport = append(out.RenameReport, rc)
+		}
+	}
+	return out, dropped
+}
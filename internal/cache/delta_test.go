@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type mapProvider struct {
+	old map[string]string
+	new map[string]string
+}
+
+func (m mapProvider) Read(path string, old bool) ([]byte, error) {
+	src := m.new
+	if old {
+		src = m.old
+	}
+	if s, ok := src[path]; ok {
+		return []byte(s), nil
+	}
+	return nil, errors.New("not found")
+}
+
+func numberedLines(prefix string, n int) []string {
+	out := make([]string, n)
+	for i := range out {
+		out[i] = fmt.Sprintf("%s line %d", prefix, i)
+	}
+	return out
+}
+
+func TestBuildDeltaRenameSimilarityPercent(t *testing.T) {
+	shared := numberedLines("shared", 4)
+	oldBody := strings.Join(append(append([]string{}, shared...), numberedLines("old", 6)...), "\n")
+	newBody := strings.Join(append(append([]string{}, shared...), numberedLines("new", 6)...), "\n")
+
+	SetContentProvider(mapProvider{
+		old: map[string]string{"a.txt": oldBody},
+		new: map[string]string{"b.txt": newBody},
+	})
+	t.Cleanup(func() {
+		SetContentProvider(nil)
+		SetRenameSimilarity(false, 8)
+		SetRenameSimilarityPercent(0)
+	})
+
+	prev := &Snapshot{Files: []SnapFile{{Path: "a.txt", Hash: "aa", Lines: 10}, {Path: "keep.txt", Hash: "kk", Lines: 1}}}
+	curr := &Snapshot{Files: []SnapFile{{Path: "b.txt", Hash: "bb", Lines: 10}, {Path: "keep.txt", Hash: "kk", Lines: 1}}}
+
+	SetRenameSimilarity(true, 8)
+	SetRenameSimilarityPercent(60)
+	d := BuildDelta(prev, curr)
+	if len(d.Renamed) != 0 || len(d.Added) != 1 || len(d.Removed) != 1 {
+		t.Fatalf("40%% similar pair should stay add/remove at 60%%: %+v", d)
+	}
+
+	SetRenameSimilarityPercent(30)
+	d = BuildDelta(prev, curr)
+	if len(d.Renamed) != 1 || len(d.Added) != 0 || len(d.Removed) != 0 {
+		t.Fatalf("40%% similar pair should be a rename at 30%%: %+v", d)
+	}
+	if d.Renamed[0].From != "a.txt" || d.Renamed[0].To != "b.txt" {
+		t.Fatalf("unexpected rename: %+v", d.Renamed[0])
+	}
+}
+
+func TestLineSimilarity(t *testing.T) {
+	if got := lineSimilarity([]string{"a", "b"}, []string{"a", "b"}); got != 100 {
+		t.Fatalf("identical = %d", got)
+	}
+	if got := lineSimilarity([]string{"a", "a", "b", "c"}, []string{"a", "x"}); got != 25 {
+		t.Fatalf("partial = %d", got)
+	}
+}
+
+func TestBuildDeltaRenameReport(t *testing.T) {
+	body := strings.Join(numberedLines("body", 20), "\n")
+	SetContentProvider(mapProvider{
+		old: map[string]string{"a.txt": body, "gone.txt": strings.Join(numberedLines("other", 20), "\n")},
+		new: map[string]string{"b.txt": body + "\nextra line"},
+	})
+	t.Cleanup(func() {
+		SetContentProvider(nil)
+		SetRenameSimilarity(false, 8)
+	})
+	SetRenameSimilarity(true, 8)
+
+	prev := &Snapshot{Files: []SnapFile{{Path: "a.txt", Hash: "aa", Lines: 20}, {Path: "gone.txt", Hash: "gg", Lines: 20}}}
+	curr := &Snapshot{Files: []SnapFile{{Path: "b.txt", Hash: "bb", Lines: 21}}}
+	d := BuildDelta(prev, curr)
+
+	want := []RenameCandidate{
+		{From: "a.txt", To: "b.txt", Metric: "simhash", Score: 6, Threshold: 8, Decision: RenameAccepted},
+		{From: "gone.txt", To: "b.txt", Metric: "simhash", Score: 22, Threshold: 8, Decision: RenameOverThreshold},
+	}
+	if !reflect.DeepEqual(d.RenameReport, want) {
+		t.Fatalf("rename report = %+v, want %+v", d.RenameReport, want)
+	}
+	if len(d.Renamed) != 1 || d.Renamed[0].From != "a.txt" {
+		t.Fatalf("unexpected renames: %+v", d.Renamed)
+	}
+}
@@ -30,19 +30,22 @@ type Snapshot struct {
 //   - Added: files present now that were not in the previous snapshot
 //   - Removed: files present previously that are no longer in the current snapshot
 //   - Changed: files whose path is the same but content hash differs
-//   - Renamed: files moved from one path to another without content change
+//   - Renamed: files moved from one path to another, possibly with changes
 //
 // Notes:
-//   - Renamed entries are one-to-one pairings (From → To) for the same content hash.
+//   - Renamed entries are one-to-one pairings (From → To) for the same content hash,
+//     or, from the similarity pass, for similar content; those carry the From
+//     hash as HashBefore and get a delta.patch section like a change.
 //   - Changed entries carry DiffPath (location inside a delta zip) and Oversize flag
 //     indicating whether the textual diff was omitted due to size limits.
 type Delta struct {
 	Added   []SnapFile `json:"added"`
 	Removed []SnapFile `json:"removed"`
 	Renamed []struct {
-		From string `json:"from"`
-		To   string `json:"to"`
-		Hash string `json:"hash"`
+		From       string `json:"from"`
+		To         string `json:"to"`
+		Hash       string `json:"hash"`
+		HashBefore string `json:"hashBefore,omitempty"`
 	} `json:"renamed"`
 	Changed []struct {
 		Path       string `json:"path"`
@@ -51,4 +54,29 @@ type Delta struct {
 		DiffPath   string `json:"diff"`
 		Oversize   bool   `json:"oversize"`
 	} `json:"changed"`
+
+	// RenameReport lists every pair scored by the similarity rename pass
+	// with its decision; empty unless that pass ran. Not serialized.
+	RenameReport []RenameCandidate `json:"-"`
+}
+
+// Rename report decisions.
+const (
+	RenameAccepted        = "accepted"         // reported as a rename
+	RenameOverThreshold   = "over-threshold"   // score outside the configured threshold
+	RenamePairedElsewhere = "paired-elsewhere" // within threshold, but a better pair won
+	RenameUnreadable      = "unreadable"       // content of either side unavailable
+)
+
+// RenameCandidate is one removed/added pair considered by the similarity
+// rename pass. With Metric "simhash", Score is the SimHash Hamming distance
+// (lower is closer) and Threshold the max distance; with "lines", Score is the
+// percent of shared lines and Threshold the minimum percent.
+type RenameCandidate struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Metric    string `json:"metric"`
+	Score     int    `json:"score"`
+	Threshold int    `json:"threshold"`
+	Decision  string `json:"decision"`
 }
@@ -0,0 +1,169 @@
+package diff
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"class-collector/internal/textutil"
+)
+
+// ErrOmitted is returned by Apply for the placeholder patch of a diff that
+// was omitted (see Omitted).
+var ErrOmitted = errors.New("diff omitted")
+
+// FilePatch is the part of a multi-file patch for one file.
+type FilePatch struct {
+	OldName string // "---" name, "/dev/null" for added files
+	NewName string // "+++" name
+	Body    string // the whole section, headers included
+}
+
+// reHunkRange matches "@@ -a[,b] +c[,d] @@" and captures all four numbers.
+var reHunkRange = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// SplitFiles cuts a concatenated patch (delta.patch) into its per-file
+// sections, each starting at a "--- " line directly followed by "+++ ".
+// Text before the first section is ignored.
+func SplitFiles(patch string) []FilePatch {
+	lines := strings.SplitAfter(patch, "\n")
+	var out []FilePatch
+	start := -1
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		out = append(out, FilePatch{
+			OldName: headerName(lines[start], "--- "),
+			NewName: headerName(lines[start+1], "+++ "),
+			Body:    strings.Join(lines[start:end], ""),
+		})
+	}
+	for i := 0; i+1 < len(lines); i++ {
+		if strings.HasPrefix(lines[i], "--- ") && strings.HasPrefix(lines[i+1], "+++ ") {
+			flush(i)
+			start = i
+			i++
+		}
+	}
+	flush(len(lines))
+	return out
+}
+
+// headerName returns the file name of a ---/+++ line, without a trailing
+// tab-separated timestamp.
+func headerName(line, prefix string) string {
+	name := strings.TrimRight(strings.TrimPrefix(line, prefix), "\r\n")
+	if i := strings.IndexByte(name, '\t'); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// Apply applies the unified patch of one file to old and returns the new
+// content. Both sides are compared after the UTF-8/LF normalization bundles
+// write patches with, and context and removed lines must then match old
+// exactly; a mismatch, a malformed hunk or overlapping hunks are errors, and
+// an omitted-diff placeholder yields ErrOmitted. The result keeps old's line endings when old uses
+// CRLF throughout, and its final newline unless a hunk reaching the end of
+// the file says otherwise ("\ No newline at end of file").
+func Apply(old []byte, patch string) ([]byte, error) {
+	crlf := bytes.Count(old, []byte("\r\n")) > 0 && bytes.Count(old, []byte("\r\n")) == bytes.Count(old, []byte("\n"))
+	text := string(textutil.NormalizeUTF8LF(old))
+	var src []string
+	if text != "" {
+		src = strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	}
+	finalNL := text == "" || strings.HasSuffix(text, "\n")
+
+	lines := strings.Split(string(textutil.NormalizeUTF8LF([]byte(patch))), "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	var out []string
+	pos := 0 // next unconsumed line of src
+	hunks := 0
+	for i := 0; i < len(lines); {
+		if strings.HasPrefix(lines[i], "# diff omitted") { // see IsOmitted
+			return nil, ErrOmitted
+		}
+		m := reHunkRange.FindStringSubmatch(lines[i])
+		if m == nil {
+			i++
+			continue
+		}
+		hunks++
+		oldStart, oldCount := hunkNum(m[1], "1"), hunkNum(m[2], "1")
+		newCount := hunkNum(m[4], "1")
+		at := oldStart - 1
+		if oldCount == 0 {
+			at = oldStart
+		}
+		if at < pos || at > len(src) {
+			return nil, fmt.Errorf("hunk %d: starts at line %d, outside the remaining file", hunks, oldStart)
+		}
+		out = append(out, src[pos:at]...)
+		pos = at
+		i++
+		oldSeen, newSeen, newEndNoNL := 0, 0, false
+		for i < len(lines) && (oldSeen < oldCount || newSeen < newCount || strings.HasPrefix(lines[i], "\\")) {
+			ln := lines[i]
+			i++
+			if ln == "" {
+				ln = " " // a blank context line whose space was trimmed
+			}
+			body := ln[1:]
+			switch ln[0] {
+			case ' ', '-':
+				if pos >= len(src) || src[pos] != body {
+					return nil, fmt.Errorf("hunk %d: line %d does not match the patch", hunks, pos+1)
+				}
+				pos++
+				oldSeen++
+				if ln[0] == ' ' {
+					out = append(out, body)
+					newSeen++
+				}
+				newEndNoNL = false
+			case '+':
+				out = append(out, body)
+				newSeen++
+				newEndNoNL = false
+			case '\\':
+				prev := lines[i-2]
+				newEndNoNL = prev == "" || prev[0] != '-'
+			default:
+				return nil, fmt.Errorf("hunk %d: unexpected line %q", hunks, ln)
+			}
+		}
+		if oldSeen != oldCount || newSeen != newCount {
+			return nil, fmt.Errorf("hunk %d: expected -%d/+%d lines, found -%d/+%d", hunks, oldCount, newCount, oldSeen, newSeen)
+		}
+		if pos == len(src) {
+			finalNL = !newEndNoNL
+		}
+	}
+	out = append(out, src[pos:]...)
+	if len(out) == 0 {
+		return []byte{}, nil
+	}
+	result := strings.Join(out, "\n")
+	if finalNL {
+		result += "\n"
+	}
+	if crlf {
+		result = strings.ReplaceAll(result, "\n", "\r\n")
+	}
+	return []byte(result), nil
+}
+
+func hunkNum(s, def string) int {
+	if s == "" {
+		s = def
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
@@ -0,0 +1,47 @@
+package diff
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestApplyReproducesUnified(t *testing.T) {
+	cases := []struct{ name, a, b string }{
+		{"edit", "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n", "a\nB\nc\nd\ne\nf\ng\nh\ni\nJ\n"},
+		{"append", "a\nb\n", "a\nb\nc\n"},
+		{"delete all", "a\nb\n", ""},
+		{"no final newline", "a\nb", "a\nc"},
+		{"add final newline", "a\nb", "a\nb\n"},
+		{"drop final newline", "a\nb\n", "a\nb"},
+		{"crlf", "a\r\nb\r\nc\r\n", "a\r\nx\r\nc\r\n"},
+	}
+	for _, tc := range cases {
+		patch, _ := Unified("f", "f", []byte(tc.a), []byte(tc.b), Options{Context: 1})
+		got, err := Apply([]byte(tc.a), patch)
+		if err != nil {
+			t.Fatalf("%s: Apply error: %v\n%s", tc.name, err, patch)
+		}
+		if string(got) != tc.b {
+			t.Fatalf("%s: Apply = %q, want %q\n%s", tc.name, got, tc.b, patch)
+		}
+	}
+
+	added, _ := Added("f", []byte("x\ny"), Options{})
+	if got, err := Apply(nil, added); err != nil || string(got) != "x\ny" {
+		t.Fatalf("Apply(added) = %q, %v", got, err)
+	}
+}
+
+func TestApplyRejectsMismatchAndOmitted(t *testing.T) {
+	patch, _ := Unified("f", "f", []byte("a\nb\n"), []byte("a\nc\n"), Options{})
+	if _, err := Apply([]byte("a\nz\n"), patch); err == nil {
+		t.Fatalf("expected a mismatch error")
+	}
+	if _, err := Apply([]byte("a\n"), Omitted("f", "f")); !errors.Is(err, ErrOmitted) {
+		t.Fatalf("Apply(omitted) error = %v, want ErrOmitted", err)
+	}
+	files := SplitFiles(patch + Omitted("/dev/null", "g"))
+	if len(files) != 2 || files[0].NewName != "f" || files[1].OldName != "/dev/null" || files[1].NewName != "g" {
+		t.Fatalf("SplitFiles = %+v", files)
+	}
+}
@@ -5,6 +5,8 @@ package diff
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -31,8 +33,20 @@ type Options struct {
 
 	// LineMode kept for backward compatibility (unified output is line-based).
 	LineMode bool
+
+	// FuncOnly drops all context lines (overriding Context). Callers pair it
+	// with AnnotateHunks so each @@ header names the enclosing symbol.
+	FuncOnly bool
+
+	// MaxTotalBytes caps the summed size of all diffs of one delta (enforced
+	// by the caller across files: changed files in path order, then the
+	// patches of added files). 0 means "no limit".
+	MaxTotalBytes int
 }
 
+// reHunkHeader matches "@@ -a[,b] +c[,d] @@" and captures the new start line.
+var reHunkHeader = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+
 // Unified produces a classic unified patch for a↦b.
 // Returns the patch body and a flag indicating it was omitted due to size.
 func Unified(aName, bName string, a, b []byte, opt Options) (body string, oversize bool) {
@@ -41,10 +55,7 @@ func Unified(aName, bName string, a, b []byte, opt Options) (body string, oversi
 		return omitted(aName, bName), true
 	}
 
-	ctx := opt.Context
-	if ctx <= 0 {
-		ctx = 4
-	}
+	ctx := contextLines(opt)
 
 	ua := splitLinesKeepNL(string(a))
 	ub := splitLinesKeepNL(string(b))
@@ -65,18 +76,19 @@ func Unified(aName, bName string, a, b []byte, opt Options) (body string, oversi
 }
 
 // Added produces a patch that adds the entire content b (no old version).
+// An empty b yields a header-only "/dev/null → bName" patch with no hunks.
 func Added(bName string, b []byte, opt Options) (string, bool) {
 	if opt.MaxBytes > 0 && len(b) > opt.MaxBytes {
 		return omitted("/dev/null", bName), true
 	}
-	ctx := opt.Context
-	if ctx <= 0 {
-		ctx = 4
-	}
+	ctx := contextLines(opt)
 	// Ensure no "b/" prefix in ToFile per policy.
 	if strings.HasPrefix(bName, "b/") {
 		bName = bName[2:]
 	}
+	if len(b) == 0 {
+		return header("/dev/null", bName), false
+	}
 	u := difflib.UnifiedDiff{
 		A:        []string{},                  // empty "from"
 		B:        splitLinesKeepNL(string(b)), // new content
@@ -91,24 +103,81 @@ func Added(bName string, b []byte, opt Options) (string, bool) {
 	return s, false
 }
 
+// AnnotateHunks appends the name returned by enclosing for each hunk's first
+// new-file line to its @@ header (git's "function context" position). Hunks
+// for which enclosing returns "" are left unchanged.
+func AnnotateHunks(body string, enclosing func(line int) string) string {
+	if enclosing == nil {
+		return body
+	}
+	lines := strings.SplitAfter(body, "\n")
+	for i, ln := range lines {
+		m := reHunkHeader.FindStringSubmatch(ln)
+		if m == nil {
+			continue
+		}
+		start, _ := strconv.Atoi(m[1])
+		if start < 1 {
+			start = 1
+		}
+		name := enclosing(start)
+		if name == "" {
+			continue
+		}
+		nl := ""
+		if strings.HasSuffix(ln, "\n") {
+			nl = "\n"
+		}
+		lines[i] = m[0] + " " + name + nl
+	}
+	return strings.Join(lines, "")
+}
+
+func contextLines(opt Options) int {
+	if opt.FuncOnly {
+		return 0
+	}
+	if opt.Context <= 0 {
+		return 4
+	}
+	return opt.Context
+}
+
+// noNewlineMarker follows a last line that has no trailing newline, as in
+// git and GNU diff output.
+const noNewlineMarker = "\\ No newline at end of file\n"
+
 // splitLinesKeepNL splits into lines and keeps newline characters,
-// which produces better unified hunks.
+// which produces better unified hunks. A last line without "\n" gets one
+// plus noNewlineMarker, so the hunk stays line-structured and a change of
+// only the final newline still shows up as a difference.
 func splitLinesKeepNL(s string) []string {
 	if s == "" {
 		return []string{}
 	}
 	// SplitAfter keeps the "\n" at the end of each element.
 	lines := strings.SplitAfter(s, "\n")
-	// If file does not end with a newline, SplitAfter keeps the last chunk
-	// without "\n" — this is fine for unified output.
+	if last := len(lines) - 1; lines[last] == "" {
+		lines = lines[:last]
+	} else {
+		lines[last] += "\n" + noNewlineMarker
+	}
 	return lines
 }
 
-// header kept for compatibility with earlier code paths (not used by difflib).
+// header renders the ---/+++ lines; used alone for empty added files.
 func header(aName, bName string) string {
 	return fmt.Sprintf("--- %s\n+++ %s\n", aName, bName)
 }
 
+// Omitted returns the placeholder patch used when a diff cannot be produced.
+func Omitted(aName, bName string) string { return omitted(aName, bName) }
+
+// IsOmitted reports whether patch is an Omitted placeholder.
+func IsOmitted(patch string) bool {
+	return strings.Contains(patch, "\n# diff omitted")
+}
+
 // omitted returns a compact placeholder when size limits are exceeded.
 func omitted(aName, bName string) string {
 	_ = time.Second // keep import stability if Options uses TimeoutSeconds elsewhere
@@ -0,0 +1,31 @@
+package diff
+
+import "testing"
+
+func TestUnifiedMarksMissingFinalNewline(t *testing.T) {
+	cases := []struct{ name, a, b, want string }{
+		{
+			"both sides", "a\nb", "a\nc",
+			"--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n\\ No newline at end of file\n",
+		},
+		{
+			"newline dropped", "a\nb\n", "a\nb",
+			"--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n a\n-b\n+b\n\\ No newline at end of file\n",
+		},
+		{
+			"newline added", "a\nb", "a\nb\n",
+			"--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+	}
+	for _, tc := range cases {
+		got, _ := Unified("a/f", "b/f", []byte(tc.a), []byte(tc.b), Options{Context: 1})
+		if got != tc.want {
+			t.Errorf("%s: Unified = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+
+	added, _ := Added("b/f", []byte("x\ny"), Options{})
+	if want := "--- /dev/null\n+++ f\n@@ -0,0 +1,2 @@\n+x\n+y\n\\ No newline at end of file\n"; added != want {
+		t.Errorf("Added = %q, want %q", added, want)
+	}
+}
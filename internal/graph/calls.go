@@ -0,0 +1,245 @@
+package graph
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"class-collector/internal/parallel"
+)
+
+// Def is a callable symbol for BuildCalls: its qualified name as in
+// symbols.json and its line range in a project file.
+type Def struct {
+	Symbol string
+	Path   string
+	Start  int
+	End    int
+}
+
+// callKeywords look like calls ("if (x)") but never are.
+var callKeywords = map[string]bool{
+	"if": true, "for": true, "while": true, "switch": true, "catch": true,
+	"return": true, "func": true, "function": true, "fun": true, "new": true,
+	"typeof": true, "sizeof": true, "super": true, "this": true, "when": true,
+	"synchronized": true, "try": true, "else": true, "do": true, "throw": true,
+}
+
+// CallNode returns the calls label of a symbol defined in relPath:
+// "go:", "java:" and "kt:" plus the qualified name, and for TS/JS (whose
+// symbols carry no module) the file's JSNode plus "#" and the name. Other
+// languages have no label.
+func CallNode(relPath, symbol string) string {
+	switch strings.ToLower(filepath.Ext(relPath)) {
+	case ".go":
+		return "go:" + symbol
+	case ".java":
+		return "java:" + symbol
+	case ".kt":
+		return "kt:" + symbol
+	case ".ts", ".tsx", ".js", ".jsx", ".mjs", ".cjs":
+		return JSNode(relPath) + "#" + symbol
+	}
+	return ""
+}
+
+// BuildCalls links callable symbols to the ones they call, as sorted,
+// deduplicated [caller, callee] CallNode pairs. Each def's line range is
+// scanned for "name(" and "qualifier.name(" outside comments and string
+// literals, and a name resolves to a def only when that is unambiguous: a
+// qualifier must match the callee's enclosing type or package
+// ("pkg.Func", "Type.method"); otherwise the first of the caller's own file,
+// its directory and the whole project holding exactly one def of that name
+// wins. Self-calls and unreadable files are skipped.
+func BuildCalls(files []File, defs []Def) [][2]string {
+	abs := make(map[string]string, len(files))
+	for _, f := range files {
+		abs[filepath.ToSlash(f.RelPath)] = f.AbsPath
+	}
+
+	type target struct {
+		node, path, qual string
+	}
+	byName := map[string][]target{}
+	byPath := map[string][]Def{}
+	for _, d := range defs {
+		node := CallNode(d.Path, d.Symbol)
+		if node == "" {
+			continue
+		}
+		name, qual := d.Symbol, ""
+		if i := strings.LastIndexByte(name, '.'); i >= 0 {
+			name, qual = name[i+1:], name[:i]
+			if j := strings.LastIndexByte(qual, '.'); j >= 0 {
+				qual = qual[j+1:]
+			}
+		}
+		byName[name] = append(byName[name], target{node: node, path: d.Path, qual: qual})
+		byPath[d.Path] = append(byPath[d.Path], d)
+	}
+
+	// unique returns the single distinct node among ts matching keep.
+	unique := func(ts []target, keep func(target) bool) (string, bool) {
+		found := ""
+		for _, t := range ts {
+			if !keep(t) {
+				continue
+			}
+			if found != "" && found != t.node {
+				return "", false
+			}
+			found = t.node
+		}
+		return found, found != ""
+	}
+
+	paths := make([]string, 0, len(byPath))
+	for p := range byPath {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	// Files are scanned on up to parallel.Limit() workers, each into its own
+	// slot; the slots are merged below.
+	found := make([][][2]string, len(paths))
+	parallel.For(len(paths), func(i int) {
+		p := paths[i]
+		a, ok := abs[p]
+		if !ok {
+			return
+		}
+		data, err := os.ReadFile(a)
+		if err != nil {
+			return
+		}
+		lines := strings.Split(string(data), "\n")
+		sites := callSites(lines)
+		for _, d := range byPath[p] {
+			caller := CallNode(d.Path, d.Symbol)
+			for _, s := range sites {
+				if s.line < d.Start || s.line > d.End {
+					continue
+				}
+				ts := byName[s.name]
+				var callee string
+				if s.qual != "" {
+					callee, ok = unique(ts, func(t target) bool { return t.qual == s.qual })
+				} else {
+					ok = false
+				}
+				if !ok {
+					callee, ok = unique(ts, func(t target) bool { return t.path == p })
+				}
+				if !ok {
+					callee, ok = unique(ts, func(t target) bool { return path.Dir(t.path) == path.Dir(p) })
+				}
+				if !ok && s.qual == "" {
+					callee, ok = unique(ts, func(target) bool { return true })
+				}
+				if ok && callee != caller {
+					found[i] = append(found[i], [2]string{caller, callee})
+				}
+			}
+		}
+	})
+
+	edgeSet := map[[2]string]struct{}{}
+	for _, es := range found {
+		for _, e := range es {
+			edgeSet[e] = struct{}{}
+		}
+	}
+	edges := make([][2]string, 0, len(edgeSet))
+	for e := range edgeSet {
+		edges = append(edges, e)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i][0] == edges[j][0] {
+			return edges[i][1] < edges[j][1]
+		}
+		return edges[i][0] < edges[j][0]
+	})
+	return edges
+}
+
+// callSite is one "name(" occurrence; qual is the identifier before a
+// preceding '.', if any.
+type callSite struct {
+	line       int
+	name, qual string
+}
+
+// callSites scans lines for call-like identifiers, skipping // and /* */
+// comments and quoted literals (a literal or comment never spans lines
+// here except /* */, which is tracked across them).
+func callSites(lines []string) []callSite {
+	var out []callSite
+	inBlock := false
+	for ln, text := range lines {
+		for i := 0; i < len(text); {
+			c := text[i]
+			switch {
+			case inBlock:
+				if end := strings.Index(text[i:], "*/"); end >= 0 {
+					i += end + 2
+					inBlock = false
+				} else {
+					i = len(text)
+				}
+			case c == '/' && i+1 < len(text) && text[i+1] == '/':
+				i = len(text)
+			case c == '/' && i+1 < len(text) && text[i+1] == '*':
+				inBlock = true
+				i += 2
+			case c == '"' || c == '\'' || c == '`':
+				i++
+				for i < len(text) && text[i] != c {
+					if text[i] == '\\' {
+						i++
+					}
+					i++
+				}
+				i++
+			case isCallIdentStart(c):
+				start := i
+				for i < len(text) && isCallIdentPart(text[i]) {
+					i++
+				}
+				name := text[start:i]
+				j := i
+				for j < len(text) && (text[j] == ' ' || text[j] == '\t') {
+					j++
+				}
+				if j < len(text) && text[j] == '(' && !callKeywords[name] {
+					out = append(out, callSite{line: ln + 1, name: name, qual: qualifierBefore(text, start)})
+				}
+			default:
+				i++
+			}
+		}
+	}
+	return out
+}
+
+// qualifierBefore returns the identifier ending just before ".name" at
+// start, or "".
+func qualifierBefore(text string, start int) string {
+	if start == 0 || text[start-1] != '.' {
+		return ""
+	}
+	end := start - 1
+	i := end
+	for i > 0 && isCallIdentPart(text[i-1]) {
+		i--
+	}
+	return text[i:end]
+}
+
+func isCallIdentStart(c byte) bool {
+	return c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isCallIdentPart(c byte) bool {
+	return isCallIdentStart(c) || c >= '0' && c <= '9'
+}
@@ -0,0 +1,81 @@
+package graph
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBuildCallsResolvesUnambiguousCallees(t *testing.T) {
+	dir := t.TempDir()
+	sources := map[string]string{
+		"server/server.go": "package server\n" +
+			"\n" +
+			"func (s *Server) Start() error {\n" + // 3
+			"\ts.listen() // Open() in a comment\n" +
+			"\tlog(\"Close() in a string\")\n" +
+			"\treturn store.Open()\n" +
+			"}\n" + // 7
+			"\n" +
+			"func (s *Server) listen() {}\n" + // 9
+			"\n" +
+			"func log(msg string) { Close() }\n", // 11
+		"store/store.go": "package store\n" +
+			"\n" +
+			"func Open() error { return nil }\n" + // 3
+			"\n" +
+			"func Close() {}\n", // 5
+		"other/close.go": "package other\n" +
+			"\n" +
+			"func Close() {}\n", // 3
+		"web/app.ts": "export function boot() {\n" +
+			"  render()\n" +
+			"}\n",
+		"web/view.ts": "export function render() {}\n",
+	}
+	var files []File
+	for rel, body := range sources {
+		abs := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(abs), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(abs, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, File{RelPath: rel, AbsPath: abs, Ext: filepath.Ext(rel)})
+	}
+	defs := []Def{
+		{Symbol: "server.Server.Start", Path: "server/server.go", Start: 3, End: 7},
+		{Symbol: "server.Server.listen", Path: "server/server.go", Start: 9, End: 9},
+		{Symbol: "server.log", Path: "server/server.go", Start: 11, End: 11},
+		{Symbol: "store.Open", Path: "store/store.go", Start: 3, End: 3},
+		{Symbol: "store.Close", Path: "store/store.go", Start: 5, End: 5},
+		{Symbol: "other.Close", Path: "other/close.go", Start: 3, End: 3},
+		{Symbol: "boot", Path: "web/app.ts", Start: 1, End: 3},
+		{Symbol: "render", Path: "web/view.ts", Start: 1, End: 1},
+	}
+
+	got := BuildCalls(files, defs)
+	// Close() from server.log is ambiguous (store and other) and is dropped.
+	want := [][2]string{
+		{"go:server.Server.Start", "go:server.Server.listen"},
+		{"go:server.Server.Start", "go:server.log"},
+		{"go:server.Server.Start", "go:store.Open"},
+		{"js:web/app#boot", "js:web/view#render"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("calls:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestTruncateKeepsCalls(t *testing.T) {
+	g := Graph{
+		Nodes: []string{"go:a", "go:b", "go:c"},
+		Edges: [][2]string{{"go:a", "go:b"}},
+		Calls: [][2]string{{"go:a.F", "go:c.G"}},
+	}
+	if got := Truncate(g, 1); !reflect.DeepEqual(got.Calls, g.Calls) {
+		t.Fatalf("Truncate calls = %q, want %q", got.Calls, g.Calls)
+	}
+}
@@ -0,0 +1,130 @@
+package graph
+
+import "sort"
+
+// Clusters maps graph nodes to community IDs. IDs are dense (0..Count-1) and
+// numbered in order of each community's lexicographically smallest node.
+type Clusters struct {
+	Count int            `json:"count"`
+	Nodes map[string]int `json:"nodes"`
+}
+
+// maxClusterPasses bounds the local-moving phase.
+const maxClusterPasses = 32
+
+// Cluster groups nodes into communities using the local-moving phase of the
+// Louvain method (greedy modularity gain) on the undirected view of g.
+//
+// Determinism: nodes are visited in sorted order, ties on gain are broken by
+// the smaller community index, and the number of passes is bounded.
+func Cluster(g Graph) Clusters {
+	nodes := append([]string(nil), g.Nodes...)
+	for _, e := range g.Edges {
+		nodes = append(nodes, e[0], e[1])
+	}
+	sort.Strings(nodes)
+	nodes = dedupSorted(nodes)
+	if len(nodes) == 0 {
+		return Clusters{Nodes: map[string]int{}}
+	}
+	idx := make(map[string]int, len(nodes))
+	for i, n := range nodes {
+		idx[n] = i
+	}
+
+	adj := make([]map[int]int, len(nodes))
+	for i := range adj {
+		adj[i] = map[int]int{}
+	}
+	m := 0
+	for _, e := range g.Edges {
+		a, b := idx[e[0]], idx[e[1]]
+		if a == b || adj[a][b] > 0 {
+			continue
+		}
+		adj[a][b] = 1
+		adj[b][a] = 1
+		m++
+	}
+
+	comm := make([]int, len(nodes))
+	tot := make([]int, len(nodes))
+	for i := range nodes {
+		comm[i] = i
+		tot[i] = len(adj[i])
+	}
+	if m > 0 {
+		localMoving(adj, comm, tot, m)
+	}
+	return relabel(nodes, comm)
+}
+
+func localMoving(adj []map[int]int, comm, tot []int, m int) {
+	twoM := float64(2 * m)
+	for pass := 0; pass < maxClusterPasses; pass++ {
+		moved := false
+		for i := range adj {
+			ki := float64(len(adj[i]))
+			if ki == 0 {
+				continue
+			}
+			links := make(map[int]int, len(adj[i]))
+			for j := range adj[i] {
+				links[comm[j]]++
+			}
+			own := comm[i]
+			tot[own] -= len(adj[i])
+
+			cands := make([]int, 0, len(links))
+			for c := range links {
+				cands = append(cands, c)
+			}
+			sort.Ints(cands)
+
+			// Staying put wins ties; otherwise the smallest community does,
+			// since candidates are visited in ascending order.
+			best := own
+			bestGain := float64(links[own]) - float64(tot[own])*ki/twoM
+			for _, c := range cands {
+				gain := float64(links[c]) - float64(tot[c])*ki/twoM
+				if gain > bestGain+1e-12 {
+					best, bestGain = c, gain
+				}
+			}
+			tot[best] += len(adj[i])
+			if best != own {
+				comm[i] = best
+				moved = true
+			}
+		}
+		if !moved {
+			return
+		}
+	}
+}
+
+func relabel(nodes []string, comm []int) Clusters {
+	ids := make(map[int]int)
+	out := Clusters{Nodes: make(map[string]int, len(nodes))}
+	for i, n := range nodes { // nodes are sorted, so first-seen = smallest member
+		id, ok := ids[comm[i]]
+		if !ok {
+			id = len(ids)
+			ids[comm[i]] = id
+		}
+		out.Nodes[n] = id
+	}
+	out.Count = len(ids)
+	return out
+}
+
+func dedupSorted(in []string) []string {
+	out := in[:0]
+	for i, s := range in {
+		if s == "" || (i > 0 && s == in[i-1]) {
+			continue
+		}
+		out = append(out, s)
+	}
+	return out
+}
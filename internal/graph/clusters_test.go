@@ -0,0 +1,40 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClusterTwoDenseGroups(t *testing.T) {
+	var edges [][2]string
+	clique := func(ns ...string) {
+		for i := range ns {
+			for j := i + 1; j < len(ns); j++ {
+				edges = append(edges, [2]string{ns[i], ns[j]})
+			}
+		}
+	}
+	clique("a", "b", "c", "d")
+	clique("e", "f", "g", "h")
+	edges = append(edges, [2]string{"d", "e"})
+	g := Graph{Nodes: []string{"a", "b", "c", "d", "e", "f", "g", "h"}, Edges: edges}
+
+	got := Cluster(g)
+	if got.Count != 2 {
+		t.Fatalf("expected 2 clusters, got %+v", got)
+	}
+	want := map[string]int{"a": 0, "b": 0, "c": 0, "d": 0, "e": 1, "f": 1, "g": 1, "h": 1}
+	if !reflect.DeepEqual(got.Nodes, want) {
+		t.Fatalf("clusters = %v, want %v", got.Nodes, want)
+	}
+	if again := Cluster(g); !reflect.DeepEqual(again, got) {
+		t.Fatalf("clustering not deterministic: %+v vs %+v", again, got)
+	}
+}
+
+func TestClusterIsolatedNodes(t *testing.T) {
+	got := Cluster(Graph{Nodes: []string{"x", "y"}})
+	if got.Count != 2 || got.Nodes["x"] != 0 || got.Nodes["y"] != 1 {
+		t.Fatalf("unexpected clusters: %+v", got)
+	}
+}
@@ -1,6 +1,6 @@
 // Package graph provides a minimal import/call graph builder for heterogeneous
-// codebases. It uses fast, regex-driven scanners for Java, Go, and TS/JS
-// to produce a coarse graph suitable for bundle navigation.
+// codebases. It uses fast, regex-driven scanners for Java, Go, TS/JS, Python,
+// Rust and Terraform to produce a coarse graph suitable for bundle navigation.
 //
 // Design goals:
 //   - Zero external dependencies
@@ -9,27 +9,53 @@
 //
 // Notes:
 //   - Nodes are language-prefixed labels to avoid collisions:
-//     java:<package>, go:<package>, js:<relpath-without-ext>, npm:<package>
+//     java:<package>, go:<package>, js:<relpath-without-ext>, npm:<package>,
+//     py:<dotted module>, rs:<crate>::<module path>, tf:<resource address>
+//   - Go imports of packages present under a vendor/ directory resolve to the
+//     vendored files' node instead of a bare external go:<import path> node.
 //   - For TS/JS, relative imports are resolved to a normalized project-relative
 //     path (without extension); bare specifiers are labeled as npm:<name>.
 //   - For Java, edges are from "java:<package-of-file>" to the imported FQN
 //     (normalized to package or wildcard as seen). For simplicity we retain
 //     the imported name as-is; you can post-process if you need package-only.
+//   - For Python and Rust, imports are resolved to the scanned module they
+//     name (see pyModules.resolve and rsModules.resolve); relative imports
+//     and crate::/self::/super:: paths are resolved against the importer.
+//   - For Terraform, edges link resource/data/module blocks to the addresses
+//     they reference (see scanTerraform); .tf files have no file-level node.
 package graph
 
 import (
 	"encoding/json"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
+
+	"class-collector/internal/parallel"
+	"class-collector/internal/textutil"
+	"class-collector/internal/warn"
 )
 
 // Graph is a simple directed graph (no weights).
 type Graph struct {
-	Nodes []string    `json:"nodes"`
-	Edges [][2]string `json:"edges"`
+	Nodes   []string    `json:"nodes"`
+	Edges   [][2]string `json:"edges"`
+	Dropped int         `json:"droppedNodes,omitempty"` // nodes removed by Truncate
+
+	// Calls optionally links callable symbols (CallNode labels, which are not
+	// listed in Nodes) to the symbols they call; see BuildCalls.
+	Calls [][2]string `json:"calls,omitempty"`
+
+	// NodeKinds optionally labels each node as internal, stdlib or external
+	// (see ClassifyNodes).
+	NodeKinds map[string]string `json:"nodeKinds,omitempty"`
+
+	// Files maps each scanned file's RelPath to its source node. It is filled
+	// by BuildCached and not serialized.
+	Files map[string]string `json:"-"`
 }
 
 // File is the minimal file descriptor expected by BuildFrom.
@@ -37,6 +63,8 @@ type File struct {
 	RelPath string // project-relative, Unix-style or OS-style both accepted
 	AbsPath string // absolute path for reading
 	Ext     string // lowercase extension including dot (e.g. ".java")
+	GoPkg   string // optional Go import path of the file's package; overrides the package clause
+	Hash    string // optional content hash; enables reuse of ImportCache entries
 }
 
 // Build keeps backward compatibility with earlier code paths and returns
@@ -46,63 +74,113 @@ func Build() Graph { return Graph{} }
 // BuildFrom scans the given files and returns a minimal import graph.
 // It tolerates unreadable files and simply skips them.
 func BuildFrom(files []File) Graph {
+	g, _ := BuildCached(files, nil)
+	return g
+}
+
+// BuildCached is BuildFrom with an optional per-file import cache: files whose
+// Hash matches their cached entry reuse the stored scan instead of being read.
+// The cache is updated in place (and pruned to files) and the RelPaths that
+// were actually scanned are returned. A nil cache scans everything.
+func BuildCached(files []File, cache *ImportCache) (Graph, []string) {
 	nodeSet := make(map[string]struct{}, 256)
 	edgeSet := make(map[[2]string]struct{}, 512)
+	fileNodes := make(map[string]string, len(files))
 
-	// Determine probable project root (common directory) and parse tsconfig.json if present.
+	// Determine probable project root (common directory); TS/JS files resolve
+	// bare specifiers with their nearest enclosing tsconfig.json.
 	rootAbs := commonDir(files)
-	var tsr *tsResolver
-	if rootAbs != "" {
-		if r, err := loadTsResolver(rootAbs); err == nil {
-			tsr = r
+	tsr := newTsResolvers(rootAbs)
+	cache.begin(tsr.fingerprint(files))
+
+	type scan struct {
+		file     File
+		from     string
+		imports  []string
+		edges    [][2]string
+		read, ok bool
+	}
+	// Cache misses are read and scanned on up to parallel.Limit() workers
+	// into per-file slots; resolvers are picked beforehand, as tsResolvers
+	// memoizes them. Files resolved through a tsconfig.json bypass the cache:
+	// whether a bare specifier maps to a project file depends on which
+	// files exist, not just on the importer's content.
+	results := make([]scan, len(files))
+	resolvers := make([]*tsResolver, len(files))
+	var misses []int
+	for i, f := range files {
+		results[i].file = f
+		if resolvers[i] = tsr.forFile(f); resolvers[i] == nil {
+			if from, imports, edges, ok := cache.lookup(f); ok {
+				results[i] = scan{f, from, imports, edges, false, true}
+				continue
+			}
 		}
+		misses = append(misses, i)
 	}
-
-	for _, f := range files {
-		ext := strings.ToLower(f.Ext)
-		data, err := os.ReadFile(f.AbsPath)
+	parallel.For(len(misses), func(j int) {
+		sc := &results[misses[j]]
+		data, err := os.ReadFile(sc.file.AbsPath)
 		if err != nil {
-			continue
+			return
 		}
+		sc.read = true
+		sc.from, sc.imports, sc.edges, sc.ok = scanFile(sc.file, data, resolvers[misses[j]])
+	})
 
-		switch ext {
-		case ".java":
-			pkg, imports := scanJava(data)
-			if pkg == "" {
-				// fallback to directory-based node if package missing
-				pkg = dirAsJavaPackage(f.RelPath)
+	var scans []scan
+	var scanned []string
+	vendor := goVendor{}
+	pyMods := pyModules{}
+	var rsMods rsModules
+	for i, sc := range results {
+		f, from := sc.file, sc.from
+		if sc.read {
+			scanned = append(scanned, f.RelPath)
+			if sc.ok && resolvers[i] == nil {
+				cache.store(f, from, sc.imports, sc.edges)
 			}
-			from := "java:" + pkg
-			addNode(nodeSet, from)
-			for _, imp := range imports {
-				to := "java:" + imp
-				addNode(nodeSet, to)
-				addEdge(edgeSet, from, to)
-			}
-
+		}
+		if !sc.ok {
+			continue
+		}
+		switch strings.ToLower(f.Ext) {
 		case ".go":
-			pkg, imports := scanGo(data)
-			if pkg == "" {
-				pkg = dirAsGoPackage(f.RelPath)
-			}
-			from := "go:" + pkg
-			addNode(nodeSet, from)
-			for _, imp := range imports {
-				to := "go:" + imp
-				addNode(nodeSet, to)
-				addEdge(edgeSet, from, to)
-			}
+			vendor.add(f.RelPath, from)
+		case ".py":
+			pyMods.add(from)
+		case ".rs":
+			rsMods.add(from)
+		}
+		scans = append(scans, sc)
+	}
+	cache.prune(files)
 
- 	case ".ts", ".tsx", ".js":
-			node, imports := scanTSJSWithResolver(f.RelPath, data, tsr)
-			from := node
-			addNode(nodeSet, from)
-			for _, imp := range imports {
-				addNode(nodeSet, imp)
-				addEdge(edgeSet, from, imp)
+	for _, sc := range scans {
+		for _, e := range sc.edges {
+			addNode(nodeSet, e[0])
+			addNode(nodeSet, e[1])
+			addEdge(edgeSet, e[0], e[1])
+		}
+		if sc.from == "" {
+			continue
+		}
+		fileNodes[sc.file.RelPath] = sc.from
+		addNode(nodeSet, sc.from)
+		for _, to := range sc.imports {
+			switch strings.ToLower(sc.file.Ext) {
+			case ".go":
+				to = vendor.resolve(sc.file.RelPath, to)
+			case ".py":
+				to = pyMods.resolve(sc.from, to)
+			case ".rs":
+				to = rsMods.resolve(sc.from, to)
+			}
+			if to == "" {
+				continue
 			}
-		default:
-			// ignore other extensions
+			addNode(nodeSet, to)
+			addEdge(edgeSet, sc.from, to)
 		}
 	}
 
@@ -124,7 +202,55 @@ func BuildFrom(files []File) Graph {
 		return edges[i][0] < edges[j][0]
 	})
 
-	return Graph{Nodes: nodes, Edges: edges}
+	return Graph{Nodes: nodes, Edges: edges, Files: fileNodes}, scanned
+}
+
+// scanFile returns the source node of f and its sorted import targets, or
+// ok=false for unsupported extensions. Files without a single source node
+// (Terraform) return from="" and their node-to-node edges instead.
+func scanFile(f File, data []byte, tsr *tsResolver) (from string, imports []string, edges [][2]string, ok bool) {
+	switch strings.ToLower(f.Ext) {
+	case ".java":
+		pkg, imps := scanJava(data)
+		if pkg == "" {
+			// fallback to directory-based node if package missing
+			pkg = dirAsJavaPackage(f.RelPath)
+		}
+		for _, imp := range imps {
+			imports = append(imports, "java:"+imp)
+		}
+		return "java:" + pkg, imports, nil, true
+
+	case ".go":
+		pkg, imps := scanGo(data)
+		if f.GoPkg != "" {
+			pkg = f.GoPkg
+		} else if pkg == "" {
+			pkg = dirAsGoPackage(f.RelPath)
+		}
+		for _, imp := range imps {
+			imports = append(imports, "go:"+imp)
+		}
+		return "go:" + pkg, imports, nil, true
+
+	case ".ts", ".tsx", ".js", ".mjs", ".cjs":
+		from, imports = scanTSJSWithResolver(f.RelPath, data, tsr)
+		return from, imports, nil, true
+
+	case ".py":
+		from, imports = scanPython(f.RelPath, data)
+		return from, imports, nil, true
+
+	case ".rs":
+		from, imports = scanRust(f.RelPath, data)
+		return from, imports, nil, true
+
+	case ".tf":
+		return "", nil, scanTerraform(data), true
+	default:
+		// ignore other extensions
+		return "", nil, nil, false
+	}
 }
 
 // --- Java scanning -----------------------------------------------------------
@@ -201,44 +327,112 @@ func dirAsGoPackage(rel string) string {
 	return parts[len(parts)-1]
 }
 
+// goVendor maps Go import paths to the nodes of collected files under a
+// vendor/ directory, so imports of vendored packages become edges to code
+// that is actually present instead of bare external nodes.
+type goVendor map[string][]vendoredPkg
+
+// vendoredPkg is one vendor/<import path> directory: root is the directory
+// holding vendor/ ("" at the project root) and node the files' source node.
+type vendoredPkg struct {
+	root, node string
+}
+
+// add records rel when it lies under a vendor/ directory; the first file of
+// each vendored package decides its node.
+func (v goVendor) add(rel, node string) {
+	dir := "/" + path.Dir(filepath.ToSlash(rel)) + "/"
+	i := strings.LastIndex(dir, "/vendor/")
+	if i < 0 {
+		return
+	}
+	imp := strings.Trim(dir[i+len("/vendor/"):], "/")
+	if imp == "" || node == "" {
+		return
+	}
+	root := strings.Trim(dir[:i], "/")
+	for _, p := range v[imp] {
+		if p.root == root {
+			return
+		}
+	}
+	v[imp] = append(v[imp], vendoredPkg{root: root, node: node})
+}
+
+// resolve maps the import target "go:<path>" of the file rel to a vendored
+// package node, using the deepest vendor/ directory whose parent encloses
+// rel (as the go tool does). Other targets are returned unchanged.
+func (v goVendor) resolve(rel, target string) string {
+	imp, ok := strings.CutPrefix(target, "go:")
+	if !ok || len(v) == 0 {
+		return target
+	}
+	dir := path.Dir(filepath.ToSlash(rel)) + "/"
+	best := -1
+	out := target
+	for _, p := range v[imp] {
+		if p.root != "" && !strings.HasPrefix(dir, p.root+"/") {
+			continue
+		}
+		if len(p.root) > best {
+			best, out = len(p.root), p.node
+		}
+	}
+	return out
+}
+
 // --- TS/JS scanning ----------------------------------------------------------
 
 var (
-	reImportFrom   = regexp.MustCompile(`(?m)^\s*import\s+[^;]*?\s+from\s+['"]([^'"]+)['"]`)
-	reImportOnly   = regexp.MustCompile(`(?m)^\s*import\s+['"]([^'"]+)['"]`)
-	reRequireCall  = regexp.MustCompile(`(?m)require\(\s*['"]([^'"]+)['"]\s*\)`)
-	reExportFrom   = regexp.MustCompile(`(?m)^\s*export\s*\{[^}]*\}\s*from\s*['"]([^'"]+)['"]`)
+	reImportFrom  = regexp.MustCompile(`(?m)^\s*import\s+[^;]*?\s+from\s+['"]([^'"]+)['"]`)
+	reImportOnly  = regexp.MustCompile(`(?m)^\s*import\s+['"]([^'"]+)['"]`)
+	reRequireCall = regexp.MustCompile(`(?m)require\(\s*['"]([^'"]+)['"]\s*\)`)
+	reExportFrom  = regexp.MustCompile(`(?m)^\s*export\s*\{[^}]*\}\s*from\s*['"]([^'"]+)['"]`)
 )
 
+// JSNode returns the graph node of the TS/JS file rel: js:<relpath> without
+// its extension, except that .mjs and .cjs keep theirs, so an ES module and
+// its CommonJS twin (lib.mjs, lib.cjs) stay distinct.
+func JSNode(rel string) string { return "js:" + jsNodePath(filepath.ToSlash(rel)) }
+
+func jsNodePath(p string) string {
+	switch ext := filepath.Ext(p); strings.ToLower(ext) {
+	case ".mjs", ".cjs":
+		return p
+	default:
+		return strings.TrimSuffix(p, ext)
+	}
+}
+
+// scanTSJSWithResolver collects the imports of a TS/JS file according to its
+// module system: .mjs files are ES modules, so only import/export statements
+// count and a require() call is reported as suspicious; .cjs files are
+// CommonJS, so only require() counts and static imports are reported. Other
+// extensions accept both.
 func scanTSJSWithResolver(rel string, data []byte, r *tsResolver) (node string, imports []string) {
 	rel = filepath.ToSlash(rel)
-	// From-node: js:<relpath-without-ext>
-	base := strings.TrimSuffix(rel, filepath.Ext(rel))
-	node = "js:" + base
+	node = JSNode(rel)
+	base := strings.TrimPrefix(node, "js:")
+	ext := strings.ToLower(filepath.Ext(rel))
+	esm, cjs := ext != ".cjs", ext != ".mjs"
 
 	set := make(map[string]struct{}, 8)
-
-	// ES6: import ... from 'spec'
-	for _, m := range reImportFrom.FindAllSubmatch(data, -1) {
-		spec := string(m[1])
-		set[normalizeTSSpec(base, spec, r)] = struct{}{}
-	}
-	// ES6: import 'spec'
-	for _, m := range reImportOnly.FindAllSubmatch(data, -1) {
-		spec := string(m[1])
-		set[normalizeTSSpec(base, spec, r)] = struct{}{}
-	}
-	// CJS: require('spec')
-	for _, m := range reRequireCall.FindAllSubmatch(data, -1) {
-		spec := string(m[1])
-		set[normalizeTSSpec(base, spec, r)] = struct{}{}
-	}
-	// Re-exports: export { X } from 'spec'
-	for _, m := range reExportFrom.FindAllSubmatch(data, -1) {
-		spec := string(m[1])
-		set[normalizeTSSpec(base, spec, r)] = struct{}{}
+	add := func(re *regexp.Regexp, allowed bool, what string) {
+		for _, m := range re.FindAllSubmatch(data, -1) {
+			spec := string(m[1])
+			if !allowed {
+				warn.Add(warn.KindSuspicious, rel, "%s %q in a %s module; not a graph edge", what, spec, strings.TrimPrefix(ext, "."))
+				continue
+			}
+			set[normalizeTSSpec(base, spec, r)] = struct{}{}
+		}
 	}
 
+	add(reImportFrom, esm, "import from") // ES6: import ... from 'spec'
+	add(reImportOnly, esm, "import")      // ES6: import 'spec'
+	add(reRequireCall, cjs, "require")    // CJS: require('spec')
+	add(reExportFrom, esm, "export from") // Re-exports: export { X } from 'spec'
+
 	imports = setToSortedSlice(set)
 	return
 }
@@ -246,6 +440,8 @@ func scanTSJSWithResolver(rel string, data []byte, r *tsResolver) (node string,
 // normalizeTSSpec resolves a TS/JS specifier into a node:
 //   - relative (./ or ../) → js:<normalized/project-relpath-without-ext>
 //   - bare (e.g. "react")  → attempts tsconfig paths/baseUrl -> js:<rel-no-ext>; else npm:<name>
+//
+// As in JSNode, .mjs and .cjs extensions are kept.
 func normalizeTSSpec(baseNoExt, spec string, r *tsResolver) string {
 	if spec == "" {
 		return ""
@@ -254,13 +450,12 @@ func normalizeTSSpec(baseNoExt, spec string, r *tsResolver) string {
 		// Resolve against the base file directory.
 		dir := filepath.Dir(baseNoExt)
 		joined := filepath.ToSlash(filepath.Clean(filepath.Join(dir, spec)))
-		joined = strings.TrimSuffix(joined, filepath.Ext(joined))
-		return "js:" + strings.TrimPrefix(joined, "./")
+		return "js:" + jsNodePath(strings.TrimPrefix(joined, "./"))
 	}
 	// Bare specifier (npm-style). Try tsconfig resolution if available.
 	if r != nil {
 		if target := r.ResolveBare(spec); target != "" {
-			return "js:" + strings.TrimSuffix(filepath.ToSlash(target), filepath.Ext(target))
+			return JSNode(target)
 		}
 	}
 	return "npm:" + spec
@@ -275,26 +470,28 @@ func normalizeTSSpec(baseNoExt, spec string, r *tsResolver) string {
 
 type tsResolver struct {
 	root    string // absolute project root
-	baseURL string // e.g., "src"
+	dir     string // root-relative directory of the tsconfig.json ("" for the root)
+	baseURL string // e.g., "src"; relative to dir
 	// patterns: key -> first target (may contain *)
 	patterns [][2]string
 }
 
-func loadTsResolver(rootAbs string) (*tsResolver, error) {
-	b, err := os.ReadFile(filepath.Join(rootAbs, "tsconfig.json"))
-	if err != nil {
-		return nil, err
-	}
+// loadTsResolver parses <rootAbs>/<dirRel>/tsconfig.json; targets in it are
+// resolved relative to dirRel, as tsc does.
+func loadTsResolver(rootAbs, dirRel string, b []byte) (*tsResolver, error) {
+	cfgRel := path.Join(dirRel, "tsconfig.json")
 	var raw struct {
 		CompilerOptions struct {
 			BaseURL string              `json:"baseUrl"`
 			Paths   map[string][]string `json:"paths"`
 		} `json:"compilerOptions"`
 	}
-	if err := json.Unmarshal(b, &raw); err != nil {
+	// tsconfig.json is JSONC: comments and trailing commas are common.
+	if err := json.Unmarshal(textutil.StripJSONC(b), &raw); err != nil {
+		warn.Add(warn.KindConfig, cfgRel, "ignored: %v", err)
 		return nil, err
 	}
-	r := &tsResolver{root: rootAbs}
+	r := &tsResolver{root: rootAbs, dir: dirRel}
 	if raw.CompilerOptions.BaseURL != "" {
 		r.baseURL = raw.CompilerOptions.BaseURL
 	}
@@ -361,6 +558,9 @@ func (r *tsResolver) joinPath(p string) string {
 	if strings.HasPrefix(p, "/") {
 		p = p[1:]
 	}
+	if r.dir != "" {
+		p = path.Join(r.dir, p)
+	}
 	return p
 }
 
@@ -0,0 +1,244 @@
+package graph
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"class-collector/internal/warn"
+)
+
+func TestBuildFromResolvesGoVendoredImports(t *testing.T) {
+	dir := t.TempDir()
+	sources := map[string]string{
+		"main.go":                                    "package main\n\nimport (\n\t\"fmt\"\n\t\"github.com/acme/yaml\"\n)\n",
+		"vendor/github.com/acme/yaml/yaml.go":        "package yaml\n\nimport \"github.com/acme/strutil\"\n",
+		"vendor/github.com/acme/strutil/s.go":        "package strutil\n",
+		"tools/gen/vendor/github.com/acme/yaml/y.go": "package yamlv1\n",
+		"tools/gen/gen.go":                           "package gen\n\nimport \"github.com/acme/yaml\"\n",
+	}
+	var files []File
+	for rel, body := range sources {
+		abs := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(abs), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(abs, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, File{RelPath: rel, AbsPath: abs, Ext: ".go"})
+	}
+
+	g := BuildFrom(files)
+	want := [][2]string{
+		{"go:gen", "go:yamlv1"},
+		{"go:main", "go:fmt"},
+		{"go:main", "go:yaml"},
+		{"go:yaml", "go:strutil"},
+	}
+	if !reflect.DeepEqual(g.Edges, want) {
+		t.Fatalf("edges = %v, want %v", g.Edges, want)
+	}
+}
+
+func TestBuildFromTerraformResourceEdges(t *testing.T) {
+	dir := t.TempDir()
+	src := `# network
+resource "aws_security_group" "web" {
+  name = "web-sg"
+}
+
+resource "aws_instance" "web" {
+  ami                    = data.aws_ami.ubuntu.id
+  instance_type          = var.instance_type
+  vpc_security_group_ids = [aws_security_group.web.id]
+  subnet_id              = module.vpc.public_subnets[0]
+  user_data              = "host=${aws_security_group.web.name} file_name.txt"
+  count                  = length(local.zones) # aws_eip.ignored.id
+}
+
+data "aws_ami" "ubuntu" {
+  most_recent = true
+}
+
+module "vpc" {
+  source = "./modules/vpc"
+}
+`
+	abs := filepath.Join(dir, "main.tf")
+	if err := os.WriteFile(abs, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	g := BuildFrom([]File{{RelPath: "main.tf", AbsPath: abs, Ext: ".tf"}})
+	want := [][2]string{
+		{"tf:aws_instance.web", "tf:aws_security_group.web"},
+		{"tf:aws_instance.web", "tf:data.aws_ami.ubuntu"},
+		{"tf:aws_instance.web", "tf:local.zones"},
+		{"tf:aws_instance.web", "tf:module.vpc"},
+		{"tf:aws_instance.web", "tf:var.instance_type"},
+	}
+	if !reflect.DeepEqual(g.Edges, want) {
+		t.Fatalf("edges = %v, want %v", g.Edges, want)
+	}
+	if len(g.Files) != 0 {
+		t.Fatalf("Terraform files must not get a source node: %v", g.Files)
+	}
+}
+
+func TestBuildFromJSModuleSystems(t *testing.T) {
+	c := &warn.Collector{}
+	warn.Use(c)
+	defer warn.Use(nil)
+
+	dir := t.TempDir()
+	sources := map[string]string{
+		"lib/util.cjs":  "const fs = require('fs');\nconst helper = require('./helper');\nmodule.exports = {};\n",
+		"lib/helper.js": "module.exports = {};\n",
+		"lib/util.mjs":  "import { readFile } from 'node:fs';\nimport helper from './helper.js';\nexport { x } from './util.cjs';\nconst legacy = require('./legacy');\n",
+		"app.ts":        "import { x } from './lib/util.mjs';\n",
+	}
+	var files []File
+	for rel, body := range sources {
+		abs := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(abs), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(abs, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, File{RelPath: rel, AbsPath: abs, Ext: filepath.Ext(rel)})
+	}
+
+	g := BuildFrom(files)
+	want := [][2]string{
+		{"js:app", "js:lib/util.mjs"},
+		{"js:lib/util.cjs", "js:lib/helper"},
+		{"js:lib/util.cjs", "npm:fs"},
+		{"js:lib/util.mjs", "js:lib/helper"},
+		{"js:lib/util.mjs", "js:lib/util.cjs"},
+		{"js:lib/util.mjs", "npm:node:fs"},
+	}
+	if !reflect.DeepEqual(g.Edges, want) {
+		t.Fatalf("edges = %v, want %v", g.Edges, want)
+	}
+	if g.Files["lib/util.mjs"] != "js:lib/util.mjs" || g.Files["lib/util.cjs"] != "js:lib/util.cjs" {
+		t.Fatalf("module nodes = %v", g.Files)
+	}
+	ws := c.List()
+	if len(ws) != 1 || ws[0].Kind != warn.KindSuspicious || ws[0].Path != "lib/util.mjs" || !strings.Contains(ws[0].Message, `require "./legacy"`) {
+		t.Fatalf("warnings = %+v", ws)
+	}
+}
+
+func TestScanPythonDropsRelativeImportBeyondRoot(t *testing.T) {
+	c := &warn.Collector{}
+	warn.Use(c)
+	defer warn.Use(nil)
+
+	_, imports := scanPython("pkg/mod.py", []byte("from ...outside import z\nfrom ..other import y\n"))
+	if want := []string{"py:other:y"}; !reflect.DeepEqual(imports, want) {
+		t.Fatalf("imports = %v, want %v", imports, want)
+	}
+	if ws := c.List(); len(ws) != 1 || ws[0].Kind != warn.KindSuspicious || ws[0].Path != "pkg/mod.py" {
+		t.Fatalf("warnings = %+v", ws)
+	}
+}
+
+func TestBuildFromPythonImports(t *testing.T) {
+	dir := t.TempDir()
+	sources := map[string]string{
+		"src/app/__init__.py":      "from .core import engine\n",
+		"src/app/core/__init__.py": "",
+		"src/app/core/engine.py": "import os, sys as system\n" +
+			"from . import util  # sibling module\n" +
+			"from ..cli import (\n    main,\n    run as go,\n)\n" +
+			"\"\"\"\nimport not_an_import\n\"\"\"\n",
+		"src/app/core/util.py": "from app.core.engine import Engine\nfrom app import *\n",
+		"src/app/cli.py":       "import requests\nfrom typing import List\n",
+		"tests/test_cli.py":    "from app.cli import main\nimport app.core\n",
+	}
+	var files []File
+	for rel, body := range sources {
+		abs := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(abs), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(abs, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, File{RelPath: rel, AbsPath: abs, Ext: ".py"})
+	}
+
+	g := BuildFrom(files)
+	want := [][2]string{
+		{"py:src.app", "py:src.app.core.engine"},
+		{"py:src.app.cli", "py:requests"},
+		{"py:src.app.cli", "py:typing"},
+		{"py:src.app.core.engine", "py:os"},
+		{"py:src.app.core.engine", "py:src.app.cli"},
+		{"py:src.app.core.engine", "py:src.app.core.util"},
+		{"py:src.app.core.engine", "py:sys"},
+		{"py:src.app.core.util", "py:src.app"},
+		{"py:src.app.core.util", "py:src.app.core.engine"},
+		{"py:tests.test_cli", "py:src.app.cli"},
+		{"py:tests.test_cli", "py:src.app.core"},
+	}
+	if !reflect.DeepEqual(g.Edges, want) {
+		t.Fatalf("edges:\n got %v\nwant %v", g.Edges, want)
+	}
+	kinds := ClassifyNodes(g, nil)
+	if kinds["py:typing"] != NodeStdlib || kinds["py:requests"] != NodeExternal || kinds["py:src.app.cli"] != NodeInternal {
+		t.Fatalf("node kinds = %v", kinds)
+	}
+}
+
+func TestBuildFromRustUses(t *testing.T) {
+	dir := t.TempDir()
+	sources := map[string]string{
+		"src/main.rs": "mod net;\nmod util;\n\nuse crate::net::{http::{self, Client}, tcp};\n" +
+			"use serde::Deserialize as De;\nuse std::io;\n// use crate::ghost;\n" +
+			"fn main() { let s = \"use crate::fake;\"; }\n",
+		"src/net/mod.rs":             "pub mod http;\npub(crate) mod tcp;\n",
+		"src/net/http.rs":            "use super::tcp::Stream;\nuse crate::util::*;\nfn f<'a>(x: &'a str) -> char { 'x' }\n",
+		"src/net/tcp.rs":             "use self::inner::X;\nmod inner { pub struct X; }\n",
+		"src/util.rs":                "",
+		"crates/my-lib/src/lib.rs":   "pub mod parse;\nuse parse::Token;\n",
+		"crates/my-lib/src/parse.rs": "use crate::Lib;\n",
+		"tests/it.rs":                "use my_lib::parse;\nextern crate core;\n",
+	}
+	var files []File
+	for rel, body := range sources {
+		abs := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(abs), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(abs, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, File{RelPath: rel, AbsPath: abs, Ext: ".rs"})
+	}
+
+	g := BuildFrom(files)
+	want := [][2]string{
+		{"rs:crate", "rs:crate::net"},
+		{"rs:crate", "rs:crate::net::http"},
+		{"rs:crate", "rs:crate::net::tcp"},
+		{"rs:crate", "rs:crate::util"},
+		{"rs:crate", "rs:serde"},
+		{"rs:crate", "rs:std"},
+		{"rs:crate::net", "rs:crate::net::http"},
+		{"rs:crate::net", "rs:crate::net::tcp"},
+		{"rs:crate::net::http", "rs:crate::net::tcp"},
+		{"rs:crate::net::http", "rs:crate::util"},
+		{"rs:crate::tests::it", "rs:core"},
+		{"rs:crate::tests::it", "rs:my_lib::parse"},
+		{"rs:my_lib", "rs:my_lib::parse"},
+		{"rs:my_lib::parse", "rs:my_lib"},
+	}
+	if !reflect.DeepEqual(g.Edges, want) {
+		t.Fatalf("edges:\n got %v\nwant %v", g.Edges, want)
+	}
+}
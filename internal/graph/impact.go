@@ -0,0 +1,60 @@
+package graph
+
+import "sort"
+
+// DefaultImpactLimit caps how many dependents Impact counts per node.
+const DefaultImpactLimit = 1000
+
+// Impact returns, for every node, the number of distinct nodes that depend on
+// it directly or transitively (reverse reachability over the edges). The
+// search stops once limit dependents are found, so scores saturate at limit
+// and the cost stays bounded on large graphs; limit <= 0 uses
+// DefaultImpactLimit. The result is independent of edge order.
+func Impact(g Graph, limit int) map[string]int {
+	if limit <= 0 {
+		limit = DefaultImpactLimit
+	}
+	importers := make(map[string][]string, len(g.Nodes))
+	for _, e := range g.Edges {
+		importers[e[1]] = append(importers[e[1]], e[0])
+	}
+	for _, in := range importers {
+		sort.Strings(in)
+	}
+
+	out := make(map[string]int, len(g.Nodes))
+	for _, n := range g.Nodes {
+		seen := map[string]struct{}{n: {}}
+		queue := []string{n}
+		count := 0
+		for len(queue) > 0 && count < limit {
+			cur := queue[0]
+			queue = queue[1:]
+			for _, dep := range importers[cur] {
+				if _, ok := seen[dep]; ok {
+					continue
+				}
+				seen[dep] = struct{}{}
+				queue = append(queue, dep)
+				if count++; count == limit {
+					break
+				}
+			}
+		}
+		out[n] = count
+	}
+	return out
+}
+
+// FileImpact maps each file in g.Files to the Impact of its source node.
+// Files whose node has no dependents are omitted.
+func FileImpact(g Graph, limit int) map[string]int {
+	scores := Impact(g, limit)
+	out := make(map[string]int, len(g.Files))
+	for path, node := range g.Files {
+		if s := scores[node]; s > 0 {
+			out[path] = s
+		}
+	}
+	return out
+}
@@ -0,0 +1,48 @@
+package graph
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileImpactRanksSharedFileAboveLeaf(t *testing.T) {
+	dir := t.TempDir()
+	sources := map[string]string{
+		"util.ts": "export const x = 1;\n",
+		"a.ts":    "import { x } from './util';\n",
+		"b.ts":    "import { x } from './util';\n",
+		"c.ts":    "import './a';\n",
+	}
+	var files []File
+	for name, body := range sources {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		files = append(files, File{RelPath: name, AbsPath: filepath.Join(dir, name), Ext: ".ts"})
+	}
+
+	scores := FileImpact(BuildFrom(files), 0)
+	if scores["util.ts"] != 3 || scores["a.ts"] != 1 {
+		t.Fatalf("unexpected scores: %v", scores)
+	}
+	if _, ok := scores["c.ts"]; ok {
+		t.Fatalf("leaf c.ts should have no impact: %v", scores)
+	}
+	if scores["util.ts"] <= scores["b.ts"] {
+		t.Fatalf("shared util.ts should outrank leaf b.ts: %v", scores)
+	}
+}
+
+func TestImpactIsCapped(t *testing.T) {
+	g := Graph{
+		Nodes: []string{"a", "b", "c", "core"},
+		Edges: [][2]string{{"a", "core"}, {"b", "core"}, {"c", "b"}},
+	}
+	if got := Impact(g, 2)["core"]; got != 2 {
+		t.Fatalf("capped impact = %d, want 2", got)
+	}
+	if got := Impact(g, 0)["core"]; got != 3 {
+		t.Fatalf("impact = %d, want 3", got)
+	}
+}
@@ -0,0 +1,113 @@
+package graph
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// ImportCacheFile is the conventional file name of a persisted ImportCache
+// inside the per-project cache directory.
+const ImportCacheFile = "graph.imports.json"
+
+// importCacheVersion is bumped whenever scanner output changes shape, which
+// invalidates previously persisted entries.
+const importCacheVersion = 5
+
+// ImportCache holds per-file scan results keyed by RelPath and validated by
+// content hash. Entries are also dropped wholesale when any tsconfig.json in
+// effect changes, and TS/JS files under a tsconfig.json are never cached, as
+// their bare specifiers resolve against the files present on disk.
+type ImportCache struct {
+	Version  int                      `json:"version"`
+	TSConfig string                   `json:"tsconfig,omitempty"` // digest of the tsconfig.json files in effect; "" when none
+	Files    map[string]CachedImports `json:"files"`
+}
+
+// CachedImports is the scan result of one file.
+type CachedImports struct {
+	Hash    string   `json:"hash"`
+	GoPkg   string   `json:"goPkg,omitempty"`
+	From    string   `json:"from"`
+	Imports []string `json:"imports,omitempty"`
+	// Edges holds the node-to-node edges of files without a single source
+	// node (Terraform); From is empty for them.
+	Edges [][2]string `json:"edges,omitempty"`
+}
+
+// LoadImportCache reads a cache from path. A missing, unreadable or
+// incompatible file yields an empty cache, never an error.
+func LoadImportCache(path string) *ImportCache {
+	c := &ImportCache{Version: importCacheVersion, Files: map[string]CachedImports{}}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return c
+	}
+	var loaded ImportCache
+	if err := json.Unmarshal(b, &loaded); err != nil || loaded.Version != importCacheVersion || loaded.Files == nil {
+		return c
+	}
+	return &loaded
+}
+
+// Save writes the cache to path (map keys are emitted sorted by encoding/json).
+func (c *ImportCache) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// begin invalidates all entries when the tsconfig fingerprint fp changed.
+func (c *ImportCache) begin(fp string) {
+	if c == nil {
+		return
+	}
+	if c.Files == nil || fp != c.TSConfig {
+		c.Files = map[string]CachedImports{}
+	}
+	c.Version = importCacheVersion
+	c.TSConfig = fp
+}
+
+func (c *ImportCache) lookup(f File) (string, []string, [][2]string, bool) {
+	if c == nil || f.Hash == "" {
+		return "", nil, nil, false
+	}
+	e, ok := c.Files[f.RelPath]
+	if !ok || e.Hash != f.Hash || e.GoPkg != f.GoPkg {
+		return "", nil, nil, false
+	}
+	return e.From, e.Imports, e.Edges, true
+}
+
+func (c *ImportCache) store(f File, from string, imports []string, edges [][2]string) {
+	if c == nil || f.Hash == "" {
+		return
+	}
+	c.Files[f.RelPath] = CachedImports{Hash: f.Hash, GoPkg: f.GoPkg, From: from, Imports: imports, Edges: edges}
+}
+
+// prune drops entries for files no longer present.
+func (c *ImportCache) prune(files []File) {
+	if c == nil {
+		return
+	}
+	keep := make(map[string]struct{}, len(files))
+	for _, f := range files {
+		keep[f.RelPath] = struct{}{}
+	}
+	for p := range c.Files {
+		if _, ok := keep[p]; !ok {
+			delete(c.Files, p)
+		}
+	}
+}
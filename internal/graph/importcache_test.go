@@ -0,0 +1,76 @@
+package graph
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBuildCachedRescansOnlyTouchedFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, body string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	write("a.go", "package demo\n\nimport \"fmt\"\n")
+	write("b.go", "package demo\n\nimport \"os\"\n")
+	files := []File{
+		{RelPath: "a.go", AbsPath: filepath.Join(dir, "a.go"), Ext: ".go", Hash: "h-a1"},
+		{RelPath: "b.go", AbsPath: filepath.Join(dir, "b.go"), Ext: ".go", Hash: "h-b1"},
+	}
+	cachePath := filepath.Join(dir, "cache", ImportCacheFile)
+
+	ic := LoadImportCache(cachePath)
+	_, scanned := BuildCached(files, ic)
+	if !reflect.DeepEqual(scanned, []string{"a.go", "b.go"}) {
+		t.Fatalf("cold build scanned %v", scanned)
+	}
+	if err := ic.Save(cachePath); err != nil {
+		t.Fatalf("save cache: %v", err)
+	}
+
+	write("b.go", "package demo\n\nimport \"strings\"\n")
+	files[1].Hash = "h-b2"
+	ic = LoadImportCache(cachePath)
+	g, scanned := BuildCached(files, ic)
+	if !reflect.DeepEqual(scanned, []string{"b.go"}) {
+		t.Fatalf("warm build scanned %v, want only b.go", scanned)
+	}
+	if fresh := BuildFrom(files); !reflect.DeepEqual(g, fresh) {
+		t.Fatalf("cached graph %+v differs from fresh %+v", g, fresh)
+	}
+}
+
+func TestBuildCachedResolvesTSAgainstNewFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, body string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	write("tsconfig.json", `{"compilerOptions": {"baseUrl": ".", "paths": {"@app/*": ["src/*"]}}}`)
+	write("index.ts", "")
+	write("src/main.ts", "import { foo } from '@app/foo';\n")
+	files := []File{
+		{RelPath: "index.ts", AbsPath: filepath.Join(dir, "index.ts"), Ext: ".ts", Hash: "h-index"},
+		{RelPath: "src/main.ts", AbsPath: filepath.Join(dir, "src", "main.ts"), Ext: ".ts", Hash: "h-main"},
+	}
+	ic := LoadImportCache(filepath.Join(dir, "cache", ImportCacheFile))
+	if g, _ := BuildCached(files, ic); !reflect.DeepEqual(g.Edges, [][2]string{{"js:src/main", "npm:@app/foo"}}) {
+		t.Fatalf("cold edges = %v", g.Edges)
+	}
+
+	// src/foo.ts appears; main.ts is unchanged but must now resolve to it.
+	write("src/foo.ts", "export const foo = 1;\n")
+	files = append(files, File{RelPath: "src/foo.ts", AbsPath: filepath.Join(dir, "src", "foo.ts"), Ext: ".ts", Hash: "h-foo"})
+	g, _ := BuildCached(files, ic)
+	if !reflect.DeepEqual(g.Edges, [][2]string{{"js:src/main", "js:src/foo"}}) {
+		t.Fatalf("warm edges = %v, want main -> foo", g.Edges)
+	}
+}
@@ -0,0 +1,135 @@
+package graph
+
+import "strings"
+
+// Node origins reported in Graph.NodeKinds.
+const (
+	NodeInternal = "internal" // resolved to a scanned project file
+	NodeStdlib   = "stdlib"   // standard library of the node's language
+	NodeExternal = "external" // third-party or unresolved
+)
+
+// goStdRoots are the top-level import path elements of the Go standard
+// library (plus cgo's "C").
+var goStdRoots = map[string]bool{
+	"archive": true, "bufio": true, "bytes": true, "cmp": true, "compress": true,
+	"container": true, "context": true, "crypto": true, "database": true, "debug": true,
+	"embed": true, "encoding": true, "errors": true, "expvar": true, "flag": true,
+	"fmt": true, "go": true, "hash": true, "html": true, "image": true, "index": true,
+	"io": true, "iter": true, "log": true, "maps": true, "math": true, "mime": true,
+	"net": true, "os": true, "path": true, "plugin": true, "reflect": true, "regexp": true,
+	"runtime": true, "slices": true, "sort": true, "strconv": true, "strings": true,
+	"structs": true, "sync": true, "syscall": true, "testing": true, "text": true,
+	"time": true, "unicode": true, "unique": true, "unsafe": true, "weak": true, "C": true,
+}
+
+// nodeBuiltins are Node.js core modules, importable without the "node:" prefix.
+var nodeBuiltins = map[string]bool{
+	"assert": true, "async_hooks": true, "buffer": true, "child_process": true, "cluster": true,
+	"console": true, "crypto": true, "dgram": true, "dns": true, "events": true, "fs": true,
+	"http": true, "http2": true, "https": true, "module": true, "net": true, "os": true,
+	"path": true, "perf_hooks": true, "process": true, "querystring": true, "readline": true,
+	"stream": true, "string_decoder": true, "timers": true, "tls": true, "tty": true,
+	"url": true, "util": true, "v8": true, "vm": true, "worker_threads": true, "zlib": true,
+}
+
+// pyStdRoots are common top-level modules of the Python standard library.
+var pyStdRoots = map[string]bool{
+	"__future__": true, "abc": true, "argparse": true, "array": true, "ast": true, "asyncio": true,
+	"base64": true, "bisect": true, "builtins": true, "calendar": true, "collections": true,
+	"concurrent": true, "configparser": true, "contextlib": true, "copy": true, "csv": true,
+	"ctypes": true, "dataclasses": true, "datetime": true, "decimal": true, "difflib": true,
+	"email": true, "enum": true, "errno": true, "fnmatch": true, "fractions": true, "functools": true,
+	"gc": true, "getpass": true, "glob": true, "gzip": true, "hashlib": true, "heapq": true,
+	"hmac": true, "html": true, "http": true, "importlib": true, "inspect": true, "io": true,
+	"ipaddress": true, "itertools": true, "json": true, "logging": true, "math": true, "mimetypes": true,
+	"multiprocessing": true, "operator": true, "os": true, "pathlib": true, "pickle": true,
+	"platform": true, "pprint": true, "queue": true, "random": true, "re": true, "secrets": true,
+	"select": true, "shlex": true, "shutil": true, "signal": true, "socket": true, "sqlite3": true,
+	"ssl": true, "stat": true, "statistics": true, "string": true, "struct": true, "subprocess": true,
+	"sys": true, "tempfile": true, "textwrap": true, "threading": true, "time": true, "timeit": true,
+	"tomllib": true, "traceback": true, "types": true, "typing": true, "unittest": true, "urllib": true,
+	"uuid": true, "warnings": true, "weakref": true, "xml": true, "zipfile": true, "zlib": true,
+}
+
+// rsStdCrates are the crates shipped with the Rust toolchain.
+var rsStdCrates = map[string]bool{"std": true, "core": true, "alloc": true, "proc_macro": true, "test": true}
+
+// ClassifyNodes labels every node of g as NodeInternal, NodeStdlib or
+// NodeExternal from its prefix:
+//
+//   - source nodes of scanned files (Files), js: paths and tf: addresses are internal
+//   - go: standard library roots are stdlib; an import path inside one of
+//     goModules (module paths declared by the project's go.mod files) is
+//     internal
+//   - java: java.*, javax.* and jdk.* are stdlib; an import whose package is a
+//     scanned package is internal
+//   - npm: Node.js core modules (and "node:" specifiers) are stdlib
+//   - py: common standard library modules are stdlib
+//   - rs: std, core, alloc, proc_macro and test are stdlib
+//
+// Everything else is external.
+func ClassifyNodes(g Graph, goModules []string) map[string]string {
+	internal := make(map[string]bool, len(g.Files))
+	for _, node := range g.Files {
+		internal[node] = true
+	}
+
+	kinds := make(map[string]string, len(g.Nodes))
+	for _, n := range g.Nodes {
+		kinds[n] = classifyNode(n, internal, goModules)
+	}
+	return kinds
+}
+
+func classifyNode(n string, internal map[string]bool, goModules []string) string {
+	if internal[n] {
+		return NodeInternal
+	}
+	prefix, name, _ := strings.Cut(n, ":")
+	switch prefix {
+	case "js", "tf":
+		return NodeInternal
+	case "go":
+		root, _, _ := strings.Cut(name, "/")
+		if goStdRoots[root] {
+			return NodeStdlib
+		}
+		for _, m := range goModules {
+			if name == m || strings.HasPrefix(name, m+"/") {
+				return NodeInternal
+			}
+		}
+	case "java":
+		if strings.HasPrefix(name, "java.") || strings.HasPrefix(name, "javax.") || strings.HasPrefix(name, "jdk.") {
+			return NodeStdlib
+		}
+		pkg := strings.TrimSuffix(name, ".*")
+		if pkg == name {
+			if i := strings.LastIndex(name, "."); i > 0 {
+				pkg = name[:i]
+			}
+		}
+		if internal["java:"+pkg] || internal["java:"+name] {
+			return NodeInternal
+		}
+	case "npm":
+		if strings.HasPrefix(name, "node:") {
+			return NodeStdlib
+		}
+		root, _, _ := strings.Cut(name, "/")
+		if nodeBuiltins[root] {
+			return NodeStdlib
+		}
+	case "py":
+		root, _, _ := strings.Cut(name, ".")
+		if pyStdRoots[root] {
+			return NodeStdlib
+		}
+	case "rs":
+		if rsStdCrates[name] {
+			return NodeStdlib
+		}
+	}
+	return NodeExternal
+}
@@ -0,0 +1,48 @@
+package graph
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClassifyNodes(t *testing.T) {
+	dir := t.TempDir()
+	sources := map[string]string{
+		"main.go":             "package main\n\nimport (\n\t\"fmt\"\n\t\"github.com/acme/yaml\"\n\t\"example.com/app/yaml\"\n)\n",
+		"yaml/yaml.go":        "package yaml\n",
+		"web/app.js":          "import React from 'react';\nimport fs from 'node:fs';\nimport { add } from './util';\n",
+		"web/util.js":         "export const add = (a, b) => a + b;\n",
+		"src/com/acme/A.java": "package com.acme;\n\nimport java.util.List;\nimport com.acme.B;\nimport org.junit.Test;\n",
+		"src/com/acme/B.java": "package com.acme;\n",
+	}
+	var files []File
+	for rel, body := range sources {
+		abs := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(abs), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(abs, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, File{RelPath: rel, AbsPath: abs, Ext: filepath.Ext(rel)})
+	}
+
+	kinds := ClassifyNodes(BuildFrom(files), []string{"example.com/app"})
+	want := map[string]string{
+		"go:fmt":                  NodeStdlib,
+		"go:github.com/acme/yaml": NodeExternal, // same last element as the scanned yaml/ directory
+		"go:example.com/app/yaml": NodeInternal,
+		"npm:react":               NodeExternal,
+		"npm:node:fs":             NodeStdlib,
+		"js:web/util":             NodeInternal,
+		"java:java.util.List":     NodeStdlib,
+		"java:com.acme.B":         NodeInternal,
+		"java:org.junit.Test":     NodeExternal,
+	}
+	for node, kind := range want {
+		if got := kinds[node]; got != kind {
+			t.Errorf("kind of %q = %q, want %q (all: %v)", node, got, kind, kinds)
+		}
+	}
+}
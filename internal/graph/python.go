@@ -0,0 +1,271 @@
+package graph
+
+import (
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"class-collector/internal/warn"
+)
+
+// --- Python scanning ---------------------------------------------------------
+
+// A Python file's node is py:<dotted project-relative path> without ".py"
+// (a package's __init__.py is its directory). Relative imports are resolved
+// against the file's own package while scanning; one climbing past the
+// project root, which Python rejects, is dropped with a warning. Absolute
+// module names are matched against the scanned files by pyModules.resolve,
+// since the source root a project imports from ("src/", the repo root, ...)
+// is not declared.
+// Names brought in by "from m import n" are kept as "m:n" until resolution
+// decides whether n is a submodule of m.
+
+var (
+	rePyImport = regexp.MustCompile(`^import\s+(.+)$`)
+	rePyFrom   = regexp.MustCompile(`^from\s+(\.*)([A-Za-z_][\w.]*)?\s+import\s+(.+)$`)
+)
+
+// PyNode returns the graph node of the Python file rel.
+func PyNode(rel string) string { return "py:" + pyModule(filepath.ToSlash(rel)) }
+
+func pyModule(rel string) string {
+	rel = strings.TrimSuffix(rel, path.Ext(rel))
+	if d, ok := strings.CutSuffix(rel, "/__init__"); ok {
+		rel = d
+	}
+	return strings.ReplaceAll(strings.Trim(rel, "/"), "/", ".")
+}
+
+// scanPython returns the sorted raw imports of a Python file: "py:<module>"
+// for "import <module>" and "py:<module>:<name>" for each name of
+// "from <module> import <name>". Docstrings and comments are skipped, and
+// parenthesized or backslash-continued import lists are joined first.
+func scanPython(rel string, data []byte) (node string, imports []string) {
+	rel = filepath.ToSlash(rel)
+	node = PyNode(rel)
+	pkg := pyModule(rel)
+	if path.Base(strings.TrimSuffix(rel, path.Ext(rel))) != "__init__" {
+		pkg = pyParent(pkg)
+	}
+	set := map[string]struct{}{}
+	for _, stmt := range pyStatements(string(data)) {
+		if m := rePyImport.FindStringSubmatch(stmt); m != nil {
+			for _, item := range strings.Split(m[1], ",") {
+				if mod := pyImportName(item); mod != "" {
+					set["py:"+mod] = struct{}{}
+				}
+			}
+			continue
+		}
+		m := rePyFrom.FindStringSubmatch(stmt)
+		if m == nil {
+			continue
+		}
+		mod := m[2]
+		if dots := len(m[1]); dots > 0 {
+			base, beyond := pkg, false
+			for i := 1; i < dots; i++ {
+				if base == "" {
+					beyond = true
+					break
+				}
+				base = pyParent(base)
+			}
+			if beyond {
+				warn.Add(warn.KindSuspicious, rel, "relative import %q goes beyond the top-level package; not a graph edge", m[1]+m[2])
+				continue
+			}
+			mod = strings.Trim(base+"."+mod, ".")
+		}
+		if mod == "" {
+			continue
+		}
+		names := strings.Trim(strings.TrimSpace(m[3]), "()")
+		for _, item := range strings.Split(names, ",") {
+			name := pyImportName(item)
+			switch {
+			case name == "" && strings.TrimSpace(item) == "*":
+				set["py:"+mod] = struct{}{}
+			case name != "" && !strings.Contains(name, "."):
+				set["py:"+mod+":"+name] = struct{}{}
+			}
+		}
+	}
+	return node, setToSortedSlice(set)
+}
+
+// pyImportName returns the dotted name of one "name [as alias]" item, or ""
+// when it is not a plain identifier path.
+func pyImportName(item string) string {
+	fields := strings.Fields(item)
+	if len(fields) == 0 || len(fields) == 2 || len(fields) > 3 || len(fields) == 3 && fields[1] != "as" {
+		return ""
+	}
+	for _, part := range strings.Split(fields[0], ".") {
+		if part == "" || !isPyIdent(part) {
+			return ""
+		}
+	}
+	return fields[0]
+}
+
+func isPyIdent(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func pyParent(mod string) string {
+	if i := strings.LastIndexByte(mod, '.'); i >= 0 {
+		return mod[:i]
+	}
+	return ""
+}
+
+// pyStatements returns the import and from-import statements of src, each
+// on one line with comments removed. Lines inside triple-quoted strings are
+// skipped; imports nested in blocks (try/except, functions) count as well.
+func pyStatements(src string) []string {
+	var out []string
+	var stmt strings.Builder
+	inStmt, depth := false, 0
+	var triple string
+	for _, line := range strings.Split(src, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if triple != "" {
+			if i := strings.Index(line, triple); i >= 0 {
+				line = line[i+3:]
+				triple = ""
+			} else {
+				continue
+			}
+		}
+		code := pyCode(line, &triple)
+		trimmed := strings.TrimSpace(code)
+		if !inStmt {
+			if !strings.HasPrefix(trimmed, "import ") && !strings.HasPrefix(trimmed, "from ") {
+				continue
+			}
+			inStmt, depth = true, 0
+			stmt.Reset()
+		}
+		cont := strings.HasSuffix(trimmed, "\\")
+		trimmed = strings.TrimSuffix(trimmed, "\\")
+		depth += strings.Count(trimmed, "(") - strings.Count(trimmed, ")")
+		stmt.WriteString(trimmed)
+		stmt.WriteByte(' ')
+		if depth <= 0 && !cont {
+			for _, s := range strings.Split(stmt.String(), ";") {
+				if s = strings.TrimSpace(s); s != "" {
+					out = append(out, s)
+				}
+			}
+			inStmt = false
+		}
+	}
+	return out
+}
+
+// pyCode returns line up to a '#' comment outside string literals. A
+// triple-quoted string left open at the end of the line is reported through
+// triple (its quote) and cuts the line there.
+func pyCode(line string, triple *string) string {
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '#':
+			return line[:i]
+		case c == '"' || c == '\'':
+			q := string(c)
+			if strings.HasPrefix(line[i:], q+q+q) {
+				end := strings.Index(line[i+3:], q+q+q)
+				if end < 0 {
+					*triple = q + q + q
+					return line[:i]
+				}
+				i += 3 + end + 2
+				continue
+			}
+			for i++; i < len(line) && line[i] != c; i++ {
+				if line[i] == '\\' {
+					i++
+				}
+			}
+		}
+	}
+	return line
+}
+
+// pyModules indexes the modules of the scanned Python files for resolving
+// absolute imports.
+type pyModules map[string]bool
+
+func (m pyModules) add(node string) {
+	if mod, ok := strings.CutPrefix(node, "py:"); ok {
+		m[mod] = true
+	}
+}
+
+// resolve maps a raw import of the file whose node is from to a node: the
+// submodule mod.name when it exists, else mod. A module matches a scanned
+// file when its dotted path equals it or ends in "."+name; with several
+// matches the one under the deepest directory enclosing the importer wins,
+// as that is the source root it runs from. Unmatched modules stay
+// py:<module> (external or stdlib); self-references resolve to "".
+func (m pyModules) resolve(from, raw string) string {
+	spec, ok := strings.CutPrefix(raw, "py:")
+	if !ok {
+		return raw
+	}
+	mod, name, hasName := strings.Cut(spec, ":")
+	importer := strings.TrimPrefix(from, "py:")
+	out, found := "", false
+	if hasName {
+		out, found = m.lookup(importer, mod+"."+name)
+	}
+	if !found {
+		if out, found = m.lookup(importer, mod); !found {
+			out = "py:" + mod
+		}
+	}
+	if out == from {
+		return ""
+	}
+	return out
+}
+
+func (m pyModules) lookup(importer, mod string) (string, bool) {
+	if m[mod] {
+		return "py:" + mod, true
+	}
+	var matches []string
+	for known := range m {
+		if strings.HasSuffix(known, "."+mod) {
+			matches = append(matches, known)
+		}
+	}
+	if len(matches) == 0 {
+		return "", false
+	}
+	sort.Strings(matches)
+	best, bestRoot := "", -1
+	for _, known := range matches {
+		root := strings.TrimSuffix(known, "."+mod)
+		if strings.HasPrefix(importer, root+".") && len(root) > bestRoot {
+			best, bestRoot = known, len(root)
+		}
+	}
+	if best == "" {
+		if len(matches) > 1 {
+			return "", false
+		}
+		best = matches[0]
+	}
+	return "py:" + best, true
+}
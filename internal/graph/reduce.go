@@ -0,0 +1,128 @@
+package graph
+
+// TransitiveReduction drops every edge u→v for which v is still reachable
+// from u through other edges, so reachability is unchanged. Cycles have no
+// unique reduction: edges inside a strongly connected component are kept and
+// only edges between components are reduced (on the condensation, which is a
+// DAG; several edges linking the same two components are all kept). Nodes,
+// their order, Dropped and NodeKinds are preserved; kept edges stay in their
+// original order, so the result is deterministic.
+func TransitiveReduction(g Graph) Graph {
+	comp := components(g)
+	n := 0
+	for _, c := range comp {
+		n = max(n, c+1)
+	}
+
+	// Condensation successors, deduplicated.
+	succ := make([]map[int]struct{}, n)
+	for i := range succ {
+		succ[i] = map[int]struct{}{}
+	}
+	for _, e := range g.Edges {
+		if a, b := comp[e[0]], comp[e[1]]; a != b {
+			succ[a][b] = struct{}{}
+		}
+	}
+
+	// reach[c] holds the components reachable from c by a non-empty path.
+	reach := make([]map[int]struct{}, n)
+	var visit func(c int) map[int]struct{}
+	visit = func(c int) map[int]struct{} {
+		if reach[c] != nil {
+			return reach[c]
+		}
+		r := map[int]struct{}{}
+		reach[c] = r // the condensation is acyclic, so no re-entry
+		for s := range succ[c] {
+			r[s] = struct{}{}
+			for t := range visit(s) {
+				r[t] = struct{}{}
+			}
+		}
+		return r
+	}
+
+	// redundant reports whether b is reachable from a via another successor.
+	redundant := func(a, b int) bool {
+		for s := range succ[a] {
+			if s == b {
+				continue
+			}
+			if _, ok := visit(s)[b]; ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	out := Graph{Nodes: g.Nodes, Dropped: g.Dropped, NodeKinds: g.NodeKinds, Files: g.Files, Calls: g.Calls, Edges: make([][2]string, 0, len(g.Edges))}
+	for _, e := range g.Edges {
+		if a, b := comp[e[0]], comp[e[1]]; a != b && redundant(a, b) {
+			continue
+		}
+		out.Edges = append(out.Edges, e)
+	}
+	return out
+}
+
+// components assigns each node (including edge endpoints missing from
+// Nodes) the index of its strongly connected component, using Tarjan's
+// algorithm over nodes in Nodes order.
+func components(g Graph) map[string]int {
+	adj := map[string][]string{}
+	order := append([]string(nil), g.Nodes...)
+	seen := make(map[string]bool, len(g.Nodes))
+	for _, v := range g.Nodes {
+		seen[v] = true
+	}
+	for _, e := range g.Edges {
+		adj[e[0]] = append(adj[e[0]], e[1])
+		for _, v := range e {
+			if !seen[v] {
+				seen[v] = true
+				order = append(order, v)
+			}
+		}
+	}
+
+	comp := make(map[string]int, len(order))
+	idx := make(map[string]int, len(order))
+	low := make(map[string]int, len(order))
+	onStack := map[string]bool{}
+	var stack []string
+	next, ncomp := 0, 0
+	var strong func(v string)
+	strong = func(v string) {
+		idx[v], low[v] = next, next
+		next++
+		stack = append(stack, v)
+		onStack[v] = true
+		for _, w := range adj[v] {
+			if _, ok := idx[w]; !ok {
+				strong(w)
+				low[v] = min(low[v], low[w])
+			} else if onStack[w] {
+				low[v] = min(low[v], idx[w])
+			}
+		}
+		if low[v] == idx[v] {
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				comp[w] = ncomp
+				if w == v {
+					break
+				}
+			}
+			ncomp++
+		}
+	}
+	for _, v := range order {
+		if _, ok := idx[v]; !ok {
+			strong(v)
+		}
+	}
+	return comp
+}
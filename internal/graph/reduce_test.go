@@ -0,0 +1,58 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTransitiveReductionDropsImpliedEdge(t *testing.T) {
+	g := Graph{
+		Nodes: []string{"A", "B", "C", "D"},
+		Edges: [][2]string{{"A", "B"}, {"A", "C"}, {"A", "D"}, {"B", "C"}, {"C", "D"}},
+	}
+	got := TransitiveReduction(g)
+	want := [][2]string{{"A", "B"}, {"B", "C"}, {"C", "D"}}
+	if !reflect.DeepEqual(got.Edges, want) || !reflect.DeepEqual(got.Nodes, g.Nodes) {
+		t.Fatalf("got %v, want edges %v", got, want)
+	}
+	if !reflect.DeepEqual(TransitiveReduction(g), got) {
+		t.Fatalf("reduction is not deterministic")
+	}
+}
+
+func TestTransitiveReductionKeepsCyclesAndReachability(t *testing.T) {
+	// B and C form a cycle whose edges stay; A→D is implied by A→B→C→D.
+	g := Graph{
+		Nodes: []string{"A", "B", "C", "D"},
+		Edges: [][2]string{{"A", "B"}, {"A", "D"}, {"B", "C"}, {"C", "B"}, {"C", "D"}},
+	}
+	got := TransitiveReduction(g)
+	want := [][2]string{{"A", "B"}, {"B", "C"}, {"C", "B"}, {"C", "D"}}
+	if !reflect.DeepEqual(got.Edges, want) {
+		t.Fatalf("edges = %v, want %v", got.Edges, want)
+	}
+	if !reflect.DeepEqual(reachable(g), reachable(got)) {
+		t.Fatalf("reachability changed")
+	}
+}
+
+func reachable(g Graph) map[[2]string]bool {
+	adj := map[string][]string{}
+	for _, e := range g.Edges {
+		adj[e[0]] = append(adj[e[0]], e[1])
+	}
+	out := map[[2]string]bool{}
+	for _, src := range g.Nodes {
+		stack := append([]string(nil), adj[src]...)
+		for len(stack) > 0 {
+			v := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if out[[2]string{src, v}] {
+				continue
+			}
+			out[[2]string{src, v}] = true
+			stack = append(stack, adj[v]...)
+		}
+	}
+	return out
+}
@@ -0,0 +1,311 @@
+package graph
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// --- Rust scanning -----------------------------------------------------------
+
+// A Rust file's node is rs:<crate>::<module path>. The crate is the
+// directory holding src/ (its name with '-' as '_', or "crate" at the project
+// root); src/lib.rs, src/main.rs and mod.rs files stand for their directory's
+// module. "use" trees are expanded, and crate::, self:: and super:: paths
+// are made absolute while scanning; rsModules.resolve then maps each path
+// to the deepest scanned module it names. Other paths name a child module
+// of the importer or, failing that, an external crate (rs:<crate>).
+
+var (
+	reRsUse    = regexp.MustCompile(`(?:^|[;{})\s])use\s+([^;]+);`)
+	reRsMod    = regexp.MustCompile(`(?:^|[;{})\s])mod\s+([A-Za-z_]\w*)\s*;`)
+	reRsExtern = regexp.MustCompile(`\bextern\s+crate\s+([A-Za-z_]\w*)`)
+	reRsAlias  = regexp.MustCompile(`\s+as\s+[A-Za-z_]\w*`)
+)
+
+// rsCrateDirs mark the non-src/ target directories of a Cargo package.
+var rsCrateDirs = map[string]bool{"tests": true, "examples": true, "benches": true}
+
+// RsNode returns the graph node of the Rust file rel.
+func RsNode(rel string) string {
+	crate, mods := rsModulePath(filepath.ToSlash(rel))
+	return "rs:" + strings.Join(append([]string{crate}, mods...), "::")
+}
+
+// rsModulePath splits rel into its crate name and module path.
+func rsModulePath(rel string) (crate string, mods []string) {
+	parts := strings.Split(strings.Trim(rel, "/"), "/")
+	file := strings.TrimSuffix(parts[len(parts)-1], filepath.Ext(rel))
+	dirs := parts[:len(parts)-1]
+	crateEnd, modStart := 0, 0
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if dirs[i] == "src" {
+			crateEnd, modStart = i, i+1
+			break
+		}
+		if rsCrateDirs[dirs[i]] {
+			crateEnd, modStart = i, i
+			break
+		}
+	}
+	crate = "crate"
+	if crateEnd > 0 {
+		crate = strings.ReplaceAll(dirs[crateEnd-1], "-", "_")
+	}
+	mods = append(mods, dirs[modStart:]...)
+	inSrc := modStart > 0 && dirs[modStart-1] == "src"
+	if file != "mod" && !(inSrc && len(mods) == 0 && (file == "lib" || file == "main")) {
+		mods = append(mods, file)
+	}
+	return crate, mods
+}
+
+// scanRust returns the sorted raw imports of a Rust file: "rs:<path>" for
+// every leaf of its use trees, each "mod name;" child and each extern crate.
+func scanRust(rel string, data []byte) (node string, imports []string) {
+	crate, mods := rsModulePath(filepath.ToSlash(rel))
+	self := append([]string{crate}, mods...)
+	node = "rs:" + strings.Join(self, "::")
+	code := rsCode(data)
+	set := map[string]struct{}{}
+	add := func(p []string) {
+		if len(p) > 0 {
+			set["rs:"+strings.Join(p, "::")] = struct{}{}
+		}
+	}
+	for _, m := range reRsUse.FindAllStringSubmatch(code, -1) {
+		for _, p := range expandUseTree(reRsAlias.ReplaceAllString(m[1], "")) {
+			add(rsAbsolute(p, crate, self))
+		}
+	}
+	for _, m := range reRsMod.FindAllStringSubmatch(code, -1) {
+		add(append(append([]string{}, self...), m[1]))
+	}
+	for _, m := range reRsExtern.FindAllStringSubmatch(code, -1) {
+		add([]string{m[1]})
+	}
+	return node, setToSortedSlice(set)
+}
+
+// rsAbsolute resolves the crate::, self:: and super:: prefixes of a use path
+// against the importing module self; other paths are returned as-is. A
+// trailing glob is dropped.
+func rsAbsolute(p []string, crate string, self []string) []string {
+	if len(p) > 0 && p[len(p)-1] == "*" {
+		p = p[:len(p)-1]
+	}
+	if len(p) == 0 {
+		return nil
+	}
+	switch p[0] {
+	case "crate":
+		return append([]string{crate}, p[1:]...)
+	case "self", "super":
+		base := append([]string{}, self...)
+		for ; len(p) > 0 && (p[0] == "self" || p[0] == "super"); p = p[1:] {
+			if p[0] == "super" && len(base) > 1 {
+				base = base[:len(base)-1]
+			}
+		}
+		return append(base, p...)
+	}
+	return p
+}
+
+// expandUseTree flattens a use tree ("a::{b, c::{d, self}}") into its
+// paths ([a b], [a c d], [a c]). Whitespace and a leading "::" are ignored.
+func expandUseTree(tree string) [][]string {
+	tree = strings.Join(strings.Fields(tree), "")
+	tree = strings.TrimPrefix(tree, "::")
+	var out [][]string
+	var walk func(prefix []string, s string)
+	walk = func(prefix []string, s string) {
+		open := strings.IndexByte(s, '{')
+		if open < 0 {
+			path := append(append([]string{}, prefix...), splitRsPath(s)...)
+			if n := len(path); n > 0 && path[n-1] == "self" {
+				path = path[:n-1]
+			}
+			out = append(out, path)
+			return
+		}
+		closeAt := strings.LastIndexByte(s, '}')
+		if closeAt < open {
+			return
+		}
+		head := append(append([]string{}, prefix...), splitRsPath(strings.TrimSuffix(s[:open], "::"))...)
+		depth, start := 0, open+1
+		for i := open + 1; i <= closeAt; i++ {
+			switch {
+			case s[i] == '{':
+				depth++
+			case s[i] == '}' && depth > 0:
+				depth--
+			case (s[i] == ',' && depth == 0) || i == closeAt:
+				if item := s[start:i]; item != "" {
+					walk(head, item)
+				}
+				start = i + 1
+			}
+		}
+	}
+	walk(nil, tree)
+	return out
+}
+
+func splitRsPath(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "::")
+}
+
+// rsCode returns data with comments (nested block comments included),
+// string, raw string and char literals replaced by spaces, so the regexes
+// only see code. Lifetimes ('a) are kept.
+func rsCode(data []byte) string {
+	out := []byte(string(data))
+	blank := func(from, to int) {
+		for i := from; i < to && i < len(out); i++ {
+			if out[i] != '\n' {
+				out[i] = ' '
+			}
+		}
+	}
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch {
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			end := i
+			for end < len(data) && data[end] != '\n' {
+				end++
+			}
+			blank(i, end)
+			i = end
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			end, depth := i+2, 1
+			for end < len(data) && depth > 0 {
+				switch {
+				case data[end] == '/' && end+1 < len(data) && data[end+1] == '*':
+					depth++
+					end += 2
+				case data[end] == '*' && end+1 < len(data) && data[end+1] == '/':
+					depth--
+					end += 2
+				default:
+					end++
+				}
+			}
+			blank(i, end)
+			i = end
+		case c == 'r' && i+1 < len(data) && (data[i+1] == '"' || data[i+1] == '#') && (i == 0 || !isCallIdentPart(data[i-1])):
+			j := i + 1
+			for j < len(data) && data[j] == '#' {
+				j++
+			}
+			if j >= len(data) || data[j] != '"' {
+				i++
+				continue
+			}
+			closing := "\"" + strings.Repeat("#", j-i-1)
+			end := strings.Index(string(data[j+1:]), closing)
+			if end < 0 {
+				end = len(data)
+			} else {
+				end += j + 1 + len(closing)
+			}
+			blank(i, end)
+			i = end
+		case c == '"':
+			end := i + 1
+			for end < len(data) && data[end] != '"' {
+				if data[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			blank(i, end+1)
+			i = end + 1
+		case c == '\'':
+			// A char literal closes within a few bytes ('x', '\n', '\u{1F600}');
+			// otherwise this is a lifetime.
+			end := i + 1
+			if end < len(data) && data[end] == '\\' {
+				end += 2
+				for end < len(data) && end-i < 12 && data[end] != '\'' {
+					end++
+				}
+			} else if end+1 < len(data) && data[end+1] == '\'' {
+				end++
+			}
+			if end < len(data) && data[end] == '\'' && end > i+1 {
+				blank(i, end+1)
+				i = end + 1
+			} else {
+				i++
+			}
+		default:
+			i++
+		}
+	}
+	return string(out)
+}
+
+// rsModules indexes the modules of the scanned Rust files for resolving
+// use paths.
+type rsModules struct {
+	mods   map[string]bool
+	crates map[string]bool
+}
+
+func (m *rsModules) add(node string) {
+	mod, ok := strings.CutPrefix(node, "rs:")
+	if !ok {
+		return
+	}
+	if m.mods == nil {
+		m.mods, m.crates = map[string]bool{}, map[string]bool{}
+	}
+	m.mods[mod] = true
+	crate, _, _ := strings.Cut(mod, "::")
+	m.crates[crate] = true
+}
+
+// resolve maps a raw rs: path of the file whose node is from to the deepest
+// scanned module it names. Paths into a scanned crate fall back to the
+// crate root, other paths are tried as children of the importer and then
+// become an external crate node. Self-references resolve to "".
+func (m *rsModules) resolve(from, raw string) string {
+	p, ok := strings.CutPrefix(raw, "rs:")
+	if !ok {
+		return raw
+	}
+	self := strings.TrimPrefix(from, "rs:")
+	parts := strings.Split(p, "::")
+	out := ""
+	switch {
+	case m.crates[parts[0]]:
+		out = "rs:" + parts[0]
+		if n := m.longest(parts); n != "" {
+			out = n
+		}
+	case m.mods[self+"::"+parts[0]]:
+		out = m.longest(append(strings.Split(self, "::"), parts...))
+	default:
+		out = "rs:" + parts[0]
+	}
+	if out == from {
+		return ""
+	}
+	return out
+}
+
+// longest returns the node of the longest scanned module prefix of parts.
+func (m *rsModules) longest(parts []string) string {
+	for n := len(parts); n > 0; n-- {
+		if mod := strings.Join(parts[:n], "::"); m.mods[mod] {
+			return "rs:" + mod
+		}
+	}
+	return ""
+}
@@ -0,0 +1,105 @@
+package graph
+
+import (
+	"regexp"
+	"sort"
+)
+
+// --- Terraform scanning ------------------------------------------------------
+
+// Terraform files have no single source node: every resource, data and
+// module block is its own node (tf:<type>.<name>, tf:data.<type>.<name>,
+// tf:module.<name>) with edges to the addresses its body references. Resolution
+// is heuristic: references are read from unquoted expressions and "${...}"
+// interpolations, and a bare <type>.<name> only counts when <type> looks like
+// a provider resource type (contains an underscore), which keeps count.index,
+// each.key, path.module and similar out.
+
+var (
+	reTFBlock = regexp.MustCompile(`(?m)^[ \t]*(?:(resource|data)[ \t]+"([^"]+)"[ \t]+"([^"]+)"|(module)[ \t]+"([^"]+)")[ \t]*\{`)
+	reTFRef   = regexp.MustCompile(`\b(?:data\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+|(?:module|var|local)\.[A-Za-z0-9_-]+|[a-z][a-z0-9]*_[a-z0-9_]+\.[A-Za-z_][A-Za-z0-9_-]*)`)
+)
+
+// scanTerraform returns the sorted, deduplicated block-to-reference edges of
+// one .tf file.
+func scanTerraform(data []byte) [][2]string {
+	set := map[[2]string]struct{}{}
+	for _, m := range reTFBlock.FindAllSubmatchIndex(data, -1) {
+		var addr string
+		switch {
+		case m[2] >= 0 && string(data[m[2]:m[3]]) == "data":
+			addr = "data." + string(data[m[4]:m[5]]) + "." + string(data[m[6]:m[7]])
+		case m[2] >= 0:
+			addr = string(data[m[4]:m[5]]) + "." + string(data[m[6]:m[7]])
+		default:
+			addr = "module." + string(data[m[10]:m[11]])
+		}
+		from := "tf:" + addr
+		for _, ref := range reTFRef.FindAll(tfExpressions(data, m[1]), -1) {
+			if to := "tf:" + string(ref); to != from {
+				set[[2]string{from, to}] = struct{}{}
+			}
+		}
+	}
+	edges := make([][2]string, 0, len(set))
+	for e := range set {
+		edges = append(edges, e)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i][0] == edges[j][0] {
+			return edges[i][1] < edges[j][1]
+		}
+		return edges[i][0] < edges[j][0]
+	})
+	return edges
+}
+
+// tfExpressions returns the body of the block whose opening brace ends just
+// before data[start], keeping only code outside string literals plus the
+// "${...}" interpolations inside them; comments are dropped. An unterminated
+// block runs to the end of data.
+func tfExpressions(data []byte, start int) []byte {
+	var out []byte
+	depth := 1
+	for i := start; i < len(data) && depth > 0; i++ {
+		c := data[i]
+		switch {
+		case c == '#' || (c == '/' && i+1 < len(data) && data[i+1] == '/'):
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			out = append(out, '\n')
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			for i += 2; i+1 < len(data) && !(data[i] == '*' && data[i+1] == '/'); i++ {
+			}
+			i++
+			out = append(out, ' ')
+		case c == '"':
+			for i++; i < len(data) && data[i] != '"' && data[i] != '\n'; i++ {
+				switch {
+				case data[i] == '\\':
+					i++
+				case data[i] == '$' && i+1 < len(data) && data[i+1] == '{':
+					end := i + 2
+					for end < len(data) && data[end] != '}' && data[end] != '"' {
+						end++
+					}
+					out = append(out, ' ')
+					out = append(out, data[i+2:end]...)
+					out = append(out, ' ')
+					i = end - 1
+				}
+			}
+			out = append(out, ' ')
+		default:
+			switch c {
+			case '{':
+				depth++
+			case '}':
+				depth--
+			}
+			out = append(out, c)
+		}
+	}
+	return out
+}
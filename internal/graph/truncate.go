@@ -0,0 +1,60 @@
+package graph
+
+import "sort"
+
+// Degrees returns the undirected degree (incident edge count) of every node.
+// Nodes without edges are present with degree 0.
+func Degrees(g Graph) map[string]int {
+	deg := make(map[string]int, len(g.Nodes))
+	for _, n := range g.Nodes {
+		deg[n] += 0
+	}
+	for _, e := range g.Edges {
+		deg[e[0]]++
+		deg[e[1]]++
+	}
+	return deg
+}
+
+// Truncate keeps at most maxNodes nodes, preferring the highest-degree ones
+// (ties broken by node name), and only the edges between kept nodes. The
+// number of removed nodes is added to Dropped. Calls are kept as they are.
+// maxNodes <= 0 disables the cap.
+func Truncate(g Graph, maxNodes int) Graph {
+	if maxNodes <= 0 || len(g.Nodes) <= maxNodes {
+		return g
+	}
+	deg := Degrees(g)
+	ranked := append([]string(nil), g.Nodes...)
+	sort.Slice(ranked, func(i, j int) bool {
+		if deg[ranked[i]] != deg[ranked[j]] {
+			return deg[ranked[i]] > deg[ranked[j]]
+		}
+		return ranked[i] < ranked[j]
+	})
+	keep := make(map[string]struct{}, maxNodes)
+	for _, n := range ranked[:maxNodes] {
+		keep[n] = struct{}{}
+	}
+
+	out := Graph{Dropped: g.Dropped + len(g.Nodes) - maxNodes, Files: g.Files, Calls: g.Calls}
+	for _, n := range g.Nodes {
+		if _, ok := keep[n]; ok {
+			out.Nodes = append(out.Nodes, n)
+			if k, ok := g.NodeKinds[n]; ok {
+				if out.NodeKinds == nil {
+					out.NodeKinds = make(map[string]string, maxNodes)
+				}
+				out.NodeKinds[n] = k
+			}
+		}
+	}
+	for _, e := range g.Edges {
+		_, a := keep[e[0]]
+		_, b := keep[e[1]]
+		if a && b {
+			out.Edges = append(out.Edges, e)
+		}
+	}
+	return out
+}
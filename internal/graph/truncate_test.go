@@ -0,0 +1,29 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTruncateKeepsTopDegree(t *testing.T) {
+	g := Graph{
+		Nodes: []string{"a", "b", "c", "d", "e"},
+		Edges: [][2]string{{"a", "b"}, {"a", "c"}, {"a", "d"}, {"b", "c"}, {"d", "e"}},
+	}
+	// degrees: a=3, b=2, c=2, d=2, e=1 → keep a plus the tie-broken b, c.
+	got := Truncate(g, 3)
+	want := Graph{
+		Nodes:   []string{"a", "b", "c"},
+		Edges:   [][2]string{{"a", "b"}, {"a", "c"}, {"b", "c"}},
+		Dropped: 2,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Truncate = %+v, want %+v", got, want)
+	}
+	if again := Truncate(g, 3); !reflect.DeepEqual(again, got) {
+		t.Fatalf("truncation not deterministic: %+v vs %+v", again, got)
+	}
+	if same := Truncate(g, 0); !reflect.DeepEqual(same, g) {
+		t.Fatalf("cap 0 should keep graph unchanged")
+	}
+}
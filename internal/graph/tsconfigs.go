@@ -0,0 +1,93 @@
+package graph
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// tsResolvers maps TS/JS files to the resolver of their nearest enclosing
+// tsconfig.json (searching up to the project root), so each package of a
+// monorepo resolves its own paths aliases. Configs are loaded once per
+// directory.
+type tsResolvers struct {
+	rootAbs string
+	nearest map[string]*tsResolver // absolute dir -> resolver in effect (nil: none)
+	sums    map[string]string      // root-relative tsconfig path -> sha256 of content
+}
+
+func newTsResolvers(rootAbs string) *tsResolvers {
+	return &tsResolvers{
+		rootAbs: rootAbs,
+		nearest: map[string]*tsResolver{},
+		sums:    map[string]string{},
+	}
+}
+
+// forFile returns the resolver for f, or nil outside TS/JS or without a
+// tsconfig.json between f and the root.
+func (s *tsResolvers) forFile(f File) *tsResolver {
+	if s.rootAbs == "" || f.AbsPath == "" || !isTSJS(f.Ext) {
+		return nil
+	}
+	return s.forDir(filepath.Dir(f.AbsPath))
+}
+
+func (s *tsResolvers) forDir(dir string) *tsResolver {
+	if r, ok := s.nearest[dir]; ok {
+		return r
+	}
+	var r *tsResolver
+	rel, err := filepath.Rel(s.rootAbs, dir)
+	inside := err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+	if inside {
+		if b, err := os.ReadFile(filepath.Join(dir, "tsconfig.json")); err == nil {
+			dirRel := filepath.ToSlash(rel)
+			if dirRel == "." {
+				dirRel = ""
+			}
+			sum := sha256.Sum256(b)
+			s.sums[filepath.ToSlash(filepath.Join(rel, "tsconfig.json"))] = hex.EncodeToString(sum[:])
+			r, _ = loadTsResolver(s.rootAbs, dirRel, b)
+		} else if dir != s.rootAbs {
+			if parent := filepath.Dir(dir); parent != dir {
+				r = s.forDir(parent)
+			}
+		}
+	}
+	s.nearest[dir] = r
+	return r
+}
+
+// fingerprint resolves every TS/JS file in files and returns a digest of the
+// tsconfig.json files in effect ("" when there are none); any edit to one of
+// them changes it.
+func (s *tsResolvers) fingerprint(files []File) string {
+	for _, f := range files {
+		s.forFile(f)
+	}
+	if len(s.sums) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(s.sums))
+	for k := range s.sums {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, k := range keys {
+		h.Write([]byte(k + "\x00" + s.sums[k] + "\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func isTSJS(ext string) bool {
+	switch strings.ToLower(ext) {
+	case ".ts", ".tsx", ".js":
+		return true
+	}
+	return false
+}
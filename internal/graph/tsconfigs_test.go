@@ -0,0 +1,78 @@
+package graph
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBuildFromResolvesPerPackageTsconfigPaths(t *testing.T) {
+	dir := t.TempDir()
+	sources := map[string]string{
+		"tools.ts":                     "export {};\n",
+		"packages/a/tsconfig.json":     `{"compilerOptions": {"baseUrl": ".", "paths": {"@a/*": ["src/*"]}}}`,
+		"packages/a/src/util.ts":       "export const u = 1;\n",
+		"packages/a/src/main.ts":       "import { u } from '@a/util';\n",
+		"packages/b/tsconfig.json":     `{"compilerOptions": {"paths": {"@b/*": ["lib/*"]}}}`,
+		"packages/b/lib/helper.ts":     "export const h = 1;\n",
+		"packages/b/src/deep/index.ts": "import { h } from '@b/helper';\nimport { u } from '@a/util';\n",
+	}
+	var files []File
+	for rel, body := range sources {
+		abs := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(abs), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(abs, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if filepath.Ext(rel) == ".ts" {
+			files = append(files, File{RelPath: rel, AbsPath: abs, Ext: ".ts"})
+		}
+	}
+
+	g := BuildFrom(files)
+	want := [][2]string{
+		{"js:packages/a/src/main", "js:packages/a/src/util"},
+		{"js:packages/b/src/deep/index", "js:packages/b/lib/helper"},
+		{"js:packages/b/src/deep/index", "npm:@a/util"},
+	}
+	if !reflect.DeepEqual(g.Edges, want) {
+		t.Fatalf("edges = %v, want %v", g.Edges, want)
+	}
+
+	// Editing a nested tsconfig must change the import cache fingerprint.
+	before := newTsResolvers(dir).fingerprint(files)
+	if err := os.WriteFile(filepath.Join(dir, "packages/b/tsconfig.json"), []byte(`{"compilerOptions": {}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if after := newTsResolvers(dir).fingerprint(files); after == before || before == "" {
+		t.Fatalf("fingerprint did not track nested tsconfig: %q -> %q", before, after)
+	}
+}
+
+func TestLoadTsResolverAcceptsJSONC(t *testing.T) {
+	cfg := []byte(`{
+  // Base options shared by all packages.
+  "compilerOptions": {
+    "baseUrl": "./src", /* resolved from this file */
+    "paths": {
+      "@app/*": ["app/*",],   // trailing comma in array
+      "~//*": ["lib/*"],
+    },
+  },
+}
+`)
+	r, err := loadTsResolver(t.TempDir(), "", cfg)
+	if err != nil {
+		t.Fatalf("loadTsResolver error: %v", err)
+	}
+	if r.baseURL != "./src" {
+		t.Fatalf("baseURL = %q", r.baseURL)
+	}
+	want := [][2]string{{"@app/*", "app/*"}, {"~//*", "lib/*"}}
+	if !reflect.DeepEqual(r.patterns, want) {
+		t.Fatalf("patterns = %v, want %v", r.patterns, want)
+	}
+}
@@ -13,7 +13,8 @@
 //   - Block markers: "/* region: DOC_BLOCK_MARKER_EXAMPLE */" | "/* endregion: DOC_BLOCK_MARKER_EXAMPLE */"
 //
 // Features:
-//   - Nested regions are supported, even with identical names (a stack per name).
+//   - Nested regions are supported, even with identical names (a stack per name),
+//     up to SetRegionLimits' depth; deeper regions are ignored with a warning.
 //   - Overlapping detection is not enforced; we trust author intent.
 //   - Duplicates from multiple syntaxes (e.g., both line and block) are de-duped.
 //   - Deterministic output sorted by (Start, End).
@@ -24,6 +25,8 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+
+	"class-collector/internal/warn"
 )
 
 // Anchor is expected to be defined in this package:
@@ -46,6 +49,26 @@ var (
 	reBlock = regexp.MustCompile(`(?is)/\*\s*(region|endregion)\s*:?\s*([A-Za-z0-9_.\-]+)\s*\*/`)
 )
 
+// Default bounds for explicit regions (see SetRegionLimits).
+const (
+	DefaultRegionMaxDepth   = 32
+	DefaultRegionMaxPerFile = 1024
+)
+
+var (
+	regionMaxDepth   = DefaultRegionMaxDepth
+	regionMaxPerFile = DefaultRegionMaxPerFile
+)
+
+// SetRegionLimits bounds explicit region anchors: regions opened while
+// maxDepth regions of the same marker syntax are already open are ignored
+// (with their end markers), and at most maxPerFile anchors are kept per file,
+// earliest first. Both emit a truncated warning; 0 disables a limit. Auto
+// anchors have their own cap (AutoAnchorConfig.MaxPerFile).
+func SetRegionLimits(maxDepth, maxPerFile int) {
+	regionMaxDepth, regionMaxPerFile = maxDepth, maxPerFile
+}
+
 // ExtractAnchors orchestrates parsing, normalization, and deduplication.
 func ExtractAnchors(path string, data []byte) []Anchor {
 	raw, _ := parseAnchorsFromFile(path, data)
@@ -68,13 +91,29 @@ func ExtractAnchors(path string, data []byte) []Anchor {
 		}
 		return merged[i].Name < merged[j].Name
 	})
+	if regionMaxPerFile > 0 && len(merged) > regionMaxPerFile {
+		warn.Add(warn.KindTruncated, path, "%d region anchors, keeping the first %d", len(merged), regionMaxPerFile)
+		merged = merged[:regionMaxPerFile]
+	}
 	return merged
 }
 
-func parseAnchorsFromFile(_ string, data []byte) ([]Anchor, error) {
+// parseAnchorsFromFile pairs region markers into anchors. Starts pushed
+// beyond regionMaxDepth are recorded as ignored (negative line / off -1) so
+// their end markers are consumed without producing an anchor.
+func parseAnchorsFromFile(path string, data []byte) ([]Anchor, error) {
 	var anchors []Anchor
+	ignored := 0
+	tooDeep := func(depth int) bool {
+		if regionMaxDepth > 0 && depth >= regionMaxDepth {
+			ignored++
+			return true
+		}
+		return false
+	}
 
 	startsByName := make(map[string][]int)
+	depth := 0
 	lines := bytes.Split(data, []byte("\n"))
 	for i, b := range lines {
 		ln := i + 1
@@ -85,12 +124,21 @@ func parseAnchorsFromFile(_ string, data []byte) ([]Anchor, error) {
 			}
 			switch strings.ToLower(kind) {
 			case "region":
+				if tooDeep(depth) {
+					startsByName[name] = append(startsByName[name], -ln)
+					continue
+				}
+				depth++
 				startsByName[name] = append(startsByName[name], ln)
 			case "endregion":
 				stack := startsByName[name]
 				if n := len(stack); n > 0 {
 					start := stack[n-1]
 					startsByName[name] = stack[:n-1]
+					if start < 0 {
+						continue
+					}
+					depth--
 					if start <= ln {
 						anchors = append(anchors, Anchor{Name: name, Start: start, End: ln})
 					}
@@ -104,6 +152,7 @@ func parseAnchorsFromFile(_ string, data []byte) ([]Anchor, error) {
 		off  int
 	}
 	var opens []open
+	skipped := 0 // ignored entries in opens
 	matches := reBlock.FindAllSubmatchIndex(data, -1)
 	for _, m := range matches {
 		kind := strings.ToLower(string(data[m[2]:m[3]]))
@@ -113,9 +162,19 @@ func parseAnchorsFromFile(_ string, data []byte) ([]Anchor, error) {
 		}
 		switch kind {
 		case "region":
-			opens = append(opens, open{name: name, off: m[0]})
+			off := m[0]
+			if tooDeep(len(opens) - skipped) {
+				off = -1
+				skipped++
+			}
+			opens = append(opens, open{name: name, off: off})
 		case "endregion":
 			for j := len(opens) - 1; j >= 0; j-- {
+				if opens[j].name == name && opens[j].off < 0 {
+					skipped--
+					opens = append(opens[:j], opens[j+1:]...)
+					break
+				}
 				if opens[j].name == name {
 					startLine := 1 + bytes.Count(data[:opens[j].off], []byte("\n"))
 					endLine := 1 + bytes.Count(data[:m[1]], []byte("\n"))
@@ -128,6 +187,9 @@ func parseAnchorsFromFile(_ string, data []byte) ([]Anchor, error) {
 			}
 		}
 	}
+	if ignored > 0 {
+		warn.Add(warn.KindTruncated, path, "%d regions nested deeper than %d ignored", ignored, regionMaxDepth)
+	}
 	return anchors, nil
 }
 
@@ -1,6 +1,13 @@
 package index
 
-import "testing"
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"class-collector/internal/warn"
+)
 
 func TestParseAnchorsFromFileFindsLineAndBlock(t *testing.T) {
 	data := []byte(`// region FOO
@@ -32,3 +39,56 @@ func TestMergeAnchorsDedupsExactMatches(t *testing.T) {
 		t.Fatalf("unexpected anchors: %#v", out)
 	}
 }
+
+func TestExtractAnchorsNestingDepthLimit(t *testing.T) {
+	SetRegionLimits(3, 0)
+	defer SetRegionLimits(DefaultRegionMaxDepth, DefaultRegionMaxPerFile)
+	c := &warn.Collector{}
+	warn.Use(c)
+	defer warn.Use(nil)
+
+	// Five nested line regions (R1 outermost) and five nested block regions,
+	// all named X for the block form so only the stack order pairs them.
+	var b strings.Builder
+	for i := 1; i <= 5; i++ {
+		fmt.Fprintf(&b, "// region R%d\n", i)
+	}
+	for i := 5; i >= 1; i-- {
+		fmt.Fprintf(&b, "// endregion R%d\n", i)
+	}
+	for i := 0; i < 5; i++ {
+		b.WriteString("/* region: X */\n")
+	}
+	for i := 0; i < 5; i++ {
+		b.WriteString("/* endregion: X */\n")
+	}
+
+	got := ExtractAnchors("deep.go", []byte(b.String()))
+	want := []Anchor{
+		{Name: "R1", Start: 1, End: 10},
+		{Name: "R2", Start: 2, End: 9},
+		{Name: "R3", Start: 3, End: 8},
+		{Name: "X", Start: 11, End: 20},
+		{Name: "X", Start: 12, End: 19},
+		{Name: "X", Start: 13, End: 18},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("anchors = %v, want %v", got, want)
+	}
+	ws := c.List()
+	if len(ws) != 1 || ws[0].Kind != warn.KindTruncated || ws[0].Path != "deep.go" || !strings.Contains(ws[0].Message, "4 regions nested deeper than 3") {
+		t.Fatalf("warnings = %+v", ws)
+	}
+}
+
+func TestExtractAnchorsMaxPerFile(t *testing.T) {
+	SetRegionLimits(0, 2)
+	defer SetRegionLimits(DefaultRegionMaxDepth, DefaultRegionMaxPerFile)
+
+	data := []byte("// region A\n// endregion A\n// region B\n// endregion B\n// region C\n// endregion C\n")
+	got := ExtractAnchors("many.go", data)
+	want := []Anchor{{Name: "A", Start: 1, End: 2}, {Name: "B", Start: 3, End: 4}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("anchors = %v, want %v", got, want)
+	}
+}
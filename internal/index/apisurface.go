@@ -0,0 +1,33 @@
+package index
+
+import "slices"
+
+// TagAPISurface marks files declaring at least the SetMinFileSymbols
+// threshold of symbols.
+const TagAPISurface = "api-surface"
+
+var minFileSymbols int
+
+// SetMinFileSymbols tags every file with n or more symbols as TagAPISurface
+// in ManFile.Tags (n <= 0 disables).
+func SetMinFileSymbols(n int) { minFileSymbols = n }
+
+// tagAPISurface counts symbols per path and tags the files that reach the
+// threshold.
+func tagAPISurface(files []ManFile, symbols []Symbol) {
+	if minFileSymbols <= 0 {
+		return
+	}
+	count := make(map[string]int, len(files))
+	for _, s := range symbols {
+		count[s.Path]++
+	}
+	for i := range files {
+		if count[files[i].Path] >= minFileSymbols && !slices.Contains(files[i].Tags, TagAPISurface) {
+			files[i].Tags = append(files[i].Tags, TagAPISurface)
+		}
+	}
+}
+
+// HasTag reports whether mf carries tag.
+func HasTag(mf ManFile, tag string) bool { return slices.Contains(mf.Tags, tag) }
@@ -0,0 +1,44 @@
+package index
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"class-collector/internal/walkwalk"
+)
+
+func TestMinFileSymbolsTagsAPISurface(t *testing.T) {
+	SetMinFileSymbols(3)
+	defer SetMinFileSymbols(0)
+
+	dir := t.TempDir()
+	sources := map[string]string{
+		"api.go":   "package demo\n\nfunc A() {}\n\nfunc B() {}\n\nfunc C() {}\n\nfunc D() {}\n\nfunc E() {}\n",
+		"small.go": "package demo\n\nfunc Only() {}\n",
+	}
+	var files []walkwalk.FileInfo
+	for name, body := range sources {
+		abs := filepath.Join(dir, name)
+		if err := os.WriteFile(abs, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, walkwalk.FileInfo{RelPath: name, AbsPath: abs, Ext: ".go"})
+	}
+	man, syms, _, _ := BuildArtifacts(dir, files, 500, nil)
+
+	count := map[string]int{}
+	for _, s := range syms.Symbols {
+		count[s.Path]++
+	}
+	if count["api.go"] != 5 || count["small.go"] != 1 {
+		t.Fatalf("symbol counts = %v, want api.go:5 small.go:1", count)
+	}
+	tagged := map[string]bool{}
+	for _, f := range man.Files {
+		tagged[f.Path] = HasTag(f, TagAPISurface)
+	}
+	if !tagged["api.go"] || tagged["small.go"] {
+		t.Fatalf("api-surface tags = %v, want only api.go", tagged)
+	}
+}
@@ -0,0 +1,146 @@
+package index
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"class-collector/internal/walkwalk"
+)
+
+// ArtifactCacheDir is the conventional subdirectory of the per-project cache
+// directory holding per-file artifact entries.
+const ArtifactCacheDir = "artifacts"
+
+// artifactCacheVersion is bumped whenever per-file artifacts change shape,
+// which invalidates previously persisted entries.
+const artifactCacheVersion = 3
+
+var artifactCache struct {
+	dir  string
+	salt string
+}
+
+// SetArtifactCache makes BuildArtifacts reuse the manifest entry, symbols,
+// slices and pointers persisted under dir for every file whose content hash,
+// path and indexing settings are unchanged, and persist fresh results there.
+// salt covers settings this package cannot see (such as the summarizer
+// command). Entries not used by a build are removed afterwards. "" disables.
+// Warnings raised while indexing a file are not repeated when it is reused.
+func SetArtifactCache(dir, salt string) {
+	artifactCache.dir, artifactCache.salt = dir, salt
+}
+
+// cachedArtifacts is the on-disk form of one file's fileArtifacts.
+type cachedArtifacts struct {
+	Version  int          `json:"version"`
+	Path     string       `json:"path"`
+	Manifest ManFile      `json:"manifest"`
+	Symbols  []Symbol     `json:"symbols,omitempty"`
+	Slices   []Slice      `json:"slices,omitempty"`
+	Pointers []Pointer    `json:"pointers,omitempty"`
+	Hygiene  *FileHygiene `json:"hygiene,omitempty"`
+	Stats    *LangStats   `json:"stats,omitempty"`
+}
+
+// artifactStore is the artifact cache of one BuildArtifacts call.
+type artifactStore struct {
+	dir      string
+	settings string
+
+	mu   sync.Mutex
+	used map[string]struct{}
+}
+
+// openArtifactStore returns the store for one build, or nil when caching is
+// off. The settings digest folds in every package knob that changes
+// per-file output.
+func openArtifactStore(maxFileLines int, langHints map[string]struct{}) *artifactStore {
+	if artifactCache.dir == "" {
+		return nil
+	}
+	// fmt prints maps with sorted keys, so the digest is deterministic.
+	settings := fmt.Sprintf("%d|%s|%s|%d|%v|%+v|%v|%v|%v|%v|%s|%v|%d|%d|%d|%v|%s",
+		artifactCacheVersion, ToolVersion, artifactCache.salt, maxFileLines, langHints,
+		autoCfg, langForExt, shebangDetect, excludeTestSymbols, slicesFromSymbols, slicesPrefer,
+		goIncludePrivate, symbolsMinConfidence, regionMaxDepth, regionMaxPerFile, summarizer != nil, parserMode)
+	return &artifactStore{dir: artifactCache.dir, settings: settings, used: map[string]struct{}{}}
+}
+
+// name returns the entry file name for f: its content hash plus a digest of
+// the path, hard-link aliases and settings, which artifacts also depend on.
+func (s *artifactStore) name(f walkwalk.FileInfo) string {
+	sum := sha256.Sum256([]byte(f.RelPath + "\x00" + strings.Join(f.Aliases, "\x00") + "\x00" + s.settings))
+	return f.SHA256Hex + "-" + hex.EncodeToString(sum[:8]) + ".json"
+}
+
+func (s *artifactStore) lookup(f walkwalk.FileInfo) (*fileArtifacts, bool) {
+	if s == nil || f.SHA256Hex == "" || f.Symlink != "" {
+		return nil, false
+	}
+	name := s.name(f)
+	s.mu.Lock()
+	s.used[name] = struct{}{}
+	s.mu.Unlock()
+	b, err := os.ReadFile(filepath.Join(s.dir, name))
+	if err != nil {
+		return nil, false
+	}
+	var e cachedArtifacts
+	if err := json.Unmarshal(b, &e); err != nil || e.Version != artifactCacheVersion || e.Path != f.RelPath {
+		return nil, false
+	}
+	e.Manifest.hygiene, e.Manifest.lineStats = e.Hygiene, e.Stats
+	return &fileArtifacts{manifest: e.Manifest, symbols: e.Symbols, slices: e.Slices, pointers: e.Pointers}, true
+}
+
+// store persists fa fail-soft: a cache that cannot be written only costs
+// the next build its reuse.
+func (s *artifactStore) store(f walkwalk.FileInfo, fa *fileArtifacts) {
+	if s == nil || fa == nil || f.SHA256Hex == "" || f.Symlink != "" {
+		return
+	}
+	b, err := json.Marshal(cachedArtifacts{
+		Version:  artifactCacheVersion,
+		Path:     f.RelPath,
+		Manifest: fa.manifest,
+		Symbols:  fa.symbols,
+		Slices:   fa.slices,
+		Pointers: fa.pointers,
+		Hygiene:  fa.manifest.hygiene,
+		Stats:    fa.manifest.lineStats,
+	})
+	if err != nil || os.MkdirAll(s.dir, 0o755) != nil {
+		return
+	}
+	path := filepath.Join(s.dir, s.name(f))
+	tmp := path + ".tmp"
+	if os.WriteFile(tmp, b, 0o644) != nil {
+		_ = os.Remove(tmp)
+		return
+	}
+	_ = os.Rename(tmp, path)
+}
+
+// prune removes entries the build did not ask for (deleted files, old
+// content, other settings).
+func (s *artifactStore) prune() {
+	if s == nil {
+		return
+	}
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if _, ok := s.used[e.Name()]; ok || e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		_ = os.Remove(filepath.Join(s.dir, e.Name()))
+	}
+}
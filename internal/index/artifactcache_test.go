@@ -0,0 +1,68 @@
+package index
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"class-collector/internal/walkwalk"
+)
+
+func TestArtifactCacheReusesUnchangedFiles(t *testing.T) {
+	root := t.TempDir()
+	cacheDir := filepath.Join(t.TempDir(), ArtifactCacheDir)
+	SetArtifactCache(cacheDir, "")
+	defer SetArtifactCache("", "")
+
+	write := func(name, body string) walkwalk.FileInfo {
+		t.Helper()
+		abs := filepath.Join(root, name)
+		if err := os.WriteFile(abs, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		// The hash is what the cache keys on; it is faked here so a reused
+		// entry is distinguishable from a fresh parse.
+		return walkwalk.FileInfo{RelPath: name, AbsPath: abs, Ext: ".go", SHA256Hex: "hash-" + name}
+	}
+	files := []walkwalk.FileInfo{
+		write("a.go", "package a\n\nfunc A() {}\n"),
+		write("b.go", "package a\n\nfunc B() {}\n"),
+	}
+	man1, syms1, _, _ := BuildArtifacts(root, files, 0, nil)
+	if len(syms1.Symbols) != 2 {
+		t.Fatalf("symbols = %+v", syms1.Symbols)
+	}
+
+	// Same hash, new content on disk: the cached artifacts win.
+	files[0] = write("a.go", "package a\n\nfunc Renamed() {}\n")
+	man2, syms2, _, _ := BuildArtifacts(root, files, 0, nil)
+	if !reflect.DeepEqual(man1.Files, man2.Files) || !reflect.DeepEqual(syms1, syms2) {
+		t.Fatalf("cached build differs:\n%+v\n%+v", syms1, syms2)
+	}
+
+	// A changed hash is re-parsed.
+	files[0].SHA256Hex = "hash-a2"
+	_, syms3, _, _ := BuildArtifacts(root, files, 0, nil)
+	if syms3.Symbols[0].Symbol != "a.Renamed" {
+		t.Fatalf("changed file not re-parsed: %+v", syms3.Symbols)
+	}
+
+	// Indexing settings are part of the key.
+	SetGoIncludePrivate(true)
+	defer SetGoIncludePrivate(false)
+	files[1] = write("b.go", "package a\n\nfunc b() {}\n")
+	_, syms4, _, _ := BuildArtifacts(root, files, 0, nil)
+	if syms4.Symbols[1].Symbol != "a.b" {
+		t.Fatalf("settings change should invalidate: %+v", syms4.Symbols)
+	}
+
+	// Only the entries of the last build survive pruning.
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("entries after prune = %d, want 2", len(entries))
+	}
+}
@@ -16,6 +16,9 @@ type AutoAnchorConfig struct {
 	IncludeImports bool
 	IncludeTests   bool
 	Prefix         string
+	// SuppressContained drops auto anchors whose range lies fully within (or
+	// equals) an explicit anchor, regardless of name.
+	SuppressContained bool
 }
 
 // DefaultAutoAnchorConfig returns the default heuristic configuration.
@@ -69,7 +72,7 @@ func BuildAutoAnchors(relPath string, data []byte, lang string, syms []Symbol, e
 	if err != nil || len(ranked) == 0 {
 		return nil
 	}
-	out, err := writeAnchors(existing, ranked, totalLines)
+	out, err := writeAnchors(existing, ranked, totalLines, cfg)
 	if err != nil {
 		return nil
 	}
@@ -159,11 +162,36 @@ func rankAndFilterAnchors(cands []anchorCandidate, cfg AutoAnchorConfig) ([]Anch
 	return anchors, nil
 }
 
-func writeAnchors(existing []Anchor, autoAnchors []Anchor, total int) ([]Anchor, error) {
+func writeAnchors(existing []Anchor, autoAnchors []Anchor, total int, cfg AutoAnchorConfig) ([]Anchor, error) {
 	out := normalizeAutoAnchors(autoAnchors, existing, total)
+	if cfg.SuppressContained {
+		out = dropContainedAnchors(out, existing)
+	}
 	return out, nil
 }
 
+// dropContainedAnchors removes anchors whose [Start, End] range is covered by
+// any explicit anchor. Order of the remaining anchors is preserved.
+func dropContainedAnchors(in []Anchor, explicit []Anchor) []Anchor {
+	if len(in) == 0 || len(explicit) == 0 {
+		return in
+	}
+	out := in[:0]
+	for _, a := range in {
+		contained := false
+		for _, e := range explicit {
+			if e.Start <= a.Start && a.End <= e.End {
+				contained = true
+				break
+			}
+		}
+		if !contained {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
 func symbolCandidate(s Symbol, lang, prefix string, minLines int) (Anchor, bool) {
 	start := s.Start
 	end := s.End
@@ -297,27 +325,73 @@ func testAnchors(relPath string, data []byte, lang string) []Anchor {
 			return nil
 		}
 		re := regexp.MustCompile(`(?m)^\s*func\s+(Test|Benchmark|Example)[A-Za-z0-9_]*\s*\(`)
-		locs := re.FindAllIndex(data, -1)
-		var out []Anchor
-		for _, loc := range locs {
-			start := 1 + bytes.Count(data[:loc[0]], []byte("\n"))
-			out = append(out, Anchor{Name: "TEST", Start: start, End: start})
-		}
-		return out
+		return testBodyAnchors(data, re.FindAllIndex(data, -1))
 	case "ts":
 		re := regexp.MustCompile(`(?m)^\s*(describe|it|test)\s*\(`)
-		locs := re.FindAllIndex(data, -1)
-		var out []Anchor
-		for _, loc := range locs {
-			start := 1 + bytes.Count(data[:loc[0]], []byte("\n"))
-			out = append(out, Anchor{Name: "TEST", Start: start, End: start})
-		}
-		return out
+		return testBodyAnchors(data, re.FindAllIndex(data, -1))
 	default:
 		return nil
 	}
 }
 
+// testBodyAnchors turns test header matches into TEST anchors spanning from
+// the header line to the brace closing the test body (the header line alone
+// when no body is found).
+func testBodyAnchors(data []byte, locs [][]int) []Anchor {
+	var out []Anchor
+	for _, loc := range locs {
+		off := loc[0]
+		for off < loc[1] && isSpaceByte(data[off]) {
+			off++ // ^\s* may have matched preceding blank lines
+		}
+		start := 1 + bytes.Count(data[:off], []byte("\n"))
+		end := start
+		if close := matchingBrace(data, loc[1]); close >= 0 {
+			end = 1 + bytes.Count(data[:close], []byte("\n"))
+		}
+		out = append(out, Anchor{Name: "TEST", Start: start, End: end})
+	}
+	return out
+}
+
+// matchingBrace returns the offset of the '}' closing the first '{' at or
+// after from, or -1. String/rune/template literals and comments are skipped
+// so braces inside them do not count.
+func matchingBrace(data []byte, from int) int {
+	depth := 0
+	for i := from; i < len(data); i++ {
+		switch c := data[i]; c {
+		case '"', '\'', '`':
+			for i++; i < len(data) && data[i] != c; i++ {
+				if data[i] == '\\' && c != '`' {
+					i++
+				}
+			}
+		case '/':
+			if i+1 < len(data) && data[i+1] == '/' {
+				for i < len(data) && data[i] != '\n' {
+					i++
+				}
+			} else if i+1 < len(data) && data[i+1] == '*' {
+				end := bytes.Index(data[i+2:], []byte("*/"))
+				if end < 0 {
+					return -1
+				}
+				i += end + 3
+			}
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				return i
+			} else if depth < 0 {
+				return -1
+			}
+		}
+	}
+	return -1
+}
+
 func coarseRange(data []byte, pattern, name string) (Anchor, bool) {
 	re := regexp.MustCompile(pattern)
 	locs := re.FindAllIndex(data, -1)
@@ -37,3 +37,80 @@ func TestRankAndFilterAnchorsRespectsCap(t *testing.T) {
 		t.Fatalf("cap should keep first anchors, got %#v", out)
 	}
 }
+
+func TestBuildAutoAnchorsSuppressContained(t *testing.T) {
+	src := []byte(`package demo
+
+// region API
+func A() {
+}
+
+func B() {
+}
+// endregion API
+`)
+	explicit := ExtractAnchors("demo.go", src)
+	if len(explicit) != 1 {
+		t.Fatalf("expected 1 explicit anchor, got %#v", explicit)
+	}
+	prev := autoCfg
+	t.Cleanup(func() { SetAutoAnchorsConfig(prev) })
+
+	cfg := DefaultAutoAnchorConfig()
+	cfg.MinLines = 1
+	SetAutoAnchorsConfig(cfg)
+	hasFuncs := func(as []Anchor) bool {
+		for _, a := range as {
+			if a.Name == "auto:FUNCS" {
+				return true
+			}
+		}
+		return false
+	}
+	if out := BuildAutoAnchors("demo.go", src, "go", nil, explicit, 10); !hasFuncs(out) {
+		t.Fatalf("default mode should keep auto:FUNCS, got %#v", out)
+	}
+
+	cfg.SuppressContained = true
+	SetAutoAnchorsConfig(cfg)
+	if out := BuildAutoAnchors("demo.go", src, "go", nil, explicit, 10); hasFuncs(out) {
+		t.Fatalf("auto:FUNCS inside explicit region should be suppressed, got %#v", out)
+	}
+}
+
+func TestTestAnchorsSpanGoTestBody(t *testing.T) {
+	src := []byte(`package demo
+
+import "testing"
+
+func TestFoo(t *testing.T) {
+	s := "}{"
+	if s == "" {
+		t.Fatal("empty") // }
+	}
+	/* } */
+	for i := 0; i < 3; i++ {
+		_ = i
+	}
+}
+
+func helper() {}
+`)
+	got := testAnchors("demo_test.go", src, "go")
+	if len(got) != 1 || got[0].Start != 5 || got[0].End != 14 {
+		t.Fatalf("TestFoo anchor = %#v, want lines 5-14", got)
+	}
+
+	prev := autoCfg
+	t.Cleanup(func() { SetAutoAnchorsConfig(prev) })
+	SetAutoAnchorsConfig(DefaultAutoAnchorConfig())
+	found := false
+	for _, a := range BuildAutoAnchors("demo_test.go", src, "go", nil, nil, 16) {
+		if a.Name == "auto:TEST" && a.Start == 5 && a.End == 14 {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("test anchor should survive the default MinLines filter")
+	}
+}
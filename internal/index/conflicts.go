@@ -0,0 +1,71 @@
+// Package index — cross-file symbol conflicts.
+//
+// A fully-qualified symbol defined in more than one file usually means
+// duplicated generated code or clashing package names. FindSymbolConflicts
+// lists those symbols for symbol-conflicts.json. Overloads within a single
+// file are not conflicts. Output is sorted by symbol, locations by (path,
+// start).
+package index
+
+import "sort"
+
+// SymbolLocation is one definition site of a conflicting symbol.
+type SymbolLocation struct {
+	Path  string `json:"path"`
+	Start int    `json:"start"`
+}
+
+// SymbolConflict is a symbol defined in more than one file.
+type SymbolConflict struct {
+	Symbol    string           `json:"symbol"`
+	Locations []SymbolLocation `json:"locations"`
+}
+
+// SymbolConflicts is the symbol-conflicts.json payload. Total counts every
+// conflicting symbol, even when Conflicts was capped.
+type SymbolConflicts struct {
+	Version   int              `json:"version"`
+	Total     int              `json:"total"`
+	Conflicts []SymbolConflict `json:"conflicts"`
+}
+
+// FindSymbolConflicts returns the symbols of syms defined in two or more
+// distinct paths, keeping at most limit entries (limit <= 0 keeps all).
+func FindSymbolConflicts(syms []Symbol, limit int) SymbolConflicts {
+	byName := map[string][]SymbolLocation{}
+	paths := map[string]map[string]struct{}{}
+	for _, s := range syms {
+		if s.Symbol == "" {
+			continue
+		}
+		byName[s.Symbol] = append(byName[s.Symbol], SymbolLocation{Path: s.Path, Start: s.Start})
+		if paths[s.Symbol] == nil {
+			paths[s.Symbol] = map[string]struct{}{}
+		}
+		paths[s.Symbol][s.Path] = struct{}{}
+	}
+
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		if len(paths[name]) > 1 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	out := SymbolConflicts{Version: 1, Total: len(names), Conflicts: []SymbolConflict{}}
+	if limit > 0 && len(names) > limit {
+		names = names[:limit]
+	}
+	for _, name := range names {
+		locs := byName[name]
+		sort.Slice(locs, func(i, j int) bool {
+			if locs[i].Path != locs[j].Path {
+				return locs[i].Path < locs[j].Path
+			}
+			return locs[i].Start < locs[j].Start
+		})
+		out.Conflicts = append(out.Conflicts, SymbolConflict{Symbol: name, Locations: locs})
+	}
+	return out
+}
@@ -0,0 +1,31 @@
+package index
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindSymbolConflicts(t *testing.T) {
+	syms := []Symbol{
+		{Symbol: "pkg.Type.Method", Path: "gen/b.go", Start: 7},
+		{Symbol: "pkg.Type.Method", Path: "a.go", Start: 3},
+		{Symbol: "pkg.Other.Run", Path: "a.go", Start: 9},
+		{Symbol: "pkg.Over.load", Path: "c.go", Start: 1},
+		{Symbol: "pkg.Over.load", Path: "c.go", Start: 5}, // overload in one file
+		{Symbol: "pkg.A.x", Path: "x1.go", Start: 1},
+		{Symbol: "pkg.A.x", Path: "x2.go", Start: 1},
+	}
+	got := FindSymbolConflicts(syms, 0)
+	want := SymbolConflicts{Version: 1, Total: 2, Conflicts: []SymbolConflict{
+		{Symbol: "pkg.A.x", Locations: []SymbolLocation{{Path: "x1.go", Start: 1}, {Path: "x2.go", Start: 1}}},
+		{Symbol: "pkg.Type.Method", Locations: []SymbolLocation{{Path: "a.go", Start: 3}, {Path: "gen/b.go", Start: 7}}},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("FindSymbolConflicts = %+v, want %+v", got, want)
+	}
+
+	capped := FindSymbolConflicts(syms, 1)
+	if capped.Total != 2 || len(capped.Conflicts) != 1 || capped.Conflicts[0].Symbol != "pkg.A.x" {
+		t.Fatalf("capped = %+v", capped)
+	}
+}
@@ -0,0 +1,65 @@
+package index
+
+import (
+	"strings"
+	"sync"
+)
+
+// Extractor pulls the package, primary type, exports and symbols out of one
+// source file. Implementations must be safe for concurrent use.
+type Extractor interface {
+	Extract(relPath string, data []byte) (pkg, kind, typ string, exports []string, syms []Symbol)
+}
+
+// ExtractorFunc adapts a plain function to Extractor.
+type ExtractorFunc func(relPath string, data []byte) (pkg, kind, typ string, exports []string, syms []Symbol)
+
+// Extract calls f(relPath, data).
+func (f ExtractorFunc) Extract(relPath string, data []byte) (pkg, kind, typ string, exports []string, syms []Symbol) {
+	return f(relPath, data)
+}
+
+var (
+	extractorsMu sync.RWMutex
+	// extractors maps a language tag (see InferLang) to its extractor. C has
+	// no namespaces or classes; the C++ rules still find structs and free
+	// functions.
+	extractors = map[string]Extractor{
+		"java":   ExtractorFunc(extractJava),
+		"go":     ExtractorFunc(extractGo),
+		"ts":     ExtractorFunc(extractTS),
+		"kt":     ExtractorFunc(extractKotlin),
+		"cs":     ExtractorFunc(extractCS),
+		"py":     ExtractorFunc(extractPy),
+		"cpp":    ExtractorFunc(extractCPP),
+		"c":      ExtractorFunc(extractCPP),
+		"objc":   ExtractorFunc(extractObjC),
+		"vue":    ExtractorFunc(extractSFC),
+		"svelte": ExtractorFunc(extractSFC),
+		"shell":  ExtractorFunc(extractShell),
+		"sql":    ExtractorFunc(extractSQL),
+	}
+)
+
+// RegisterExtractor installs e for the language tag lang (case-insensitive),
+// replacing any previous extractor, built-ins included. The package is
+// internal, so only code built into class-collector itself can register one.
+// A registered tag is accepted by -lang-for-ext, which is how new extensions
+// are routed to it (e.g. ".foo=foo"). It panics if lang is empty or e is nil.
+func RegisterExtractor(lang string, e Extractor) {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if lang == "" || e == nil {
+		panic("index: RegisterExtractor needs a language and an extractor")
+	}
+	extractorsMu.Lock()
+	defer extractorsMu.Unlock()
+	extractors[lang] = e
+}
+
+// LookupExtractor returns the extractor registered for lang.
+func LookupExtractor(lang string) (Extractor, bool) {
+	extractorsMu.RLock()
+	defer extractorsMu.RUnlock()
+	e, ok := extractors[strings.ToLower(lang)]
+	return e, ok
+}
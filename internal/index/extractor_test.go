@@ -0,0 +1,87 @@
+package index
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"class-collector/internal/walkwalk"
+)
+
+// fooExtractor treats every "def NAME" line as a function.
+type fooExtractor struct{}
+
+func (fooExtractor) Extract(relPath string, data []byte) (pkg, kind, typ string, exports []string, syms []Symbol) {
+	for i, line := range strings.Split(string(data), "\n") {
+		if name, ok := strings.CutPrefix(line, "def "); ok {
+			exports = append(exports, name)
+			syms = append(syms, Symbol{Symbol: name, Kind: "func", Path: relPath, Start: i + 1, End: i + 1})
+		}
+	}
+	return "foo", "module", "Foo", exports, syms
+}
+
+func TestRegisterExtractorCustomLanguage(t *testing.T) {
+	RegisterExtractor("foo", fooExtractor{})
+	defer func() {
+		extractorsMu.Lock()
+		delete(extractors, "foo")
+		extractorsMu.Unlock()
+	}()
+
+	overrides, err := ParseLangForExt(".foo=foo")
+	if err != nil {
+		t.Fatalf("ParseLangForExt: %v", err)
+	}
+	SetLangForExt(overrides)
+	defer SetLangForExt(nil)
+
+	dir := t.TempDir()
+	abs := filepath.Join(dir, "main.foo")
+	if err := os.WriteFile(abs, []byte("def alpha\nx = 1\ndef beta\n"), 0o644); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+	files := []walkwalk.FileInfo{{RelPath: "main.foo", AbsPath: abs, Ext: ".foo"}}
+	idx, err := gatherSymbolsIndex(files, 500, nil)
+	if err != nil {
+		t.Fatalf("gatherSymbolsIndex error: %v", err)
+	}
+	if len(idx.manifest) != 1 || idx.manifest[0].Class != "Foo" || idx.manifest[0].Package != "foo" {
+		t.Fatalf("expected Foo entry, got %#v", idx.manifest)
+	}
+	if got := idx.manifest[0].Exports; len(got) != 2 || got[0] != "alpha" || got[1] != "beta" {
+		t.Fatalf("exports = %v", got)
+	}
+	if len(idx.symbols) != 2 || idx.symbols[1].Symbol != "beta" || idx.symbols[1].Start != 3 {
+		t.Fatalf("symbols = %#v", idx.symbols)
+	}
+}
+
+func TestLookupExtractorBuiltinsAndConcurrency(t *testing.T) {
+	for _, lang := range []string{"go", "java", "ts", "c", "vue"} {
+		if _, ok := LookupExtractor(lang); !ok {
+			t.Fatalf("built-in %q not registered", lang)
+		}
+	}
+	if _, ok := LookupExtractor("cobol"); ok {
+		t.Fatalf("unexpected extractor for cobol")
+	}
+	if _, err := ParseLangForExt(".cob=cobol"); err == nil {
+		t.Fatalf("expected unknown language error")
+	}
+
+	defer func() {
+		extractorsMu.Lock()
+		delete(extractors, "bar")
+		extractorsMu.Unlock()
+	}()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() { defer wg.Done(); RegisterExtractor("bar", fooExtractor{}) }()
+		go func() { defer wg.Done(); FileSymbols("a.go", []byte("package a\nfunc A() {}\n")) }()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,48 @@
+// Package index — Go module attribution for multi-module repositories.
+package index
+
+import (
+	"path"
+	"strings"
+)
+
+// EnclosingGoModule returns the module whose Dir is the longest prefix of the
+// project-relative path rel.
+func EnclosingGoModule(mods []GoModule, rel string) (GoModule, bool) {
+	rel = strings.ReplaceAll(rel, "\\", "/")
+	var best GoModule
+	found := false
+	for _, m := range mods {
+		if m.Dir != "." && rel != m.Dir && !strings.HasPrefix(rel, m.Dir+"/") {
+			continue
+		}
+		if !found || moduleDepth(m.Dir) > moduleDepth(best.Dir) {
+			best, found = m, true
+		}
+	}
+	return best, found
+}
+
+// GoImportPath returns the import path of the package containing the Go file
+// rel, derived from its enclosing module, or "" when rel is outside all modules.
+func GoImportPath(mods []GoModule, rel string) string {
+	m, ok := EnclosingGoModule(mods, rel)
+	if !ok {
+		return ""
+	}
+	dir := path.Dir(strings.ReplaceAll(rel, "\\", "/"))
+	if m.Dir != "." {
+		dir = strings.TrimPrefix(strings.TrimPrefix(dir, m.Dir), "/")
+	}
+	if dir == "." || dir == "" {
+		return m.Path
+	}
+	return m.Path + "/" + dir
+}
+
+func moduleDepth(dir string) int {
+	if dir == "." {
+		return 0
+	}
+	return 1 + strings.Count(dir, "/")
+}
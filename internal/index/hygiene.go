@@ -0,0 +1,97 @@
+// Package index — whitespace hygiene report.
+//
+// This file flags formatting inconsistencies that bundles normalize away
+// (line endings become LF when sources are written): mixed CRLF/LF line
+// endings, trailing whitespace and files indented with both tabs and spaces.
+// Files are analysed when indexed, as read from disk (after decoding to
+// UTF-8 but before any normalization). Output is sorted by path.
+package index
+
+import (
+	"bytes"
+	"sort"
+)
+
+// Hygiene issue tags.
+const (
+	HygieneMixedEOL    = "mixed-eol"
+	HygieneTrailingWS  = "trailing-whitespace"
+	HygieneMixedIndent = "mixed-indent"
+)
+
+// FileHygiene holds the line counters of a file with at least one issue.
+type FileHygiene struct {
+	Path        string   `json:"path"`
+	CRLF        int      `json:"crlf"`
+	LF          int      `json:"lf"`
+	TrailingWS  int      `json:"trailingWhitespace"`
+	TabIndent   int      `json:"tabIndented"`
+	SpaceIndent int      `json:"spaceIndented"`
+	Issues      []string `json:"issues"`
+}
+
+// Hygiene is the hygiene.json payload: flagged files plus how many files
+// carry each issue.
+type Hygiene struct {
+	Files  []FileHygiene  `json:"files"`
+	Counts map[string]int `json:"counts"`
+}
+
+// BuildHygiene reports the files of man with mixed line endings, trailing
+// whitespace or mixed indentation, as found when BuildArtifacts read them.
+func BuildHygiene(man Manifest) Hygiene {
+	out := Hygiene{Files: []FileHygiene{}, Counts: map[string]int{}}
+	for _, f := range man.Files {
+		if f.hygiene == nil {
+			continue
+		}
+		fh := *f.hygiene
+		fh.Path = f.Path
+		for _, is := range fh.Issues {
+			out.Counts[is]++
+		}
+		out.Files = append(out.Files, fh)
+	}
+	sort.Slice(out.Files, func(i, j int) bool { return out.Files[i].Path < out.Files[j].Path })
+	return out
+}
+
+// checkHygiene counts line endings, lines with trailing spaces or tabs, and
+// lines indented with a tab or with two or more spaces (a single space is
+// usually block-comment continuation, not indentation).
+func checkHygiene(data []byte) FileHygiene {
+	var fh FileHygiene
+	for len(data) > 0 {
+		line := data
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			line, data = data[:i], data[i+1:]
+			if bytes.HasSuffix(line, []byte("\r")) {
+				line = line[:len(line)-1]
+				fh.CRLF++
+			} else {
+				fh.LF++
+			}
+		} else {
+			data = nil
+		}
+		if n := len(line); n > 0 && (line[n-1] == ' ' || line[n-1] == '\t') {
+			fh.TrailingWS++
+		}
+		switch {
+		case bytes.HasPrefix(line, []byte("\t")):
+			fh.TabIndent++
+		case bytes.HasPrefix(line, []byte("  ")) && len(bytes.TrimSpace(line)) > 0:
+			fh.SpaceIndent++
+		}
+	}
+	if fh.CRLF > 0 && fh.LF > 0 {
+		fh.Issues = append(fh.Issues, HygieneMixedEOL)
+	}
+	if fh.TrailingWS > 0 {
+		fh.Issues = append(fh.Issues, HygieneTrailingWS)
+	}
+	if fh.TabIndent > 0 && fh.SpaceIndent > 0 {
+		fh.Issues = append(fh.Issues, HygieneMixedIndent)
+	}
+	return fh
+}
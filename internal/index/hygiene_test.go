@@ -0,0 +1,50 @@
+package index
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"class-collector/internal/walkwalk"
+)
+
+func TestBuildHygiene(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, body string) walkwalk.FileInfo {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		return walkwalk.FileInfo{RelPath: name, AbsPath: p, Ext: filepath.Ext(name)}
+	}
+	files := []walkwalk.FileInfo{
+		write("z_mixed.go", "package a\r\n\r\nfunc A() {}\n"),
+		write("a_trailing.go", "package a \n\nfunc B() {}\t\n"),
+		write("clean.go", "package a\n\nfunc C() {\n\treturn\n}\n"),
+		write("crlf.go", "package a\r\nfunc D() {}\r\n"),
+		write("indent.py", "def f():\n\tpass\n\ndef g():\n    pass\n"),
+	}
+	man, _, _, _ := BuildArtifacts(dir, files, 500, nil)
+	h := BuildHygiene(man)
+	var paths []string
+	for _, f := range h.Files {
+		paths = append(paths, f.Path)
+	}
+	if want := []string{"a_trailing.go", "indent.py", "z_mixed.go"}; !reflect.DeepEqual(paths, want) {
+		t.Fatalf("flagged = %v, want %v", paths, want)
+	}
+	trailing, indent, mixed := h.Files[0], h.Files[1], h.Files[2]
+	if trailing.TrailingWS != 2 || !reflect.DeepEqual(trailing.Issues, []string{HygieneTrailingWS}) {
+		t.Fatalf("trailing = %+v", trailing)
+	}
+	if indent.TabIndent != 1 || indent.SpaceIndent != 1 || !reflect.DeepEqual(indent.Issues, []string{HygieneMixedIndent}) {
+		t.Fatalf("indent = %+v", indent)
+	}
+	if mixed.CRLF != 2 || mixed.LF != 1 || !reflect.DeepEqual(mixed.Issues, []string{HygieneMixedEOL}) {
+		t.Fatalf("mixed = %+v", mixed)
+	}
+	if want := map[string]int{HygieneMixedEOL: 1, HygieneTrailingWS: 1, HygieneMixedIndent: 1}; !reflect.DeepEqual(h.Counts, want) {
+		t.Fatalf("counts = %v, want %v", h.Counts, want)
+	}
+}
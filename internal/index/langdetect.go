@@ -0,0 +1,170 @@
+package index
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// langSniffLines is how many leading lines InferLang inspects for ambiguous
+// extensions.
+const langSniffLines = 64
+
+var (
+	reObjCToken = regexp.MustCompile(`(?m)^\s*(?:@interface|@implementation|@protocol|#import)\b`)
+	reCPPToken  = regexp.MustCompile(`(?m)^\s*(?:class\s+\w|template\s*<|namespace\s+\w)`)
+)
+
+var langForExt map[string]string
+
+// shebangLangs maps an interpreter named on a "#!" line to a language tag.
+// Tags without a registered extractor (rb) only take effect once one is
+// registered with RegisterExtractor.
+var shebangLangs = map[string]string{
+	"python": "py", "python2": "py", "python3": "py", "pypy": "py", "pypy3": "py",
+	"node": "ts", "nodejs": "ts", "deno": "ts", "bun": "ts", "ts-node": "ts", "tsx": "ts",
+	"ruby": "rb", "sh": "shell", "bash": "shell", "zsh": "shell", "dash": "shell", "ksh": "shell",
+}
+
+var shebangDetect = true
+
+// SetShebangDetection toggles routing extensionless and ".txt" files by
+// their "#!" interpreter line (on by default).
+func SetShebangDetection(enable bool) { shebangDetect = enable }
+
+// SetLangForExt installs per-extension language overrides (keys are
+// lower-case extensions with a leading '.'), bypassing content sniffing.
+func SetLangForExt(m map[string]string) { langForExt = m }
+
+// ParseLangForExt parses "ext=lang" pairs such as ".h=objc,m=objc". Leading
+// dots are optional and extensions are case-insensitive.
+func ParseLangForExt(csv string) (map[string]string, error) {
+	out := map[string]string{}
+	for _, part := range strings.Split(csv, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		ext, lang, ok := strings.Cut(part, "=")
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		lang = strings.ToLower(strings.TrimSpace(lang))
+		if !ok || ext == "" || ext == "." {
+			return nil, fmt.Errorf("lang-for-ext: want ext=lang, got %q", part)
+		}
+		if _, known := LookupExtractor(lang); !known {
+			return nil, fmt.Errorf("lang-for-ext: unknown language %q for %s", lang, ext)
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		out[ext] = lang
+	}
+	return out, nil
+}
+
+// InferLang returns the language tag used to pick a symbol extractor. It
+// honours SetLangForExt overrides, then disambiguates extensions shared by
+// several languages from the first langSniffLines lines:
+//
+//   - ".h": @interface/@protocol/#import → "objc"; class/template/namespace
+//     → "cpp"; otherwise "c"
+//   - ".m": Objective-C tokens → "objc"; otherwise "" (e.g. MATLAB)
+//   - no extension or ".txt": a "#!" line naming a known interpreter
+//     ("#!/usr/bin/env python3", "#!/bin/bash") selects its language when
+//     an extractor is registered for it (see SetShebangDetection)
+//
+// Other extensions fall back to InferLangByExt (".ts" and ".tsx" already
+// share one extractor, so they need no sniffing).
+func InferLang(relPath string, data []byte) string {
+	ext := strings.ToLower(filepath.Ext(relPath))
+	if lang, ok := langForExt[ext]; ok {
+		return lang
+	}
+	switch ext {
+	case ".h":
+		head := sniffHead(data)
+		switch {
+		case reObjCToken.Match(head):
+			return "objc"
+		case reCPPToken.Match(head):
+			return "cpp"
+		default:
+			return "c"
+		}
+	case ".m":
+		if reObjCToken.Match(sniffHead(data)) {
+			return "objc"
+		}
+		return ""
+	case "", ".txt":
+		if lang := shebangLang(data); lang != "" {
+			return lang
+		}
+	}
+	return InferLangByExt(ext)
+}
+
+// shebangLang returns the language of the interpreter on data's "#!" line,
+// or "" when there is none, it is unknown, or it has no extractor. Both
+// "#!/usr/bin/python3" and "#!/usr/bin/env [-S] python3" forms are accepted.
+func shebangLang(data []byte) string {
+	if !shebangDetect || !bytes.HasPrefix(data, []byte("#!")) {
+		return ""
+	}
+	line, _, _ := bytes.Cut(data[2:], []byte("\n"))
+	fields := strings.Fields(string(line))
+	if len(fields) == 0 {
+		return ""
+	}
+	interp := filepath.Base(fields[0])
+	if interp == "env" {
+		interp = ""
+		for _, f := range fields[1:] {
+			if !strings.HasPrefix(f, "-") && !strings.Contains(f, "=") {
+				interp = f
+				break
+			}
+		}
+	}
+	lang, ok := shebangLangs[interp]
+	if !ok {
+		// Versioned names such as python3.12.
+		lang, ok = shebangLangs[strings.TrimRight(interp, "0123456789.")]
+	}
+	if !ok {
+		return ""
+	}
+	if _, known := LookupExtractor(lang); !known {
+		return ""
+	}
+	return lang
+}
+
+// sniffHead returns at most the first langSniffLines lines of data.
+func sniffHead(data []byte) []byte {
+	end := 0
+	for n := 0; n < langSniffLines && end < len(data); n++ {
+		i := bytes.IndexByte(data[end:], '\n')
+		if i < 0 {
+			return data
+		}
+		end += i + 1
+	}
+	return data[:end]
+}
+
+// matchesLangHints reports whether a file passes the -langs filter. Either
+// the detected language or the coarse extension tag may match, so "cpp"
+// keeps C and Objective-C headers that were refined by content.
+func matchesLangHints(hints map[string]struct{}, lang, ext string) bool {
+	if len(hints) == 0 {
+		return true
+	}
+	if _, ok := hints[lang]; ok {
+		return true
+	}
+	_, ok := hints[InferLangByExt(ext)]
+	return ok
+}
@@ -0,0 +1,129 @@
+package index
+
+import (
+	"reflect"
+	"testing"
+
+	"class-collector/internal/walkwalk"
+)
+
+const objcHeader = `#import <Foundation/Foundation.h>
+
+@interface Greeter : NSObject
+- (NSString *)greet:(NSString *)name;
++ (instancetype)shared;
+@end
+`
+
+const cHeader = `#ifndef UTIL_H
+#define UTIL_H
+
+struct point { int x, y; };
+
+int add(int a, int b);
+
+#endif
+`
+
+func TestInferLangDisambiguatesHeaders(t *testing.T) {
+	cases := []struct {
+		path, src, want string
+	}{
+		{"inc/greeter.h", objcHeader, "objc"},
+		{"inc/util.h", cHeader, "c"},
+		{"inc/vec.h", "#pragma once\ntemplate <typename T>\nclass Vec {};\n", "cpp"},
+		{"src/greeter.m", "#import \"greeter.h\"\n@implementation Greeter\n@end\n", "objc"},
+		{"scripts/plot.m", "x = linspace(0, 1);\nplot(x)\n", ""},
+		{"web/app.tsx", "export const A = () => <div/>;\n", "ts"},
+	}
+	for _, tc := range cases {
+		if got := InferLang(tc.path, []byte(tc.src)); got != tc.want {
+			t.Errorf("InferLang(%s) = %q, want %q", tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestInferLangOverride(t *testing.T) {
+	m, err := ParseLangForExt(" H=cpp , .m=objc")
+	if err != nil {
+		t.Fatalf("ParseLangForExt error: %v", err)
+	}
+	if want := map[string]string{".h": "cpp", ".m": "objc"}; !reflect.DeepEqual(m, want) {
+		t.Fatalf("parsed = %v, want %v", m, want)
+	}
+	SetLangForExt(m)
+	defer SetLangForExt(nil)
+	if got := InferLang("inc/greeter.h", []byte(objcHeader)); got != "cpp" {
+		t.Fatalf("override ignored: got %q", got)
+	}
+
+	for _, bad := range []string{".h", ".h=swift", "=c"} {
+		if _, err := ParseLangForExt(bad); err == nil {
+			t.Errorf("ParseLangForExt(%q): expected error", bad)
+		}
+	}
+}
+
+func TestProcessFileRoutesHeadersByContent(t *testing.T) {
+	objc, err := processFile(walkwalk.FileInfo{RelPath: "inc/greeter.h", Ext: ".h"}, []byte(objcHeader), 0, nil)
+	if err != nil || objc == nil {
+		t.Fatalf("processFile objc: %v", err)
+	}
+	if objc.manifest.Class != "Greeter" || objc.manifest.Kind != "class" {
+		t.Fatalf("objc header not routed to objc extractor: %+v", objc.manifest)
+	}
+	if want := []string{"greet()", "shared()"}; !reflect.DeepEqual(objc.manifest.Exports, want) {
+		t.Fatalf("objc exports = %v, want %v", objc.manifest.Exports, want)
+	}
+	if got := objc.symbols[1].Symbol; got != "Greeter.greet" {
+		t.Fatalf("objc method symbol = %q, want Greeter.greet", got)
+	}
+
+	c, err := processFile(walkwalk.FileInfo{RelPath: "inc/util.h", Ext: ".h"}, []byte(cHeader), 0, map[string]struct{}{"cpp": {}})
+	if err != nil || c == nil {
+		t.Fatalf("C header dropped by cpp lang hint: %v", err)
+	}
+	if c.manifest.Kind != "struct" || c.manifest.Class != "point" {
+		t.Fatalf("C header not extracted: %+v", c.manifest)
+	}
+}
+
+func TestInferLangShebang(t *testing.T) {
+	cases := []struct {
+		path, src, want string
+	}{
+		{"bin/deploy", "#!/usr/bin/env python3\nprint('hi')\n", "py"},
+		{"bin/tool", "#!/usr/bin/python3.12 -u\n", "py"},
+		{"bin/serve", "#!/usr/bin/env -S node --no-warnings\n", "ts"},
+		{"notes.txt", "#!/usr/bin/env python\n", "py"},
+		{"bin/build", "#!/bin/bash\nset -e\n", "shell"},
+		{"bin/rake", "#!/usr/bin/env ruby\n", ""}, // no rb extractor registered
+		{"bin/plain", "print('no shebang')\n", ""},
+		{"lib/util.go", "#!/usr/bin/env python3\n", "go"},
+	}
+	for _, tc := range cases {
+		if got := InferLang(tc.path, []byte(tc.src)); got != tc.want {
+			t.Errorf("InferLang(%s) = %q, want %q", tc.path, got, tc.want)
+		}
+	}
+	SetShebangDetection(false)
+	defer SetShebangDetection(true)
+	if got := InferLang("bin/deploy", []byte("#!/usr/bin/env python3\n")); got != "" {
+		t.Fatalf("shebang detection disabled, got %q", got)
+	}
+}
+
+func TestProcessFileExtensionlessPythonScript(t *testing.T) {
+	src := "#!/usr/bin/env python3\n\nclass Deployer:\n    def run(self):\n        pass\n\n\ndef main():\n    Deployer().run()\n"
+	fa, err := processFile(walkwalk.FileInfo{RelPath: "bin/deploy"}, []byte(src), 0, map[string]struct{}{"py": {}})
+	if err != nil || fa == nil {
+		t.Fatalf("processFile: %v", err)
+	}
+	var names []string
+	for _, s := range fa.symbols {
+		names = append(names, s.Symbol)
+	}
+	if want := []string{"bin.run", "bin.main"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("script not routed to the Python extractor: %+v symbols=%v", fa.manifest, names)
+	}
+}
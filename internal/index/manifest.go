@@ -12,14 +12,59 @@ import (
 	"sort"
 
 	"class-collector/internal/graph"
+	"class-collector/internal/parallel"
+	"class-collector/internal/progress"
+	"class-collector/internal/textutil"
 	"class-collector/internal/walkwalk"
+	"class-collector/internal/warn"
 )
 
+// Bundle ID algorithms selectable via SetBundleIDAlgo.
+const (
+	BundleIDContent   = "content"    // file paths and hashes only
+	BundleIDModule    = "module"     // content plus the module name
+	BundleIDModuleGit = "module+git" // content plus module name and git commit
+)
+
+var (
+	bundleIDAlgo   = BundleIDContent
+	bundleIDCommit string
+)
+
+// SetBundleIDAlgo selects what ComputeBundleID folds in besides file
+// contents; commit is only used by BundleIDModuleGit.
+func SetBundleIDAlgo(algo, commit string) {
+	bundleIDAlgo, bundleIDCommit = algo, commit
+}
+
+// BundleIDAlgo returns the active algorithm for manifest.bundle_id_algo, or
+// "" for the default content-only ID.
+func BundleIDAlgo() string {
+	if bundleIDAlgo == BundleIDContent {
+		return ""
+	}
+	return bundleIDAlgo
+}
+
 // ComputeBundleID computes a canonical hash over manifest entries.
 // It concatenates lines "<normalized-path>:<lowercase-hash>\n" sorted by path,
-// then returns SHA-256 hex(lowercase) of the UTF-8 bytes.
+// then returns SHA-256 hex(lowercase) of the UTF-8 bytes. Under
+// BundleIDModule/BundleIDModuleGit, "\x00module:<name>\n" (and
+// "\x00git:<commit>\n") lines follow; NUL cannot occur in a path, so they
+// never collide with file lines.
 func ComputeBundleID(man Manifest) string {
-	if len(man.Files) == 0 {
+	return BundleIDFor(man, bundleIDAlgo, bundleIDCommit)
+}
+
+// BundleIDFor is ComputeBundleID under an explicit algorithm ("" means
+// BundleIDContent) and commit, independent of SetBundleIDAlgo; verifying a
+// bundle recomputes its ID with the algorithm it records.
+func BundleIDFor(man Manifest, algo, commit string) string {
+	if algo == "" {
+		algo = BundleIDContent
+	}
+	folded := algo == BundleIDModule || algo == BundleIDModuleGit
+	if len(man.Files) == 0 && !folded {
 		sum := sha256.Sum256(nil)
 		return hex.EncodeToString(sum[:])
 	}
@@ -30,6 +75,12 @@ func ComputeBundleID(man Manifest) string {
 		lines = append(lines, p+":"+h)
 	}
 	sort.Strings(lines)
+	if folded {
+		lines = append(lines, "\x00module:"+man.Module)
+	}
+	if algo == BundleIDModuleGit {
+		lines = append(lines, "\x00git:"+commit)
+	}
 	var buf bytes.Buffer
 	for _, ln := range lines {
 		buf.WriteString(ln)
@@ -98,7 +149,8 @@ type fileArtifacts struct {
 }
 
 // BuildArtifacts remains the primary entry point for callers that expect the
-// original tuple signature. Internally it delegates to buildArtifactsSet.
+// original tuple signature. Internally it delegates to buildArtifactsSet; the
+// tuple has no graph, so none is built (callers use graph.BuildFrom).
 func BuildArtifacts(root string, files []walkwalk.FileInfo, maxFileLines int, langHints map[string]struct{}) (Manifest, Symbols, []Slice, []Pointer) {
 	art, err := buildArtifactsSet(root, files, maxFileLines, langHints)
 	if err != nil {
@@ -112,22 +164,29 @@ func buildArtifactsSet(root string, files []walkwalk.FileInfo, maxFileLines int,
 	if err != nil {
 		return Artifacts{}, err
 	}
-	g, err := computeGraph(files)
-	if err != nil {
-		return Artifacts{}, err
-	}
-	return assembleArtifacts(root, idx, g)
+	return assembleArtifacts(root, idx, graph.Graph{})
 }
 
+// gatherSymbolsIndex reads and indexes files on up to parallel.Limit()
+// workers; results are merged in input order, so the index does not depend
+// on scheduling.
 func gatherSymbolsIndex(files []walkwalk.FileInfo, maxFileLines int, langHints map[string]struct{}) (symbolsIndex, error) {
-	var idx symbolsIndex
-	for _, f := range files {
-		data, err := os.ReadFile(f.AbsPath)
-		if err != nil {
-			continue
+	results := make([]*fileArtifacts, len(files))
+	store := openArtifactStore(maxFileLines, langHints)
+	parallel.For(len(files), func(i int) {
+		progress.Tick()
+		if fa, ok := store.lookup(files[i]); ok {
+			results[i] = fa
+			return
 		}
-		fa, err := processFile(f, data, maxFileLines, langHints)
-		if err != nil || fa == nil {
+		results[i] = indexFile(files[i], maxFileLines, langHints)
+		store.store(files[i], results[i])
+	})
+	store.prune()
+
+	var idx symbolsIndex
+	for _, fa := range results {
+		if fa == nil {
 			continue
 		}
 		idx.manifest = append(idx.manifest, fa.manifest)
@@ -138,52 +197,73 @@ func gatherSymbolsIndex(files []walkwalk.FileInfo, maxFileLines int, langHints m
 	return idx, nil
 }
 
-func processFile(f walkwalk.FileInfo, data []byte, maxFileLines int, langHints map[string]struct{}) (*fileArtifacts, error) {
-	anchors := ExtractAnchors(f.RelPath, data)
-	lang := InferLangByExt(f.Ext)
-	var pkg, kind, typ string
-	var exports []string
-	var syms []Symbol
-
-	switch lang {
-	case "java":
-		pkg, kind, typ, exports, syms = extractJava(f.RelPath, data)
-	case "go":
-		pkg, kind, typ, exports, syms = extractGo(f.RelPath, data)
-	case "ts":
-		pkg, kind, typ, exports, syms = extractTS(f.RelPath, data)
-	case "kt":
-		pkg, kind, typ, exports, syms = extractKotlin(f.RelPath, data)
-	case "cs":
-		pkg, kind, typ, exports, syms = extractCS(f.RelPath, data)
-	case "py":
-		pkg, kind, typ, exports, syms = extractPy(f.RelPath, data)
-	case "cpp":
-		pkg, kind, typ, exports, syms = extractCPP(f.RelPath, data)
-	default:
-		kind = "file"
+// TagNonUTF8 marks files in no detected encoding, indexed with their invalid
+// bytes replaced by U+FFFD (ManFile.Encoding is textutil.EncNonUTF8).
+const TagNonUTF8 = "non-utf8"
+
+// indexFile returns the artifacts of one collected file, or nil when it is
+// skipped (unreadable, filtered by langHints, or an unrecorded symlink).
+func indexFile(f walkwalk.FileInfo, maxFileLines int, langHints map[string]struct{}) *fileArtifacts {
+	if f.Symlink != "" {
+		if mf, ok := symlinkEntry(f, langHints); ok {
+			return &fileArtifacts{manifest: mf}
+		}
+		return nil
+	}
+	data, err := os.ReadFile(f.AbsPath)
+	if err != nil {
+		warn.Add(warn.KindUnreadable, f.RelPath, "skipped: %v", warn.Cause(err))
+		return nil
+	}
+	data, enc := textutil.ToUTF8(data)
+	if enc == textutil.EncNonUTF8 {
+		warn.Add(warn.KindEncoding, f.RelPath, "unknown encoding; invalid bytes replaced with U+FFFD")
 	}
+	fa, err := processFile(f, data, maxFileLines, langHints)
+	if err != nil || fa == nil {
+		return nil
+	}
+	fa.manifest.Encoding = enc
+	if enc == textutil.EncNonUTF8 {
+		fa.manifest.Tags = append(fa.manifest.Tags, TagNonUTF8)
+	}
+	if fh := checkHygiene(data); len(fh.Issues) > 0 {
+		fa.manifest.hygiene = &fh
+	}
+	ls := countLines(data, statsLang(f.Ext))
+	fa.manifest.lineStats = &ls
+	return fa
+}
 
+// symlinkEntry describes an unfollowed symlink; its target is never read.
+func symlinkEntry(f walkwalk.FileInfo, langHints map[string]struct{}) (ManFile, bool) {
 	if len(langHints) > 0 {
-		if _, ok := langHints[lang]; !ok {
-			return nil, nil
+		if _, ok := langHints[InferLangByExt(f.Ext)]; !ok {
+			return ManFile{}, false
 		}
 	}
+	return ManFile{Path: f.RelPath, Kind: "symlink", Symlink: f.Symlink}, true
+}
 
-	totalLines := 1 + bytes.Count(data, []byte("\n"))
+var excludeTestSymbols bool
 
-	sort.Slice(syms, func(i, j int) bool { return syms[i].Start < syms[j].Start })
-	for i := range syms {
-		if i+1 < len(syms) {
-			syms[i].End = syms[i+1].Start - 1
-			if syms[i].End < syms[i].Start {
-				syms[i].End = syms[i].Start
-			}
-		} else {
-			syms[i].End = totalLines
-		}
+// SetExcludeTestSymbols drops the symbols (and with them the symbol pointers)
+// of test files (IsTestPath); the files stay in the manifest with their
+// exports, anchors and slices.
+func SetExcludeTestSymbols(enable bool) { excludeTestSymbols = enable }
+
+func processFile(f walkwalk.FileInfo, data []byte, maxFileLines int, langHints map[string]struct{}) (*fileArtifacts, error) {
+	anchors := ExtractAnchors(f.RelPath, data)
+	lang := InferLang(f.RelPath, data)
+	pkg, kind, typ, exports, syms := extractByLang(lang, f.RelPath, data)
+
+	if !matchesLangHints(langHints, lang, f.Ext) {
+		return nil, nil
 	}
 
+	totalLines := CountLines(data)
+	finalizeSymbolEnds(syms, totalLines, exactEnds(lang))
+
 	if aa := BuildAutoAnchors(f.RelPath, data, lang, syms, anchors, totalLines); len(aa) > 0 {
 		anchors = append(anchors, aa...)
 	}
@@ -193,18 +273,31 @@ func processFile(f walkwalk.FileInfo, data []byte, maxFileLines int, langHints m
 		Package: pkg,
 		Class:   typ,
 		Kind:    kind,
-		Summary: "",
+		Role:    ClassifyRole(f.RelPath, data),
+		Summary: summarize(f.RelPath, data),
 		Exports: exports,
 		Hash:    f.SHA256Hex,
 		Lines:   totalLines,
 		Anchors: anchors,
+		Aliases: f.Aliases,
+	}
+	if lang == "ts" {
+		mf.ReExports = tsReExports(data)
+	}
+	if annotationLangs[lang] && typ != "" {
+		mf.Annotations = typeAnnotations(data, kind, typ)
 	}
 
 	var slices []Slice
-	if sl := BuildSlices(f.RelPath, anchors, totalLines, maxFileLines); len(sl) > 0 {
+	if slicesFromSymbols && len(syms) > 0 {
+		slices = BuildSymbolSlices(f.RelPath, syms, totalLines)
+	} else if sl := BuildSlices(f.RelPath, anchors, totalLines, maxFileLines); len(sl) > 0 {
 		slices = append(slices, sl...)
 	}
 	pointers := BuildAnchorPointers(f.RelPath, anchors)
+	if excludeTestSymbols && IsTestPath(f.RelPath) {
+		syms = nil
+	}
 
 	return &fileArtifacts{
 		manifest: mf,
@@ -214,19 +307,73 @@ func processFile(f walkwalk.FileInfo, data []byte, maxFileLines int, langHints m
 	}, nil
 }
 
-func computeGraph(files []walkwalk.FileInfo) (graph.Graph, error) {
-	if len(files) == 0 {
-		return graph.Graph{}, nil
+// FileSymbols returns the symbols of a single file with End finalized, using
+// the same extractors as the manifest. Unknown languages yield nil.
+func FileSymbols(relPath string, data []byte) []Symbol {
+	lang := InferLang(relPath, data)
+	_, _, _, _, syms := extractByLang(lang, relPath, data)
+	finalizeSymbolEnds(syms, CountLines(data), exactEnds(lang))
+	return syms
+}
+
+// CountLines returns 1 + the number of '\n' in data, or 0 for empty content,
+// so a zero-length file is distinguishable from a single blank line.
+func CountLines(data []byte) int {
+	if len(data) == 0 {
+		return 0
+	}
+	return 1 + bytes.Count(data, []byte("\n"))
+}
+
+// extractByLang runs the extractor for lang and drops callable symbols below
+// the -symbols-min-confidence threshold. Parsed symbols (-parser precise)
+// are not filtered.
+func extractByLang(lang, relPath string, data []byte) (pkg, kind, typ string, exports []string, syms []Symbol) {
+	pkg, kind, typ, exports, syms = extractRaw(lang, relPath, data)
+	if annotationLangs[lang] {
+		attachAnnotations(data, syms)
+	}
+	if symbolsMinConfidence > 0 && !exactEnds(lang) && len(syms) > 0 {
+		syms, exports = dropLowConfidence(data, syms, exports)
+	}
+	return pkg, kind, typ, exports, syms
+}
+
+// extractRaw runs the precise or registered extractor for lang without
+// filtering. Languages without an extractor are plain files.
+func extractRaw(lang, relPath string, data []byte) (pkg, kind, typ string, exports []string, syms []Symbol) {
+	if e, ok := preciseExtractor(lang); ok {
+		return e.Extract(relPath, data)
 	}
-	gfiles := make([]graph.File, 0, len(files))
-	for _, f := range files {
-		gfiles = append(gfiles, graph.File{
-			RelPath: f.RelPath,
-			AbsPath: f.AbsPath,
-			Ext:     f.Ext,
-		})
+	if e, ok := LookupExtractor(lang); ok {
+		return e.Extract(relPath, data)
+	}
+	return "", "file", "", nil, nil
+}
+
+// finalizeSymbolEnds sorts syms by (Start, Symbol) and, unless the extractor
+// already reported exact ranges, sets each End to the line before the next
+// symbol (or totalLines for the last one).
+func finalizeSymbolEnds(syms []Symbol, totalLines int, exact bool) {
+	sort.Slice(syms, func(i, j int) bool {
+		if syms[i].Start != syms[j].Start {
+			return syms[i].Start < syms[j].Start
+		}
+		return syms[i].Symbol < syms[j].Symbol
+	})
+	if exact {
+		return
+	}
+	for i := range syms {
+		if i+1 < len(syms) {
+			syms[i].End = syms[i+1].Start - 1
+			if syms[i].End < syms[i].Start {
+				syms[i].End = syms[i].Start
+			}
+		} else {
+			syms[i].End = totalLines
+		}
 	}
-	return graph.BuildFrom(gfiles), nil
 }
 
 func assembleArtifacts(root string, idx symbolsIndex, g graph.Graph) (Artifacts, error) {
@@ -260,6 +407,9 @@ func assembleArtifacts(root string, idx symbolsIndex, g graph.Graph) (Artifacts,
 	sort.Slice(symbols, func(i, j int) bool {
 		if symbols[i].Path == symbols[j].Path {
 			if symbols[i].Start == symbols[j].Start {
+				if symbols[i].End == symbols[j].End {
+					return symbols[i].Symbol < symbols[j].Symbol
+				}
 				return symbols[i].End < symbols[j].End
 			}
 			return symbols[i].Start < symbols[j].Start
@@ -280,8 +430,11 @@ func assembleArtifacts(root string, idx symbolsIndex, g graph.Graph) (Artifacts,
 		return pointers[i].ID < pointers[j].ID
 	})
 
+	tagAPISurface(manFiles, symbols)
 	man := Manifest{Module: filepath.Base(root), Files: manFiles}
 	man.BundleID = ComputeBundleID(man)
+	man.BundleIDAlgo = BundleIDAlgo()
+	man.ToolVersion, man.ManifestVersion = ToolVersion, ManifestVersion
 	symOut := Symbols{Version: 1, Symbols: symbols}
 
 	return Artifacts{
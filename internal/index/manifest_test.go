@@ -1,9 +1,15 @@
 package index
 
 import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"class-collector/internal/graph"
+	"class-collector/internal/walkwalk"
+	"class-collector/internal/warn"
 )
 
 func TestAssembleArtifactsSortingAndPointers(t *testing.T) {
@@ -66,3 +72,222 @@ func TestAssembleArtifactsSortingAndPointers(t *testing.T) {
 		t.Fatalf("graph not propagated")
 	}
 }
+
+func TestSameLineSymbolsOrderedByName(t *testing.T) {
+	same := []Symbol{
+		{Symbol: "web.b", Kind: "func", Path: "web/consts.ts", Start: 1, End: 1},
+		{Symbol: "web.a", Kind: "func", Path: "web/consts.ts", Start: 1, End: 1},
+		{Symbol: "web.c", Kind: "func", Path: "web/consts.ts", Start: 1, End: 1},
+	}
+	var want []Symbol
+	for run := 0; run < 3; run++ {
+		// Rotate the extraction order; the output must not depend on it.
+		in := append(append([]Symbol(nil), same[run:]...), same[:run]...)
+		art, err := assembleArtifacts("module", symbolsIndex{
+			manifest: []ManFile{{Path: "web/consts.ts", Hash: "aa", Lines: 1}},
+			symbols:  in,
+		}, graph.Graph{})
+		if err != nil {
+			t.Fatalf("assembleArtifacts error: %v", err)
+		}
+		got := art.Symbols.Symbols
+		if got[0].Symbol != "web.a" || got[1].Symbol != "web.b" || got[2].Symbol != "web.c" {
+			t.Fatalf("run %d: symbols not name-ordered: %+v", run, got)
+		}
+		if want == nil {
+			want = got
+		} else if !reflect.DeepEqual(got, want) {
+			t.Fatalf("run %d: unstable order %+v vs %+v", run, got, want)
+		}
+
+		syms := append([]Symbol(nil), in...)
+		finalizeSymbolEnds(syms, 4, false)
+		if syms[0].Symbol != "web.a" || syms[2].Symbol != "web.c" || syms[2].End != 4 {
+			t.Fatalf("run %d: finalizeSymbolEnds order %+v", run, syms)
+		}
+	}
+}
+
+func TestGatherSymbolsIndexRecordsSymlinkWithoutReading(t *testing.T) {
+	files := []walkwalk.FileInfo{
+		{RelPath: "link.go", AbsPath: "/nonexistent/link.go", Ext: ".go", Symlink: "../shared/link.go"},
+	}
+	idx, err := gatherSymbolsIndex(files, 500, nil)
+	if err != nil {
+		t.Fatalf("gatherSymbolsIndex error: %v", err)
+	}
+	if len(idx.manifest) != 1 {
+		t.Fatalf("expected symlink entry, got %#v", idx.manifest)
+	}
+	mf := idx.manifest[0]
+	if mf.Kind != "symlink" || mf.Symlink != "../shared/link.go" || mf.Hash != "" || mf.Lines != 0 {
+		t.Fatalf("unexpected symlink entry: %#v", mf)
+	}
+}
+
+func TestProcessFileEmptyHasZeroLines(t *testing.T) {
+	f := walkwalk.FileInfo{RelPath: "empty.go", Ext: ".go", SHA256Hex: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"}
+	fa, err := processFile(f, nil, 500, nil)
+	if err != nil || fa == nil {
+		t.Fatalf("processFile error: %v", err)
+	}
+	if fa.manifest.Lines != 0 || len(fa.manifest.Anchors) != 0 || len(fa.symbols) != 0 || len(fa.slices) != 0 {
+		t.Fatalf("unexpected empty-file artifacts: %#v", fa)
+	}
+	if got := CountLines([]byte("\n")); got != 2 {
+		t.Fatalf("CountLines(newline) = %d, want 2", got)
+	}
+}
+
+func TestProcessFileSlicesFromSymbols(t *testing.T) {
+	SetSlicesFromSymbols(true)
+	defer SetSlicesFromSymbols(false)
+
+	data := []byte("package demo\n\nfunc First() {\n}\n\nfunc Second() {\n}\n")
+	f := walkwalk.FileInfo{RelPath: "demo.go", Ext: ".go"}
+	fa, err := processFile(f, data, 500, nil)
+	if err != nil || fa == nil {
+		t.Fatalf("processFile error: %v", err)
+	}
+	if len(fa.slices) != 2 || fa.slices[0].Slice != "demo.First" || fa.slices[1].Slice != "demo.Second" {
+		t.Fatalf("expected one slice per symbol, got %#v", fa.slices)
+	}
+	if fa.slices[0].End >= fa.slices[1].Start || fa.slices[1].End != 8 {
+		t.Fatalf("symbol slices should cover consecutive ranges: %#v", fa.slices)
+	}
+}
+
+func TestGatherSymbolsIndexWarnsOnUnreadableFile(t *testing.T) {
+	c := &warn.Collector{}
+	warn.Use(c)
+	defer warn.Use(nil)
+
+	files := []walkwalk.FileInfo{{RelPath: "gone.go", AbsPath: "/nonexistent/gone.go", Ext: ".go"}}
+	if _, err := gatherSymbolsIndex(files, 500, nil); err != nil {
+		t.Fatalf("gatherSymbolsIndex error: %v", err)
+	}
+	got := c.List()
+	if len(got) != 1 || got[0].Kind != warn.KindUnreadable || got[0].Path != "gone.go" {
+		t.Fatalf("expected unreadable warning, got %#v", got)
+	}
+	if strings.Contains(got[0].Message, "/nonexistent") {
+		t.Fatalf("warning leaks the absolute path: %q", got[0].Message)
+	}
+}
+
+func TestGatherSymbolsIndexRecordsLatin1Encoding(t *testing.T) {
+	dir := t.TempDir()
+	abs := filepath.Join(dir, "Cafe.java")
+	src := "package demo;\n// caf\xe9\npublic class Cafe {}\n"
+	if err := os.WriteFile(abs, []byte(src), 0o644); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+	files := []walkwalk.FileInfo{{RelPath: "Cafe.java", AbsPath: abs, Ext: ".java"}}
+	idx, err := gatherSymbolsIndex(files, 500, nil)
+	if err != nil {
+		t.Fatalf("gatherSymbolsIndex error: %v", err)
+	}
+	if len(idx.manifest) != 1 || idx.manifest[0].Encoding != "latin-1" || idx.manifest[0].Class != "Cafe" {
+		t.Fatalf("expected latin-1 entry for Cafe, got %#v", idx.manifest)
+	}
+}
+
+func TestGatherSymbolsIndexTagsUndetectedEncoding(t *testing.T) {
+	dir := t.TempDir()
+	abs := filepath.Join(dir, "jp.txt")
+	if err := os.WriteFile(abs, []byte("\x82\xb1\x82\xf1\x82\xc9\x82\xbf\x82\xcd\n"), 0o644); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+	files := []walkwalk.FileInfo{{RelPath: "jp.txt", AbsPath: abs, Ext: ".txt"}}
+	idx, err := gatherSymbolsIndex(files, 500, nil)
+	if err != nil {
+		t.Fatalf("gatherSymbolsIndex error: %v", err)
+	}
+	if len(idx.manifest) != 1 || idx.manifest[0].Encoding != "non-utf8" || !HasTag(idx.manifest[0], TagNonUTF8) {
+		t.Fatalf("expected a non-utf8 tagged entry, got %#v", idx.manifest)
+	}
+}
+
+func TestComputeBundleIDAlgo(t *testing.T) {
+	defer SetBundleIDAlgo(BundleIDContent, "")
+	files := []ManFile{{Path: "a.go", Hash: "aa"}}
+	a := Manifest{Module: "example.com/a", Files: files}
+	b := Manifest{Module: "example.com/b", Files: files}
+
+	SetBundleIDAlgo(BundleIDContent, "")
+	if ComputeBundleID(a) != ComputeBundleID(b) {
+		t.Fatalf("content ids should ignore the module name")
+	}
+	contentID := ComputeBundleID(a)
+
+	SetBundleIDAlgo(BundleIDModule, "")
+	if ComputeBundleID(a) == ComputeBundleID(b) {
+		t.Fatalf("module ids should differ by module name")
+	}
+	if ComputeBundleID(a) == contentID {
+		t.Fatalf("module id should differ from content id")
+	}
+	if BundleIDAlgo() != BundleIDModule {
+		t.Fatalf("BundleIDAlgo = %q", BundleIDAlgo())
+	}
+
+	SetBundleIDAlgo(BundleIDModuleGit, "1111")
+	first := ComputeBundleID(a)
+	SetBundleIDAlgo(BundleIDModuleGit, "2222")
+	if ComputeBundleID(a) == first {
+		t.Fatalf("module+git ids should differ by commit")
+	}
+}
+
+func TestManifestVersionsExcludedFromBundleID(t *testing.T) {
+	idx := symbolsIndex{manifest: []ManFile{{Path: "a.go", Hash: "aa", Lines: 1}}}
+	art, err := assembleArtifacts("/tmp/demo", idx, graph.Graph{})
+	if err != nil {
+		t.Fatalf("assembleArtifacts error: %v", err)
+	}
+	man := art.Manifest
+	if man.ToolVersion != ToolVersion || man.ManifestVersion != ManifestVersion || man.ToolVersion == "" || man.ManifestVersion < 1 {
+		t.Fatalf("versions = %q/%d, want %q/%d", man.ToolVersion, man.ManifestVersion, ToolVersion, ManifestVersion)
+	}
+
+	other := man
+	other.ToolVersion, other.ManifestVersion = "99.0.0", ManifestVersion+1
+	if got := ComputeBundleID(other); got != man.BundleID {
+		t.Fatalf("bundle ID changed with versions: %s vs %s", got, man.BundleID)
+	}
+}
+
+func TestExcludeTestSymbols(t *testing.T) {
+	SetExcludeTestSymbols(true)
+	defer SetExcludeTestSymbols(false)
+
+	dir := t.TempDir()
+	var files []walkwalk.FileInfo
+	for name, body := range map[string]string{
+		"a.go":      "package a\n\nfunc A() {}\n",
+		"a_test.go": "package a\n\nfunc TestA(t *testing.T) {}\n",
+	} {
+		abs := filepath.Join(dir, name)
+		if err := os.WriteFile(abs, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, walkwalk.FileInfo{RelPath: name, AbsPath: abs, Ext: ".go"})
+	}
+	man, syms, _, ptrs := BuildArtifacts(dir, files, 500, nil)
+
+	var paths []string
+	for _, f := range man.Files {
+		paths = append(paths, f.Path)
+	}
+	if want := []string{"a.go", "a_test.go"}; !reflect.DeepEqual(paths, want) {
+		t.Fatalf("manifest files = %v, want %v", paths, want)
+	}
+	if len(syms.Symbols) != 1 || syms.Symbols[0].Symbol != "a.A" {
+		t.Fatalf("symbols = %+v, want only a.A", syms.Symbols)
+	}
+	for _, p := range ptrs {
+		if p.Path == "a_test.go" && p.Sym != "" {
+			t.Fatalf("symbol pointer for a test file: %+v", p)
+		}
+	}
+}
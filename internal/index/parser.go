@@ -0,0 +1,525 @@
+// Package index — precise extraction backend (-parser precise).
+//
+// The default extractors match declarations with regular expressions and
+// leave End to finalizeSymbolEnds. The precise backend tokenizes Java,
+// Kotlin and TS/JS sources (comments, strings, text blocks, template and
+// regex literals are skipped correctly) and walks the bracket structure, so
+// it reports nested types, members named after their enclosing type, real
+// End lines (the closing brace or the end of the declaration) and the
+// parameter types of every callable in Symbol.Signature. It needs no
+// grammar files or cgo; other languages keep their regex extractors.
+package index
+
+import "strings"
+
+// Extraction backends for SetParser.
+const (
+	ParserRegex   = "regex"   // regular-expression extractors (default)
+	ParserPrecise = "precise" // structural parser for Java, Kotlin and TS/JS
+)
+
+var parserMode = ParserRegex
+
+// preciseExtractors are the languages covered by ParserPrecise.
+var preciseExtractors = map[string]Extractor{
+	"java": ExtractorFunc(parseJava),
+	"kt":   ExtractorFunc(parseKotlin),
+	"ts":   ExtractorFunc(parseTS),
+}
+
+// SetParser selects the extraction backend (ParserRegex or ParserPrecise;
+// "" means regex). ParserPrecise replaces the java, kt and ts extractors,
+// including ones installed with RegisterExtractor.
+func SetParser(mode string) {
+	if mode == "" {
+		mode = ParserRegex
+	}
+	parserMode = mode
+}
+
+// preciseExtractor returns the precise extractor for lang when that backend
+// is selected.
+func preciseExtractor(lang string) (Extractor, bool) {
+	if parserMode != ParserPrecise {
+		return nil, false
+	}
+	e, ok := preciseExtractors[lang]
+	return e, ok
+}
+
+// exactEnds reports whether the symbols extracted for lang carry their real
+// End lines.
+func exactEnds(lang string) bool {
+	_, ok := preciseExtractor(lang)
+	return ok
+}
+
+// Token kinds.
+const (
+	tokIdent = iota
+	tokPunct
+	tokString
+	tokNumber
+)
+
+// token is one lexeme. nl reports a line break since the previous token,
+// which Kotlin and TS use to end declarations without semicolons.
+type token struct {
+	kind int
+	text string
+	line int
+	nl   bool
+}
+
+// lexer splits C-family source into tokens, dropping comments and reducing
+// string, character, template and regex literals to one tokString each.
+type lexer struct {
+	src  []byte
+	lang string
+	pos  int
+	line int
+	nl   bool
+	toks []token
+}
+
+func tokenize(lang string, data []byte) []token {
+	l := &lexer{src: data, lang: lang, line: 1}
+	l.run(false)
+	return l.toks
+}
+
+// run lexes until EOF or, when nested (inside a "${...}" template
+// expression), until the unmatched '}'. Nested tokens are not recorded.
+func (l *lexer) run(nested bool) {
+	depth := 0
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '\n':
+			l.line++
+			l.nl = true
+			l.pos++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f':
+			l.pos++
+		case c == '/' && l.peek(1) == '/':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+		case c == '/' && l.peek(1) == '*':
+			l.blockComment()
+		case c == '"' || c == '\'' || c == '`':
+			line, nl := l.line, l.nl
+			l.quoted(c)
+			l.nl = nl // line breaks inside the literal do not count
+			l.emit(nested, tokString, "\"\"", line)
+		case c == '/' && l.lang == "ts" && l.regexAllowed():
+			line := l.line
+			l.regex()
+			l.emit(nested, tokString, "//", line)
+		case isIdentStart(c):
+			start := l.pos
+			for l.pos < len(l.src) && isIdentPart(l.src[l.pos]) {
+				l.pos++
+			}
+			l.emit(nested, tokIdent, string(l.src[start:l.pos]), l.line)
+		case c >= '0' && c <= '9':
+			start := l.pos
+			for l.pos < len(l.src) && (isIdentPart(l.src[l.pos]) || l.src[l.pos] == '.' && l.peek(1) >= '0' && l.peek(1) <= '9') {
+				l.pos++
+			}
+			l.emit(nested, tokNumber, string(l.src[start:l.pos]), l.line)
+		default:
+			text := string(c)
+			if (c == '=' || c == '-') && l.peek(1) == '>' {
+				text += ">"
+			}
+			l.pos += len(text)
+			if nested {
+				switch c {
+				case '{':
+					depth++
+				case '}':
+					if depth == 0 {
+						return
+					}
+					depth--
+				}
+			}
+			l.emit(nested, tokPunct, text, l.line)
+		}
+	}
+}
+
+func (l *lexer) peek(n int) byte {
+	if l.pos+n < len(l.src) {
+		return l.src[l.pos+n]
+	}
+	return 0
+}
+
+func (l *lexer) emit(nested bool, kind int, text string, line int) {
+	if !nested {
+		l.toks = append(l.toks, token{kind: kind, text: text, line: line, nl: l.nl})
+	}
+	l.nl = false
+}
+
+// blockComment skips a /* */ comment; Kotlin block comments nest.
+func (l *lexer) blockComment() {
+	depth := 0
+	for l.pos < len(l.src) {
+		switch {
+		case l.src[l.pos] == '/' && l.peek(1) == '*':
+			depth++
+			l.pos += 2
+			continue
+		case l.src[l.pos] == '*' && l.peek(1) == '/':
+			depth--
+			l.pos += 2
+			if depth == 0 || l.lang != "kt" {
+				return
+			}
+			continue
+		case l.src[l.pos] == '\n':
+			l.line++
+		}
+		l.pos++
+	}
+}
+
+// quoted skips a literal opened by q at l.pos: a Java/Kotlin """ text block,
+// a TS template (with ${} expressions), a Kotlin template string, or a plain
+// single-line string or character literal.
+func (l *lexer) quoted(q byte) {
+	triple := q == '"' && l.peek(1) == '"' && l.peek(2) == '"' && l.lang != "ts"
+	templ := q == '`' || (q == '"' && l.lang == "kt")
+	if triple {
+		l.pos += 3
+	} else {
+		l.pos++
+	}
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '\\' && !(triple && l.lang == "kt"):
+			if l.peek(1) == '\n' {
+				l.line++
+			}
+			l.pos += 2
+			continue
+		case c == '\n':
+			if !triple && q != '`' {
+				return // unterminated: resynchronise on the next line
+			}
+			l.line++
+		case templ && c == '$' && l.peek(1) == '{':
+			l.pos += 2
+			l.run(true)
+			continue
+		case triple && c == '"' && l.peek(1) == '"' && l.peek(2) == '"':
+			l.pos += 3
+			for l.pos < len(l.src) && l.src[l.pos] == '"' {
+				l.pos++ // """" ends with a quote inside the block
+			}
+			return
+		case !triple && c == q:
+			l.pos++
+			return
+		}
+		l.pos++
+	}
+}
+
+// regexAllowed reports whether a '/' starts a regex literal: after an
+// operator, an opening bracket or a keyword, not after a value.
+func (l *lexer) regexAllowed() bool {
+	if len(l.toks) == 0 {
+		return true
+	}
+	prev := l.toks[len(l.toks)-1]
+	switch prev.kind {
+	case tokString, tokNumber:
+		return false
+	case tokIdent:
+		switch prev.text {
+		case "return", "typeof", "case", "in", "of", "new", "delete", "void", "throw", "yield", "await", "instanceof":
+			return true
+		}
+		return false
+	}
+	return prev.text != ")" && prev.text != "]" && prev.text != "}"
+}
+
+// regex skips a /.../flags literal, stopping at the line end if unclosed.
+func (l *lexer) regex() {
+	l.pos++
+	inClass := false
+	for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+		c := l.src[l.pos]
+		switch {
+		case c == '\\':
+			l.pos++
+		case c == '[':
+			inClass = true
+		case c == ']':
+			inClass = false
+		case c == '/' && !inClass:
+			l.pos++
+			for l.pos < len(l.src) && isIdentPart(l.src[l.pos]) {
+				l.pos++
+			}
+			return
+		}
+		l.pos++
+	}
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || c >= '0' && c <= '9'
+}
+
+// parser walks a token slice. The language parsers share its bracket
+// helpers and symbol bookkeeping.
+type parser struct {
+	toks    []token
+	i       int
+	relPath string
+	pkg     string
+	syms    []Symbol
+}
+
+func (p *parser) eof() bool { return p.i >= len(p.toks) }
+
+// at returns the token n ahead, or a zero token past the end.
+func (p *parser) at(n int) token {
+	if p.i+n < len(p.toks) && p.i+n >= 0 {
+		return p.toks[p.i+n]
+	}
+	return token{kind: -1}
+}
+
+// is reports whether the current token is the punctuation or identifier s.
+func (p *parser) is(s string) bool {
+	t := p.at(0)
+	return (t.kind == tokPunct || t.kind == tokIdent) && t.text == s
+}
+
+// lastLine is the line of the token before the current one.
+func (p *parser) lastLine() int {
+	if p.i > 0 && p.i <= len(p.toks) {
+		return p.toks[p.i-1].line
+	}
+	return 1
+}
+
+// closerOf maps an opening bracket to its closer.
+var closerOf = map[string]string{"(": ")", "[": "]", "{": "}"}
+
+// skipGroup consumes the bracketed group opening at the current token and
+// returns the line of its closer (the last line when unbalanced).
+func (p *parser) skipGroup() int {
+	var stack []string
+	for !p.eof() {
+		t := p.toks[p.i]
+		p.i++
+		if t.kind != tokPunct {
+			continue
+		}
+		if c, ok := closerOf[t.text]; ok {
+			stack = append(stack, c)
+		} else if len(stack) > 0 && t.text == stack[len(stack)-1] {
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				return t.line
+			}
+		}
+	}
+	return p.lastLine()
+}
+
+// closeBrace consumes the '}' ending a body and returns its line (the last
+// line at EOF).
+func (p *parser) closeBrace() int {
+	if p.eof() {
+		return p.lastLine()
+	}
+	p.i++
+	return p.toks[p.i-1].line
+}
+
+// group consumes a bracketed group and returns its inner tokens.
+func (p *parser) group() []token {
+	start := p.i
+	p.skipGroup()
+	end := p.i - 1
+	if end <= start || p.toks[end].text != closerOf[p.toks[start].text] {
+		end = p.i
+	}
+	return p.toks[start+1 : end]
+}
+
+// skipAngles consumes a <...> type parameter or argument list.
+func (p *parser) skipAngles() {
+	depth := 0
+	for !p.eof() {
+		switch {
+		case p.is("<"):
+			depth++
+		case p.is(">"):
+			depth--
+		case p.is("(") || p.is("["):
+			p.skipGroup()
+			continue
+		case p.is("{") || p.is("}") || p.is(";"):
+			return
+		}
+		p.i++
+		if depth <= 0 {
+			return
+		}
+	}
+}
+
+// skipAnnotation consumes "@Name", "@a.b.Name", "@file:Name" and an
+// optional argument list.
+func (p *parser) skipAnnotation() {
+	p.i++ // '@'
+	for p.at(0).kind == tokIdent {
+		p.i++
+		if !p.is(".") && !p.is(":") || p.at(1).kind != tokIdent {
+			break
+		}
+		p.i++
+	}
+	if p.is("(") && !p.at(0).nl {
+		p.skipGroup()
+	}
+}
+
+// add records a symbol.
+func (p *parser) add(owner, name, kind string, start, end int, sig string) {
+	if end < start {
+		end = start
+	}
+	p.syms = append(p.syms, Symbol{
+		Symbol:    joinSym(p.pkg, owner, name),
+		Kind:      kind,
+		Path:      p.relPath,
+		Start:     start,
+		End:       end,
+		Signature: sig,
+	})
+}
+
+// callableExports lists "name()" for every callable symbol, in source order.
+func callableExports(syms []Symbol) []string {
+	var out []string
+	for _, s := range syms {
+		if isCallableKind(s.Kind) {
+			out = append(out, s.Symbol[strings.LastIndexByte(s.Symbol, '.')+1:]+"()")
+		}
+	}
+	return out
+}
+
+// splitParams splits parameter tokens at top-level commas.
+func splitParams(toks []token) [][]token {
+	var out [][]token
+	depth, start := 0, 0
+	for i, t := range toks {
+		if t.kind != tokPunct {
+			continue
+		}
+		switch t.text {
+		case "(", "[", "{", "<":
+			depth++
+		case ")", "]", "}", ">":
+			depth--
+		case ",":
+			if depth == 0 {
+				out = append(out, toks[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if start < len(toks) {
+		out = append(out, toks[start:])
+	}
+	return out
+}
+
+// dropAnnotations removes leading "@Name(...)" annotations and the given
+// modifier words from a parameter.
+func dropAnnotations(toks []token, modifiers ...string) []token {
+	for len(toks) > 0 {
+		switch {
+		case toks[0].text == "@" && toks[0].kind == tokPunct:
+			i := 1
+			for i < len(toks) && (toks[i].kind == tokIdent || toks[i].text == "." || toks[i].text == ":") {
+				i++
+			}
+			if i < len(toks) && toks[i].text == "(" {
+				depth := 0
+				for ; i < len(toks); i++ {
+					if toks[i].text == "(" {
+						depth++
+					} else if toks[i].text == ")" {
+						if depth--; depth == 0 {
+							i++
+							break
+						}
+					}
+				}
+			}
+			toks = toks[i:]
+		case toks[0].kind == tokIdent && hasWord(modifiers, toks[0].text):
+			toks = toks[1:]
+		default:
+			return toks
+		}
+	}
+	return toks
+}
+
+func hasWord(words []string, w string) bool {
+	for _, x := range words {
+		if x == w {
+			return true
+		}
+	}
+	return false
+}
+
+// spacedOps are written with a space on both sides by joinTokens.
+var spacedOps = map[string]bool{"|": true, "&": true, "=>": true, "->": true}
+
+// joinTokens renders tokens compactly: a space only between words and after
+// commas, around spacedOps and in "? extends T".
+func joinTokens(toks []token) string {
+	var b strings.Builder
+	for i, t := range toks {
+		if i > 0 {
+			prev := toks[i-1]
+			word := func(t token) bool { return t.kind != tokPunct }
+			switch {
+			case word(prev) && word(t), prev.text == ",":
+				b.WriteByte(' ')
+			case spacedOps[t.text], spacedOps[prev.text]:
+				b.WriteByte(' ')
+			case prev.text == "?" && (t.text == "extends" || t.text == "super"):
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(t.text)
+	}
+	return b.String()
+}
+
+// formatSignature renders parameter types as "(A, B)".
+func formatSignature(types []string) string {
+	return "(" + strings.Join(types, ", ") + ")"
+}
@@ -0,0 +1,211 @@
+package index
+
+// Java structure for the precise backend: package, (nested) classes,
+// interfaces, enums, records and annotation types, their methods and
+// constructors. Members are qualified by the full type path, e.g.
+// "com.acme.Outer.Inner.run"; local and anonymous classes inside method
+// bodies are not listed.
+
+// javaModifiers precede declarations in type bodies.
+var javaModifiers = map[string]bool{
+	"public": true, "protected": true, "private": true, "static": true,
+	"final": true, "abstract": true, "native": true, "synchronized": true,
+	"transient": true, "volatile": true, "strictfp": true, "default": true,
+	"sealed": true,
+}
+
+// parseJava is the precise Java extractor. The primary type is the first
+// public top-level type, else the first top-level type.
+func parseJava(relPath string, data []byte) (pkg, kind, typ string, exports []string, syms []Symbol) {
+	p := &parser{toks: tokenize("java", data), relPath: relPath}
+	if p.is("package") {
+		p.i++
+		name := ""
+		for !p.eof() && !p.is(";") {
+			name += p.at(0).text
+			p.i++
+		}
+		p.pkg = name
+	}
+	top := p.javaMembers("", "", false)
+	kind = "file"
+	for _, t := range top {
+		if t.public || kind == "file" {
+			kind, typ = t.kind, t.name
+			if t.public {
+				break
+			}
+		}
+	}
+	return p.pkg, kind, typ, callableExports(p.syms), p.syms
+}
+
+// javaType is a type declared directly in a body.
+type javaType struct {
+	kind, name string
+	public     bool
+}
+
+// javaMembers parses declarations up to the '}' closing the body of type
+// owner (named typeName), or to EOF at the top level, and returns the types
+// declared there. Enum bodies start with their constants.
+func (p *parser) javaMembers(owner, typeName string, isEnum bool) []javaType {
+	var types []javaType
+	if isEnum {
+		for !p.eof() && !p.is(";") && !p.is("}") {
+			if p.is("(") || p.is("{") {
+				p.skipGroup()
+				continue
+			}
+			p.i++
+		}
+	}
+	for !p.eof() && !p.is("}") {
+		switch {
+		case p.is(";"):
+			p.i++
+			continue
+		case p.is("import"):
+			for !p.eof() && !p.is(";") {
+				p.i++
+			}
+			continue
+		case p.is("{"):
+			p.skipGroup() // initializer block
+			continue
+		}
+		public := false
+		for {
+			switch {
+			case p.is("@") && !p.at(1).nl && p.at(1).text == "interface":
+			case p.is("@"):
+				p.skipAnnotation()
+				continue
+			case p.is("non") && p.at(1).text == "-" && p.at(2).text == "sealed":
+				p.i += 3
+				continue
+			case p.at(0).kind == tokIdent && javaModifiers[p.at(0).text]:
+				public = public || p.is("public")
+				p.i++
+				continue
+			}
+			break
+		}
+		if p.eof() || p.is("}") {
+			break
+		}
+		start := p.at(0).line
+		if k, ok := p.javaTypeKeyword(); ok {
+			name := p.at(0).text
+			p.i++
+			for !p.eof() && !p.is("{") && !p.is(";") && !p.is("}") {
+				if p.is("(") || p.is("[") {
+					p.skipGroup()
+					continue
+				}
+				p.i++
+			}
+			end := p.lastLine()
+			if p.is("{") {
+				p.i++
+				p.javaMembers(joinSym("", owner, name), name, k == "enum")
+				end = p.closeBrace()
+			}
+			p.add(owner, name, k, start, end, "")
+			types = append(types, javaType{kind: k, name: name, public: public})
+			continue
+		}
+		p.javaMember(owner, typeName, start)
+	}
+	return types
+}
+
+// javaTypeKeyword consumes a type keyword ("record" only when followed by a
+// name) and reports the symbol kind.
+func (p *parser) javaTypeKeyword() (string, bool) {
+	switch {
+	case p.is("@") && p.at(1).text == "interface":
+		p.i += 2
+		return "annotation", true
+	case p.is("class"), p.is("interface"), p.is("enum"):
+		k := p.at(0).text
+		p.i++
+		return k, true
+	case p.is("record") && p.at(1).kind == tokIdent && (p.at(2).text == "(" || p.at(2).text == "<"):
+		p.i++
+		return "record", true
+	}
+	return "", false
+}
+
+// javaMember parses a field, method or constructor starting at the current
+// token (modifiers already consumed).
+func (p *parser) javaMember(owner, typeName string, start int) {
+	var decl []token
+	for !p.eof() {
+		switch {
+		case p.is("<") && len(decl) == 0:
+			p.skipAngles() // generic method type parameters
+			continue
+		case p.is("("):
+			if len(decl) == 0 || decl[len(decl)-1].kind != tokIdent {
+				p.skipGroup()
+				continue
+			}
+			name := decl[len(decl)-1].text
+			kind := "method"
+			if name == typeName && len(decl) == 1 {
+				kind = "ctor"
+			}
+			sig := javaSignature(p.group())
+			for !p.eof() && !p.is("{") && !p.is(";") && !p.is("}") {
+				p.i++ // throws clause, annotation element default
+			}
+			end := p.lastLine()
+			if p.is("{") {
+				end = p.skipGroup()
+			} else if p.is(";") {
+				end = p.at(0).line
+				p.i++
+			}
+			p.add(owner, name, kind, start, end, sig)
+			return
+		case p.is("{") && len(decl) == 1 && decl[0].text == typeName:
+			// compact record constructor
+			p.add(owner, typeName, "ctor", start, p.skipGroup(), "")
+			return
+		case p.is("="), p.is(";"), p.is("{"):
+			for !p.eof() && !p.is(";") && !p.is("}") {
+				if p.is("(") || p.is("[") || p.is("{") {
+					p.skipGroup()
+					continue
+				}
+				p.i++
+			}
+			if p.is(";") {
+				p.i++
+			}
+			return
+		case p.is("}"):
+			return
+		}
+		decl = append(decl, p.at(0))
+		p.i++
+	}
+}
+
+// javaSignature lists parameter types, without annotations, "final" or
+// names: "(String, int...)".
+func javaSignature(params []token) string {
+	var types []string
+	for _, prm := range splitParams(params) {
+		prm = dropAnnotations(prm, "final")
+		if len(prm) > 1 && prm[len(prm)-1].kind == tokIdent {
+			prm = prm[:len(prm)-1]
+		}
+		if len(prm) > 0 {
+			types = append(types, joinTokens(prm))
+		}
+	}
+	return formatSignature(types)
+}
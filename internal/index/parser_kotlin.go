@@ -0,0 +1,284 @@
+package index
+
+import "strings"
+
+// Kotlin structure for the precise backend: package, (nested) classes,
+// interfaces, objects and companion objects, functions (including
+// extensions and expression bodies) and secondary constructors. Kotlin has
+// no mandatory semicolons, so a declaration without a body ends at the last
+// token before the next declaration that starts on a new line.
+
+// ktModifiers precede declarations.
+var ktModifiers = map[string]bool{
+	"public": true, "private": true, "protected": true, "internal": true,
+	"open": true, "abstract": true, "final": true, "sealed": true,
+	"data": true, "inner": true, "override": true, "suspend": true,
+	"inline": true, "tailrec": true, "operator": true, "infix": true,
+	"external": true, "value": true, "const": true, "lateinit": true,
+	"expect": true, "actual": true, "annotation": true, "enum": true,
+	"companion": true,
+}
+
+// ktKeywords start declarations.
+var ktKeywords = map[string]bool{
+	"fun": true, "val": true, "var": true, "class": true, "interface": true,
+	"object": true, "constructor": true, "init": true, "typealias": true,
+}
+
+// ktParamModifiers are dropped from parameters in signatures.
+var ktParamModifiers = []string{
+	"noinline", "crossinline", "val", "var", "private", "public", "protected",
+	"internal", "override", "open", "final", "vararg",
+}
+
+// parseKotlin is the precise Kotlin extractor. The primary type is the first
+// top-level class, interface or object.
+func parseKotlin(relPath string, data []byte) (pkg, kind, typ string, exports []string, syms []Symbol) {
+	p := &parser{toks: tokenize("kt", data), relPath: relPath}
+	kind = "file"
+	for _, t := range p.ktMembers("", false) {
+		kind, typ = t.kind, t.name
+		break
+	}
+	return p.pkg, kind, typ, callableExports(p.syms), p.syms
+}
+
+// ktDeclStart reports whether t can begin a declaration.
+func ktDeclStart(t token) bool {
+	if t.kind == tokPunct {
+		return t.text == "@" || t.text == "}"
+	}
+	return t.kind == tokIdent && (ktModifiers[t.text] || ktKeywords[t.text])
+}
+
+// ktSkipDecl consumes the rest of a declaration and returns its last line.
+func (p *parser) ktSkipDecl() int {
+	end := p.lastLine()
+	for first := true; !p.eof() && !p.is("}"); first = false {
+		t := p.at(0)
+		if !first && t.nl && ktDeclStart(t) {
+			break
+		}
+		if p.is(";") {
+			p.i++
+			return t.line
+		}
+		if _, open := closerOf[t.text]; open && t.kind == tokPunct {
+			end = p.skipGroup()
+			continue
+		}
+		end = t.line
+		p.i++
+	}
+	return end
+}
+
+// ktMembers parses declarations up to the '}' closing owner's body, or to
+// EOF at the top level, and returns the types declared there.
+func (p *parser) ktMembers(owner string, isEnum bool) []javaType {
+	var types []javaType
+	if isEnum {
+		for first := true; !p.eof() && !p.is("}"); first = false {
+			if p.is(";") {
+				p.i++
+				break
+			}
+			if !first && p.at(0).nl && ktDeclStart(p.at(0)) && !p.is("@") {
+				break
+			}
+			if p.is("(") || p.is("{") {
+				p.skipGroup()
+				continue
+			}
+			p.i++
+		}
+	}
+	typeName := owner
+	if i := strings.LastIndexByte(owner, '.'); i >= 0 {
+		typeName = owner[i+1:]
+	}
+	for !p.eof() && !p.is("}") {
+		switch {
+		case p.is(";"):
+			p.i++
+			continue
+		case p.is("@"):
+			p.skipAnnotation()
+			continue
+		case p.is("package") && owner == "":
+			p.i++
+			for p.at(0).kind == tokIdent || p.is(".") && !p.at(0).nl {
+				p.pkg += p.at(0).text
+				p.i++
+				if p.at(0).nl {
+					break
+				}
+			}
+			continue
+		case p.is("import") && owner == "":
+			p.i++
+			for !p.eof() && !p.at(0).nl {
+				p.i++
+			}
+			continue
+		}
+		start := p.at(0).line
+		isEnumClass, companion := false, false
+		for p.at(0).kind == tokIdent && ktModifiers[p.at(0).text] && p.at(1).kind == tokIdent {
+			isEnumClass = isEnumClass || p.is("enum")
+			companion = companion || p.is("companion")
+			p.i++
+		}
+		if p.is("fun") && p.at(1).text == "interface" {
+			p.i++
+		}
+		switch {
+		case p.is("class"), p.is("interface"), p.is("object"):
+			kind := p.at(0).text
+			if isEnumClass {
+				kind = "enum"
+			}
+			p.i++
+			name := "Companion"
+			if p.at(0).kind == tokIdent && !p.at(0).nl {
+				name = p.at(0).text
+				p.i++
+			} else if !companion {
+				p.ktSkipDecl() // object expression
+				continue
+			}
+			end := p.lastLine()
+			for !p.eof() && !p.is("{") && !p.is("}") && !p.is(";") && !(p.at(0).nl && ktDeclStart(p.at(0))) {
+				if p.is("(") || p.is("[") {
+					end = p.skipGroup()
+					continue
+				}
+				end = p.at(0).line
+				p.i++
+			}
+			if p.is("{") {
+				p.i++
+				p.ktMembers(joinSym("", owner, name), isEnumClass)
+				end = p.closeBrace()
+			}
+			p.add(owner, name, kind, start, end, "")
+			types = append(types, javaType{kind: kind, name: name})
+		case p.is("fun"):
+			p.i++
+			p.ktFun(owner, start)
+		case p.is("constructor") && owner != "":
+			p.i++
+			if !p.is("(") {
+				p.ktSkipDecl()
+				continue
+			}
+			sig := ktSignature(p.group())
+			end := p.lastLine()
+			for !p.eof() && !p.is("{") && !p.is("}") && !(p.at(0).nl && ktDeclStart(p.at(0))) {
+				if p.is("(") {
+					end = p.skipGroup() // delegation: this(...) / super(...)
+					continue
+				}
+				end = p.at(0).line
+				p.i++
+			}
+			if p.is("{") {
+				end = p.skipGroup()
+			}
+			p.add(owner, typeName, "ctor", start, end, sig)
+		case p.is("init") && p.at(1).text == "{":
+			p.i++
+			p.skipGroup()
+		default:
+			p.i++
+			p.ktSkipDecl()
+		}
+	}
+	return types
+}
+
+// ktFun parses a function after "fun": type parameters, receiver, name,
+// parameters, return type and a block or expression body.
+func (p *parser) ktFun(owner string, start int) {
+	if p.is("<") {
+		p.skipAngles()
+	}
+	name := ""
+	for !p.eof() && !p.is("(") {
+		if p.is("{") || p.is("}") || p.is("=") || p.at(0).nl && ktDeclStart(p.at(0)) {
+			return
+		}
+		if p.at(0).kind == tokIdent {
+			name = p.at(0).text
+		}
+		p.i++
+	}
+	if p.eof() || name == "" {
+		return
+	}
+	sig := ktSignature(p.group())
+	end := p.lastLine()
+	for !p.eof() && !p.is("{") && !p.is("=") && !p.is("}") && !p.is(";") && !(p.at(0).nl && ktDeclStart(p.at(0))) {
+		if p.is("(") {
+			end = p.skipGroup() // function return type
+			continue
+		}
+		end = p.at(0).line
+		p.i++
+	}
+	switch {
+	case p.is("{"):
+		end = p.skipGroup()
+	case p.is("="):
+		p.i++
+		end = p.ktSkipDecl()
+	}
+	kind := "method"
+	if owner == "" {
+		kind = "func"
+	}
+	p.add(owner, name, kind, start, end, sig)
+}
+
+// ktSignature lists parameter types: "(String, Int = 0)" becomes
+// "(String, Int)", a vararg parameter "vararg Int".
+func ktSignature(params []token) string {
+	var types []string
+	for _, prm := range splitParams(params) {
+		vararg := len(prm) > 0 && prm[0].text == "vararg"
+		prm = dropAnnotations(prm, ktParamModifiers...)
+		var typ []token
+		depth := 0
+		for i, t := range prm {
+			if t.kind == tokPunct {
+				switch t.text {
+				case "(", "[", "{", "<":
+					depth++
+				case ")", "]", "}", ">":
+					depth--
+				}
+			}
+			if depth != 0 {
+				continue
+			}
+			if t.text == ":" && typ == nil {
+				typ = prm[i+1:]
+			} else if t.text == "=" && typ != nil {
+				typ = typ[:len(typ)-len(prm[i:])]
+				break
+			}
+		}
+		if typ == nil {
+			typ = prm
+		}
+		if len(typ) == 0 {
+			continue
+		}
+		s := joinTokens(typ)
+		if vararg {
+			s = "vararg " + s
+		}
+		types = append(types, s)
+	}
+	return formatSignature(types)
+}
@@ -0,0 +1,318 @@
+package index
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// symbolRanges renders symbols as "name kind start-end signature" lines.
+func symbolRanges(syms []Symbol) []string {
+	out := make([]string, 0, len(syms))
+	for _, s := range syms {
+		line := fmt.Sprintf("%s %s %d-%d", s.Symbol, s.Kind, s.Start, s.End)
+		if s.Signature != "" {
+			line += " " + s.Signature
+		}
+		out = append(out, line)
+	}
+	return out
+}
+
+func TestPreciseJavaNestedTypesAndOverloads(t *testing.T) {
+	SetParser(ParserPrecise)
+	defer SetParser("")
+	src := `package com.acme;
+
+/* class Fake { */
+public class Server {
+    private static final String BRACE = "}";
+
+    public Server() {
+        this(1);
+    }
+
+    @Override
+    public void run() {
+        Runnable r = new Runnable() { public void run() {} };
+    }
+
+    public <R> R map(final Function<? super String, R> f, int... xs) { return null; }
+    public int map(String s) { return 0; }
+
+    static class Inner {
+        void go() {
+        }
+    }
+}
+`
+	syms := FileSymbols("src/Server.java", []byte(src))
+	want := []string{
+		"com.acme.Server class 4-23",
+		"com.acme.Server.Server ctor 7-9 ()",
+		"com.acme.Server.run method 12-14 ()",
+		"com.acme.Server.map method 16-16 (Function<? super String, R>, int...)",
+		"com.acme.Server.map method 17-17 (String)",
+		"com.acme.Server.Inner class 19-22",
+		"com.acme.Server.Inner.go method 20-21 ()",
+	}
+	if got := symbolRanges(syms); !reflect.DeepEqual(got, want) {
+		t.Fatalf("symbols:\n got %q\nwant %q", got, want)
+	}
+	if run, _ := symbolByName(syms, "com.acme.Server.run"); !reflect.DeepEqual(run.Annotations, []string{"@Override"}) {
+		t.Fatalf("run annotations = %q", run.Annotations)
+	}
+}
+
+func TestPreciseKotlinBodiesAndSignatures(t *testing.T) {
+	src := `package com.acme
+
+data class User(val name: String) {
+    constructor(id: Int) : this(id.toString()) {
+        println("${id} }")
+    }
+    fun greet(prefix: String = "hi"): String = "$prefix $name"
+    companion object {
+        fun of(vararg parts: String): User = User(parts.joinToString())
+    }
+}
+
+fun top(a: Int) =
+    a + 1
+`
+	pkg, kind, typ, exports, syms := parseKotlin("User.kt", []byte(src))
+	if pkg != "com.acme" || kind != "class" || typ != "User" {
+		t.Fatalf("pkg/kind/typ = %q/%q/%q", pkg, kind, typ)
+	}
+	finalizeSymbolEnds(syms, CountLines([]byte(src)), true)
+	want := []string{
+		"com.acme.User class 3-11",
+		"com.acme.User.User ctor 4-6 (Int)",
+		"com.acme.User.greet method 7-7 (String)",
+		"com.acme.User.Companion object 8-10",
+		"com.acme.User.Companion.of method 9-9 (vararg String)",
+		"com.acme.top func 13-14 (Int)",
+	}
+	if got := symbolRanges(syms); !reflect.DeepEqual(got, want) {
+		t.Fatalf("symbols:\n got %q\nwant %q", got, want)
+	}
+	if want := []string{"User()", "greet()", "of()", "top()"}; !reflect.DeepEqual(exports, want) {
+		t.Fatalf("exports = %q, want %q", exports, want)
+	}
+}
+
+func TestPreciseTSClassMembersAndLiterals(t *testing.T) {
+	src := "export const re = /[}{]/g;\n" +
+		"export class Svc {\n" +
+		"  private s = `a ${'}'} b`\n" +
+		"  constructor(private readonly dep: Dep, opt?: Opts) {\n" +
+		"  }\n" +
+		"  find(id: string): Item;\n" +
+		"  find(id: any): Item {\n" +
+		"    return {} as Item;\n" +
+		"  }\n" +
+		"  handle = async (e: Event): Promise<void> => {\n" +
+		"  }\n" +
+		"}\n" +
+		"export function helper<T>(a: T, ...rest: T[]): { a: T } {\n" +
+		"  return { a }\n" +
+		"}\n" +
+		"namespace NS {\n" +
+		"  export const arrow = (x: number) => x * 2\n" +
+		"}\n"
+	_, kind, typ, exports, syms := parseTS("svc.ts", []byte(src))
+	if kind != "class" || typ != "Svc" {
+		t.Fatalf("kind/typ = %q/%q", kind, typ)
+	}
+	finalizeSymbolEnds(syms, CountLines([]byte(src)), true)
+	want := []string{
+		"Svc class 2-12",
+		"Svc.constructor ctor 4-5 (Dep, Opts)",
+		"Svc.find method 6-6 (string)",
+		"Svc.find method 7-9 (any)",
+		"Svc.handle method 10-11 (Event)",
+		"helper func 13-15 (T, ...T[])",
+		"NS namespace 16-18",
+		"NS.arrow func 17-17 (number)",
+	}
+	if got := symbolRanges(syms); !reflect.DeepEqual(got, want) {
+		t.Fatalf("symbols:\n got %q\nwant %q", got, want)
+	}
+	if want := []string{"helper()", "arrow()"}; !reflect.DeepEqual(exports, want) {
+		t.Fatalf("exports = %q, want %q", exports, want)
+	}
+}
+
+func TestParserRegexIsDefault(t *testing.T) {
+	src := "package a;\npublic class A {\n  void f() {}\n}\n"
+	syms := FileSymbols("A.java", []byte(src))
+	for _, s := range syms {
+		if s.Signature != "" {
+			t.Fatalf("regex backend set a signature: %+v", s)
+		}
+	}
+	if len(syms) != 1 || syms[0].Start != 3 {
+		t.Fatalf("regex backend ranges changed: %+v", syms)
+	}
+}
+
+// preciseCases are sources whose tricky constructs the precise backend must
+// see through, with the symbols it reports.
+var preciseCases = []struct {
+	name, path, src string
+	want            []string
+}{
+	{
+		"java generics closing with >>", "p/Cache.java",
+		"package p;\n" +
+			"class Cache<K, V extends Comparable<V>> {\n" +
+			"    Map<K, List<V>> byKey() { return null; }\n" +
+			"    <T extends List<Map<K, V>>> T pick(Map<String, List<Integer>> m) { return null; }\n" +
+			"    int after() { return a >> 2 >>> 1; }\n" +
+			"}\n",
+		[]string{
+			"p.Cache class 2-6",
+			"p.Cache.byKey method 3-3 ()",
+			"p.Cache.pick method 4-4 (Map<String, List<Integer>>)",
+			"p.Cache.after method 5-5 ()",
+		},
+	},
+	{
+		"java annotations with arguments", "p/Users.java",
+		"package p;\n" +
+			"@RestController\n" +
+			"@RequestMapping(value = \"/users\", produces = {\"application/json\"})\n" +
+			"public class Users {\n" +
+			"    @GetMapping(path = \"/{id}\", params = \"x=)\")\n" +
+			"    public User get(@PathVariable(\"id\") long id) { return null; }\n" +
+			"}\n",
+		[]string{
+			"p.Users class 4-7",
+			"p.Users.get method 6-6 (long)",
+		},
+	},
+	{
+		"java nested and anonymous classes", "p/Outer.java",
+		"package p;\n" +
+			"class Outer {\n" +
+			"    interface Cb { void call(); }\n" +
+			"    enum Mode { A, B; void m() {} }\n" +
+			"    void run() {\n" +
+			"        Cb cb = new Cb() {\n" +
+			"            public void call() {}\n" +
+			"        };\n" +
+			"    }\n" +
+			"    static class Nested {\n" +
+			"        class Deeper { void d() {} }\n" +
+			"    }\n" +
+			"}\n",
+		[]string{
+			"p.Outer class 2-13",
+			"p.Outer.Cb interface 3-3",
+			"p.Outer.Cb.call method 3-3 ()",
+			"p.Outer.Mode enum 4-4",
+			"p.Outer.Mode.m method 4-4 ()",
+			"p.Outer.run method 5-9 ()",
+			"p.Outer.Nested class 10-12",
+			"p.Outer.Nested.Deeper class 11-11",
+			"p.Outer.Nested.Deeper.d method 11-11 ()",
+		},
+	},
+	{
+		"kotlin extension funs and a named companion", "p/Repo.kt",
+		"package p\n" +
+			"\n" +
+			"fun String.shout(): String = uppercase()\n" +
+			"fun <T> List<T>.second(): T = this[1]\n" +
+			"\n" +
+			"class Repo {\n" +
+			"    companion object Factory {\n" +
+			"        fun create(): Repo = Repo()\n" +
+			"    }\n" +
+			"    fun Int.twice() = this * 2\n" +
+			"}\n",
+		[]string{
+			"p.shout func 3-3 ()",
+			"p.second func 4-4 ()",
+			"p.Repo class 6-11",
+			"p.Repo.Factory object 7-9",
+			"p.Repo.Factory.create method 8-8 ()",
+			"p.Repo.twice method 10-10 ()",
+		},
+	},
+	{
+		"ts decorators, overloads, template and regex literals", "x.ts",
+		"@Component({ selector: \"app-x\", template: `<div>{{ x }}</div>` })\n" +
+			"export class X {\n" +
+			"  @Input() name = \"\";\n" +
+			"  @HostListener(\"click\", [\"$event\"])\n" +
+			"  onClick(e: MouseEvent) {}\n" +
+			"  parse(s: string): number;\n" +
+			"  parse(s: string, radix: number): number;\n" +
+			"  parse(s: string, radix?: number): number {\n" +
+			"    const re = /\\{(\\d+)\\}/g;\n" +
+			"    return `${s.replace(re, \"}\")}`.length;\n" +
+			"  }\n" +
+			"}\n" +
+			"export function f(a: string): void;\n" +
+			"export function f(a: number): void;\n" +
+			"export function f(a: any) {}\n",
+		[]string{
+			"X class 2-12",
+			"X.onClick method 5-5 (MouseEvent)",
+			"X.parse method 6-6 (string)",
+			"X.parse method 7-7 (string, number)",
+			"X.parse method 8-11 (string, number)",
+			"f func 13-13 (string)",
+			"f func 14-14 (number)",
+			"f func 15-15 (any)",
+		},
+	},
+}
+
+func TestPreciseTrickyConstructs(t *testing.T) {
+	SetParser(ParserPrecise)
+	defer SetParser("")
+	for _, tc := range preciseCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := symbolRanges(FileSymbols(tc.path, []byte(tc.src))); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("symbols:\n got %q\nwant %q", got, tc.want)
+			}
+		})
+	}
+
+	syms := FileSymbols("x.ts", []byte(preciseCases[4].src))
+	if x, _ := symbolByName(syms, "X"); !reflect.DeepEqual(x.Annotations, []string{"@Component({ selector: \"app-x\", template: `<div>{{ x }}</div>` })"}) {
+		t.Fatalf("X annotations = %q", x.Annotations)
+	}
+	syms = FileSymbols("p/Users.java", []byte(preciseCases[1].src))
+	if get, _ := symbolByName(syms, "p.Users.get"); !reflect.DeepEqual(get.Annotations, []string{"@GetMapping(path = \"/{id}\", params = \"x=)\")"}) {
+		t.Fatalf("get annotations = %q", get.Annotations)
+	}
+}
+
+// FuzzTokenize checks that the lexer and the precise extractors terminate
+// without panicking on arbitrary input and keep token lines in order.
+func FuzzTokenize(f *testing.F) {
+	for _, tc := range preciseCases {
+		f.Add(tc.src)
+	}
+	f.Add("`${`${'}'}`}` /[/]/ /* unterminated")
+	f.Add("\"\"\"text\nblock\"\"\" '\\'' \"${a}\"")
+	f.Fuzz(func(t *testing.T, src string) {
+		data := []byte(src)
+		lines := CountLines(data)
+		for _, lang := range []string{"java", "kt", "ts"} {
+			prev := 1
+			for _, tok := range tokenize(lang, data) {
+				if tok.line < prev || tok.line > lines+1 {
+					t.Fatalf("%s: token %q on line %d after line %d (%d lines)", lang, tok.text, tok.line, prev, lines)
+				}
+				prev = tok.line
+			}
+		}
+		parseJava("F.java", data)
+		parseKotlin("F.kt", data)
+		parseTS("f.ts", data)
+	})
+}
@@ -0,0 +1,541 @@
+package index
+
+// TS/JS structure for the precise backend: top-level and namespace
+// classes, interfaces, enums, functions (including overload signatures) and
+// arrow functions bound to const/let/var, class members, and the shorthand
+// methods of exported const object literals. Statements and members may end
+// at a line break, as in ASI-style code. Exports follow the regex extractor:
+// exported functions and arrow functions, let/var names and re-exports.
+
+// tsDeclWords start top-level statements.
+var tsDeclWords = map[string]bool{
+	"import": true, "export": true, "class": true, "function": true,
+	"const": true, "let": true, "var": true, "interface": true, "type": true,
+	"enum": true, "namespace": true, "module": true, "declare": true,
+	"abstract": true, "async": true,
+}
+
+// tsMemberModifiers precede class members.
+var tsMemberModifiers = map[string]bool{
+	"public": true, "private": true, "protected": true, "static": true,
+	"readonly": true, "abstract": true, "async": true, "override": true,
+	"declare": true, "accessor": true, "get": true, "set": true,
+}
+
+// tsParamModifiers are dropped from parameters in signatures.
+var tsParamModifiers = []string{"public", "private", "protected", "readonly", "override"}
+
+// tsContinuation are tokens after which a line break does not end a
+// statement or member.
+var tsContinuation = map[string]bool{
+	"=": true, ",": true, ".": true, "(": true, "[": true, "=>": true,
+	"+": true, "-": true, "*": true, "/": true, "?": true, ":": true,
+	"|": true, "&": true, "<": true, "!": true, "%": true,
+}
+
+// tsTypeLiteralAfter are tokens after which '{' opens an object type rather
+// than a body.
+var tsTypeLiteralAfter = map[string]bool{
+	":": true, "|": true, "&": true, "<": true, ",": true, "(": true, "=>": true, "[": true,
+}
+
+// parseTS is the precise TS/JS extractor. The primary type is the first
+// exported class, else the first exported interface.
+func parseTS(relPath string, data []byte) (pkg, kind, typ string, exports []string, syms []Symbol) {
+	p := &parser{toks: tokenize("ts", data), relPath: relPath}
+	ts := &tsParse{parser: p}
+	ts.statements("")
+	kind, typ = "file", ""
+	if ts.class != "" {
+		kind, typ = "class", ts.class
+	} else if ts.iface != "" {
+		kind, typ = "interface", ts.iface
+	}
+	for _, re := range tsReExports(data) {
+		if re.Name != "*" {
+			ts.exports = append(ts.exports, re.Name+"()")
+		}
+	}
+	return "", kind, typ, ts.exports, p.syms
+}
+
+type tsParse struct {
+	*parser
+	exports []string
+	class   string // first exported class
+	iface   string // first exported interface
+}
+
+// continues reports whether the line break before the current token is
+// inside an expression or type.
+func (p *tsParse) continues() bool {
+	return p.i > 0 && tsContinuation[p.toks[p.i-1].text] && p.toks[p.i-1].kind == tokPunct
+}
+
+// skipStatement consumes a statement up to ';', the enclosing '}' or a line
+// break before a declaration keyword, and returns its last line.
+func (p *tsParse) skipStatement() int {
+	end := p.lastLine()
+	for first := true; !p.eof() && !p.is("}"); first = false {
+		t := p.at(0)
+		if !first && t.nl && (tsDeclWords[t.text] || t.text == "@") && !p.continues() {
+			break
+		}
+		if p.is(";") {
+			p.i++
+			return t.line
+		}
+		if _, open := closerOf[t.text]; open && t.kind == tokPunct {
+			end = p.skipGroup()
+			continue
+		}
+		end = t.line
+		p.i++
+	}
+	return end
+}
+
+// memberStart reports whether the current token begins a new class member
+// on a new line.
+func (p *tsParse) memberStart() bool {
+	t := p.at(0)
+	return t.nl && !p.continues() &&
+		(t.kind == tokIdent || t.kind == tokString || t.text == "@" || t.text == "#" || t.text == "[")
+}
+
+// skipMember consumes a property up to ';', the closing '}' or the next
+// member, and returns its last line.
+func (p *tsParse) skipMember() int {
+	end := p.lastLine()
+	for first := true; !p.eof() && !p.is("}"); first = false {
+		if !first && p.memberStart() {
+			break
+		}
+		if p.is(";") {
+			p.i++
+			return p.lastLine()
+		}
+		if _, open := closerOf[p.at(0).text]; open && p.at(0).kind == tokPunct {
+			end = p.skipGroup()
+			continue
+		}
+		end = p.at(0).line
+		p.i++
+	}
+	return end
+}
+
+// returnType skips an optional ": Type" annotation; '{' opens an object type
+// only after a type operator.
+func (p *tsParse) returnType() {
+	if !p.is(":") {
+		return
+	}
+	p.i++
+	for first := true; !p.eof(); first = false {
+		switch {
+		case p.is("{"):
+			if !tsTypeLiteralAfter[p.toks[p.i-1].text] {
+				return
+			}
+			p.skipGroup()
+			continue
+		case p.is("(") || p.is("["):
+			p.skipGroup()
+			continue
+		case p.is(";"), p.is("}"), p.is("="):
+			return
+		case p.is("=>") && p.toks[p.i-1].text != ")":
+			return // an arrow body follows; "=>" after ')' belongs to a function type
+		case !first && p.memberStart():
+			return
+		}
+		p.i++
+	}
+}
+
+// body consumes a '{' body or a bodiless declaration's ';' and returns the
+// last line.
+func (p *tsParse) body() int {
+	if p.is("{") {
+		return p.skipGroup()
+	}
+	end := p.lastLine()
+	if p.is(";") {
+		end = p.at(0).line
+		p.i++
+	}
+	return end
+}
+
+// statements parses declarations up to the '}' closing a namespace, or to
+// EOF at the top level.
+func (p *tsParse) statements(owner string) {
+	for !p.eof() && !p.is("}") {
+		if p.is(";") {
+			p.i++
+			continue
+		}
+		if p.is("@") {
+			p.skipAnnotation()
+			continue
+		}
+		start := p.at(0).line
+		exported, def := false, false
+		if p.is("export") {
+			exported = true
+			p.i++
+			if p.is("default") {
+				def = true
+				p.i++
+			}
+		}
+		for p.is("declare") || p.is("abstract") || p.is("async") || p.is("const") && p.at(1).text == "enum" {
+			p.i++
+		}
+		switch {
+		case p.is("class"):
+			p.i++
+			name := ""
+			if p.at(0).kind == tokIdent && !p.is("extends") && !p.is("implements") {
+				name = p.at(0).text
+			} else if def {
+				name = "default"
+			}
+			p.headerTo()
+			end := p.lastLine()
+			if p.is("{") {
+				p.i++
+				p.classMembers(joinSym("", owner, name))
+				end = p.closeBrace()
+			}
+			if name != "" {
+				p.add(owner, name, "class", start, end, "")
+				if exported && p.class == "" && owner == "" {
+					p.class = name
+				}
+			}
+		case p.is("interface"), p.is("enum"):
+			kind := p.at(0).text
+			p.i++
+			if p.at(0).kind != tokIdent {
+				p.skipStatement()
+				continue
+			}
+			name := p.at(0).text
+			p.headerTo()
+			end := p.body()
+			p.add(owner, name, kind, start, end, "")
+			if kind == "interface" && exported && p.iface == "" && owner == "" {
+				p.iface = name
+			}
+		case (p.is("namespace") || p.is("module")) && p.at(1).kind == tokIdent:
+			p.i++
+			name := ""
+			for !p.eof() && !p.is("{") && !p.is(";") {
+				name += p.at(0).text
+				p.i++
+			}
+			end := p.lastLine()
+			if p.is("{") {
+				p.i++
+				p.statements(joinSym("", owner, name))
+				end = p.closeBrace()
+			}
+			p.add(owner, name, "namespace", start, end, "")
+		case p.is("function"):
+			p.i++
+			if p.is("*") {
+				p.i++
+			}
+			name := "default"
+			if p.at(0).kind == tokIdent {
+				name = p.at(0).text
+				p.i++
+			} else if !def {
+				p.skipStatement()
+				continue
+			}
+			if p.is("<") {
+				p.skipAngles()
+			}
+			if !p.is("(") {
+				p.skipStatement()
+				continue
+			}
+			sig := tsSignature(p.group())
+			p.returnType()
+			p.add(owner, name, "func", start, p.body(), sig)
+			if exported {
+				p.exports = append(p.exports, name+"()")
+			}
+		case p.is("const"), p.is("let"), p.is("var"):
+			p.variable(owner, start, exported)
+		default:
+			p.skipStatement()
+		}
+	}
+}
+
+// headerTo advances to the '{' opening a declaration body, stopping early
+// at ';' or the enclosing '}'.
+func (p *tsParse) headerTo() {
+	for !p.eof() && !p.is("{") && !p.is(";") && !p.is("}") {
+		if p.is("(") || p.is("[") {
+			p.skipGroup()
+			continue
+		}
+		p.i++
+	}
+}
+
+// variable parses a const/let/var statement: arrow and function
+// expressions become functions, a const object literal contributes its
+// shorthand methods.
+func (p *tsParse) variable(owner string, start int, exported bool) {
+	isConst := p.is("const")
+	p.i++
+	if p.at(0).kind != tokIdent {
+		p.skipStatement() // destructuring
+		return
+	}
+	name := p.at(0).text
+	p.i++
+	for !p.eof() && !p.is("=") && !p.is(";") && !p.is("}") && !(p.at(0).nl && !p.continues()) {
+		if p.is("(") || p.is("[") || p.is("{") {
+			p.skipGroup() // type annotation
+			continue
+		}
+		p.i++
+	}
+	if !p.is("=") {
+		p.skipStatement()
+		if exported && !isConst {
+			p.exports = append(p.exports, name)
+		}
+		return
+	}
+	p.i++
+	if sig, ok := p.arrow(); ok {
+		p.add(owner, name, "func", start, p.skipStatement(), sig)
+		if exported {
+			p.exports = append(p.exports, name+"()")
+		}
+		return
+	}
+	if exported && !isConst {
+		p.exports = append(p.exports, name)
+	}
+	if isConst && exported && p.is("{") {
+		p.i++
+		p.objectMethods(joinSym("", owner, name))
+		p.closeBrace()
+		if p.at(0).nl && !p.continues() {
+			return
+		}
+	}
+	p.skipStatement()
+}
+
+// arrow recognises "[async] (params) [: T] =>", "[async] x =>" and
+// "[async] function [name](params)" at the current token and consumes up to
+// the body. It returns the parameter signature.
+func (p *tsParse) arrow() (string, bool) {
+	save := p.i
+	if p.is("async") {
+		p.i++
+	}
+	switch {
+	case p.is("function"):
+		p.i++
+		if p.is("*") {
+			p.i++
+		}
+		if p.at(0).kind == tokIdent {
+			p.i++
+		}
+		if p.is("<") {
+			p.skipAngles()
+		}
+		if p.is("(") {
+			sig := tsSignature(p.group())
+			p.returnType()
+			return sig, true
+		}
+	case p.at(0).kind == tokIdent && p.at(1).text == "=>":
+		p.i += 2
+		return formatSignature([]string{"any"}), true
+	case p.is("(") || p.is("<"):
+		if p.is("<") {
+			p.skipAngles()
+		}
+		if !p.is("(") {
+			break
+		}
+		sig := tsSignature(p.group())
+		p.returnType()
+		if p.is("=>") {
+			p.i++
+			return sig, true
+		}
+	}
+	p.i = save
+	return "", false
+}
+
+// classMembers parses a class body up to its closing '}'.
+func (p *tsParse) classMembers(owner string) {
+	for !p.eof() && !p.is("}") {
+		if p.is(";") || p.is(",") {
+			p.i++
+			continue
+		}
+		if p.is("@") {
+			p.skipAnnotation()
+			continue
+		}
+		start := p.at(0).line
+		for p.at(0).kind == tokIdent && tsMemberModifiers[p.at(0).text] && p.modifierApplies() {
+			p.i++
+		}
+		if p.is("*") {
+			p.i++
+		}
+		name := ""
+		switch {
+		case p.is("#") && p.at(1).kind == tokIdent:
+			name = "#" + p.at(1).text
+			p.i += 2
+		case p.at(0).kind == tokIdent:
+			name = p.at(0).text
+			p.i++
+		case p.is("["):
+			p.skipGroup()
+		case p.is("{") || p.is("("):
+			p.skipGroup() // static block, stray group
+			continue
+		default:
+			p.i++
+		}
+		if p.is("?") || p.is("!") {
+			p.i++
+		}
+		if p.is("<") {
+			p.skipAngles()
+		}
+		switch {
+		case p.is("("):
+			sig := tsSignature(p.group())
+			p.returnType()
+			end := p.body()
+			if name == "" {
+				continue
+			}
+			kind := "method"
+			if name == "constructor" {
+				kind = "ctor"
+			}
+			p.add(owner, name, kind, start, end, sig)
+		case p.is("="):
+			p.i++
+			sig, ok := p.arrow()
+			end := p.skipMember()
+			if ok && name != "" {
+				p.add(owner, name, "method", start, end, sig)
+			}
+		default:
+			p.skipMember()
+		}
+	}
+}
+
+// modifierApplies reports whether the modifier-like current token is a
+// modifier rather than a member named "static", "get" and so on.
+func (p *tsParse) modifierApplies() bool {
+	next := p.at(1)
+	if next.nl {
+		return false
+	}
+	switch next.text {
+	case "(", ":", "=", ";", "?", "!", "<", "}", ",":
+		return false
+	}
+	return true
+}
+
+// objectMethods lists the shorthand methods of an object literal up to its
+// closing '}'; other entries are skipped.
+func (p *tsParse) objectMethods(owner string) {
+	for !p.eof() && !p.is("}") {
+		start := p.at(0).line
+		for (p.is("async") || p.is("get") || p.is("set") || p.is("*")) && p.at(1).text != "(" && p.at(1).text != ":" {
+			p.i++
+		}
+		if p.at(0).kind == tokIdent && p.at(1).text == "(" {
+			name := p.at(0).text
+			p.i++
+			sig := tsSignature(p.group())
+			p.returnType()
+			end := p.lastLine()
+			if p.is("{") {
+				end = p.skipGroup()
+			}
+			p.add(owner, name, "method", start, end, sig)
+		}
+		for !p.eof() && !p.is(",") && !p.is("}") {
+			if _, open := closerOf[p.at(0).text]; open && p.at(0).kind == tokPunct {
+				p.skipGroup()
+				continue
+			}
+			p.i++
+		}
+		if p.is(",") {
+			p.i++
+		}
+	}
+}
+
+// tsSignature lists parameter types: "(a: string, ...rest: number[])"
+// becomes "(string, ...number[])"; untyped parameters are "any".
+func tsSignature(params []token) string {
+	var types []string
+	for _, prm := range splitParams(params) {
+		prm = dropAnnotations(prm, tsParamModifiers...)
+		rest := len(prm) > 3 && prm[0].text == "." && prm[1].text == "." && prm[2].text == "."
+		typ := []token(nil)
+		depth := 0
+		for i, t := range prm {
+			if t.kind == tokPunct {
+				switch t.text {
+				case "(", "[", "{", "<":
+					depth++
+				case ")", "]", "}", ">":
+					depth--
+				}
+			}
+			if depth != 0 {
+				continue
+			}
+			if t.text == ":" && typ == nil {
+				typ = prm[i+1:]
+			} else if t.text == "=" && typ != nil {
+				typ = typ[:len(typ)-len(prm[i:])]
+				break
+			} else if t.text == "=" {
+				break
+			}
+		}
+		if len(prm) == 0 {
+			continue
+		}
+		s := "any"
+		if len(typ) > 0 {
+			s = joinTokens(typ)
+		}
+		if rest {
+			s = "..." + s
+		}
+		types = append(types, s)
+	}
+	return formatSignature(types)
+}
@@ -0,0 +1,106 @@
+// Package index — per-file role classification.
+//
+// Every manifest entry gets a coarse role used for ranking and filtering:
+//   - generated: content carries a "Code generated ... DO NOT EDIT." or
+//     "@generated" marker near the top of the file
+//   - test: IsTestPath (a /test/ folder or a _test.go file)
+//   - doc: .md/.txt and similar prose files
+//   - config: lockfiles, build descriptors and yaml/json/toml/xml settings
+//   - source: everything else
+//
+// The checks run in the order above, so a generated test is "generated".
+package index
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// File roles stored in ManFile.Role.
+const (
+	RoleSource    = "source"
+	RoleTest      = "test"
+	RoleConfig    = "config"
+	RoleDoc       = "doc"
+	RoleGenerated = "generated"
+)
+
+// generatedScanBytes bounds how much of a file is searched for markers.
+const generatedScanBytes = 2048
+
+var (
+	docExts = map[string]struct{}{
+		".md": {}, ".markdown": {}, ".txt": {}, ".rst": {}, ".adoc": {},
+	}
+	configExts = map[string]struct{}{
+		".yaml": {}, ".yml": {}, ".json": {}, ".toml": {}, ".xml": {},
+		".gradle": {}, ".properties": {}, ".ini": {}, ".cfg": {}, ".conf": {},
+	}
+	configNames = map[string]struct{}{
+		"go.mod": {}, "go.sum": {}, "go.work": {}, "go.work.sum": {},
+		"package-lock.json": {}, "yarn.lock": {}, "pnpm-lock.yaml": {},
+		"cargo.lock": {}, "poetry.lock": {}, "gemfile.lock": {}, "composer.lock": {},
+		"makefile": {}, "dockerfile": {}, "gradlew": {}, "mvnw": {},
+	}
+)
+
+// IsTestPath reports whether a path belongs to a tests folder or ends with _test.go.
+func IsTestPath(p string) bool {
+	pp := strings.ReplaceAll(p, "\\", "/")
+	return strings.Contains(pp, "/test/") || strings.HasSuffix(pp, "_test.go")
+}
+
+// ClassifyRole returns the role of the file at relPath with contents data.
+func ClassifyRole(relPath string, data []byte) string {
+	if isGenerated(data) {
+		return RoleGenerated
+	}
+	if IsTestPath(relPath) {
+		return RoleTest
+	}
+	base := strings.ToLower(path.Base(strings.ReplaceAll(relPath, "\\", "/")))
+	ext := path.Ext(base)
+	if _, ok := docExts[ext]; ok {
+		return RoleDoc
+	}
+	if _, ok := configNames[base]; ok {
+		return RoleConfig
+	}
+	if _, ok := configExts[ext]; ok || strings.HasSuffix(base, ".lock") {
+		return RoleConfig
+	}
+	return RoleSource
+}
+
+// ClassifyFileRole is ClassifyRole for the file at absPath, reading only the
+// head the generated-code markers are searched in. An empty absPath (an
+// unfollowed symlink) is classified by path alone.
+func ClassifyFileRole(relPath, absPath string) (string, error) {
+	if absPath == "" {
+		return ClassifyRole(relPath, nil), nil
+	}
+	f, err := os.Open(absPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	head := make([]byte, generatedScanBytes)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	return ClassifyRole(relPath, head[:n]), nil
+}
+
+func isGenerated(data []byte) bool {
+	if len(data) > generatedScanBytes {
+		data = data[:generatedScanBytes]
+	}
+	if bytes.Contains(data, []byte("@generated")) {
+		return true
+	}
+	return bytes.Contains(data, []byte("Code generated")) && bytes.Contains(data, []byte("DO NOT EDIT"))
+}
@@ -0,0 +1,23 @@
+package index
+
+import "testing"
+
+func TestClassifyRole(t *testing.T) {
+	cases := []struct {
+		path string
+		data string
+		want string
+	}{
+		{"pkg/server_test.go", "package pkg\n", RoleTest},
+		{"README.md", "# Title\n", RoleDoc},
+		{"pkg/server.go", "package pkg\n", RoleSource},
+		{"go.sum", "example.com/x v1.0.0 h1:abc=\n", RoleConfig},
+		{"deploy/values.yaml", "replicas: 1\n", RoleConfig},
+		{"pkg/api.pb.go", "// Code generated by protoc-gen-go. DO NOT EDIT.\npackage pkg\n", RoleGenerated},
+	}
+	for _, c := range cases {
+		if got := ClassifyRole(c.path, []byte(c.data)); got != c.want {
+			t.Fatalf("ClassifyRole(%q) = %q, want %q", c.path, got, c.want)
+		}
+	}
+}
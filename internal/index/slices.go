@@ -7,6 +7,9 @@
 //   - If anchors exist, we emit one slice per (normalized) anchor.
 //   - Otherwise, for large files we chunk the file into consecutive ranges
 //     of at most maxFileLines lines (1-based, inclusive).
+//   - With SetSlicesFromSymbols(true), files that have symbols instead get
+//     one slice per symbol, named by the symbol (see BuildSymbolSlices).
+//   - With SetSlicesPrefer, nested slices are thinned (see preferSlices).
 //   - Output is deterministic: anchors are normalized (clamped, sorted, deduped)
 //     and chunk slices are emitted in ascending order.
 package index
@@ -16,6 +19,51 @@ import (
 	"sort"
 )
 
+var slicesFromSymbols bool
+
+// SetSlicesFromSymbols toggles symbol-backed slices for files with symbols.
+func SetSlicesFromSymbols(enable bool) { slicesFromSymbols = enable }
+
+// Nesting preferences for SetSlicesPrefer.
+const (
+	SlicesPreferAll   = "all"   // keep containing and contained slices
+	SlicesPreferInner = "inner" // drop a slice that redundantly contains others
+	SlicesPreferOuter = "outer" // drop the slices nested in such a container
+)
+
+var slicesPrefer = SlicesPreferAll
+
+// SetSlicesPrefer selects how anchor and symbol slices that nest are reduced
+// (SlicesPreferAll, SlicesPreferInner or SlicesPreferOuter; "" means all).
+func SetSlicesPrefer(mode string) {
+	if mode == "" {
+		mode = SlicesPreferAll
+	}
+	slicesPrefer = mode
+}
+
+// BuildSymbolSlices emits one slice per symbol over its [Start..End] range,
+// clamped to totalLines, sorted by (Start, End, Name) with exact duplicates
+// removed. Symbols must already have End finalized.
+func BuildSymbolSlices(relPath string, syms []Symbol, totalLines int) []Slice {
+	if len(syms) == 0 {
+		return nil
+	}
+	if totalLines < 1 {
+		totalLines = 1
+	}
+	as := make([]Anchor, 0, len(syms))
+	for _, s := range syms {
+		as = append(as, Anchor{Name: s.Symbol, Start: s.Start, End: s.End})
+	}
+	na := normalizeAnchorsForSlices(as, totalLines)
+	out := make([]Slice, 0, len(na))
+	for _, a := range na {
+		out = append(out, Slice{Path: relPath, Slice: a.Name, Start: a.Start, End: a.End})
+	}
+	return preferSlices(out)
+}
+
 // BuildSlices creates per-file slices based on anchors or by chunking.
 //
 //	relPath     — project-relative path (stored into Slice.Path)
@@ -26,7 +74,8 @@ import (
 //
 // Behavior:
 //   - When anchors are present, they take precedence: one slice per anchor.
-//     Anchors are clamped to [1..totalLines], sorted, and exact duplicates removed.
+//     Anchors are clamped to [1..totalLines], sorted, and exact duplicates removed;
+//     nested anchors are then reduced per SetSlicesPrefer.
 //   - When no anchors are present:
 //   - if totalLines <= maxFileLines → no slices (file small enough);
 //   - else → consecutive "chunk_<start>" slices covering [1..totalLines].
@@ -51,7 +100,7 @@ func BuildSlices(relPath string, anchors []Anchor, totalLines, maxFileLines int)
 				End:   a.End,
 			})
 		}
-		return out
+		return preferSlices(out)
 	}
 
 	// 2) Chunking for large files without anchors
@@ -133,3 +182,56 @@ func normalizeAnchorsForSlices(in []Anchor, total int) []Anchor {
 	}
 	return uniq
 }
+
+// preferSlices applies the SetSlicesPrefer policy to sorted slices of one
+// file. A slice is a redundant container when it strictly contains at least
+// two other slices that together cover at least half of its lines (a
+// whole-file anchor around its sections, a class around its methods). inner
+// drops such containers; outer drops the slices they contain. The relative
+// order of kept slices is unchanged.
+func preferSlices(in []Slice) []Slice {
+	if (slicesPrefer != SlicesPreferInner && slicesPrefer != SlicesPreferOuter) || len(in) < 3 {
+		return in
+	}
+	drop := make([]bool, len(in))
+	for i, c := range in {
+		var inner []int
+		for j, s := range in {
+			if j != i && c.Start <= s.Start && s.End <= c.End && (s.Start != c.Start || s.End != c.End) {
+				inner = append(inner, j)
+			}
+		}
+		if len(inner) < 2 || 2*coveredLines(in, inner) < c.End-c.Start+1 {
+			continue
+		}
+		if slicesPrefer == SlicesPreferInner {
+			drop[i] = true
+			continue
+		}
+		for _, j := range inner {
+			drop[j] = true
+		}
+	}
+	out := make([]Slice, 0, len(in))
+	for i, s := range in {
+		if !drop[i] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// coveredLines counts the distinct lines covered by in[idx...]; idx must be
+// in ascending Start order.
+func coveredLines(in []Slice, idx []int) int {
+	n, reach := 0, 0
+	for _, k := range idx {
+		s := in[k]
+		start := max(s.Start, reach+1)
+		if s.End >= start {
+			n += s.End - start + 1
+			reach = s.End
+		}
+	}
+	return n
+}
@@ -0,0 +1,44 @@
+package index
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuildSlicesPrefer(t *testing.T) {
+	defer SetSlicesPrefer("")
+	anchors := []Anchor{
+		{Name: "FILE", Start: 1, End: 40},
+		{Name: "A", Start: 2, End: 20},
+		{Name: "B", Start: 21, End: 39},
+		{Name: "C", Start: 41, End: 50},
+	}
+	names := func(sl []Slice) []string {
+		var out []string
+		for _, s := range sl {
+			out = append(out, s.Slice)
+		}
+		return out
+	}
+	for _, tc := range []struct {
+		mode string
+		want []string
+	}{
+		{"", []string{"FILE", "A", "B", "C"}},
+		{SlicesPreferAll, []string{"FILE", "A", "B", "C"}},
+		{SlicesPreferInner, []string{"A", "B", "C"}},
+		{SlicesPreferOuter, []string{"FILE", "C"}},
+	} {
+		SetSlicesPrefer(tc.mode)
+		if got := names(BuildSlices("a.go", anchors, 50, 0)); !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("prefer %q: slices = %v, want %v", tc.mode, got, tc.want)
+		}
+	}
+
+	// Inner anchors covering too little of the container keep it.
+	SetSlicesPrefer(SlicesPreferInner)
+	sparse := []Anchor{{Name: "FILE", Start: 1, End: 100}, {Name: "A", Start: 2, End: 5}, {Name: "B", Start: 6, End: 9}}
+	if got := names(BuildSlices("a.go", sparse, 100, 0)); !reflect.DeepEqual(got, []string{"FILE", "A", "B"}) {
+		t.Fatalf("sparse container should be kept, got %v", got)
+	}
+}
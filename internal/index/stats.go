@@ -0,0 +1,115 @@
+// Package index — per-language line statistics.
+//
+// This file computes a cloc-style overview (files, lines, blank, comment, code)
+// grouped by the coarse language tag from InferLangByExt. Comment detection is
+// heuristic and line-based:
+//   - C-like languages (go, java, ts, kt, cs, cpp): "//" lines and /* ... */ blocks
+//   - Python, shell: "#" lines
+//   - Other files: no comment detection (non-blank lines count as code)
+//
+// Line totals follow the manifest convention (1 + number of '\n'), so the sum
+// of blank+comment+code always equals lines. Output is sorted by language.
+package index
+
+import (
+	"bytes"
+	"sort"
+)
+
+// LangStats holds line counters for a single language (or the grand total).
+type LangStats struct {
+	Lang    string `json:"lang"`
+	Files   int    `json:"files"`
+	Lines   int    `json:"lines"`
+	Blank   int    `json:"blank"`
+	Comment int    `json:"comment"`
+	Code    int    `json:"code"`
+}
+
+// Stats is the stats.json payload.
+type Stats struct {
+	Languages []LangStats `json:"languages"`
+	Total     LangStats   `json:"total"`
+}
+
+// otherLang labels files without a known language tag.
+const otherLang = "other"
+
+// BuildStats aggregates per-language line counts over the files of man, as
+// counted when BuildArtifacts read them. Recorded symlinks are left out.
+func BuildStats(man Manifest) Stats {
+	byLang := make(map[string]*LangStats)
+	total := LangStats{Lang: "total"}
+	for _, f := range man.Files {
+		if f.lineStats == nil {
+			continue
+		}
+		fs := *f.lineStats
+		ls := byLang[fs.Lang]
+		if ls == nil {
+			ls = &LangStats{Lang: fs.Lang}
+			byLang[fs.Lang] = ls
+		}
+		addStats(ls, fs)
+		addStats(&total, fs)
+	}
+	out := Stats{Languages: make([]LangStats, 0, len(byLang)), Total: total}
+	for _, ls := range byLang {
+		out.Languages = append(out.Languages, *ls)
+	}
+	sort.Slice(out.Languages, func(i, j int) bool { return out.Languages[i].Lang < out.Languages[j].Lang })
+	return out
+}
+
+// statsLang is the stats.json language of a file extension.
+func statsLang(ext string) string {
+	if lang := InferLangByExt(ext); lang != "" {
+		return lang
+	}
+	return otherLang
+}
+
+func addStats(dst *LangStats, src LangStats) {
+	dst.Files += src.Files
+	dst.Lines += src.Lines
+	dst.Blank += src.Blank
+	dst.Comment += src.Comment
+	dst.Code += src.Code
+}
+
+// countLines classifies every line of data as blank, comment or code.
+func countLines(data []byte, lang string) LangStats {
+	st := LangStats{Lang: lang, Files: 1}
+	cLike := false
+	hashComments := false
+	switch lang {
+	case "go", "java", "ts", "kt", "cs", "cpp":
+		cLike = true
+	case "py", "shell":
+		hashComments = true
+	}
+	inBlock := false
+	for _, raw := range bytes.Split(data, []byte("\n")) {
+		st.Lines++
+		ln := bytes.TrimSpace(raw)
+		switch {
+		case inBlock:
+			st.Comment++
+			if bytes.Contains(ln, []byte("*/")) {
+				inBlock = false
+			}
+		case len(ln) == 0:
+			st.Blank++
+		case cLike && bytes.HasPrefix(ln, []byte("//")):
+			st.Comment++
+		case cLike && bytes.HasPrefix(ln, []byte("/*")):
+			st.Comment++
+			inBlock = !bytes.Contains(ln[2:], []byte("*/"))
+		case hashComments && bytes.HasPrefix(ln, []byte("#")):
+			st.Comment++
+		default:
+			st.Code++
+		}
+	}
+	return st
+}
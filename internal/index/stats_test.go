@@ -0,0 +1,40 @@
+package index
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"class-collector/internal/walkwalk"
+)
+
+func TestBuildStatsPerLanguageTotals(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, body string) walkwalk.FileInfo {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		return walkwalk.FileInfo{RelPath: name, AbsPath: p, Ext: filepath.Ext(name)}
+	}
+	files := []walkwalk.FileInfo{
+		write("a.go", "package a\n\n// Foo does things.\nfunc Foo() {}"),
+		write("b.go", "/* block\ncomment */\npackage a"),
+		write("c.ts", "export const x = 1;\n\n"),
+	}
+	man, _, _, _ := BuildArtifacts(dir, files, 500, nil)
+	st := BuildStats(man)
+	if len(st.Languages) != 2 {
+		t.Fatalf("languages = %+v", st.Languages)
+	}
+	goSt, tsSt := st.Languages[0], st.Languages[1]
+	if goSt.Lang != "go" || goSt.Files != 2 || goSt.Lines != 7 || goSt.Blank != 1 || goSt.Comment != 3 || goSt.Code != 3 {
+		t.Fatalf("go stats = %+v", goSt)
+	}
+	if tsSt.Lang != "ts" || tsSt.Files != 1 || tsSt.Lines != 3 || tsSt.Blank != 2 || tsSt.Code != 1 {
+		t.Fatalf("ts stats = %+v", tsSt)
+	}
+	if st.Total.Files != 3 || st.Total.Lines != 10 {
+		t.Fatalf("total = %+v", st.Total)
+	}
+}
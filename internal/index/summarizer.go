@@ -0,0 +1,61 @@
+package index
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// SummarizerFunc returns a short description of the file at path (content in
+// data) for ManFile.Summary; "" leaves the summary empty.
+type SummarizerFunc func(path string, data []byte) string
+
+var summarizer SummarizerFunc
+
+// SetSummarizer installs fn to fill ManFile.Summary for every indexed file
+// (nil disables). It must be safe for concurrent use.
+func SetSummarizer(fn SummarizerFunc) { summarizer = fn }
+
+// summarize runs the installed summarizer fail-soft: a panic or empty result
+// leaves the summary empty, and only the first line is kept.
+func summarize(path string, data []byte) (summary string) {
+	if summarizer == nil {
+		return ""
+	}
+	defer func() {
+		if recover() != nil {
+			summary = ""
+		}
+	}()
+	line, _, _ := strings.Cut(summarizer(path, data), "\n")
+	return strings.TrimSpace(line)
+}
+
+// CommandSummarizer returns a SummarizerFunc that runs argv with the file
+// content on stdin and CLASS_COLLECTOR_PATH set to its project-relative path,
+// and uses the first line of stdout. A failing, slow (over timeout when
+// positive) or silent command yields "".
+func CommandSummarizer(argv []string, timeout time.Duration) SummarizerFunc {
+	if len(argv) == 0 {
+		return nil
+	}
+	return func(path string, data []byte) string {
+		ctx := context.Background()
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+		cmd.Env = append(os.Environ(), "CLASS_COLLECTOR_PATH="+path)
+		cmd.Stdin = bytes.NewReader(data)
+		out, err := cmd.Output()
+		if err != nil {
+			return ""
+		}
+		return string(out)
+	}
+}
@@ -0,0 +1,59 @@
+package index
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"class-collector/internal/walkwalk"
+)
+
+func TestSummarizerFillsManifestSummary(t *testing.T) {
+	SetSummarizer(func(path string, data []byte) string {
+		if strings.HasSuffix(path, "boom.go") {
+			panic("summarizer failure")
+		}
+		return path + ": " + strings.Fields(string(data))[0] + "\nignored second line"
+	})
+	defer SetSummarizer(nil)
+
+	dir := t.TempDir()
+	var files []walkwalk.FileInfo
+	for _, name := range []string{"a.go", "boom.go"} {
+		abs := filepath.Join(dir, name)
+		if err := os.WriteFile(abs, []byte("package demo\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, walkwalk.FileInfo{RelPath: name, AbsPath: abs, Ext: ".go"})
+	}
+	idx, err := gatherSymbolsIndex(files, 500, nil)
+	if err != nil {
+		t.Fatalf("gatherSymbolsIndex error: %v", err)
+	}
+	if got := idx.manifest[0].Summary; got != "a.go: package" {
+		t.Fatalf("summary = %q", got)
+	}
+	if got := idx.manifest[1].Summary; got != "" {
+		t.Fatalf("a panicking summarizer must leave the summary empty, got %q", got)
+	}
+}
+
+func TestCommandSummarizer(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no sh")
+	}
+	fn := CommandSummarizer([]string{sh, "-c", `printf '%s has %s lines\n' "$CLASS_COLLECTOR_PATH" "$(wc -l | tr -d ' ')"`}, 10*time.Second)
+	if got := fn("x/a.go", []byte("one\ntwo\n")); strings.TrimSpace(got) != "x/a.go has 2 lines" {
+		t.Fatalf("summary = %q", got)
+	}
+	if got := CommandSummarizer([]string{sh, "-c", "exit 3"}, 0)("a.go", nil); got != "" {
+		t.Fatalf("failing command should yield no summary, got %q", got)
+	}
+	if CommandSummarizer(nil, 0) != nil {
+		t.Fatalf("empty command should disable summarizing")
+	}
+}
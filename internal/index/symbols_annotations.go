@@ -0,0 +1,113 @@
+package index
+
+import (
+	"regexp"
+	"strings"
+)
+
+// annotationLangs are the languages whose "@Name" / "@Name(args)"
+// annotations and decorators are recorded on symbols.
+var annotationLangs = map[string]bool{"java": true, "kt": true, "py": true, "ts": true}
+
+// attachAnnotations fills Symbol.Annotations with the annotations written
+// immediately before each declaration: leading ones on the declaration line
+// itself, then whole annotation lines scanning upward until a blank or other
+// line. Annotations keep their arguments as written, e.g.
+// `@GetMapping("/users")`, in source order. Arguments spanning several lines
+// end the scan.
+func attachAnnotations(data []byte, syms []Symbol) {
+	if len(syms) == 0 {
+		return
+	}
+	lines := strings.Split(string(data), "\n")
+	for i := range syms {
+		syms[i].Annotations = annotationsAbove(lines, syms[i].Start)
+	}
+}
+
+// typeAnnotations returns the annotations of the first "<kind> <typ>"
+// declaration in data, the file's primary type.
+func typeAnnotations(data []byte, kind, typ string) []string {
+	decl := regexp.MustCompile(`\b` + regexp.QuoteMeta(kind) + `\s+` + regexp.QuoteMeta(typ) + `\b`)
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		if decl.MatchString(line) {
+			return annotationsAbove(lines, i+1)
+		}
+	}
+	return nil
+}
+
+// annotationsAbove returns the annotations attached to the declaration on
+// 1-based line start.
+func annotationsAbove(lines []string, start int) []string {
+	if start < 1 || start > len(lines) {
+		return nil
+	}
+	out, _ := splitAnnotations(lines[start-1])
+	for i := start - 2; i >= 0; i-- {
+		anns, rest := splitAnnotations(lines[i])
+		if len(anns) == 0 || strings.TrimSpace(rest) != "" {
+			break
+		}
+		out = append(anns, out...)
+	}
+	return out
+}
+
+// splitAnnotations parses the annotations at the start of line and returns
+// them with the remaining text.
+func splitAnnotations(line string) (anns []string, rest string) {
+	rest = strings.TrimLeft(line, " \t")
+	for strings.HasPrefix(rest, "@") {
+		n := 1
+		for n < len(rest) && isAnnotationNameByte(rest[n]) {
+			n++
+		}
+		if n == 1 {
+			break
+		}
+		if n < len(rest) && rest[n] == '(' {
+			end := closingParen(rest, n)
+			if end < 0 {
+				break
+			}
+			n = end + 1
+		}
+		anns = append(anns, rest[:n])
+		rest = strings.TrimLeft(rest[n:], " \t")
+	}
+	return anns, rest
+}
+
+func isAnnotationNameByte(c byte) bool {
+	return c == '_' || c == '.' || c == '$' || c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
+
+// closingParen returns the index of the ')' matching the '(' at open,
+// skipping quoted strings, or -1 when it is not on this line.
+func closingParen(s string, open int) int {
+	depth := 0
+	var quote byte
+	for i := open; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,85 @@
+package index
+
+import (
+	"reflect"
+	"testing"
+
+	"class-collector/internal/walkwalk"
+)
+
+func symbolByName(syms []Symbol, name string) (Symbol, bool) {
+	for _, s := range syms {
+		if s.Symbol == name {
+			return s, true
+		}
+	}
+	return Symbol{}, false
+}
+
+func TestAnnotationsJavaMethod(t *testing.T) {
+	src := `package demo.web;
+
+@RestController
+@RequestMapping("/api")
+public class UserController {
+
+    // Lists users.
+    @GetMapping("/users")
+    @ResponseBody
+    public List<User> list() {
+        return users;
+    }
+
+    public void plain() {}
+}
+`
+	_, _, _, _, syms := extractByLang("java", "UserController.java", []byte(src))
+	list, ok := symbolByName(syms, "demo.web.UserController.list")
+	if !ok {
+		t.Fatalf("list() not found in %+v", syms)
+	}
+	if want := []string{`@GetMapping("/users")`, "@ResponseBody"}; !reflect.DeepEqual(list.Annotations, want) || list.Start != 10 {
+		t.Fatalf("list = %+v, want annotations %v on line 10", list, want)
+	}
+	if want := []string{"@RestController", `@RequestMapping("/api")`}; !reflect.DeepEqual(typeAnnotations([]byte(src), "class", "UserController"), want) {
+		t.Fatalf("class annotations = %v, want %v", typeAnnotations([]byte(src), "class", "UserController"), want)
+	}
+	if plain, _ := symbolByName(syms, "demo.web.UserController.plain"); plain.Annotations != nil {
+		t.Fatalf("plain() should have no annotations, got %v", plain.Annotations)
+	}
+}
+
+func TestAnnotationsTSClassAndPython(t *testing.T) {
+	ts := `import { Component } from '@angular/core';
+
+@Component({ selector: 'app-user', template: '<p>(x)</p>' })
+export class UserCard {
+}
+`
+	art, err := processFile(walkwalk.FileInfo{RelPath: "user-card.ts", Ext: ".ts"}, []byte(ts), 0, nil)
+	if err != nil || art == nil || art.manifest.Class != "UserCard" {
+		t.Fatalf("processFile = %+v, %v", art, err)
+	}
+	if want := []string{`@Component({ selector: 'app-user', template: '<p>(x)</p>' })`}; !reflect.DeepEqual(art.manifest.Annotations, want) {
+		t.Fatalf("UserCard annotations = %v, want %v", art.manifest.Annotations, want)
+	}
+
+	py := "@app.route('/users')\n@login_required\ndef users():\n    pass\n"
+	_, _, _, _, syms := extractByLang("py", "views.py", []byte(py))
+	if len(syms) != 1 || !reflect.DeepEqual(syms[0].Annotations, []string{"@app.route('/users')", "@login_required"}) {
+		t.Fatalf("python decorators = %+v", syms)
+	}
+}
+
+func TestSplitAnnotations(t *testing.T) {
+	anns, rest := splitAnnotations("  @Override @Deprecated(since = \"1)\") public void x() {")
+	if !reflect.DeepEqual(anns, []string{"@Override", `@Deprecated(since = "1)")`}) || rest != "public void x() {" {
+		t.Fatalf("anns=%v rest=%q", anns, rest)
+	}
+	if anns, _ := splitAnnotations("@Multi("); anns != nil {
+		t.Fatalf("unterminated arguments should not parse, got %v", anns)
+	}
+	if anns, _ := splitAnnotations("email@example.com"); anns != nil {
+		t.Fatalf("non-leading @ should not parse, got %v", anns)
+	}
+}
@@ -53,6 +53,9 @@ func joinSym(pkg, typ, name string) string {
 //   - ".java" → "java"
 //   - ".go"   → "go"
 //   - TS/JS family (".ts", ".tsx", ".js", ".jsx", ".mjs", ".cjs") → "ts"
+//   - ".vue" → "vue", ".svelte" → "svelte" (script blocks use the TS extractor)
+//   - ".sh", ".bash" → "shell"
+//   - ".sql" → "sql"
 //   - unknown/other → "" (caller may skip symbol extraction)
 func InferLangByExt(ext string) string {
 	e := strings.TrimSpace(strings.ToLower(ext))
@@ -79,6 +82,14 @@ func InferLangByExt(ext string) string {
 		return "py"
 	case ".cpp", ".cc", ".cxx", ".hpp", ".hh", ".h":
 		return "cpp"
+	case ".vue":
+		return "vue"
+	case ".svelte":
+		return "svelte"
+	case ".sh", ".bash":
+		return "shell"
+	case ".sql":
+		return "sql"
 	default:
 		return ""
 	}
@@ -0,0 +1,192 @@
+package index
+
+import (
+	"strings"
+)
+
+// symbolsMinConfidence drops heuristic callable symbols scoring below it;
+// 0 keeps everything.
+var symbolsMinConfidence int
+
+// SetSymbolsMinConfidence sets the 0..100 threshold below which method,
+// function and constructor symbols from the regex extractors are dropped.
+// Go symbols are regex matches too and are scored like the others; their
+// "func" keyword alone puts a declaration at 70. Symbols from -parser
+// precise are never filtered.
+func SetSymbolsMinConfidence(n int) { symbolsMinConfidence = n }
+
+// confidenceKeywords are names a callable regex can capture from control
+// flow or operators ("} else if (x) {", "return sizeof(x);").
+var confidenceKeywords = map[string]bool{
+	"if": true, "for": true, "foreach": true, "while": true, "switch": true,
+	"catch": true, "return": true, "sizeof": true, "typeof": true, "do": true,
+	"else": true, "new": true, "delete": true, "throw": true, "using": true,
+	"lock": true, "when": true, "with": true, "elif": true, "assert": true,
+}
+
+// confidenceModifiers are tokens that, preceding a name on its line, suggest
+// a declaration rather than a call.
+var confidenceModifiers = map[string]bool{
+	"public": true, "private": true, "protected": true, "internal": true,
+	"static": true, "final": true, "abstract": true, "virtual": true,
+	"inline": true, "override": true, "async": true, "export": true,
+	"extern": true, "synchronized": true, "open": true, "suspend": true,
+	"constexpr": true, "def": true, "fun": true, "function": true,
+	"func": true, "void": true, "explicit": true,
+}
+
+// confidenceCallers are tokens that, directly preceding a name, mark a call
+// site rather than a declaration ("return helper(x);").
+var confidenceCallers = map[string]bool{
+	"return": true, "new": true, "else": true, "throw": true, "case": true,
+	"await": true, "yield": true, "delete": true, "co_return": true,
+}
+
+// isCallableKind reports whether kind is scored; type symbols come from
+// explicit keywords and are always kept.
+func isCallableKind(kind string) bool {
+	return kind == "method" || kind == "func" || kind == "ctor"
+}
+
+// symbolConfidence scores a callable symbol 0..100 from cues around its
+// declaration: 50 to start, +30 when the parameter list is followed by a
+// body ('{', "=>" or Python's ':'), +20 for a preceding modifier, -10 for a
+// trailing ';' (prototype or call), -30 for a trailing '=' (field
+// initializer), -40 for a preceding call keyword. Control-flow names score 0.
+func symbolConfidence(lines []string, s Symbol) int {
+	if !isCallableKind(s.Kind) {
+		return 100
+	}
+	name := s.Symbol
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		name = name[i+1:]
+	}
+	if confidenceKeywords[name] {
+		return 0
+	}
+	// Regex matches may start on a preceding blank line, so look a few
+	// lines ahead for the name.
+	ln, col := -1, -1
+	for i := s.Start - 1; i >= 0 && i < len(lines) && i < s.Start+3; i++ {
+		if c := nameCallIndex(lines[i], name); c >= 0 {
+			ln, col = i, c
+			break
+		}
+	}
+	if ln < 0 {
+		return 50
+	}
+	score := 50
+	before := strings.Fields(lines[ln][:col])
+	for _, tok := range before {
+		if confidenceModifiers[tok] {
+			score += 20
+			break
+		}
+	}
+	if n := len(before); n > 0 && confidenceCallers[before[n-1]] {
+		score -= 40
+	}
+	switch afterParams(lines, ln, col+len(name)) {
+	case "{", "=>", ":":
+		score += 30
+	case ";":
+		score -= 10
+	case "=":
+		score -= 30
+	}
+	return max(0, min(100, score))
+}
+
+// nameCallIndex returns the column of name when it appears as a whole word
+// followed by optional spaces and '(', or -1.
+func nameCallIndex(line, name string) int {
+	for off := 0; ; {
+		i := strings.Index(line[off:], name)
+		if i < 0 {
+			return -1
+		}
+		i += off
+		off = i + len(name)
+		if i > 0 && isIdentByte(line[i-1]) {
+			continue
+		}
+		if rest := strings.TrimLeft(line[off:], " \t"); strings.HasPrefix(rest, "(") {
+			return i
+		}
+	}
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// afterParams skips the balanced parameter list starting at or after col on
+// line ln (spanning at most 20 lines) and returns the first terminator that
+// follows: "{", "=>", "=", ";", ":" (at end of line), or "" if none is found.
+func afterParams(lines []string, ln, col int) string {
+	depth, opened := 0, false
+	for i := ln; i < len(lines) && i < ln+20; i++ {
+		line := lines[i]
+		start := 0
+		if i == ln {
+			start = col
+		}
+		for j := start; j < len(line); j++ {
+			c := line[j]
+			if !opened || depth > 0 {
+				switch c {
+				case '(':
+					depth++
+					opened = true
+				case ')':
+					depth--
+				}
+				continue
+			}
+			switch c {
+			case '{', ';':
+				return string(c)
+			case '=':
+				if j+1 < len(line) && line[j+1] == '>' {
+					return "=>"
+				}
+				return "="
+			case ':':
+				if strings.TrimSpace(line[j+1:]) == "" {
+					return ":"
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// dropLowConfidence removes symbols scoring below symbolsMinConfidence along
+// with their "name()" exports, unless a kept symbol shares the name.
+func dropLowConfidence(data []byte, syms []Symbol, exports []string) ([]Symbol, []string) {
+	lines := strings.Split(string(data), "\n")
+	kept := syms[:0]
+	dropped := map[string]bool{}
+	keptNames := map[string]bool{}
+	for _, s := range syms {
+		name := s.Symbol[strings.LastIndexByte(s.Symbol, '.')+1:]
+		if symbolConfidence(lines, s) < symbolsMinConfidence {
+			dropped[name+"()"] = true
+			continue
+		}
+		keptNames[name+"()"] = true
+		kept = append(kept, s)
+	}
+	if len(dropped) == 0 {
+		return kept, exports
+	}
+	out := exports[:0]
+	for _, e := range exports {
+		if dropped[e] && !keptNames[e] {
+			continue
+		}
+		out = append(out, e)
+	}
+	return kept, out
+}
@@ -0,0 +1,93 @@
+package index
+
+import (
+	"strings"
+	"testing"
+)
+
+const cppWithControlFlow = `int helper(int x);
+
+int compute(int x) {
+    if (x > 0) {
+        return helper(x);
+    }
+    else if (x < -10) {
+        return 0;
+    }
+    return 1;
+}
+`
+
+func symbolNames(syms []Symbol) string {
+	names := make([]string, 0, len(syms))
+	for _, s := range syms {
+		names = append(names, s.Symbol)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestSymbolsMinConfidenceDropsControlFlow(t *testing.T) {
+	_, _, _, exports, syms := extractByLang("cpp", "calc.cpp", []byte(cppWithControlFlow))
+	if got := symbolNames(syms); !strings.Contains(got, "if") {
+		t.Fatalf("default should keep the false positive, got %s", got)
+	}
+
+	SetSymbolsMinConfidence(50)
+	defer SetSymbolsMinConfidence(0)
+	_, _, _, exports, syms = extractByLang("cpp", "calc.cpp", []byte(cppWithControlFlow))
+	for _, s := range syms {
+		if s.Symbol == "if" || s.Symbol == "helper" && s.Start != 1 {
+			t.Fatalf("low-confidence symbol %q (line %d) kept: %s", s.Symbol, s.Start, symbolNames(syms))
+		}
+	}
+	if !strings.Contains(symbolNames(syms), "compute") {
+		t.Fatalf("definition with a body dropped: %s", symbolNames(syms))
+	}
+	for _, e := range exports {
+		if e == "if()" {
+			t.Fatalf("export of dropped symbol kept: %v", exports)
+		}
+	}
+}
+
+func TestSymbolConfidenceCues(t *testing.T) {
+	lines := strings.Split(`public void run() {
+    String name = compute(x);
+    void close();
+    return helper(y);
+`, "\n")
+	cases := []struct {
+		sym  Symbol
+		want int
+	}{
+		{Symbol{Symbol: "a.B.run", Kind: "method", Start: 1}, 100},
+		{Symbol{Symbol: "a.B.compute", Kind: "method", Start: 2}, 40},
+		{Symbol{Symbol: "a.B.close", Kind: "method", Start: 3}, 60},
+		{Symbol{Symbol: "helper", Kind: "func", Start: 4}, 0},
+		{Symbol{Symbol: "a.B", Kind: "class", Start: 1}, 100},
+		{Symbol{Symbol: "while", Kind: "func", Start: 1}, 0},
+	}
+	for _, tc := range cases {
+		if got := symbolConfidence(lines, tc.sym); got != tc.want {
+			t.Errorf("symbolConfidence(%s) = %d, want %d", tc.sym.Symbol, got, tc.want)
+		}
+	}
+}
+
+func TestSymbolConfidenceGo(t *testing.T) {
+	lines := strings.Split(`func (s *Server) Start(ctx context.Context) error {
+func add(a, b int) int
+`, "\n")
+	cases := []struct {
+		sym  Symbol
+		want int
+	}{
+		{Symbol{Symbol: "srv.Server.Start", Kind: "method", Start: 1}, 100},
+		{Symbol{Symbol: "srv.add", Kind: "func", Start: 2}, 70}, // assembly stub, no body
+	}
+	for _, tc := range cases {
+		if got := symbolConfidence(lines, tc.sym); got != tc.want {
+			t.Errorf("symbolConfidence(%s) = %d, want %d", tc.sym.Symbol, got, tc.want)
+		}
+	}
+}
@@ -7,6 +7,9 @@
 // Features:
 //   - Detects functions and methods (methods have a receiver).
 //   - Emits qualified symbol names using joinSym(pkg, recvType, name).
+//   - Records generic type parameters ("[T any]") in Symbol.TypeParams.
+//   - Sets Symbol.Visibility from the name's case; only exported names are
+//     listed in exports unless SetGoIncludePrivate(true).
 //   - Start line is 1-based; End is finalized by the caller (next symbol or EOF).
 //   - Robust receiver parsing: strips pointers (*), package qualifiers (pkg.Type),
 //     and generic brackets (T constraints) to get a clean base type.
@@ -20,25 +23,44 @@ import (
 	"bytes"
 	"regexp"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 var (
 	// package mypkg
 	reGoPkg = regexp.MustCompile(`(?m)^\s*package\s+([A-Za-z0-9_]+)\s*$`)
 
-	// func <Name>(...) or func (<recv>) <Name>(...)
+	// func <Name>(...), func <Name>[<tparams>](...) or func (<recv>) <Name>(...)
 	// Groups:
 	//   1: receiver block (optional), including parentheses: "(r *T) "
 	//   2: function/method name
-	reGoFunc = regexp.MustCompile(`(?m)^\s*func\s+(\([^)]+\)\s*)?([A-Za-z0-9_]+)\s*\(`)
+	// The match ends just past the opening '[' or '(' that follows the name.
+	reGoFunc = regexp.MustCompile(`(?m)^\s*func\s+(\([^)]+\)\s*)?([A-Za-z0-9_]+)\s*[\[(]`)
 )
 
+var goIncludePrivate bool
+
+// SetGoIncludePrivate makes Go exports also list unexported functions and
+// methods. Symbols always include both.
+func SetGoIncludePrivate(enable bool) { goIncludePrivate = enable }
+
+// goVisibility reports "exported" for names starting with an upper-case
+// letter, per the Go spec, and "unexported" otherwise.
+func goVisibility(name string) string {
+	r, _ := utf8.DecodeRuneInString(name)
+	if unicode.IsUpper(r) {
+		return "exported"
+	}
+	return "unexported"
+}
+
 // extractGo returns:
 //
 //	pkg   — detected package name
 //	kind  — "file" (Go has no single primary "type" per file)
 //	typ   — empty (reserved for languages with file-scoped primary types)
-//	exports — function names with "()" suffix for quick overview
+//	exports — exported function names with "()" suffix for quick overview
 //	syms  — collected symbols with 1-based Start (End finalized by caller)
 func extractGo(relPath string, data []byte) (pkg, kind, typ string, exports []string, syms []Symbol) {
 	lineOf := func(off int) int { return 1 + bytes.Count(data[:off], []byte("\n")) }
@@ -60,23 +82,52 @@ func extractGo(relPath string, data []byte) (pkg, kind, typ string, exports []st
 			recvType = receiverBaseType(recvBlock)
 		}
 
+		vis := goVisibility(name)
 		kindSym := "func"
 		if recvType != "" {
 			kindSym = "method"
 		}
 
 		syms = append(syms, Symbol{
-			Symbol: joinSym(pkg, recvType, name),
-			Kind:   kindSym,
-			Path:   relPath,
-			Start:  start,
-			End:    start, // finalized later by caller
+			Symbol:     joinSym(pkg, recvType, name),
+			Kind:       kindSym,
+			Path:       relPath,
+			Start:      start,
+			End:        start, // finalized later by caller
+			TypeParams: goTypeParams(data, idx[1]-1),
+			Visibility: vis,
 		})
-		exports = append(exports, name+"()")
+		if vis == "exported" || goIncludePrivate {
+			exports = append(exports, name+"()")
+		}
 	}
 	return
 }
 
+// goTypeParams returns the bracketed type parameter list starting at
+// data[open] (e.g. "[K comparable, V any]"), or "" when data[open] is not '['.
+// Nested brackets such as "[S ~[]E, E any]" are balanced; whitespace runs are
+// collapsed so multi-line lists render on one line. Works for both
+// "func Name[...]" and "type Name[...]" declarations.
+func goTypeParams(data []byte, open int) string {
+	if open < 0 || open >= len(data) || data[open] != '[' {
+		return ""
+	}
+	depth := 0
+	for i := open; i < len(data); i++ {
+		switch data[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return strings.Join(strings.Fields(string(data[open:i+1])), " ")
+			}
+		}
+	}
+	return ""
+}
+
 // receiverBaseType extracts a clean base type from a receiver block.
 // Input examples:
 //
@@ -0,0 +1,63 @@
+package index
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestExtractGoGenericTypeParams(t *testing.T) {
+	src := []byte(`package gen
+
+func Map[T, U any](in []T, f func(T) U) []U { return nil }
+
+func Keys[M ~map[K]V, K comparable, V any](m M) []K { return nil }
+
+func (s *Set[T]) Add(v T) {}
+
+func Plain() {}
+`)
+	_, _, _, _, syms := extractGo("gen.go", src)
+	want := []struct{ sym, params string }{
+		{"gen.Map", "[T, U any]"},
+		{"gen.Keys", "[M ~map[K]V, K comparable, V any]"},
+		{"gen.Set.Add", ""},
+		{"gen.Plain", ""},
+	}
+	if len(syms) != len(want) {
+		t.Fatalf("symbols = %+v", syms)
+	}
+	for i, w := range want {
+		if syms[i].Symbol != w.sym || syms[i].TypeParams != w.params {
+			t.Fatalf("symbol[%d] = %q %q, want %q %q", i, syms[i].Symbol, syms[i].TypeParams, w.sym, w.params)
+		}
+	}
+}
+
+func TestGoTypeParamsGenericType(t *testing.T) {
+	src := []byte("type Set[T comparable] struct{ m map[T]struct{} }\n")
+	open := bytes.IndexByte(src, '[')
+	if got := goTypeParams(src, open); got != "[T comparable]" {
+		t.Fatalf("goTypeParams = %q", got)
+	}
+	if got := goTypeParams([]byte("type Plain struct{}"), 10); got != "" {
+		t.Fatalf("non-generic type should have no params, got %q", got)
+	}
+}
+
+func TestExtractGoExportsOnlyExportedByDefault(t *testing.T) {
+	src := []byte("package demo\n\nfunc Public() {}\n\nfunc helper() {}\n")
+	_, _, _, exports, syms := extractGo("demo.go", src)
+	if len(exports) != 1 || exports[0] != "Public()" {
+		t.Fatalf("exports = %v, want [Public()]", exports)
+	}
+	if len(syms) != 2 || syms[0].Visibility != "exported" || syms[1].Visibility != "unexported" {
+		t.Fatalf("symbols should keep both with visibility: %+v", syms)
+	}
+
+	SetGoIncludePrivate(true)
+	defer SetGoIncludePrivate(false)
+	_, _, _, exports, _ = extractGo("demo.go", src)
+	if len(exports) != 2 || exports[1] != "helper()" {
+		t.Fatalf("exports with private = %v", exports)
+	}
+}
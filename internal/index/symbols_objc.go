@@ -0,0 +1,73 @@
+package index
+
+import (
+	"bytes"
+	"regexp"
+)
+
+var (
+	reObjCType   = regexp.MustCompile(`(?m)^\s*@(interface|implementation|protocol)\s+([A-Za-z_]\w*)`)
+	reObjCMethod = regexp.MustCompile(`(?m)^\s*[-+]\s*\([^)]*\)\s*([A-Za-z_]\w*)`)
+)
+
+// extractObjC performs shallow regex-based extraction for Objective-C files.
+// It recognizes @interface/@implementation (kind "class") and @protocol
+// (kind "interface") blocks, and -/+ method declarations, which are
+// attributed to the nearest preceding type. Only the first selector keyword
+// is used as the method name.
+//
+// Exports contain method names with trailing "()".
+func extractObjC(relPath string, data []byte) (pkg, kind, typ string, exports []string, syms []Symbol) {
+	s := string(data)
+	lineAt := func(off int) int { return 1 + bytes.Count(data[:off], []byte("\n")) }
+
+	types := reObjCType.FindAllStringSubmatchIndex(s, -1)
+	seen := map[string]struct{}{}
+	for _, m := range types {
+		kw, name := s[m[2]:m[3]], s[m[4]:m[5]]
+		k := "class"
+		if kw == "protocol" {
+			k = "interface"
+		}
+		if typ == "" {
+			typ, kind = name, k
+		}
+		if _, dup := seen[name]; dup {
+			continue // @interface and @implementation of the same class
+		}
+		seen[name] = struct{}{}
+		syms = append(syms, Symbol{Symbol: name, Kind: k, Path: relPath, Start: lineAt(m[0]), End: lineAt(m[0])})
+	}
+	if kind == "" {
+		kind = "file"
+	}
+
+	for _, m := range reObjCMethod.FindAllStringSubmatchIndex(s, -1) {
+		name := s[m[2]:m[3]]
+		owner := ""
+		for _, t := range types {
+			if t[0] > m[0] {
+				break
+			}
+			owner = s[t[4]:t[5]]
+		}
+		line := lineAt(m[0])
+		syms = append(syms, Symbol{Symbol: joinSym("", owner, name), Kind: "method", Path: relPath, Start: line, End: line})
+		exports = append(exports, name+"()")
+	}
+
+	// Deduplicate exports (stable): headers declare what .m files define.
+	if len(exports) > 1 {
+		seenExp := make(map[string]struct{}, len(exports))
+		uniq := make([]string, 0, len(exports))
+		for _, e := range exports {
+			if _, ok := seenExp[e]; ok {
+				continue
+			}
+			seenExp[e] = struct{}{}
+			uniq = append(uniq, e)
+		}
+		exports = uniq
+	}
+	return pkg, kind, typ, exports, syms
+}
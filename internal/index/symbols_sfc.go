@@ -0,0 +1,64 @@
+package index
+
+import (
+	"bytes"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	reSFCScriptOpen  = regexp.MustCompile(`(?i)<script\b[^>]*>`)
+	reSFCScriptClose = regexp.MustCompile(`(?i)</script\s*>`)
+)
+
+// extractSFC handles Vue and Svelte single-file components: every <script>
+// block (including <script setup> and <script context="module">) is run
+// through the TS extractor with line numbers shifted back to the component
+// file. The component itself is recorded as the primary type, named after the
+// file ("UserCard.vue" → "UserCard").
+func extractSFC(relPath string, data []byte) (pkg, kind, typ string, exports []string, syms []Symbol) {
+	typ = strings.TrimSuffix(filepath.Base(relPath), filepath.Ext(relPath))
+	kind = "component"
+	syms = append(syms, Symbol{Symbol: typ, Kind: "component", Path: relPath, Start: 1, End: 1})
+
+	for _, blk := range sfcScriptBlocks(data) {
+		_, _, _, ex, ss := extractTS(relPath, blk.body)
+		exports = append(exports, ex...)
+		for _, s := range ss {
+			s.Start += blk.offset
+			s.End += blk.offset
+			syms = append(syms, s)
+		}
+	}
+	return "", kind, typ, exports, syms
+}
+
+type sfcBlock struct {
+	body   []byte
+	offset int // lines before body in the component file
+}
+
+// sfcScriptBlocks returns the contents of the <script> blocks in data. When
+// the opening tag ends its line, the body starts on the next line so the
+// extractor's line 1 is the block's first line.
+func sfcScriptBlocks(data []byte) []sfcBlock {
+	var out []sfcBlock
+	for pos := 0; pos < len(data); {
+		open := reSFCScriptOpen.FindIndex(data[pos:])
+		if open == nil {
+			break
+		}
+		start := pos + open[1]
+		if nl := bytes.IndexByte(data[start:], '\n'); nl >= 0 && len(bytes.TrimSpace(data[start:start+nl])) == 0 {
+			start += nl + 1
+		}
+		end := len(data)
+		if cl := reSFCScriptClose.FindIndex(data[start:]); cl != nil {
+			end = start + cl[0]
+		}
+		out = append(out, sfcBlock{body: data[start:end], offset: bytes.Count(data[:start], []byte("\n"))})
+		pos = end
+	}
+	return out
+}
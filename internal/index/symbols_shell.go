@@ -0,0 +1,48 @@
+package index
+
+import (
+	"bytes"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// reShellFunc matches the two function definition forms at the start of a
+// line: "name() {" (POSIX) and "function name {" / "function name() {" (bash,
+// ksh). The body may open on the same line ('{' or a '(' subshell) or on the
+// next one.
+var reShellFunc = regexp.MustCompile(`(?m)^[ \t]*(?:function[ \t]+([A-Za-z_][\w.:-]*)[ \t]*(?:\([ \t]*\))?|([A-Za-z_][\w.:-]*)[ \t]*\([ \t]*\))[ \t]*(?:[{(]|$)`)
+
+// Shell minimal extractor (.sh, .bash)
+// - Package is the script name without extension (deploy.sh → deploy)
+// - Every function definition becomes a "func" symbol, nested ones included
+func extractShell(relPath string, data []byte) (pkg, kind, typ string, exports []string, syms []Symbol) {
+	lineOf := func(off int) int { return 1 + bytes.Count(data[:off], []byte("\n")) }
+
+	base := filepath.Base(filepath.ToSlash(relPath))
+	pkg = strings.TrimSuffix(base, filepath.Ext(base))
+	kind = "file"
+
+	for _, m := range reShellFunc.FindAllSubmatchIndex(data, -1) {
+		name := ""
+		switch {
+		case m[2] >= 0:
+			name = string(data[m[2]:m[3]])
+		case m[4] >= 0:
+			name = string(data[m[4]:m[5]])
+		}
+		if name == "" {
+			continue
+		}
+		start := lineOf(m[0])
+		syms = append(syms, Symbol{
+			Symbol: joinSym(pkg, "", name),
+			Kind:   "func",
+			Path:   relPath,
+			Start:  start,
+			End:    start,
+		})
+		exports = append(exports, name+"()")
+	}
+	return
+}
@@ -0,0 +1,58 @@
+package index
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractShellFunctions(t *testing.T) {
+	src := `#!/usr/bin/env bash
+set -euo pipefail
+
+# usage() { is only a comment
+log() {
+  echo "$*" >&2
+}
+
+function deploy {
+  log "deploying"
+}
+
+function cleanup() {
+  rm -rf "$tmp"
+}
+
+build ()
+{
+  make all
+}
+`
+	if got := InferLangByExt(".bash"); got != "shell" {
+		t.Fatalf("InferLangByExt(.bash) = %q, want shell", got)
+	}
+	pkg, kind, _, exports, syms := extractShell("scripts/release.sh", []byte(src))
+	if pkg != "release" || kind != "file" {
+		t.Fatalf("pkg/kind = %q/%q", pkg, kind)
+	}
+	type got struct {
+		Symbol string
+		Kind   string
+		Start  int
+	}
+	var gotSyms []got
+	for _, s := range syms {
+		gotSyms = append(gotSyms, got{s.Symbol, s.Kind, s.Start})
+	}
+	want := []got{
+		{"release.log", "func", 5},
+		{"release.deploy", "func", 9},
+		{"release.cleanup", "func", 13},
+		{"release.build", "func", 17},
+	}
+	if !reflect.DeepEqual(gotSyms, want) {
+		t.Fatalf("symbols = %+v, want %+v", gotSyms, want)
+	}
+	if w := []string{"log()", "deploy()", "cleanup()", "build()"}; !reflect.DeepEqual(exports, w) {
+		t.Fatalf("exports = %v, want %v", exports, w)
+	}
+}
@@ -0,0 +1,63 @@
+package index
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// sqlIdent is one identifier part: bare, "double-quoted", `backticked` or
+// [bracketed].
+const sqlIdent = "(?:[A-Za-z_][\\w$]*|\"[^\"\\n]+\"|`[^`\\n]+`|\\[[^\\]\\n]+\\])"
+
+// reSQLCreate matches CREATE statements for tables, views, functions and
+// procedures, including common modifiers (OR REPLACE, TEMPORARY, UNLOGGED,
+// MATERIALIZED, IF NOT EXISTS), case-insensitively. Group 1 is the object
+// type, group 2 the possibly schema-qualified name.
+var reSQLCreate = regexp.MustCompile(`(?im)^[ \t]*CREATE[ \t]+(?:OR[ \t]+(?:REPLACE|ALTER)[ \t]+)?(?:(?:GLOBAL|LOCAL)[ \t]+)?(?:TEMP(?:ORARY)?[ \t]+)?(?:UNLOGGED[ \t]+)?(?:MATERIALIZED[ \t]+)?(TABLE|VIEW|FUNCTION|PROCEDURE|PROC)[ \t]+(?:IF[ \t]+NOT[ \t]+EXISTS[ \t]+)?(` + sqlIdent + `(?:[ \t]*\.[ \t]*` + sqlIdent + `)*)`)
+
+// SQL minimal extractor (.sql)
+// - One symbol per CREATE TABLE/VIEW/FUNCTION/PROCEDURE, named by the object
+// - Names keep a written schema prefix and lose their quotes
+// - Kind is table, view, function or procedure
+func extractSQL(relPath string, data []byte) (pkg, kind, typ string, exports []string, syms []Symbol) {
+	lineOf := func(off int) int { return 1 + bytes.Count(data[:off], []byte("\n")) }
+	kind = "file"
+
+	for _, m := range reSQLCreate.FindAllSubmatchIndex(data, -1) {
+		objKind := strings.ToLower(string(data[m[2]:m[3]]))
+		if objKind == "proc" {
+			objKind = "procedure"
+		}
+		name := sqlObjectName(string(data[m[4]:m[5]]))
+		start := lineOf(m[0])
+		syms = append(syms, Symbol{
+			Symbol: name,
+			Kind:   objKind,
+			Path:   relPath,
+			Start:  start,
+			End:    start,
+		})
+		exports = append(exports, name)
+	}
+	return
+}
+
+// sqlObjectName drops whitespace around dots and the quoting of each part:
+// `"app" . [Users]` → app.Users.
+func sqlObjectName(raw string) string {
+	parts := strings.Split(raw, ".")
+	for i, p := range parts {
+		p = strings.TrimSpace(p)
+		if len(p) >= 2 {
+			switch p[0] {
+			case '"', '`':
+				p = p[1 : len(p)-1]
+			case '[':
+				p = strings.TrimSuffix(p[1:], "]")
+			}
+		}
+		parts[i] = p
+	}
+	return strings.Join(parts, ".")
+}
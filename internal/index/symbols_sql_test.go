@@ -0,0 +1,53 @@
+package index
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractSQLObjects(t *testing.T) {
+	src := `-- schema
+CREATE TABLE users (
+  id serial PRIMARY KEY
+);
+
+create table if not exists "billing"."Invoices" (
+  id int
+);
+
+CREATE OR REPLACE FUNCTION total(uid int) RETURNS numeric AS $$
+  SELECT 1;
+$$ LANGUAGE sql;
+`
+	if got := InferLangByExt(".SQL"); got != "sql" {
+		t.Fatalf("InferLangByExt(.SQL) = %q, want sql", got)
+	}
+	_, kind, _, exports, syms := extractSQL("db/schema.sql", []byte(src))
+	if kind != "file" {
+		t.Fatalf("kind = %q", kind)
+	}
+	type got struct {
+		Symbol, Kind string
+		Start        int
+	}
+	var gotSyms []got
+	for _, s := range syms {
+		gotSyms = append(gotSyms, got{s.Symbol, s.Kind, s.Start})
+	}
+	want := []got{
+		{"users", "table", 2},
+		{"billing.Invoices", "table", 6},
+		{"total", "function", 10},
+	}
+	if !reflect.DeepEqual(gotSyms, want) {
+		t.Fatalf("symbols = %+v, want %+v", gotSyms, want)
+	}
+	if w := []string{"users", "billing.Invoices", "total"}; !reflect.DeepEqual(exports, w) {
+		t.Fatalf("exports = %v, want %v", exports, w)
+	}
+
+	_, _, _, _, syms = extractSQL("v.sql", []byte("CREATE MATERIALIZED VIEW [dbo].[Active] AS SELECT 1;\nCREATE PROC dbo.Purge AS DELETE FROM t;\n"))
+	if len(syms) != 2 || syms[0].Symbol != "dbo.Active" || syms[0].Kind != "view" || syms[1].Kind != "procedure" {
+		t.Fatalf("view/procedure symbols = %+v", syms)
+	}
+}
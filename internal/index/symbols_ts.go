@@ -11,7 +11,8 @@ var (
 	reTsFunc             = regexp.MustCompile(`(?m)^\s*export\s+(?:async\s+)?function\*?\s+([A-Za-z_$][\w$]*)\s*\(`)
 	reTsDefaultNamedFunc = regexp.MustCompile(`(?m)^\s*export\s+default\s+function\s+([A-Za-z_$][\w$]*)\s*\(`)
 	reTsDefaultAnonFunc  = regexp.MustCompile(`(?m)^\s*export\s+default\s+function\s*\(`)
-	reTsReExportList     = regexp.MustCompile(`(?m)^\s*export\s*\{([^}]*)\}\s*from\s*['\"][^'\"]+['\"]`)
+	reTsReExportList     = regexp.MustCompile(`(?m)^\s*export\s*\{([^}]*)\}\s*from\s*['\"]([^'\"]+)['\"]`)
+	reTsReExportAll      = regexp.MustCompile(`(?m)^\s*export\s*\*\s*(?:as\s+([A-Za-z_$][\w$]*)\s+)?from\s*['\"]([^'\"]+)['\"]`)
 	reTsLetVar           = regexp.MustCompile(`(?m)^\s*export\s+(?:let|var)\s+([A-Za-z_$][\w$]*)\s*=`)
 	reTsConstArrow       = regexp.MustCompile(`(?m)^\s*export\s+const\s+([A-Za-z_$][\w$]*)\s*=\s*(?:async\s*)?(?:\([^)]*\)|[A-Za-z_$][\w$]*)\s*=>`)
 	reTsConstObject      = regexp.MustCompile(`(?m)^\s*export\s+const\s+([A-Za-z_$][\w$]*)\s*=\s*\{`)
@@ -38,7 +39,14 @@ func extractTS(relPath string, data []byte) (pkg string, kind string, typ string
 
 func scanTS(relPath string, data []byte) tsScanResult {
 	res := tsScanResult{kind: "file"}
-	lineOf := func(off int) int { return 1 + bytes.Count(data[:off], []byte("\n")) }
+	// Matches start with ^\s*, which may span blank lines; count from the
+	// first non-space byte so symbols land on their own line.
+	lineOf := func(off int) int {
+		for off < len(data) && isSpaceByte(data[off]) {
+			off++
+		}
+		return 1 + bytes.Count(data[:off], []byte("\n"))
+	}
 
 	if m := reTsClass.FindSubmatchIndex(data); m != nil {
 		res.kind = "class"
@@ -78,13 +86,9 @@ func scanTS(relPath string, data []byte) tsScanResult {
 		res.exports = append(res.exports, "default()")
 	}
 
-	for _, m := range reTsReExportList.FindAllSubmatch(data, -1) {
-		items := bytes.TrimSpace(m[1])
-		for _, part := range bytes.Split(items, []byte(",")) {
-			name := string(bytes.TrimSpace(bytes.Split(part, []byte(" as "))[0]))
-			if name != "" {
-				res.exports = append(res.exports, name+"()")
-			}
+	for _, re := range tsReExports(data) {
+		if re.Name != "*" {
+			res.exports = append(res.exports, re.Name+"()")
 		}
 	}
 
@@ -122,6 +126,39 @@ func scanTS(relPath string, data []byte) tsScanResult {
 	return res
 }
 
+// tsReExports returns the re-export statements of a TS/JS file: list forms
+// first, then star forms, each in source order.
+func tsReExports(data []byte) []ReExport {
+	var out []ReExport
+	for _, m := range reTsReExportList.FindAllSubmatch(data, -1) {
+		from := string(m[2])
+		for _, part := range bytes.Split(m[1], []byte(",")) {
+			f := bytes.Fields(part)
+			if len(f) > 1 && string(f[0]) == "type" {
+				f = f[1:] // inline type modifier (TS 4.5)
+			}
+			switch {
+			case len(f) == 1:
+				out = append(out, ReExport{From: from, Name: string(f[0]), As: string(f[0])})
+			case len(f) == 3 && string(f[1]) == "as":
+				out = append(out, ReExport{From: from, Name: string(f[0]), As: string(f[2])})
+			}
+		}
+	}
+	for _, m := range reTsReExportAll.FindAllSubmatch(data, -1) {
+		as := "*"
+		if len(m[1]) > 0 {
+			as = string(m[1])
+		}
+		out = append(out, ReExport{From: string(m[2]), Name: "*", As: as})
+	}
+	return out
+}
+
+func isSpaceByte(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
 func toSymbolsTS(relPath string, res tsScanResult) []Symbol {
 	if len(res.symbols) == 0 {
 		return nil
@@ -1,6 +1,11 @@
 package index
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+
+	"class-collector/internal/walkwalk"
+)
 
 func TestScanTSBasic(t *testing.T) {
 	src := []byte(`
@@ -31,3 +36,83 @@ export const baz = () => {}
 		}
 	}
 }
+
+func TestTSReExportsKeepAliases(t *testing.T) {
+	src := []byte(`export { Foo as Bar } from './x'
+export {
+  default as Widget,
+  type Props,
+  helper,
+} from "./widget";
+export * from './all'
+export * as utils from './utils'
+`)
+	want := []ReExport{
+		{From: "./x", Name: "Foo", As: "Bar"},
+		{From: "./widget", Name: "default", As: "Widget"},
+		{From: "./widget", Name: "Props", As: "Props"},
+		{From: "./widget", Name: "helper", As: "helper"},
+		{From: "./all", Name: "*", As: "*"},
+		{From: "./utils", Name: "*", As: "utils"},
+	}
+	if got := tsReExports(src); !reflect.DeepEqual(got, want) {
+		t.Fatalf("re-exports = %+v, want %+v", got, want)
+	}
+
+	fa, err := processFile(walkwalk.FileInfo{RelPath: "index.ts", Ext: ".ts"}, src, 500, nil)
+	if err != nil || fa == nil {
+		t.Fatalf("processFile = %v, %v", fa, err)
+	}
+	if !reflect.DeepEqual(fa.manifest.ReExports, want) {
+		t.Fatalf("manifest re-exports = %+v", fa.manifest.ReExports)
+	}
+	if got := fa.manifest.Exports; !reflect.DeepEqual(got, []string{"Foo()", "default()", "Props()", "helper()"}) {
+		t.Fatalf("exports = %v", got)
+	}
+}
+
+const vueComponent = `<template>
+  <div>{{ label }}</div>
+</template>
+
+<script setup lang="ts">
+import { ref } from 'vue'
+
+export function formatLabel(s: string) {
+  return s.trim()
+}
+</script>
+
+<script lang="ts">
+export const toUpper = (s: string) => s.toUpperCase()
+</script>
+`
+
+func TestExtractVueScriptBlocks(t *testing.T) {
+	if got := InferLangByExt(".vue"); got != "vue" {
+		t.Fatalf("InferLangByExt(.vue) = %q", got)
+	}
+	_, kind, typ, exports, syms := extractByLang("vue", "src/components/UserCard.vue", []byte(vueComponent))
+	if kind != "component" || typ != "UserCard" {
+		t.Fatalf("kind/typ = %q/%q, want component/UserCard", kind, typ)
+	}
+	lines := map[string]int{}
+	for _, s := range syms {
+		lines[s.Symbol] = s.Start
+	}
+	want := map[string]int{"UserCard": 1, "formatLabel": 8, "toUpper": 14}
+	if !reflect.DeepEqual(lines, want) {
+		t.Fatalf("symbol lines = %v, want %v", lines, want)
+	}
+	if !reflect.DeepEqual(exports, []string{"formatLabel()", "toUpper()"}) {
+		t.Fatalf("exports = %v", exports)
+	}
+}
+
+func TestExtractSvelteInlineScript(t *testing.T) {
+	src := "<script>export function go() {}</script>\n<h1>hi</h1>\n"
+	_, _, typ, _, syms := extractByLang("svelte", "App.svelte", []byte(src))
+	if typ != "App" || len(syms) != 2 || syms[1].Symbol != "go" || syms[1].Start != 1 {
+		t.Fatalf("typ=%q syms=%+v", typ, syms)
+	}
+}
@@ -0,0 +1,129 @@
+package index
+
+import (
+	"sort"
+	"strings"
+)
+
+// SymbolNode is a symbol with its nested members, akin to an LSP
+// DocumentSymbol.
+type SymbolNode struct {
+	Symbol
+	Children []SymbolNode `json:"children,omitempty"`
+}
+
+// SymbolTree is the hierarchical form of Symbols written for
+// -symbols-format tree. Format is always "tree", which tells readers apart
+// from the flat list.
+type SymbolTree struct {
+	Version int          `json:"version"`
+	Format  string       `json:"format"`
+	Symbols []SymbolNode `json:"symbols"`
+}
+
+// BuildSymbolTree nests the flat symbols by qualified name: within a file,
+// each symbol becomes a child of the longest other symbol whose name is a
+// dotted prefix of its own ("pkg.Server.start" under "pkg.Server"). Parent
+// ranges are widened to cover their children, since flat Ends stop at the
+// next symbol. Roots are sorted by (path, start, end, symbol) and children by
+// (start, end, symbol).
+func BuildSymbolTree(s Symbols) SymbolTree {
+	type key struct{ path, name string }
+	type node struct {
+		sym      Symbol
+		children []*node
+	}
+	nodes := make([]*node, len(s.Symbols))
+	byName := make(map[key]*node, len(s.Symbols))
+	for i, sym := range s.Symbols {
+		nodes[i] = &node{sym: sym}
+		if _, dup := byName[key{sym.Path, sym.Symbol}]; !dup {
+			byName[key{sym.Path, sym.Symbol}] = nodes[i]
+		}
+	}
+
+	var roots []*node
+	for _, n := range nodes {
+		var parent *node
+		for name := n.sym.Symbol; parent == nil; {
+			i := strings.LastIndexByte(name, '.')
+			if i < 0 {
+				break
+			}
+			name = name[:i]
+			parent = byName[key{n.sym.Path, name}]
+		}
+		if parent != nil {
+			parent.children = append(parent.children, n)
+		} else {
+			roots = append(roots, n)
+		}
+	}
+
+	var convert func(n *node) SymbolNode
+	convert = func(n *node) SymbolNode {
+		out := SymbolNode{Symbol: n.sym}
+		for _, c := range n.children {
+			cn := convert(c)
+			if cn.Start < out.Start {
+				out.Start = cn.Start
+			}
+			if cn.End > out.End {
+				out.End = cn.End
+			}
+			out.Children = append(out.Children, cn)
+		}
+		sortSymbolNodes(out.Children)
+		return out
+	}
+	tree := SymbolTree{Version: s.Version, Format: "tree", Symbols: make([]SymbolNode, 0, len(roots))}
+	for _, r := range roots {
+		tree.Symbols = append(tree.Symbols, convert(r))
+	}
+	sortSymbolNodes(tree.Symbols)
+	return tree
+}
+
+// FlattenSymbolTree returns the tree's symbols as a flat, sorted list. Parent
+// ranges keep the widened extents computed by BuildSymbolTree.
+func FlattenSymbolTree(t SymbolTree) Symbols {
+	out := Symbols{Version: t.Version}
+	var walk func(ns []SymbolNode)
+	walk = func(ns []SymbolNode) {
+		for _, n := range ns {
+			out.Symbols = append(out.Symbols, n.Symbol)
+			walk(n.Children)
+		}
+	}
+	walk(t.Symbols)
+	sort.SliceStable(out.Symbols, func(i, j int) bool {
+		a, b := out.Symbols[i], out.Symbols[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Start != b.Start {
+			return a.Start < b.Start
+		}
+		if a.End != b.End {
+			return a.End < b.End
+		}
+		return a.Symbol < b.Symbol
+	})
+	return out
+}
+
+func sortSymbolNodes(ns []SymbolNode) {
+	sort.SliceStable(ns, func(i, j int) bool {
+		a, b := ns[i], ns[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Start != b.Start {
+			return a.Start < b.Start
+		}
+		if a.End != b.End {
+			return a.End < b.End
+		}
+		return a.Symbol.Symbol < b.Symbol.Symbol
+	})
+}
@@ -0,0 +1,39 @@
+package index
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuildSymbolTreeNestsMethodsUnderClass(t *testing.T) {
+	flat := Symbols{Version: 1, Symbols: []Symbol{
+		{Symbol: "org.acme.Server", Kind: "class", Path: "Server.java", Start: 3, End: 4},
+		{Symbol: "org.acme.Server.start", Kind: "method", Path: "Server.java", Start: 5, End: 8},
+		{Symbol: "org.acme.Server.stop", Kind: "method", Path: "Server.java", Start: 9, End: 12},
+		{Symbol: "org.acme.Util", Kind: "class", Path: "Util.java", Start: 1, End: 5},
+	}}
+
+	tree := BuildSymbolTree(flat)
+	if tree.Format != "tree" || tree.Version != 1 || len(tree.Symbols) != 2 {
+		t.Fatalf("unexpected tree header/roots: %+v", tree)
+	}
+	server := tree.Symbols[0]
+	if server.Symbol.Symbol != "org.acme.Server" || server.Start != 3 || server.End != 12 {
+		t.Fatalf("class node should span its methods: %+v", server.Symbol)
+	}
+	var kids []string
+	for _, c := range server.Children {
+		kids = append(kids, c.Symbol.Symbol)
+	}
+	if want := []string{"org.acme.Server.start", "org.acme.Server.stop"}; !reflect.DeepEqual(kids, want) {
+		t.Fatalf("children = %v, want %v", kids, want)
+	}
+	if len(tree.Symbols[1].Children) != 0 {
+		t.Fatalf("Util should have no children: %+v", tree.Symbols[1])
+	}
+
+	back := FlattenSymbolTree(tree)
+	if len(back.Symbols) != 4 || back.Symbols[1].Symbol != "org.acme.Server.start" {
+		t.Fatalf("flatten lost symbols: %+v", back.Symbols)
+	}
+}
@@ -17,6 +17,7 @@ type ManFile struct {
 	Package   string   `json:"package,omitempty"`   // language package/namespace (if any)
 	Class     string   `json:"class,omitempty"`     // primary type (e.g., Java class name)
 	Kind      string   `json:"kind,omitempty"`      // "class"|"interface"|"enum"|"file"|...
+	Role      string   `json:"role,omitempty"`      // "source"|"test"|"config"|"doc"|"generated"
 	Summary   string   `json:"summary,omitempty"`   // optional short description
 	Hash      string   `json:"hash,omitempty"`      // content hash (e.g., sha256 hex)
 	Exports   []string `json:"exports,omitempty"`   // quick API surface (e.g., ["start()", ...])
@@ -24,18 +25,73 @@ type ManFile struct {
 	Tags      []string `json:"tags,omitempty"`      // arbitrary labels (navigation)
 	Lines     int      `json:"lines,omitempty"`     // total number of lines in file
 	Anchors   []Anchor `json:"anchors,omitempty"`   // region anchors detected in file
+	GoModule  string   `json:"goModule,omitempty"`  // path of the nearest enclosing Go module (multi-module repos)
+	Symlink   string   `json:"symlink,omitempty"`   // link target when Kind is "symlink" (not followed)
+	Encoding  string   `json:"encoding,omitempty"`  // original encoding when not plain UTF-8 (content is indexed transcoded)
+	Impact    int      `json:"impact,omitempty"`    // transitive dependents of the file's graph node (-impact)
+	Aliases   []string `json:"aliases,omitempty"`   // other paths hard-linked (-dedup-hardlinks) or identical (-exclude-duplicate-content) to this file
+	// ReExports maps names re-exported from other modules (TS/JS barrels) to
+	// the name they are exported under.
+	ReExports []ReExport `json:"reExports,omitempty"`
+	// Annotations lists the annotations/decorators written just before the
+	// declaration of the primary type (Class), e.g. ["@RestController"].
+	Annotations []string `json:"annotations,omitempty"`
+	// GoImportPath is the import path of a Go file's package (multi-module
+	// repos), where Package keeps the package clause.
+	GoImportPath string `json:"goImportPath,omitempty"`
+
+	hygiene   *FileHygiene // whitespace issues found when indexed (see BuildHygiene)
+	lineStats *LangStats   // blank/comment/code counts found when indexed (see BuildStats)
+}
+
+// ReExport is one name of an "export { Name as As } from 'From'" statement;
+// As equals Name when there is no alias. "export * as ns from" records Name
+// "*", and a plain "export * from" records Name and As "*".
+type ReExport struct {
+	From string `json:"from"`
+	Name string `json:"name"`
+	As   string `json:"as"`
+}
+
+// GoModule records a go.mod boundary: Dir is the project-relative directory
+// holding go.mod ("." for the root) and Path is its declared module path.
+type GoModule struct {
+	Dir  string `json:"dir"`
+	Path string `json:"path"`
 }
 
+// Producer stamps recorded in every manifest. ManifestVersion is bumped when
+// manifest.json changes incompatibly; ToolVersion follows releases.
+const (
+	ToolVersion     = "0.1.0"
+	ManifestVersion = 1
+)
+
 // Manifest is the top-level index of a bundle/module.
 type Manifest struct {
-	Module       string    `json:"module"`                 // human-readable module name
-	JDK          string    `json:"jdk,omitempty"`          // optional JDK version for Java projects
-	Build        string    `json:"build,omitempty"`        // "maven"|"gradle"|"go"|"node"|...
-	PackagesRoot string    `json:"packagesRoot,omitempty"` // optional packages root (if relevant)
-	Entrypoints  []string  `json:"entrypoints,omitempty"`  // optional fully-qualified entry symbols
-	SourceGlobs  []string  `json:"sourceGlobs,omitempty"`  // optional source patterns
-	Files        []ManFile `json:"files"`                  // manifest entries (deterministic order)
-	BundleID     string    `json:"bundle_id,omitempty"`    // canonical bundle hash (SHA-256 over sorted "path:hash\n")
+	Module       string     `json:"module"`                 // human-readable module name
+	JDK          string     `json:"jdk,omitempty"`          // optional JDK version for Java projects
+	Build        string     `json:"build,omitempty"`        // "maven"|"gradle"|"go"|"node"|...
+	PackagesRoot string     `json:"packagesRoot,omitempty"` // optional packages root (if relevant)
+	Entrypoints  []string   `json:"entrypoints,omitempty"`  // optional fully-qualified entry symbols
+	SourceGlobs  []string   `json:"sourceGlobs,omitempty"`  // optional source patterns
+	Files        []ManFile  `json:"files"`                  // manifest entries (deterministic order)
+	GoModules    []GoModule `json:"goModules,omitempty"`    // all go.mod boundaries, sorted by Dir
+	SrcArchive   string     `json:"srcArchive,omitempty"`   // sibling archive holding src/ (-emit-src-compressed)
+	BundleID     string     `json:"bundle_id,omitempty"`    // canonical bundle hash (SHA-256 over sorted "path:hash\n")
+	// BundleIDAlgo names what BundleID folds in besides content ("module",
+	// "module+git"); empty means content only.
+	BundleIDAlgo string `json:"bundle_id_algo,omitempty"`
+
+	// ToolVersion and ManifestVersion identify the producer and schema; they
+	// are not part of BundleID, so IDs survive tool upgrades.
+	ToolVersion     string `json:"toolVersion,omitempty"`
+	ManifestVersion int    `json:"manifestVersion,omitempty"`
+
+	// OptionsFingerprint hashes the tool version and the options that shape
+	// the bundle's content; written with -skip-if-unchanged, which compares
+	// it along with BundleID. Not part of BundleID.
+	OptionsFingerprint string `json:"optionsFingerprint,omitempty"`
 }
 
 // Symbol represents a discovered code symbol suitable for navigation.
@@ -47,6 +103,20 @@ type Symbol struct {
 	Path   string `json:"path"`   // project-relative file path
 	Start  int    `json:"start"`  // 1-based
 	End    int    `json:"end"`    // 1-based
+	// TypeParams holds the generic parameter list as written, e.g. "[T any]".
+	TypeParams string `json:"typeParams,omitempty"`
+	// Visibility is "exported" or "unexported" where the language defines it
+	// by naming (currently Go).
+	Visibility string `json:"visibility,omitempty"`
+	// Annotations lists the annotations/decorators written just before the
+	// declaration, e.g. ["@GetMapping(\"/users\")"] (Java, Kotlin, TS, Python).
+	Annotations []string `json:"annotations,omitempty"`
+	// Signature lists the parameter types of a callable, e.g. "(String, int)",
+	// which tells overloads apart (-parser precise).
+	Signature string `json:"signature,omitempty"`
+	// ImportPath is the import path of a Go symbol's package in multi-module
+	// repos; Symbol keeps the package clause as its qualifier.
+	ImportPath string `json:"importPath,omitempty"`
 }
 
 // Symbols wraps the flat list for easier JSON emission/versioning.
@@ -0,0 +1,240 @@
+// Package meta — lockfile dependency summary.
+//
+// This file reads the dependency lockfiles found at the project root and
+// reduces them to name/version pairs for dependencies.json:
+//   - Go:     go.sum (versions), go.mod (direct vs "// indirect")
+//   - Node:   package-lock.json (lockfileVersion 1 "dependencies" tree or
+//     2/3 "packages" map; direct = root package dependencies)
+//   - Python: requirements.txt (every pinned requirement is direct)
+//
+// Parsing is tolerant: malformed lines or entries are skipped and a lockfile
+// that cannot be read or decoded contributes nothing.
+package meta
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Dependency is one resolved package version.
+type Dependency struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	Direct  bool   `json:"direct"`
+}
+
+// DepSet lists the dependencies read from one lockfile, sorted by
+// (name, version).
+type DepSet struct {
+	Ecosystem    string       `json:"ecosystem"` // "go"|"node"|"python"
+	Lockfile     string       `json:"lockfile"`  // project-relative path
+	Dependencies []Dependency `json:"dependencies"`
+}
+
+// Dependencies is the dependencies.json payload; sets are sorted by Lockfile.
+type Dependencies struct {
+	Version int      `json:"version"`
+	Sets    []DepSet `json:"sets"`
+}
+
+// Counts returns the number of direct and transitive entries in s.
+func (s DepSet) Counts() (direct, transitive int) {
+	for _, d := range s.Dependencies {
+		if d.Direct {
+			direct++
+		} else {
+			transitive++
+		}
+	}
+	return direct, transitive
+}
+
+// DetectDependencies summarizes the lockfiles at the root of root. It returns
+// nil when none is present or none yields a dependency.
+func DetectDependencies(root string) *Dependencies {
+	var sets []DepSet
+	if data, err := os.ReadFile(filepath.Join(root, "go.sum")); err == nil {
+		gomod, _ := os.ReadFile(filepath.Join(root, "go.mod"))
+		if deps := ParseGoSum(data, gomod); len(deps) > 0 {
+			sets = append(sets, DepSet{Ecosystem: "go", Lockfile: "go.sum", Dependencies: deps})
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(root, "package-lock.json")); err == nil {
+		if deps := ParsePackageLock(data); len(deps) > 0 {
+			sets = append(sets, DepSet{Ecosystem: "node", Lockfile: "package-lock.json", Dependencies: deps})
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(root, "requirements.txt")); err == nil {
+		if deps := ParseRequirements(data); len(deps) > 0 {
+			sets = append(sets, DepSet{Ecosystem: "python", Lockfile: "requirements.txt", Dependencies: deps})
+		}
+	}
+	if len(sets) == 0 {
+		return nil
+	}
+	sort.Slice(sets, func(i, j int) bool { return sets[i].Lockfile < sets[j].Lockfile })
+	return &Dependencies{Version: 1, Sets: sets}
+}
+
+// ParseGoSum returns one entry per module version in go.sum ("/go.mod"
+// hash lines fold into their version). A module is direct when gomod
+// requires it without an "// indirect" comment.
+func ParseGoSum(sum, gomod []byte) []Dependency {
+	direct := goDirectRequires(gomod)
+	seen := map[string]bool{}
+	var out []Dependency
+	sc := bufio.NewScanner(bytes.NewReader(sum))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) != 3 || !strings.HasPrefix(fields[2], "h1:") {
+			continue
+		}
+		name, ver := fields[0], strings.TrimSuffix(fields[1], "/go.mod")
+		key := name + "@" + ver
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, Dependency{Name: name, Version: ver, Direct: direct[name]})
+	}
+	return sortDeps(out)
+}
+
+// goDirectRequires returns the modules gomod requires directly, from both the
+// single-line and block forms of "require".
+func goDirectRequires(gomod []byte) map[string]bool {
+	out := map[string]bool{}
+	inBlock := false
+	sc := bufio.NewScanner(bytes.NewReader(gomod))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		switch {
+		case inBlock && line == ")":
+			inBlock = false
+			continue
+		case line == "require (":
+			inBlock = true
+			continue
+		case strings.HasPrefix(line, "require "):
+			line = strings.TrimSpace(strings.TrimPrefix(line, "require "))
+		case !inBlock:
+			continue
+		}
+		if strings.Contains(line, "// indirect") {
+			continue
+		}
+		if fields := strings.Fields(line); len(fields) >= 2 {
+			out[fields[0]] = true
+		}
+	}
+	return out
+}
+
+// ParsePackageLock returns the installed packages of an npm lockfile. For
+// lockfileVersion 2/3 the "packages" map is used (nested node_modules paths
+// are reduced to the innermost package name); version 1 falls back to the
+// recursive "dependencies" tree.
+func ParsePackageLock(data []byte) []Dependency {
+	type lockDep struct {
+		Version      string             `json:"version"`
+		Dependencies map[string]lockDep `json:"dependencies"`
+	}
+	var lock struct {
+		Packages map[string]struct {
+			Version         string            `json:"version"`
+			Dependencies    map[string]string `json:"dependencies"`
+			DevDependencies map[string]string `json:"devDependencies"`
+		} `json:"packages"`
+		Dependencies map[string]lockDep `json:"dependencies"`
+	}
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	var out []Dependency
+	add := func(name, ver string, direct bool) {
+		if name == "" || seen[name+"@"+ver] {
+			return
+		}
+		seen[name+"@"+ver] = true
+		out = append(out, Dependency{Name: name, Version: ver, Direct: direct})
+	}
+
+	if len(lock.Packages) > 0 {
+		root := lock.Packages[""]
+		for key, p := range lock.Packages {
+			i := strings.LastIndex(key, "node_modules/")
+			if i < 0 {
+				continue // the root package or a workspace link target
+			}
+			name := key[i+len("node_modules/"):]
+			nested := strings.Contains(key[:i], "node_modules/")
+			_, dep := root.Dependencies[name]
+			_, dev := root.DevDependencies[name]
+			add(name, p.Version, !nested && (dep || dev))
+		}
+		return sortDeps(out)
+	}
+
+	var walk func(deps map[string]lockDep, direct bool)
+	walk = func(deps map[string]lockDep, direct bool) {
+		for name, d := range deps {
+			add(name, d.Version, direct)
+			walk(d.Dependencies, false)
+		}
+	}
+	walk(lock.Dependencies, true)
+	return sortDeps(out)
+}
+
+// ParseRequirements returns the requirements of a pip requirements file.
+// "name==1.2" yields version "1.2"; other specifiers are kept as written
+// (">=1.0"); options, URLs and comments are skipped.
+func ParseRequirements(data []byte) []Dependency {
+	var out []Dependency
+	seen := map[string]bool{}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := sc.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		if i := strings.Index(line, ";"); i >= 0 {
+			line = line[:i] // environment marker
+		}
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "-") || strings.Contains(line, "://") {
+			continue
+		}
+		name, ver := line, ""
+		if i := strings.IndexAny(line, "=<>!~"); i >= 0 {
+			name, ver = strings.TrimSpace(line[:i]), strings.TrimSpace(line[i:])
+			ver = strings.TrimPrefix(ver, "==")
+		}
+		if i := strings.Index(name, "["); i >= 0 {
+			name = name[:i] // extras
+		}
+		if name == "" || seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		out = append(out, Dependency{Name: name, Version: ver, Direct: true})
+	}
+	return sortDeps(out)
+}
+
+func sortDeps(deps []Dependency) []Dependency {
+	sort.Slice(deps, func(i, j int) bool {
+		if deps[i].Name != deps[j].Name {
+			return deps[i].Name < deps[j].Name
+		}
+		return deps[i].Version < deps[j].Version
+	})
+	return deps
+}
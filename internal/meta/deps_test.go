@@ -0,0 +1,94 @@
+package meta
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseGoSum(t *testing.T) {
+	sum := []byte(`github.com/pmezard/go-difflib v1.0.0 h1:4DBwDE0NGyQoBHbLQYPwSUPoCMWR5BEzIk/f1lZbAQM=
+github.com/pmezard/go-difflib v1.0.0/go.mod h1:iKH77koFhYxTK1pcRnkKkqfTogsbg7gZNVY4sRDYZ/4=
+golang.org/x/text v0.3.0/go.mod h1:NqM8EUOU14njkJ3fqMW+pc6Ldnwhi/IjpwHt7yyuwOQ=
+golang.org/x/text v0.14.0 h1:ScX5w1eTa3QqT8oi6+ziP7dTV1S2+ALU0bI+0zXKWiQ=
+not a sum line
+`)
+	gomod := []byte(`module example.com/app
+
+go 1.22
+
+require github.com/pmezard/go-difflib v1.0.0
+
+require (
+	golang.org/x/text v0.14.0 // indirect
+)
+`)
+	got := ParseGoSum(sum, gomod)
+	want := []Dependency{
+		{Name: "github.com/pmezard/go-difflib", Version: "v1.0.0", Direct: true},
+		{Name: "golang.org/x/text", Version: "v0.14.0"},
+		{Name: "golang.org/x/text", Version: "v0.3.0"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ParseGoSum = %+v, want %+v", got, want)
+	}
+}
+
+func TestParsePackageLock(t *testing.T) {
+	v3 := []byte(`{
+  "name": "web", "lockfileVersion": 3,
+  "packages": {
+    "": {"name": "web", "dependencies": {"react": "^18.2.0"}, "devDependencies": {"typescript": "^5.0.0"}},
+    "node_modules/react": {"version": "18.2.0", "dependencies": {"loose-envify": "^1.1.0"}},
+    "node_modules/loose-envify": {"version": "1.4.0"},
+    "node_modules/typescript": {"version": "5.4.5", "dev": true},
+    "node_modules/react/node_modules/loose-envify": {"version": "1.3.0"}
+  }
+}`)
+	want := []Dependency{
+		{Name: "loose-envify", Version: "1.3.0"},
+		{Name: "loose-envify", Version: "1.4.0"},
+		{Name: "react", Version: "18.2.0", Direct: true},
+		{Name: "typescript", Version: "5.4.5", Direct: true},
+	}
+	if got := ParsePackageLock(v3); !reflect.DeepEqual(got, want) {
+		t.Fatalf("ParsePackageLock(v3) = %+v, want %+v", got, want)
+	}
+
+	v1 := []byte(`{"lockfileVersion": 1, "dependencies": {
+  "react": {"version": "16.14.0", "dependencies": {"object-assign": {"version": "4.1.1"}}}
+}}`)
+	want = []Dependency{
+		{Name: "object-assign", Version: "4.1.1"},
+		{Name: "react", Version: "16.14.0", Direct: true},
+	}
+	if got := ParsePackageLock(v1); !reflect.DeepEqual(got, want) {
+		t.Fatalf("ParsePackageLock(v1) = %+v, want %+v", got, want)
+	}
+	if got := ParsePackageLock([]byte("{broken")); got != nil {
+		t.Fatalf("malformed lockfile should yield nil, got %+v", got)
+	}
+}
+
+func TestDetectDependencies(t *testing.T) {
+	root := t.TempDir()
+	if DetectDependencies(root) != nil {
+		t.Fatalf("expected nil without lockfiles")
+	}
+	reqs := "# pinned\nrequests==2.31.0\nDjango[argon2]>=4.2 ; python_version >= \"3.8\"\n-r dev.txt\n"
+	if err := os.WriteFile(filepath.Join(root, "requirements.txt"), []byte(reqs), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got := DetectDependencies(root)
+	if got == nil || len(got.Sets) != 1 || got.Sets[0].Ecosystem != "python" {
+		t.Fatalf("unexpected dependencies: %+v", got)
+	}
+	want := []Dependency{
+		{Name: "Django", Version: ">=4.2", Direct: true},
+		{Name: "requests", Version: "2.31.0", Direct: true},
+	}
+	if !reflect.DeepEqual(got.Sets[0].Dependencies, want) {
+		t.Fatalf("requirements = %+v, want %+v", got.Sets[0].Dependencies, want)
+	}
+}
@@ -0,0 +1,45 @@
+package meta
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGitCommitResolvesRefs(t *testing.T) {
+	const loose = "1111111111111111111111111111111111111111"
+	const packed = "2222222222222222222222222222222222222222"
+
+	root := t.TempDir()
+	git := filepath.Join(root, ".git")
+	writeFile(t, filepath.Join(git, "HEAD"), "ref: refs/heads/main\n")
+	writeFile(t, filepath.Join(git, "refs", "heads", "main"), loose+"\n")
+	writeFile(t, filepath.Join(git, "packed-refs"), "# pack-refs with: peeled\n"+packed+" refs/heads/dev\n")
+	if got := GitCommit(root); got != loose {
+		t.Fatalf("loose ref: got %q", got)
+	}
+
+	writeFile(t, filepath.Join(git, "HEAD"), "ref: refs/heads/dev\n")
+	if got := GitCommit(root); got != packed {
+		t.Fatalf("packed ref: got %q", got)
+	}
+
+	writeFile(t, filepath.Join(git, "HEAD"), packed+"\n")
+	if got := GitCommit(root); got != packed {
+		t.Fatalf("detached HEAD: got %q", got)
+	}
+
+	if got := GitCommit(t.TempDir()); got != "" {
+		t.Fatalf("non-repo: got %q", got)
+	}
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,92 @@
+package meta
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"class-collector/internal/index"
+)
+
+// skipModuleDirs are never searched for nested go.mod files.
+var skipModuleDirs = map[string]struct{}{
+	"vendor": {}, "node_modules": {}, "testdata": {},
+}
+
+// DetectGoModules finds every go.mod under root (skipping hidden, vendor,
+// node_modules and testdata directories) and returns the module boundaries
+// sorted by directory. A go.mod without a module line is named after its
+// directory.
+func DetectGoModules(root string) []index.GoModule {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil
+	}
+	var mods []index.GoModule
+	_ = filepath.WalkDir(absRoot, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if p == absRoot {
+				return nil
+			}
+			name := d.Name()
+			if _, skip := skipModuleDirs[name]; skip || strings.HasPrefix(name, ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() != "go.mod" {
+			return nil
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(absRoot, filepath.Dir(p))
+		if err != nil {
+			return nil
+		}
+		mod, _ := parseGoMod(string(b))
+		if mod == "" {
+			mod = filepath.Base(filepath.Dir(p))
+		}
+		mods = append(mods, index.GoModule{Dir: filepath.ToSlash(rel), Path: mod})
+		return nil
+	})
+	sort.Slice(mods, func(i, j int) bool { return mods[i].Dir < mods[j].Dir })
+	return mods
+}
+
+// ApplyGoModules records the module boundaries in the manifest, attributes
+// each file to its nearest enclosing module and records the per-module import
+// path of Go files and of their symbols in syms (which may be nil). It is a
+// no-op for repos with at most one module, where the root module already
+// describes every file.
+func ApplyGoModules(mods []index.GoModule, m *index.Manifest, syms *index.Symbols) {
+	if m == nil || len(mods) < 2 {
+		return
+	}
+	m.GoModules = append([]index.GoModule(nil), mods...)
+	for i := range m.Files {
+		f := &m.Files[i]
+		if mod, ok := index.EnclosingGoModule(mods, f.Path); ok {
+			f.GoModule = mod.Path
+		}
+		if strings.HasSuffix(f.Path, ".go") {
+			f.GoImportPath = index.GoImportPath(mods, f.Path)
+		}
+	}
+	if syms == nil {
+		return
+	}
+	for i := range syms.Symbols {
+		s := &syms.Symbols[i]
+		if strings.HasSuffix(s.Path, ".go") {
+			s.ImportPath = index.GoImportPath(mods, s.Path)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package meta
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"class-collector/internal/index"
+)
+
+func TestGoModulesNestedAttribution(t *testing.T) {
+	root := t.TempDir()
+	for rel, body := range map[string]string{
+		"go.mod":           "module example.com/app\n\ngo 1.22\n",
+		"main.go":          "package main\n",
+		"internal/x/x.go":  "package x\n",
+		"tools/go.mod":     "module example.com/app/tools\n",
+		"tools/gen/gen.go": "package gen\n",
+	} {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+			t.Fatalf("write %s: %v", rel, err)
+		}
+	}
+
+	mods := DetectGoModules(root)
+	want := []index.GoModule{{Dir: ".", Path: "example.com/app"}, {Dir: "tools", Path: "example.com/app/tools"}}
+	if !reflect.DeepEqual(mods, want) {
+		t.Fatalf("modules = %+v, want %+v", mods, want)
+	}
+
+	man := index.Manifest{Files: []index.ManFile{{Path: "internal/x/x.go"}, {Path: "main.go"}, {Path: "tools/gen/gen.go"}}}
+	syms := index.Symbols{Symbols: []index.Symbol{{Symbol: "gen.Run", Path: "tools/gen/gen.go"}}}
+	ApplyGoModules(mods, &man, &syms)
+	got := []string{man.Files[0].GoModule, man.Files[1].GoModule, man.Files[2].GoModule}
+	if !reflect.DeepEqual(got, []string{"example.com/app", "example.com/app", "example.com/app/tools"}) {
+		t.Fatalf("attribution = %v", got)
+	}
+	got = []string{man.Files[0].GoImportPath, man.Files[1].GoImportPath, man.Files[2].GoImportPath}
+	if !reflect.DeepEqual(got, []string{"example.com/app/internal/x", "example.com/app", "example.com/app/tools/gen"}) {
+		t.Fatalf("import paths = %v", got)
+	}
+	if p := syms.Symbols[0].ImportPath; p != "example.com/app/tools/gen" {
+		t.Fatalf("symbol import path = %q", p)
+	}
+	if len(man.GoModules) != 2 {
+		t.Fatalf("manifest modules = %+v", man.GoModules)
+	}
+
+	if p := index.GoImportPath(mods, "tools/gen/gen.go"); p != "example.com/app/tools/gen" {
+		t.Fatalf("import path = %q", p)
+	}
+	if p := index.GoImportPath(mods, "main.go"); p != "example.com/app" {
+		t.Fatalf("root import path = %q", p)
+	}
+}
@@ -0,0 +1,55 @@
+// Package parallel bounds the worker pools used while building bundles. A
+// single process-wide limit (SetLimit, the CLI's -jobs/-max-concurrency)
+// caps every pool, so resource usage stays predictable on shared machines; a
+// limit of 1 runs all work serially, in order, on the calling goroutine.
+package parallel
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+var limit atomic.Int64
+
+// SetLimit sets the maximum number of concurrent workers per pool; n <= 0
+// restores the default, GOMAXPROCS.
+func SetLimit(n int) { limit.Store(int64(max(n, 0))) }
+
+// Limit returns the effective worker limit (at least 1).
+func Limit() int {
+	if n := int(limit.Load()); n > 0 {
+		return n
+	}
+	return max(runtime.GOMAXPROCS(0), 1)
+}
+
+// For calls fn(i) for every i in [0, n) on at most Limit() goroutines and
+// returns when all calls are done. Callers write results into per-index slots
+// so output order does not depend on scheduling. With a limit of 1 (or
+// n <= 1) fn runs in index order on the calling goroutine.
+func For(n int, fn func(i int)) {
+	workers := min(Limit(), n)
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1)) - 1
+				if i >= n {
+					return
+				}
+				fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
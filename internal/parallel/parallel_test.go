@@ -0,0 +1,49 @@
+package parallel
+
+import (
+	"reflect"
+	"runtime"
+	"sync/atomic"
+	"testing"
+)
+
+func TestForVisitsEveryIndexOnce(t *testing.T) {
+	defer SetLimit(0)
+	for _, lim := range []int{1, 3, 64} {
+		SetLimit(lim)
+		hits := make([]int32, 100)
+		var running, peak atomic.Int32
+		For(len(hits), func(i int) {
+			if n := running.Add(1); n > peak.Load() {
+				peak.Store(n)
+			}
+			atomic.AddInt32(&hits[i], 1)
+			running.Add(-1)
+		})
+		for i, h := range hits {
+			if h != 1 {
+				t.Fatalf("limit %d: index %d visited %d times", lim, i, h)
+			}
+		}
+		if int(peak.Load()) > lim {
+			t.Fatalf("limit %d: %d workers ran at once", lim, peak.Load())
+		}
+	}
+}
+
+func TestForSerialKeepsOrder(t *testing.T) {
+	SetLimit(1)
+	defer SetLimit(0)
+	var order []int
+	For(5, func(i int) { order = append(order, i) })
+	if want := []int{0, 1, 2, 3, 4}; !reflect.DeepEqual(order, want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+}
+
+func TestLimitDefault(t *testing.T) {
+	SetLimit(-3)
+	if got := Limit(); got != runtime.GOMAXPROCS(0) {
+		t.Fatalf("default limit = %d, want GOMAXPROCS %d", got, runtime.GOMAXPROCS(0))
+	}
+}
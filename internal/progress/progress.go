@@ -0,0 +1,92 @@
+// Package progress reports phase transitions and periodic item counts for
+// long runs. Output goes only to the writer given to New (stderr in the CLI),
+// never to stdout or an archive; with no Reporter installed every call is a
+// no-op.
+package progress
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// DefaultEvery is the item interval between count lines.
+const DefaultEvery = 500
+
+// Reporter writes "progress:" lines to w.
+type Reporter struct {
+	mu    sync.Mutex
+	w     io.Writer
+	every int
+	phase string
+	count int
+}
+
+// New returns a Reporter printing a count line every `every` items
+// (DefaultEvery when every <= 0).
+func New(w io.Writer, every int) *Reporter {
+	if every <= 0 {
+		every = DefaultEvery
+	}
+	return &Reporter{w: w, every: every}
+}
+
+// Phase closes the current phase (printing its final count, if any) and
+// starts a new one.
+func (r *Reporter) Phase(name string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.flush()
+	r.phase, r.count = name, 0
+	fmt.Fprintf(r.w, "progress: %s\n", name)
+}
+
+// Tick counts one item in the current phase.
+func (r *Reporter) Tick() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.count++
+	if r.count%r.every == 0 {
+		fmt.Fprintf(r.w, "progress: %s %d files\n", r.phase, r.count)
+	}
+}
+
+// Done closes the current phase.
+func (r *Reporter) Done() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.flush()
+	r.phase, r.count = "", 0
+	fmt.Fprintln(r.w, "progress: done")
+}
+
+// flush prints the final count of the current phase unless Tick just did.
+func (r *Reporter) flush() {
+	if r.count > 0 && r.count%r.every != 0 {
+		fmt.Fprintf(r.w, "progress: %s %d files\n", r.phase, r.count)
+	}
+}
+
+var active *Reporter
+
+// Use installs r as the reporter that the package-level functions drive
+// (nil disables).
+func Use(r *Reporter) { active = r }
+
+// Phase starts a phase on the active reporter, if any.
+func Phase(name string) { active.Phase(name) }
+
+// Tick counts one item on the active reporter, if any.
+func Tick() { active.Tick() }
+
+// Done closes the run on the active reporter, if any.
+func Done() { active.Done() }
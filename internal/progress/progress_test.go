@@ -0,0 +1,36 @@
+package progress
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReporterCountsAndNilIsNoop(t *testing.T) {
+	var nilr *Reporter
+	nilr.Phase("collect")
+	nilr.Tick()
+	nilr.Done()
+
+	var buf bytes.Buffer
+	Use(New(&buf, 2))
+	defer Use(nil)
+	Phase("collect")
+	for i := 0; i < 5; i++ {
+		Tick()
+	}
+	Phase("index")
+	Tick()
+	Tick()
+	Done()
+
+	want := "progress: collect\n" +
+		"progress: collect 2 files\n" +
+		"progress: collect 4 files\n" +
+		"progress: collect 5 files\n" +
+		"progress: index\n" +
+		"progress: index 2 files\n" +
+		"progress: done\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("output:\n%s\nwant:\n%s", got, want)
+	}
+}
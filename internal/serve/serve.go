@@ -0,0 +1,165 @@
+// Package serve exposes the artifacts of a FULL bundle over a small
+// read-only HTTP API, so editor plugins and agents can query a project live
+// instead of unpacking bundles.
+//
+// Only requests addressed to 127.0.0.1:<port> or localhost:<port> are
+// answered, so a web page cannot reach the API through DNS rebinding.
+//
+// Routes (all GET):
+//
+//	/manifest.json       the manifest
+//	/symbols.json        the symbols index
+//	/graph.json          the import graph
+//	/slices/{path...}    slices of one file, with their text
+//	/diffs/{path...}     unified diff of one file against the baseline
+package serve
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"class-collector/internal/graph"
+	"class-collector/internal/index"
+)
+
+// State is one consistent view of the project. Contents maps manifest paths
+// to the file contents read when the state was built, so slice text always
+// matches the indexed lines. Diff returns the unified diff of a manifest
+// path against the baseline ("" when unchanged); it may be nil when no
+// baseline is known.
+type State struct {
+	Contents map[string][]byte
+	Manifest index.Manifest
+	Symbols  index.Symbols
+	Slices   []index.Slice
+	Graph    graph.Graph
+	Diff     func(path string) string
+}
+
+// Server serves the most recent State passed to Update. It is safe for
+// concurrent use: requests always see a whole State, never a mix of two.
+type Server struct {
+	mu    sync.RWMutex
+	state *State
+	mux   *http.ServeMux
+	hosts map[string]bool // accepted Host headers
+}
+
+// New returns a Server for a listener on port with no state; every route
+// answers 503 until the first Update, and requests with any Host header
+// other than 127.0.0.1:port or localhost:port get 403.
+func New(port int) *Server {
+	p := strconv.Itoa(port)
+	s := &Server{mux: http.NewServeMux(), hosts: map[string]bool{
+		net.JoinHostPort("127.0.0.1", p): true,
+		net.JoinHostPort("localhost", p): true,
+	}}
+	s.mux.HandleFunc("GET /manifest.json", s.with(func(w http.ResponseWriter, _ *http.Request, st *State) {
+		writeJSON(w, st.Manifest)
+	}))
+	s.mux.HandleFunc("GET /symbols.json", s.with(func(w http.ResponseWriter, _ *http.Request, st *State) {
+		writeJSON(w, st.Symbols)
+	}))
+	s.mux.HandleFunc("GET /graph.json", s.with(func(w http.ResponseWriter, _ *http.Request, st *State) {
+		writeJSON(w, st.Graph)
+	}))
+	s.mux.HandleFunc("GET /slices/{path...}", s.with(serveSlices))
+	s.mux.HandleFunc("GET /diffs/{path...}", s.with(serveDiff))
+	return s
+}
+
+// Update replaces the served state.
+func (s *Server) Update(st State) {
+	s.mu.Lock()
+	s.state = &st
+	s.mu.Unlock()
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.hosts[strings.ToLower(r.Host)] {
+		http.Error(w, "unexpected Host header", http.StatusForbidden)
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// with resolves the current state for a handler.
+func (s *Server) with(h func(http.ResponseWriter, *http.Request, *State)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.RLock()
+		st := s.state
+		s.mu.RUnlock()
+		if st == nil {
+			http.Error(w, "index not built yet", http.StatusServiceUnavailable)
+			return
+		}
+		h(w, r, st)
+	}
+}
+
+// sliceText is a slice with the lines it covers.
+type sliceText struct {
+	index.Slice
+	Text string `json:"text"`
+}
+
+func serveSlices(w http.ResponseWriter, r *http.Request, st *State) {
+	p := r.PathValue("path")
+	if !hasFile(st.Manifest, p) {
+		http.NotFound(w, r)
+		return
+	}
+	var lines []string
+	if data := st.Contents[p]; len(data) > 0 {
+		lines = strings.SplitAfter(string(data), "\n")
+	}
+	out := []sliceText{}
+	for _, sl := range st.Slices {
+		if sl.Path != p {
+			continue
+		}
+		st := sliceText{Slice: sl}
+		if sl.Start >= 1 && sl.Start <= sl.End && sl.End <= len(lines) {
+			st.Text = strings.Join(lines[sl.Start-1:sl.End], "")
+		}
+		out = append(out, st)
+	}
+	writeJSON(w, out)
+}
+
+func serveDiff(w http.ResponseWriter, r *http.Request, st *State) {
+	p := r.PathValue("path")
+	if !hasFile(st.Manifest, p) {
+		http.NotFound(w, r)
+		return
+	}
+	body := ""
+	if st.Diff != nil {
+		body = st.Diff(p)
+	}
+	w.Header().Set("Content-Type", "text/x-diff; charset=utf-8")
+	_, _ = w.Write([]byte(body))
+}
+
+func hasFile(man index.Manifest, p string) bool {
+	for _, f := range man.Files {
+		if f.Path == p {
+			return true
+		}
+	}
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(append(data, '\n'))
+}
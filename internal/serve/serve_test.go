@@ -0,0 +1,83 @@
+package serve
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"class-collector/internal/graph"
+	"class-collector/internal/index"
+)
+
+func get(t *testing.T, h http.Handler, target string) (int, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.Host = "127.0.0.1:8080"
+	h.ServeHTTP(rec, req)
+	body, _ := io.ReadAll(rec.Result().Body)
+	return rec.Code, string(body)
+}
+
+func TestServerRoutes(t *testing.T) {
+	s := New(8080)
+	if code, _ := get(t, s, "/manifest.json"); code != http.StatusServiceUnavailable {
+		t.Fatalf("before Update: status %d, want 503", code)
+	}
+	s.Update(State{
+		Contents: map[string][]byte{"pkg/a.go": []byte("package pkg\n\nfunc A() {}\n")},
+		Manifest: index.Manifest{Module: "demo", Files: []index.ManFile{{Path: "pkg/a.go", Lines: 3}}},
+		Symbols:  index.Symbols{Symbols: []index.Symbol{{Symbol: "pkg.A", Path: "pkg/a.go", Start: 3, End: 3}}},
+		Slices:   []index.Slice{{Path: "pkg/a.go", Slice: "A", Start: 3, End: 3}},
+		Graph:    graph.Graph{Nodes: []string{"pkg"}},
+		Diff:     func(p string) string { return "diff of " + p },
+	})
+
+	code, body := get(t, s, "/manifest.json")
+	var man index.Manifest
+	if code != http.StatusOK || json.Unmarshal([]byte(body), &man) != nil || man.Module != "demo" {
+		t.Fatalf("manifest: %d %s", code, body)
+	}
+	if code, body := get(t, s, "/graph.json"); code != http.StatusOK || !json.Valid([]byte(body)) {
+		t.Fatalf("graph: %d %s", code, body)
+	}
+
+	code, body = get(t, s, "/slices/pkg/a.go")
+	var slices []sliceText
+	if code != http.StatusOK || json.Unmarshal([]byte(body), &slices) != nil {
+		t.Fatalf("slices: %d %s", code, body)
+	}
+	if len(slices) != 1 || slices[0].Text != "func A() {}\n" {
+		t.Fatalf("slices = %+v", slices)
+	}
+
+	if code, body := get(t, s, "/diffs/pkg/a.go"); code != http.StatusOK || body != "diff of pkg/a.go" {
+		t.Fatalf("diff: %d %q", code, body)
+	}
+	if code, _ := get(t, s, "/diffs/missing.go"); code != http.StatusNotFound {
+		t.Fatalf("unknown file: status %d, want 404", code)
+	}
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/manifest.json", nil)
+	req.Host = "localhost:8080"
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("POST: status %d, want 405", rec.Code)
+	}
+}
+
+func TestServerRejectsForeignHost(t *testing.T) {
+	s := New(8080)
+	s.Update(State{Manifest: index.Manifest{Module: "demo"}})
+	for _, host := range []string{"evil.example:8080", "127.0.0.1:9090", "localhost", ""} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/manifest.json", nil)
+		req.Host = host
+		s.ServeHTTP(rec, req)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("Host %q: status %d, want 403", host, rec.Code)
+		}
+	}
+}
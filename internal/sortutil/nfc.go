@@ -0,0 +1,16 @@
+package sortutil
+
+import "golang.org/x/text/unicode/norm"
+
+// NormalizePath returns p in Unicode Normalization Form C, so a file name
+// enumerated decomposed (NFD, as macOS file systems report it) and the same
+// name typed precomposed compare, sort and hash identically. Paths made only
+// of runes below U+0300 are returned unchanged without allocating.
+func NormalizePath(p string) string {
+	for _, r := range p {
+		if r >= 0x300 {
+			return norm.NFC.String(p)
+		}
+	}
+	return p
+}
@@ -0,0 +1,42 @@
+package sortutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizePath(t *testing.T) {
+	for _, tc := range []struct{ in, want string }{
+		{"src/main.go", "src/main.go"},
+		{"café.go", "café.go"},
+		{"café.go", "café.go"},
+		{"Å/x", "Å/x"},
+		// Marks out of canonical order are reordered before composing.
+		{"ẹ́", "ẹ́"},
+		{"ẹ́", "ẹ́"},
+		// Hangul syllables compose algorithmically.
+		{"한", "한"},
+		{"한", "한"},
+		// Leading marks have no starter to attach to.
+		{"́a", "́a"},
+	} {
+		if got := NormalizePath(tc.in); got != tc.want {
+			t.Errorf("NormalizePath(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestStablePathSortUnicodeVariants(t *testing.T) {
+	nfd := []string{"z.go", "résumé.go", "a.go"}
+	nfc := []string{"résumé.go", "a.go", "z.go"}
+	want := []string{"a.go", "résumé.go", "z.go"}
+	if got := StablePathSort(nfd); !reflect.DeepEqual(got, want) {
+		t.Fatalf("NFD input: got %q, want %q", got, want)
+	}
+	if got := StablePathSort(nfc); !reflect.DeepEqual(got, want) {
+		t.Fatalf("NFC input: got %q, want %q", got, want)
+	}
+	if nfd[1] != "résumé.go" {
+		t.Fatal("input slice was modified")
+	}
+}
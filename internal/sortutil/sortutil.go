@@ -2,11 +2,14 @@ package sortutil
 
 import "sort"
 
-// StablePathSort returns a new slice containing the input paths sorted
-// lexicographically. The original slice is not modified.
+// StablePathSort returns a new slice containing the input paths normalized
+// with NormalizePath and sorted lexicographically, so NFC and NFD spellings
+// of a name land in the same place. The original slice is not modified.
 func StablePathSort(paths []string) []string {
 	out := make([]string, len(paths))
-	copy(out, paths)
+	for i, p := range paths {
+		out[i] = NormalizePath(p)
+	}
 	sort.Strings(out)
 	return out
 }
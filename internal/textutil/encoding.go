@@ -0,0 +1,160 @@
+package textutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encoding labels reported by ToUTF8. Plain UTF-8 is reported as "".
+const (
+	EncUTF8BOM     = "utf-8-bom"
+	EncUTF16LE     = "utf-16le"
+	EncUTF16BE     = "utf-16be"
+	EncLatin1      = "latin-1"
+	EncWindows1252 = "windows-1252"
+	EncNonUTF8     = "non-utf8" // undetected; invalid sequences become U+FFFD
+)
+
+// maxHighByteRatio bounds the share of bytes >= 0x80 for the single-byte
+// heuristic: Western European text is mostly ASCII, whereas multi-byte
+// encodings such as Shift-JIS or GBK are dense in high bytes.
+const maxHighByteRatio = 0.3
+
+// cp1252 maps bytes 0x80..0x9F to their Windows-1252 code points; zero marks
+// the five undefined positions.
+var cp1252 = [32]rune{
+	0x20AC, 0, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0, 0x017D, 0,
+	0, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0, 0x017E, 0,
+}
+
+// ToUTF8 returns b as UTF-8 along with its detected original encoding:
+// a UTF-8 BOM is stripped, UTF-16 with a BOM is decoded, and invalid UTF-8
+// that looks like single-byte Western text is decoded as Latin-1 (or
+// Windows-1252 when bytes 0x80..0x9F occur). Anything else is tagged
+// EncNonUTF8 with invalid sequences replaced by U+FFFD. Valid UTF-8 is
+// returned unchanged with "".
+func ToUTF8(b []byte) ([]byte, string) {
+	switch {
+	case bytes.HasPrefix(b, []byte{0xEF, 0xBB, 0xBF}):
+		return bytes.ToValidUTF8(b[3:], []byte("\uFFFD")), EncUTF8BOM
+	case bytes.HasPrefix(b, []byte{0xFF, 0xFE}):
+		return decodeUTF16(b[2:], binary.LittleEndian), EncUTF16LE
+	case bytes.HasPrefix(b, []byte{0xFE, 0xFF}):
+		return decodeUTF16(b[2:], binary.BigEndian), EncUTF16BE
+	}
+	if utf8.Valid(b) {
+		return b, ""
+	}
+	if out, enc, ok := decodeSingleByte(b); ok {
+		return out, enc
+	}
+	return bytes.ToValidUTF8(b, []byte("\uFFFD")), EncNonUTF8
+}
+
+func decodeUTF16(b []byte, order binary.ByteOrder) []byte {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		units = append(units, order.Uint16(b[i:]))
+	}
+	var out bytes.Buffer
+	out.Grow(len(units))
+	for _, r := range utf16.Decode(units) {
+		out.WriteRune(r)
+	}
+	return out.Bytes()
+}
+
+// decodeSingleByte applies the Latin-1/Windows-1252 heuristic: no NUL bytes,
+// no undefined Windows-1252 positions, and a low share of high bytes.
+func decodeSingleByte(b []byte) ([]byte, string, bool) {
+	high, c1 := 0, false
+	for _, c := range b {
+		switch {
+		case c == 0:
+			return nil, "", false
+		case c >= 0x80 && c <= 0x9F:
+			if cp1252[c-0x80] == 0 {
+				return nil, "", false
+			}
+			c1 = true
+			high++
+		case c >= 0xA0:
+			high++
+		}
+	}
+	if float64(high) > maxHighByteRatio*float64(len(b)) {
+		return nil, "", false
+	}
+	var out bytes.Buffer
+	out.Grow(len(b) + high)
+	for _, c := range b {
+		switch {
+		case c < 0x80:
+			out.WriteByte(c)
+		case c <= 0x9F:
+			out.WriteRune(cp1252[c-0x80])
+		default:
+			out.WriteRune(rune(c))
+		}
+	}
+	if c1 {
+		return out.Bytes(), EncWindows1252, true
+	}
+	return out.Bytes(), EncLatin1, true
+}
+
+// FromUTF8 re-encodes UTF-8 text b into enc, an encoding reported by ToUTF8,
+// so that content decoded for patching can be compared with hashes of the
+// original bytes. It reports false when enc is EncNonUTF8 (the decode was
+// lossy) or b holds a rune the target encoding cannot represent.
+func FromUTF8(b []byte, enc string) ([]byte, bool) {
+	switch enc {
+	case "":
+		return b, true
+	case EncUTF8BOM:
+		return append([]byte{0xEF, 0xBB, 0xBF}, b...), true
+	case EncUTF16LE:
+		return encodeUTF16(b, []byte{0xFF, 0xFE}, binary.LittleEndian), true
+	case EncUTF16BE:
+		return encodeUTF16(b, []byte{0xFE, 0xFF}, binary.BigEndian), true
+	case EncLatin1, EncWindows1252:
+		return encodeSingleByte(b)
+	}
+	return nil, false
+}
+
+func encodeUTF16(b, bom []byte, order binary.AppendByteOrder) []byte {
+	units := utf16.Encode([]rune(string(b)))
+	out := make([]byte, len(bom), len(bom)+2*len(units))
+	copy(out, bom)
+	for _, u := range units {
+		out = order.AppendUint16(out, u)
+	}
+	return out
+}
+
+// encodeSingleByte is the inverse of decodeSingleByte. Code points below
+// U+0100 map to themselves and the Windows-1252 extras to 0x80..0x9F, so
+// text decoded under either label round-trips.
+func encodeSingleByte(b []byte) ([]byte, bool) {
+	out := make([]byte, 0, len(b))
+	for _, r := range string(b) {
+		if r < 0x100 {
+			out = append(out, byte(r))
+			continue
+		}
+		i := 0
+		for i < len(cp1252) && cp1252[i] != r {
+			i++
+		}
+		if i == len(cp1252) {
+			return nil, false
+		}
+		out = append(out, byte(0x80+i))
+	}
+	return out, true
+}
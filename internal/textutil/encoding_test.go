@@ -0,0 +1,35 @@
+package textutil
+
+import "testing"
+
+func TestToUTF8(t *testing.T) {
+	// An empty want skips the content check (replacement output is lossy).
+	cases := []struct {
+		name, in, want, enc string
+	}{
+		{"utf8", "café\n", "café\n", ""},
+		{"bom", "\xef\xbb\xbfx\n", "x\n", EncUTF8BOM},
+		{"utf16le", "\xff\xfeh\x00\xe9\x00", "hé", EncUTF16LE},
+		{"latin1", "// caf\xe9 cr\xe8me\n", "// café crème\n", EncLatin1},
+		{"cp1252", "say \x93hi\x94\n", "say “hi”\n", EncWindows1252},
+		{"shift-jis", "\x82\xb1\x82\xf1\x82\xc9\x82\xbf\x82\xcd", "", EncNonUTF8},
+	}
+	for _, c := range cases {
+		got, enc := ToUTF8([]byte(c.in))
+		if enc != c.enc || (c.want != "" && string(got) != c.want) {
+			t.Fatalf("%s: got (%q, %q), want (%q, %q)", c.name, got, enc, c.want, c.enc)
+		}
+	}
+}
+
+func TestFromUTF8RoundTrips(t *testing.T) {
+	for _, in := range []string{"café\n", "\xef\xbb\xbfx\n", "\xff\xfeh\x00\xe9\x00", "\xfe\xff\x00h\x00\xe9", "// caf\xe9 cr\xe8me\n", "say \x93hi\x94\n"} {
+		text, enc := ToUTF8([]byte(in))
+		if got, ok := FromUTF8(text, enc); !ok || string(got) != in {
+			t.Fatalf("FromUTF8(%q, %q) = (%q, %v), want %q", text, enc, got, ok, in)
+		}
+	}
+	if _, ok := FromUTF8([]byte("日本"), EncLatin1); ok {
+		t.Fatalf("FromUTF8 encoded runes Latin-1 cannot represent")
+	}
+}
@@ -0,0 +1,81 @@
+package textutil
+
+// StripJSONC turns JSON-with-comments (tsconfig.json, VS Code settings) into
+// plain JSON: a leading UTF-8 BOM is dropped, "//" and "/* */" comments and
+// trailing commas before '}' or ']' are blanked out. String literals are left
+// untouched, and blanking keeps every newline so decode errors still point at
+// the right line. Unterminated comments run to the end of the input. The
+// input is not modified.
+func StripJSONC(b []byte) []byte {
+	if len(b) >= 3 && b[0] == 0xEF && b[1] == 0xBB && b[2] == 0xBF {
+		b = b[3:]
+	}
+	out := make([]byte, len(b))
+	copy(out, b)
+
+	blank := func(from, to int) {
+		for i := from; i < to; i++ {
+			if out[i] != '\n' && out[i] != '\r' {
+				out[i] = ' '
+			}
+		}
+	}
+
+	// Pass 1: comments.
+	for i := 0; i < len(out); i++ {
+		switch c := out[i]; {
+		case c == '"':
+			i = skipJSONString(out, i)
+		case c == '/' && i+1 < len(out) && out[i+1] == '/':
+			end := i
+			for end < len(out) && out[end] != '\n' {
+				end++
+			}
+			blank(i, end)
+			i = end
+		case c == '/' && i+1 < len(out) && out[i+1] == '*':
+			end := i + 2
+			for end < len(out) && !(out[end] == '*' && end+1 < len(out) && out[end+1] == '/') {
+				end++
+			}
+			end = min(end+2, len(out))
+			blank(i, end)
+			i = end - 1
+		}
+	}
+
+	// Pass 2: trailing commas (comments are whitespace by now).
+	for i := 0; i < len(out); i++ {
+		switch out[i] {
+		case '"':
+			i = skipJSONString(out, i)
+		case ',':
+			j := i + 1
+			for j < len(out) && isJSONSpace(out[j]) {
+				j++
+			}
+			if j < len(out) && (out[j] == '}' || out[j] == ']') {
+				out[i] = ' '
+			}
+		}
+	}
+	return out
+}
+
+// skipJSONString returns the index of the quote closing the string that
+// opens at b[open], or len(b)-1 when it is unterminated.
+func skipJSONString(b []byte, open int) int {
+	for i := open + 1; i < len(b); i++ {
+		switch b[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return len(b) - 1
+}
+
+func isJSONSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
@@ -0,0 +1,29 @@
+package textutil
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestStripJSONC(t *testing.T) {
+	in := "\xEF\xBB\xBF{\n  // line\n  \"url\": \"http://x/*y*/\", /* block\n  spans */ \"list\": [1, 2,],\n  \"esc\": \"a\\\"//b\",\n}\n"
+	out := StripJSONC([]byte(in))
+	var v struct {
+		URL  string `json:"url"`
+		List []int  `json:"list"`
+		Esc  string `json:"esc"`
+	}
+	if err := json.Unmarshal(out, &v); err != nil {
+		t.Fatalf("unmarshal: %v\n%s", err, out)
+	}
+	if v.URL != "http://x/*y*/" || len(v.List) != 2 || v.Esc != `a"//b` {
+		t.Fatalf("decoded %+v", v)
+	}
+	if got, want := strings.Count(string(out), "\n"), strings.Count(in, "\n"); got != want {
+		t.Fatalf("newlines = %d, want %d", got, want)
+	}
+	if string(StripJSONC([]byte(`{"a": 1}`))) != `{"a": 1}` {
+		t.Fatalf("plain JSON must pass through unchanged")
+	}
+}
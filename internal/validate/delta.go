@@ -0,0 +1,64 @@
+package validate
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+
+	"class-collector/internal/ziputil"
+)
+
+// Delta cross-checks a delta index against the ZIP entries actually written:
+//
+//   - Every changed entry must reference a diff path, and that patch must be
+//     among writtenPatches (oversize entries are exempt).
+//   - Every added entry must have a corresponding "<addedPrefix>/<path>" entry.
+//
+// index is any JSON-serialisable value shaped like delta.index.json.
+// writtenPatches holds ZIP entry names ("diffs/...", "added/...").
+func Delta(index any, addedPrefix string, writtenPatches []string) error {
+	var raw struct {
+		Added []struct {
+			Path string `json:"path"`
+		} `json:"added"`
+		Changed []struct {
+			Path     string `json:"path"`
+			Diff     string `json:"diff"`
+			Oversize bool   `json:"oversize"`
+		} `json:"changed"`
+	}
+	b, err := json.Marshal(index)
+	if err != nil {
+		return fmt.Errorf("delta index: %w", err)
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return fmt.Errorf("delta index: %w", err)
+	}
+
+	written := make(map[string]struct{}, len(writtenPatches))
+	for _, name := range writtenPatches {
+		written[name] = struct{}{}
+	}
+
+	var errs errlist
+	for i, c := range raw.Changed {
+		if c.Oversize {
+			continue
+		}
+		prefix := fmt.Sprintf("changed[%d] (%s)", i, c.Path)
+		if c.Diff == "" {
+			errs.add("%s: diff path must be non-empty", prefix)
+			continue
+		}
+		if _, ok := written[c.Diff]; !ok {
+			errs.add("%s: referenced patch %q was not written", prefix, c.Diff)
+		}
+	}
+	for i, a := range raw.Added {
+		want := ziputil.SanitizePath(path.Join(addedPrefix, a.Path))
+		if _, ok := written[want]; !ok {
+			errs.add("added[%d] (%s): missing %q entry", i, a.Path, want)
+		}
+	}
+	return errs.err()
+}
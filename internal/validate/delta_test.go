@@ -0,0 +1,37 @@
+package validate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDeltaMatchingPatches(t *testing.T) {
+	idx := map[string]any{
+		"added":   []map[string]any{{"path": "pkg/new.go"}},
+		"changed": []map[string]any{{"path": "main.go", "diff": "diffs/main.go.patch"}},
+	}
+	if err := Delta(idx, "added", []string{"diffs/main.go.patch", "added/pkg/new.go"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestDeltaMismatchedPatches(t *testing.T) {
+	idx := map[string]any{
+		"added": []map[string]any{{"path": "pkg/new.go"}},
+		"changed": []map[string]any{
+			{"path": "main.go", "diff": "diffs/main.go.patch"},
+			{"path": "big.go", "diff": "diffs/big.go.patch", "oversize": true},
+		},
+	}
+	err := Delta(idx, "added", []string{"diffs/other.go.patch"})
+	if err == nil {
+		t.Fatalf("expected validation error")
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, `"diffs/main.go.patch" was not written`) || !strings.Contains(msg, `"added/pkg/new.go"`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(msg, "big.go") {
+		t.Fatalf("oversize entry should be exempt: %v", err)
+	}
+}
@@ -24,11 +24,13 @@ import (
 //   - Module should be non-empty.
 //   - Each file must have a normalized relative path (no absolute, no "..").
 //   - Hash, if present, must be a 64-char lowercase hex (sha256).
-//   - Lines >= 1.
+//   - Lines >= 1 (except symlink entries, whose content is not read, and
+//     empty files, whose hash is the SHA-256 of no bytes and Lines is 0).
 //   - Anchors must have non-empty names, 1-based ranges, Start <= End,
 //     and End <= file Lines.
 //   - No duplicate file paths.
 //   - Optional: warn-as-error on backslashes in paths (ZIP uses forward slashes).
+//   - Files sorted by path (an error only under SetStrict; otherwise a warning).
 //
 // The function returns nil if everything looks fine, or a single aggregated
 // error describing all the issues found.
@@ -73,8 +75,8 @@ func Manifest(m index.Manifest) error {
 			errs.add("%s: hash must be 64 lowercase hex chars (sha256), got %q", prefix, f.Hash)
 		}
 
-		// Lines
-		if f.Lines < 1 {
+		// Lines (symlinks are recorded without reading content)
+		if f.Lines < 1 && f.Kind != "symlink" && !(f.Lines == 0 && f.Hash == emptySHA256) {
 			errs.add("%s: lines must be >= 1 (got %d)", prefix, f.Lines)
 		}
 
@@ -96,10 +98,10 @@ func Manifest(m index.Manifest) error {
 		}
 	}
 
-	// Optional determinism check: ensure manifest files are sorted by path.
+	// Determinism check: ensure manifest files are sorted by path.
 	// (Harmless if not, but helps keep ZIP byte-for-byte stable.)
 	if !isSortedByPath(m.Files) {
-		errs.add("manifest.files should be sorted by path for deterministic bundles")
+		determinism(&errs, "manifest.files should be sorted by path for deterministic bundles")
 	}
 
 	return errs.err()
@@ -110,7 +112,7 @@ func Manifest(m index.Manifest) error {
 //   - Version >= 1
 //   - Every symbol has non-empty Symbol and Path
 //   - Start >= 1, End >= Start
-//   - (Optional) deterministic order (by Path, Start, End) — warned as error
+//   - Deterministic order (by Path, Start, End) — an error only under SetStrict
 func Symbols(s index.Symbols) error {
 	var errs errlist
 
@@ -144,9 +146,9 @@ func Symbols(s index.Symbols) error {
 		}
 	}
 
-	// Optional determinism check: encourage sorted output.
+	// Determinism check: encourage sorted output.
 	if !isSortedSymbols(s.Symbols) {
-		errs.add("symbols list should be sorted (path, start, end) for determinism")
+		determinism(&errs, "symbols list should be sorted (path, start, end, symbol) for determinism")
 	}
 
 	return errs.err()
@@ -156,6 +158,9 @@ func Symbols(s index.Symbols) error {
 
 var reHex64 = regexp.MustCompile(`^[0-9a-f]{64}$`)
 
+// emptySHA256 is the SHA-256 of zero bytes, the hash of every empty file.
+const emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
 func hasDotDot(p string) bool {
 	for _, seg := range strings.Split(p, "/") {
 		if seg == ".." {
@@ -183,6 +188,9 @@ func isSortedSymbols(syms []index.Symbol) bool {
 	return sort.SliceIsSorted(syms, func(i, j int) bool {
 		if syms[i].Path == syms[j].Path {
 			if syms[i].Start == syms[j].Start {
+				if syms[i].End == syms[j].End {
+					return syms[i].Symbol < syms[j].Symbol
+				}
 				return syms[i].End < syms[j].End
 			}
 			return syms[i].Start < syms[j].Start
@@ -0,0 +1,136 @@
+package validate
+
+import (
+	"strings"
+	"testing"
+
+	"class-collector/internal/index"
+	"class-collector/internal/warn"
+)
+
+func strictFixture() (index.Manifest, index.Symbols, []index.Slice, []index.Pointer) {
+	man := index.Manifest{
+		Module: "demo",
+		Files: []index.ManFile{
+			{Path: "a.go", Lines: 10},
+			{Path: "b.go", Lines: 5},
+		},
+	}
+	syms := index.Symbols{Version: 1, Symbols: []index.Symbol{
+		{Symbol: "a.Run", Kind: "func", Path: "a.go", Start: 2, End: 10},
+		{Symbol: "b.Init", Kind: "func", Path: "b.go", Start: 1, End: 5},
+	}}
+	slices := []index.Slice{
+		{Path: "a.go", Slice: "chunk_1", Start: 1, End: 10},
+		{Path: "b.go", Slice: "chunk_1", Start: 1, End: 5},
+	}
+	pointers := []index.Pointer{
+		{ID: "a.Run", Path: "a.go", Sym: "a.Run", Start: 2, End: 10},
+		{ID: "b.go#top", Path: "b.go", Start: 1, End: 1},
+	}
+	return man, syms, slices, pointers
+}
+
+func TestCrossCheckPasses(t *testing.T) {
+	man, syms, slices, pointers := strictFixture()
+	if err := CrossCheck(man, syms, slices, pointers); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCrossCheckFailures(t *testing.T) {
+	cases := []struct {
+		name   string
+		mutate func(*index.Symbols, *[]index.Slice, *[]index.Pointer)
+		want   string
+	}{
+		{
+			name:   "symbol end beyond file",
+			mutate: func(s *index.Symbols, _ *[]index.Slice, _ *[]index.Pointer) { s.Symbols[1].End = 6 },
+			want:   "symbols[1] (b.Init): end must be <= file lines (5), got 6",
+		},
+		{
+			name:   "symbol file missing",
+			mutate: func(s *index.Symbols, _ *[]index.Slice, _ *[]index.Pointer) { s.Symbols[1].Path = "c.go" },
+			want:   `symbols[1] (b.Init): file "c.go" is not in the manifest`,
+		},
+		{
+			name:   "pointer to unknown symbol",
+			mutate: func(_ *index.Symbols, _ *[]index.Slice, p *[]index.Pointer) { (*p)[0].Sym = "a.Stop" },
+			want:   `pointers[0] (a.Run): symbol "a.Stop" not found in a.go`,
+		},
+		{
+			name:   "pointer to unknown file",
+			mutate: func(_ *index.Symbols, _ *[]index.Slice, p *[]index.Pointer) { (*p)[1].Path = "c.go" },
+			want:   `pointers[1] (b.go#top): file "c.go" is not in the manifest`,
+		},
+		{
+			name:   "slice beyond file",
+			mutate: func(_ *index.Symbols, sl *[]index.Slice, _ *[]index.Pointer) { (*sl)[0].End = 11 },
+			want:   "slices[0] (chunk_1): range 1-11 must lie within file lines 1-10",
+		},
+		{
+			name:   "slice file missing",
+			mutate: func(_ *index.Symbols, sl *[]index.Slice, _ *[]index.Pointer) { (*sl)[1].Path = "c.go" },
+			want:   `slices[1] (chunk_1): file "c.go" is not in the manifest`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			man, syms, slices, pointers := strictFixture()
+			tc.mutate(&syms, &slices, &pointers)
+			err := CrossCheck(man, syms, slices, pointers)
+			if err == nil {
+				t.Fatalf("expected error containing %q", tc.want)
+			}
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("error %q does not contain %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestSortednessWarnsUnlessStrict(t *testing.T) {
+	man, syms, _, _ := strictFixture()
+	man.Files[0], man.Files[1] = man.Files[1], man.Files[0]
+	syms.Symbols[0], syms.Symbols[1] = syms.Symbols[1], syms.Symbols[0]
+
+	c := &warn.Collector{}
+	warn.Use(c)
+	defer warn.Use(nil)
+
+	if err := Manifest(man); err != nil {
+		t.Fatalf("non-strict manifest: unexpected error: %v", err)
+	}
+	if err := Symbols(syms); err != nil {
+		t.Fatalf("non-strict symbols: unexpected error: %v", err)
+	}
+	if got := c.List(); len(got) != 2 || got[0].Kind != warn.KindValidate {
+		t.Fatalf("expected two validate warnings, got %+v", got)
+	}
+
+	SetStrict(true)
+	defer SetStrict(false)
+	if err := Manifest(man); err == nil || !strings.Contains(err.Error(), "sorted by path") {
+		t.Fatalf("strict manifest: expected sortedness error, got %v", err)
+	}
+	if err := Symbols(syms); err == nil || !strings.Contains(err.Error(), "sorted (path, start, end, symbol)") {
+		t.Fatalf("strict symbols: expected sortedness error, got %v", err)
+	}
+}
+
+func TestSymbolsSortedTieBreaksOnName(t *testing.T) {
+	SetStrict(true)
+	defer SetStrict(false)
+	sorted := index.Symbols{Version: 1, Symbols: []index.Symbol{
+		{Symbol: "a", Kind: "func", Path: "x.ts", Start: 1, End: 1},
+		{Symbol: "b", Kind: "func", Path: "x.ts", Start: 1, End: 1},
+	}}
+	if err := Symbols(sorted); err != nil {
+		t.Fatalf("name-ordered symbols rejected: %v", err)
+	}
+	sorted.Symbols[0].Symbol, sorted.Symbols[1].Symbol = "b", "a"
+	if err := Symbols(sorted); err == nil {
+		t.Fatalf("same-line symbols out of name order accepted")
+	}
+}
@@ -0,0 +1,83 @@
+package validate
+
+import (
+	"fmt"
+
+	"class-collector/internal/index"
+	"class-collector/internal/warn"
+)
+
+var strict bool
+
+// SetStrict makes determinism (sortedness) issues hard errors. By default
+// they are recorded as warnings so an unsorted but otherwise valid bundle
+// is still written.
+func SetStrict(enable bool) { strict = enable }
+
+// determinism reports an ordering issue according to the strict setting.
+func determinism(errs *errlist, msg string) {
+	if strict {
+		errs.add("%s", msg)
+		return
+	}
+	warn.Add(warn.KindValidate, "", "%s", msg)
+}
+
+// CrossCheck validates references between artifacts (the opt-in strict
+// checks):
+//
+//   - Every symbol's file is in the manifest and End <= its Lines.
+//   - Every slice's file is in the manifest and the range lies within
+//     [1..Lines].
+//   - Every symbol-backed pointer (Sym set) names a symbol in the same file;
+//     other pointers must reference a manifest file.
+func CrossCheck(m index.Manifest, s index.Symbols, slices []index.Slice, pointers []index.Pointer) error {
+	var errs errlist
+
+	lines := make(map[string]int, len(m.Files))
+	for _, f := range m.Files {
+		lines[f.Path] = f.Lines
+	}
+	type symKey struct{ sym, path string }
+	syms := make(map[symKey]struct{}, len(s.Symbols))
+
+	for i, sym := range s.Symbols {
+		syms[symKey{sym.Symbol, sym.Path}] = struct{}{}
+		prefix := fmt.Sprintf("symbols[%d] (%s)", i, sym.Symbol)
+		n, ok := lines[sym.Path]
+		if !ok {
+			errs.add("%s: file %q is not in the manifest", prefix, sym.Path)
+			continue
+		}
+		if sym.End > n {
+			errs.add("%s: end must be <= file lines (%d), got %d", prefix, n, sym.End)
+		}
+	}
+
+	for i, sl := range slices {
+		prefix := fmt.Sprintf("slices[%d] (%s)", i, sl.Slice)
+		n, ok := lines[sl.Path]
+		if !ok {
+			errs.add("%s: file %q is not in the manifest", prefix, sl.Path)
+			continue
+		}
+		if sl.Start < 1 || sl.End < sl.Start || sl.End > n {
+			errs.add("%s: range %d-%d must lie within file lines 1-%d", prefix, sl.Start, sl.End, n)
+		}
+	}
+
+	for i, p := range pointers {
+		prefix := fmt.Sprintf("pointers[%d] (%s)", i, p.ID)
+		if p.Sym != "" {
+			if _, ok := syms[symKey{p.Sym, p.Path}]; !ok {
+				errs.add("%s: symbol %q not found in %s", prefix, p.Sym, p.Path)
+			}
+			continue
+		}
+		if _, ok := lines[p.Path]; !ok {
+			errs.add("%s: file %q is not in the manifest", prefix, p.Path)
+		}
+	}
+
+	return errs.err()
+}
@@ -6,13 +6,20 @@ import (
 	"bufio"
 	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
 	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
+
+	"class-collector/internal/parallel"
+	"class-collector/internal/progress"
+	"class-collector/internal/sortutil"
 )
 
 // FileInfo is a minimal, deterministic descriptor of a collected file.
@@ -22,6 +29,10 @@ type FileInfo struct {
 	Size      int64  // size in bytes
 	SHA256Hex string // lowercase hex sha256 of the file contents
 	Ext       string // lowercase extension including dot (e.g., ".java")
+	Symlink   string // link target for recorded (unfollowed) symlinks; contents are never read
+	// Aliases lists the other paths hard-linked to this file (DedupHardlinks)
+	// or byte-identical to it (DedupContent).
+	Aliases []string
 }
 
 type walkerConfig struct {
@@ -32,42 +43,105 @@ type walkerConfig struct {
 	maxBytes       int64
 	maxFileBytes   int64
 	useGitignore   bool
+	globalIgnore   bool
 	followSymlinks bool
+	skipSubmodules bool
+	recordSymlinks bool
+	caseSensitive  bool
+	modifiedSince  time.Time
+	dedupLinks     bool
+	dedupContent   bool
+	keep           func(FileInfo) bool
+	rules          filterRules
 }
 
 type walkState struct {
-	cfg      walkerConfig
-	root     string
-	patterns []gitPattern
-	total    int64
-	files    []FileInfo
-}
-
-// CollectFiles walks src and returns files matching the provided filters.
-func CollectFiles(
-	src string,
-	exts, exclude map[string]struct{},
-	includes []string,
-	maxBytes int64,
-	maxFileBytes int64,
-	useGitignore bool,
-	followSymlinks bool,
-) ([]FileInfo, int64, error) {
+	cfg        walkerConfig
+	root       string
+	patterns   []gitPattern
+	submodules map[string]struct{}
+	total      int64
+	planned    int64      // what the candidates would charge to maxBytes if all are readable
+	candidates []FileInfo // walked files in walk order, not yet hashed
+	files      []FileInfo
+	inodes     map[inode]int  // index into candidates of the first path per hard-linked inode
+	byContent  map[string]int // index into files of the first non-empty file per SHA-256 (dedupContent)
+}
+
+// Options are the filters and limits of CollectFiles; zero fields leave
+// their filter or limit off.
+type Options struct {
+	Exts         map[string]struct{} // extensions to collect, with the dot
+	Exclude      map[string]struct{} // path segments and rules to skip
+	Includes     []string            // path substrings collected despite the filters; "!rule" re-includes
+	MaxBytes     int64               // total size budget (0: unlimited); the walk stops once the files listed would fill it
+	MaxFileBytes int64               // per-file size limit (0: unlimited)
+	UseGitignore bool
+	// GlobalGitignore also applies the user's global ignore file (see
+	// GlobalGitignorePath), with lower precedence than the repository
+	// .gitignore.
+	GlobalGitignore bool
+	FollowSymlinks  bool
+	// SkipSubmodules prunes the directories declared as submodule paths in
+	// the root .gitmodules file.
+	SkipSubmodules bool
+	// RecordSymlinks returns each symlink, when they are not followed, with
+	// its target in Symlink instead of dropping it.
+	RecordSymlinks bool
+	// CaseSensitiveExt matches file extensions against Exts literally
+	// instead of lowercasing them first.
+	CaseSensitiveExt bool
+	// ModifiedSince, when non-zero, keeps only regular files whose mtime is
+	// not before it (symlinks are unaffected).
+	ModifiedSince time.Time
+	// Keep, when set, is asked about every file that passed the other
+	// filters, before it counts against MaxBytes or is hashed; files it
+	// rejects are dropped. Symlinks arrive with Symlink set.
+	Keep func(FileInfo) bool
+	// DedupHardlinks hashes and returns a file reachable under several
+	// hard-linked paths once, under its first path in walk order, with the
+	// others in Aliases; platforms without inode information collect every
+	// path.
+	DedupHardlinks bool
+	// DedupContent returns the non-empty files of every set of byte-identical
+	// ones once, under the first path in walk order, with the others in
+	// Aliases. Duplicates are merged before the MaxBytes budget is charged,
+	// so they cost nothing; empty files and symlinks are never merged.
+	DedupContent bool
+}
+
+// CollectFiles walks src and returns the files matching opts with their
+// total size.
+func CollectFiles(src string, opts Options) ([]FileInfo, int64, error) {
+	exclude, includes, rules := splitFilterRules(opts.Exclude, opts.Includes)
 	cfg := walkerConfig{
 		src:            src,
-		exts:           exts,
+		exts:           opts.Exts,
 		exclude:        exclude,
 		includes:       includes,
-		maxBytes:       maxBytes,
-		maxFileBytes:   maxFileBytes,
-		useGitignore:   useGitignore,
-		followSymlinks: followSymlinks,
+		rules:          rules,
+		maxBytes:       opts.MaxBytes,
+		maxFileBytes:   opts.MaxFileBytes,
+		useGitignore:   opts.UseGitignore,
+		followSymlinks: opts.FollowSymlinks,
+		skipSubmodules: opts.SkipSubmodules,
+		recordSymlinks: opts.RecordSymlinks,
+		caseSensitive:  opts.CaseSensitiveExt,
+		globalIgnore:   opts.GlobalGitignore,
+		modifiedSince:  opts.ModifiedSince,
+		dedupLinks:     opts.DedupHardlinks,
+		dedupContent:   opts.DedupContent,
+		keep:           opts.Keep,
 	}
 	root, patterns, err := resolveRootsAndIgnores(cfg)
 	if err != nil {
 		return nil, 0, err
 	}
-	files, total, err := scanDir(root, cfg, patterns)
+	var submodules map[string]struct{}
+	if cfg.skipSubmodules {
+		submodules = parseGitmodules(filepath.Join(root, ".gitmodules"))
+	}
+	files, total, err := scanDir(root, cfg, patterns, submodules)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -80,29 +154,122 @@ func resolveRootsAndIgnores(cfg walkerConfig) (string, []gitPattern, error) {
 	if err != nil {
 		return "", nil, err
 	}
+	var pats []gitPattern
+	if cfg.globalIgnore {
+		if p := GlobalGitignorePath(); p != "" {
+			if gp, err := parseGitignore(p); err == nil {
+				pats = append(pats, gp...)
+			}
+		}
+	}
 	if !cfg.useGitignore {
-		return srcAbs, nil, nil
+		return srcAbs, pats, nil
 	}
-	pats, err := parseGitignore(filepath.Join(srcAbs, ".gitignore"))
+	rp, err := parseGitignore(filepath.Join(srcAbs, ".gitignore"))
 	if err != nil {
-		return srcAbs, nil, nil
+		return srcAbs, pats, nil
 	}
-	return srcAbs, pats, nil
+	// Later patterns win in matchGitignore, so repo rules override global ones.
+	return srcAbs, append(pats, rp...), nil
+}
+
+// GlobalGitignorePath returns git's default global excludes file:
+// $XDG_CONFIG_HOME/git/ignore, falling back to ~/.config/git/ignore.
+// It returns "" when neither location can be determined.
+func GlobalGitignorePath() string {
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "git", "ignore")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return ""
+	}
+	return filepath.Join(home, ".config", "git", "ignore")
 }
 
-func scanDir(root string, cfg walkerConfig, patterns []gitPattern) ([]FileInfo, int64, error) {
-	state := &walkState{cfg: cfg, root: root, patterns: patterns}
+func scanDir(root string, cfg walkerConfig, patterns []gitPattern, submodules map[string]struct{}) ([]FileInfo, int64, error) {
+	state := &walkState{cfg: cfg, root: root, patterns: patterns, submodules: submodules}
 	if err := filepath.WalkDir(root, state.visit); err != nil {
 		return nil, 0, err
 	}
+	state.hashCandidates()
 	return state.files, state.total, nil
 }
 
+// hashCandidates hashes the walked files on up to parallel.Limit() workers
+// and keeps them as a serial walk would: in walk order while the maxBytes
+// budget lasts, dropping unreadable files and (dedupContent) duplicates
+// without charging them to it. With a budget, files are hashed a chunk at a
+// time so that no more than a chunk is read past its end.
+func (ws *walkState) hashCandidates() {
+	chunk := len(ws.candidates)
+	if ws.cfg.maxBytes > 0 {
+		chunk = parallel.Limit() * 16
+	}
+	for start := 0; start < len(ws.candidates); start += chunk {
+		batch := ws.candidates[start:min(start+chunk, len(ws.candidates))]
+		sums := make([]string, len(batch))
+		parallel.For(len(batch), func(i int) {
+			if batch[i].Symlink == "" {
+				sums[i], _ = sha256File(batch[i].AbsPath) // "" when unreadable
+			}
+		})
+		for i, f := range batch {
+			if ws.cfg.maxBytes > 0 && ws.total >= ws.cfg.maxBytes {
+				return
+			}
+			if f.Symlink == "" {
+				if sums[i] == "" {
+					continue
+				}
+				if ws.mergeDuplicate(f, sums[i]) {
+					continue
+				}
+				if ws.cfg.maxBytes > 0 && ws.total+f.Size > ws.cfg.maxBytes {
+					continue
+				}
+				f.SHA256Hex = sums[i]
+				ws.total += f.Size
+				if ws.cfg.dedupContent && f.Size > 0 {
+					if ws.byContent == nil {
+						ws.byContent = map[string]int{}
+					}
+					ws.byContent[sums[i]] = len(ws.files)
+				}
+			}
+			ws.files = append(ws.files, f)
+			progress.Tick()
+		}
+	}
+	for i := range ws.files {
+		if len(ws.files[i].Aliases) > 1 {
+			sort.Strings(ws.files[i].Aliases)
+		}
+	}
+}
+
+// mergeDuplicate adds f (and its aliases) to the aliases of the kept file
+// with content sum and reports whether there is one (dedupContent only).
+// Empty files are never merged: they share a hash without being copies.
+func (ws *walkState) mergeDuplicate(f FileInfo, sum string) bool {
+	if !ws.cfg.dedupContent || f.Size == 0 {
+		return false
+	}
+	k, ok := ws.byContent[sum]
+	if !ok {
+		return false
+	}
+	ws.files[k].Aliases = append(append(ws.files[k].Aliases, f.RelPath), f.Aliases...)
+	return true
+}
+
 func (ws *walkState) visit(path string, d fs.DirEntry, err error) error {
 	if err != nil {
 		return nil
 	}
-	if ws.cfg.maxBytes > 0 && ws.total >= ws.cfg.maxBytes {
+	// Once the candidates fill the budget nothing later can be kept, so
+	// stop walking instead of listing files only to drop them.
+	if ws.cfg.maxBytes > 0 && ws.planned >= ws.cfg.maxBytes {
 		if d.IsDir() {
 			return filepath.SkipDir
 		}
@@ -129,7 +296,8 @@ func (ws *walkState) relative(path string) (string, bool) {
 	if err != nil {
 		return "", false
 	}
-	rel = filepath.ToSlash(rel)
+	// NFC so decomposed (macOS) and precomposed names sort and match alike.
+	rel = sortutil.NormalizePath(filepath.ToSlash(rel))
 	if strings.HasPrefix(rel, "../") || rel == ".." {
 		return "", false
 	}
@@ -137,11 +305,15 @@ func (ws *walkState) relative(path string) (string, bool) {
 }
 
 func (ws *walkState) shouldSkip(rel string, d fs.DirEntry) bool {
-	base := filepath.Base(rel)
-	if _, bad := ws.cfg.exclude[base]; bad || hasExcludedPrefix(base, ws.cfg.exclude) {
+	if d.IsDir() {
+		if _, ok := ws.submodules[rel]; ok {
+			return true
+		}
+	}
+	if ws.excluded(rel, d.IsDir()) {
 		return true
 	}
-	if ws.cfg.useGitignore && matchGitignore(ws.patterns, rel, d.IsDir()) {
+	if (ws.cfg.useGitignore || ws.cfg.globalIgnore) && matchGitignore(ws.patterns, rel, d.IsDir()) {
 		return true
 	}
 	return false
@@ -156,6 +328,11 @@ func (ws *walkState) handleDir(d fs.DirEntry) error {
 
 func (ws *walkState) handleFile(path, rel string, d fs.DirEntry) error {
 	if !ws.cfg.followSymlinks && isSymlink(d) {
+		// Exclude and gitignore rules already ran in visit; the link must
+		// also pass the extension/include filter its target would face.
+		if ws.cfg.recordSymlinks && shouldInclude(path, ws.cfg) {
+			ws.recordSymlink(path, rel)
+		}
 		return nil
 	}
 	info, err := d.Info()
@@ -165,29 +342,67 @@ func (ws *walkState) handleFile(path, rel string, d fs.DirEntry) error {
 	if ws.cfg.maxFileBytes > 0 && info.Size() > ws.cfg.maxFileBytes {
 		return nil
 	}
-	if !shouldInclude(path, ws.cfg) {
+	if !ws.cfg.modifiedSince.IsZero() && info.ModTime().Before(ws.cfg.modifiedSince) {
 		return nil
 	}
-	sumHex, err := sha256File(path)
-	if err != nil {
+	if !shouldInclude(path, ws.cfg) {
 		return nil
 	}
-	if ws.cfg.maxBytes > 0 && ws.total+info.Size() > ws.cfg.maxBytes {
+	fi := FileInfo{
+		RelPath: rel,
+		AbsPath: path,
+		Size:    info.Size(),
+		Ext:     strings.ToLower(filepath.Ext(path)),
+	}
+	if ws.cfg.keep != nil && !ws.cfg.keep(fi) {
 		return nil
 	}
-	ws.files = append(ws.files, FileInfo{
-		RelPath:   rel,
-		AbsPath:   path,
-		Size:      info.Size(),
-		SHA256Hex: sumHex,
-		Ext:       strings.ToLower(filepath.Ext(path)),
-	})
-	ws.total += info.Size()
+	key, linked := inode{}, false
+	if ws.cfg.dedupLinks {
+		if key, linked = inodeKey(info); linked {
+			if i, seen := ws.inodes[key]; seen {
+				ws.candidates[i].Aliases = append(ws.candidates[i].Aliases, rel)
+				return nil
+			}
+		}
+	}
+	ws.candidates = append(ws.candidates, fi)
+	if ws.cfg.maxBytes > 0 && ws.planned+fi.Size <= ws.cfg.maxBytes {
+		ws.planned += fi.Size
+	}
+	if linked {
+		if ws.inodes == nil {
+			ws.inodes = map[inode]int{}
+		}
+		ws.inodes[key] = len(ws.candidates) - 1
+	}
 	return nil
 }
 
+// recordSymlink stores the symlink at path with its target, without reading
+// or hashing what it points to.
+func (ws *walkState) recordSymlink(path, rel string) {
+	target, err := os.Readlink(path)
+	if err != nil {
+		return
+	}
+	fi := FileInfo{
+		RelPath: rel,
+		AbsPath: path,
+		Ext:     strings.ToLower(filepath.Ext(path)),
+		Symlink: filepath.ToSlash(target),
+	}
+	if ws.cfg.keep != nil && !ws.cfg.keep(fi) {
+		return
+	}
+	ws.candidates = append(ws.candidates, fi)
+}
+
 func shouldInclude(path string, cfg walkerConfig) bool {
-	ext := strings.ToLower(filepath.Ext(path))
+	ext := filepath.Ext(path)
+	if !cfg.caseSensitive {
+		ext = strings.ToLower(ext)
+	}
 	if len(cfg.exts) == 0 {
 		return true
 	}
@@ -232,6 +447,191 @@ func hasExcludedPrefix(base string, exclude map[string]struct{}) bool {
 	return false
 }
 
+// excluded applies -exclude entries (base-name prefixes and path patterns) and
+// the '!' re-include rules. Without negations a matching directory is pruned.
+// With negations, excluded directories an anchored re-include rule may reach
+// into are walked (see mayReinclude) and every file is checked against its
+// nearest excluded ancestor instead.
+func (ws *walkState) excluded(rel string, isDir bool) bool {
+	rules := ws.cfg.rules
+	if !ws.excludedSelf(rel, isDir) {
+		if len(rules.neg) == 0 || isDir {
+			return false
+		}
+		// A parent may have been kept only because of a negation.
+		for dir := parentDir(rel); dir != ""; dir = parentDir(dir) {
+			if ws.excludedSelf(dir, true) {
+				return !rules.negated(dir, true) && !rules.negated(rel, isDir)
+			}
+		}
+		return false
+	}
+	if rules.negated(rel, isDir) {
+		return false
+	}
+	if isDir && rules.mayReinclude(rel) {
+		return false
+	}
+	return true
+}
+
+func (ws *walkState) excludedSelf(rel string, isDir bool) bool {
+	base := filepath.Base(rel)
+	if _, bad := ws.cfg.exclude[base]; bad || hasExcludedPrefix(base, ws.cfg.exclude) {
+		return true
+	}
+	return matchGitignore(ws.cfg.rules.pos, rel, isDir)
+}
+
+func parentDir(rel string) string {
+	if i := strings.LastIndexByte(rel, '/'); i > 0 {
+		return rel[:i]
+	}
+	return ""
+}
+
+// filterRules holds gitignore-style patterns derived from -exclude/-include.
+// pos are path patterns that exclude (entries containing '/'), neg are
+// '!'-prefixed entries that re-include, negRaw keeps their source text.
+type filterRules struct {
+	pos    []gitPattern
+	neg    []gitPattern
+	negRaw []string
+}
+
+// splitFilterRules separates plain base-name excludes and substring includes
+// from gitignore-style entries. An exclude entry containing '/' becomes a path
+// pattern; any '!'-prefixed entry (in either list) becomes a re-include rule.
+func splitFilterRules(exclude map[string]struct{}, includes []string) (map[string]struct{}, []string, filterRules) {
+	var rules filterRules
+	plain := make(map[string]struct{}, len(exclude))
+	keys := make([]string, 0, len(exclude))
+	for k := range exclude {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		switch {
+		case strings.HasPrefix(k, "!"):
+			rules.addNeg(k[1:])
+		case strings.Contains(k, "/"):
+			rules.pos = append(rules.pos, compileFilterPattern(k, false))
+		default:
+			plain[k] = struct{}{}
+		}
+	}
+	incs := make([]string, 0, len(includes))
+	for _, inc := range includes {
+		if strings.HasPrefix(inc, "!") {
+			rules.addNeg(inc[1:])
+			continue
+		}
+		incs = append(incs, inc)
+	}
+	return plain, incs, rules
+}
+
+func (r *filterRules) addNeg(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+	r.neg = append(r.neg, compileFilterPattern(line, true))
+	r.negRaw = append(r.negRaw, strings.TrimPrefix(line, "/"))
+}
+
+func compileFilterPattern(line string, neg bool) gitPattern {
+	dirOnly := strings.HasSuffix(line, "/")
+	line = strings.TrimSuffix(line, "/")
+	anchored := strings.HasPrefix(line, "/") || strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	return gitPattern{neg: neg, dirOnly: dirOnly, anchored: anchored, rx: compileGitGlob(line, anchored, dirOnly)}
+}
+
+// negated reports whether a re-include rule matches rel.
+func (r filterRules) negated(rel string, isDir bool) bool {
+	for _, p := range r.neg {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if p.rx.MatchString(rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// mayReinclude reports whether some re-include rule could match a path below
+// dir, in which case the directory must be walked rather than pruned. As in
+// gitignore, an unanchored rule (a bare name such as "keep.go") does not
+// re-include files inside an excluded directory, so only anchored rules
+// whose literal leading directories agree with dir count.
+func (r filterRules) mayReinclude(dir string) bool {
+	prefix := dir + "/"
+	for i, p := range r.neg {
+		if !p.anchored {
+			continue
+		}
+		lit := r.negRaw[i]
+		if j := strings.IndexAny(lit, "*?["); j >= 0 {
+			lit = lit[:j]
+			if strings.HasPrefix(prefix, lit[:strings.LastIndexByte(lit, '/')+1]) {
+				return true
+			}
+		}
+		if strings.HasPrefix(lit, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// RepoReadme returns the top-level README of root (README.md preferred, then
+// any README or README.* by name), or ok=false when there is none.
+func RepoReadme(root string) (fi FileInfo, ok bool) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return FileInfo{}, false
+	}
+	name := ""
+	for _, e := range entries {
+		n := e.Name()
+		if !e.Type().IsRegular() {
+			continue
+		}
+		if n == "README.md" {
+			name = n
+			break
+		}
+		lower := strings.ToLower(n)
+		if name == "" && (lower == "readme" || strings.HasPrefix(lower, "readme.")) {
+			name = n
+		}
+	}
+	if name == "" {
+		return FileInfo{}, false
+	}
+	abs, err := filepath.Abs(filepath.Join(root, name))
+	if err != nil {
+		return FileInfo{}, false
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return FileInfo{}, false
+	}
+	sumHex, err := sha256File(abs)
+	if err != nil {
+		return FileInfo{}, false
+	}
+	return FileInfo{
+		RelPath:   sortutil.NormalizePath(name),
+		AbsPath:   abs,
+		Size:      info.Size(),
+		SHA256Hex: sumHex,
+		Ext:       strings.ToLower(filepath.Ext(name)),
+	}, true
+}
+
 // sha256File computes a hex-encoded sha256 for the file at path.
 func sha256File(path string) (string, error) {
 	f, err := os.Open(path)
@@ -246,6 +646,41 @@ func sha256File(path string) (string, error) {
 	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
+// ---------------- .gitmodules support ----------------
+
+// parseGitmodules returns the set of submodule paths (project-relative, '/'
+// separated) declared in a .gitmodules file. Only "path = ..." keys are read;
+// a missing or unreadable file yields nil.
+func parseGitmodules(path string) map[string]struct{} {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var res map[string]struct{}
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		key, val, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(key) != "path" {
+			continue
+		}
+		val = strings.Trim(strings.TrimSpace(val), `"`)
+		val = strings.Trim(filepath.ToSlash(val), "/")
+		if val == "" {
+			continue
+		}
+		if res == nil {
+			res = make(map[string]struct{})
+		}
+		res[val] = struct{}{}
+	}
+	return res
+}
+
 // ---------------- .gitignore support ----------------
 
 type gitPattern struct {
@@ -297,13 +732,27 @@ func parseGitignore(path string) ([]gitPattern, error) {
 	return res, nil
 }
 
+// GlobMatcher returns a predicate matching project-relative paths against a
+// gitignore-style glob anchored at the root: '*' and '?' do not cross '/',
+// while '**' spans any number of directories (including none, so
+// "src/**/*.go" also matches "src/main.go"). An empty glob yields nil.
+func GlobMatcher(glob string) func(string) bool {
+	glob = strings.TrimPrefix(filepath.ToSlash(strings.TrimSpace(glob)), "/")
+	if glob == "" {
+		return nil
+	}
+	return compileGitGlob(glob, true, false).MatchString
+}
+
 func compileGitGlob(glob string, anchored, dirOnly bool) *regexp.Regexp {
 	// Escape regex meta, then translate gitignore globs
 	esc := regexp.QuoteMeta(glob)
-	// Undo escapes for glob syntax
+	// Undo escapes for glob syntax; "**/" also matches no directory at all
+	esc = strings.ReplaceAll(esc, "\\*\\*/", "__DOUBLESTARDIR__")
 	esc = strings.ReplaceAll(esc, "\\*\\*", "__DOUBLESTAR__")
 	esc = strings.ReplaceAll(esc, "\\*", "[^/]*")
 	esc = strings.ReplaceAll(esc, "\\?", "[^/]")
+	esc = strings.ReplaceAll(esc, "__DOUBLESTARDIR__", "(?:.*/)?")
 	esc = strings.ReplaceAll(esc, "__DOUBLESTAR__", ".*")
 	var pattern string
 	if anchored {
@@ -334,3 +783,62 @@ func matchGitignore(pats []gitPattern, rel string, isDir bool) bool {
 	}
 	return ignored
 }
+
+// ParseModifiedSince resolves a -modified-since value against now: a Go
+// duration ("36h", "90m") or a whole number of days ("7d") is a window ending
+// now; otherwise the value must be a date ("2006-01-02", local time) or an
+// RFC 3339 timestamp. Empty yields the zero time (no filter).
+func ParseModifiedSince(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		if d < 0 {
+			return time.Time{}, fmt.Errorf("modified-since: negative duration %q", value)
+		}
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("modified-since: want a duration (36h, 7d), a date (2006-01-02) or RFC 3339 time, got %q", value)
+}
+
+// ResolvePath returns the file under root that the project path rel (as
+// FileInfo.RelPath reports it, NFC-normalized) names on disk. Components
+// missing under their NFC spelling are matched against the directory
+// entries by normalized name, so decomposed (NFD) names resolve too; when
+// nothing matches, the plain join is returned.
+func ResolvePath(root, rel string) string {
+	dir := root
+	for _, name := range strings.Split(rel, "/") {
+		next := filepath.Join(dir, name)
+		if _, err := os.Lstat(next); err != nil {
+			ents, rerr := os.ReadDir(dir)
+			if rerr != nil {
+				return filepath.Join(root, filepath.FromSlash(rel))
+			}
+			found := false
+			for _, e := range ents {
+				if sortutil.NormalizePath(e.Name()) == name {
+					next, found = filepath.Join(dir, e.Name()), true
+					break
+				}
+			}
+			if !found {
+				return filepath.Join(root, filepath.FromSlash(rel))
+			}
+		}
+		dir = next
+	}
+	return dir
+}
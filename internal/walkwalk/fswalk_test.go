@@ -0,0 +1,405 @@
+package walkwalk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"class-collector/internal/parallel"
+)
+
+func writeTree(t *testing.T, root string, files ...string) {
+	t.Helper()
+	for _, f := range files {
+		p := filepath.Join(root, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(p, []byte("package x\n"), 0o644); err != nil {
+			t.Fatalf("write %s: %v", f, err)
+		}
+	}
+}
+
+func relPaths(files []FileInfo) []string {
+	out := make([]string, 0, len(files))
+	for _, f := range files {
+		out = append(out, f.RelPath)
+	}
+	return out
+}
+
+func TestCollectFilesExcludeWithNegatedInclude(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, "main.go", "generated/drop.go", "generated/keep.go", "generated/sub/deep.go", "vendor/lib.go")
+
+	exts := map[string]struct{}{".go": {}}
+	exclude := map[string]struct{}{"generated/": {}, "vendor": {}}
+	files, _, err := CollectFiles(root, Options{Exts: exts, Exclude: exclude, Includes: []string{"!generated/keep.go"}, SkipSubmodules: true})
+	if err != nil {
+		t.Fatalf("CollectFiles error: %v", err)
+	}
+	want := []string{"generated/keep.go", "main.go"}
+	if got := relPaths(files); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestCollectFilesNegationInExcludeList(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, "gen/a.go", "gen/b.go", "gen/keep/c.go")
+
+	exts := map[string]struct{}{".go": {}}
+	exclude := map[string]struct{}{"gen/": {}, "!gen/keep/*.go": {}}
+	files, _, err := CollectFiles(root, Options{Exts: exts, Exclude: exclude, SkipSubmodules: true})
+	if err != nil {
+		t.Fatalf("CollectFiles error: %v", err)
+	}
+	want := []string{"gen/keep/c.go"}
+	if got := relPaths(files); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestCollectFilesUnanchoredNegationStaysOutOfExcludedDirs(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, "keep_me.go", "keep_drop.go", "vendor/keep_me.go", "vendor/lib.go", "dist/app.go")
+
+	exts := map[string]struct{}{".go": {}}
+	exclude := map[string]struct{}{"vendor": {}, "keep_": {}, "dist": {}, "!keep_me.go": {}, "!dist": {}}
+	opts := Options{Exts: exts, Exclude: exclude, SkipSubmodules: true}
+	files, _, err := CollectFiles(root, opts)
+	if err != nil {
+		t.Fatalf("CollectFiles error: %v", err)
+	}
+	want := []string{"dist/app.go", "keep_me.go"}
+	if got := relPaths(files); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	_, _, rules := splitFilterRules(exclude, nil)
+	if rules.mayReinclude("vendor") {
+		t.Fatal("an unanchored negation forces the walk into vendor/")
+	}
+}
+
+func TestGlobMatcherDoubleStarMatchesNoDirectory(t *testing.T) {
+	match := GlobMatcher("src/**/*.go")
+	for p, want := range map[string]bool{
+		"src/main.go":       true,
+		"src/pkg/a/util.go": true,
+		"src/pkg/util.java": false,
+		"tools/gen.go":      false,
+	} {
+		if got := match(p); got != want {
+			t.Errorf("match(%q) = %v, want %v", p, got, want)
+		}
+	}
+	if GlobMatcher("  ") != nil {
+		t.Error("empty glob should yield a nil matcher")
+	}
+}
+
+func TestCollectFilesSubmodules(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, "main.go", "third_party/lib/lib.go", "third_party/own.go")
+	gitmodules := "[submodule \"lib\"]\n\tpath = third_party/lib\n\turl = https://example.com/lib.git\n"
+	if err := os.WriteFile(filepath.Join(root, ".gitmodules"), []byte(gitmodules), 0o644); err != nil {
+		t.Fatalf("write .gitmodules: %v", err)
+	}
+
+	exts := map[string]struct{}{".go": {}}
+	files, _, err := CollectFiles(root, Options{Exts: exts, SkipSubmodules: true})
+	if err != nil {
+		t.Fatalf("CollectFiles error: %v", err)
+	}
+	if got, want := relPaths(files), []string{"main.go", "third_party/own.go"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("skip: got %v, want %v", got, want)
+	}
+
+	files, _, err = CollectFiles(root, Options{Exts: exts})
+	if err != nil {
+		t.Fatalf("CollectFiles error: %v", err)
+	}
+	if got, want := relPaths(files), []string{"main.go", "third_party/lib/lib.go", "third_party/own.go"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("include: got %v, want %v", got, want)
+	}
+}
+
+func TestCollectFilesRecordsSymlinks(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, "main.go")
+	if err := os.Symlink("missing/target.go", filepath.Join(root, "link.go")); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+
+	exts := map[string]struct{}{".go": {}}
+	files, _, err := CollectFiles(root, Options{Exts: exts, SkipSubmodules: true, RecordSymlinks: true})
+	if err != nil {
+		t.Fatalf("CollectFiles error: %v", err)
+	}
+	if got, want := relPaths(files), []string{"link.go", "main.go"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if link := files[0]; link.Symlink != "missing/target.go" || link.SHA256Hex != "" || link.Size != 0 {
+		t.Fatalf("unexpected symlink entry: %+v", link)
+	}
+
+	// Links failing the extension or exclude filters are not recorded.
+	if err := os.Symlink("main.go", filepath.Join(root, "link.png")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "gen"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../main.go", filepath.Join(root, "gen", "link.go")); err != nil {
+		t.Fatal(err)
+	}
+	exclude := map[string]struct{}{"gen": {}}
+	files, _, err = CollectFiles(root, Options{Exts: exts, Exclude: exclude, SkipSubmodules: true, RecordSymlinks: true})
+	if err != nil {
+		t.Fatalf("CollectFiles error: %v", err)
+	}
+	if got, want := relPaths(files), []string{"link.go", "main.go"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("filtered links: got %v, want %v", got, want)
+	}
+
+	files, _, err = CollectFiles(root, Options{Exts: exts, SkipSubmodules: true})
+	if err != nil {
+		t.Fatalf("CollectFiles error: %v", err)
+	}
+	if got, want := relPaths(files), []string{"main.go"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("without recording: got %v, want %v", got, want)
+	}
+}
+
+func TestCollectFilesCaseSensitiveExt(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, "lower.h", "upper.H")
+
+	exts := map[string]struct{}{".h": {}}
+	files, _, err := CollectFiles(root, Options{Exts: exts, SkipSubmodules: true, CaseSensitiveExt: true})
+	if err != nil {
+		t.Fatalf("CollectFiles error: %v", err)
+	}
+	if got, want := relPaths(files), []string{"lower.h"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("case-sensitive: got %v, want %v", got, want)
+	}
+
+	files, _, err = CollectFiles(root, Options{Exts: exts, SkipSubmodules: true})
+	if err != nil {
+		t.Fatalf("CollectFiles error: %v", err)
+	}
+	if got, want := relPaths(files), []string{"lower.h", "upper.H"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("case-insensitive: got %v, want %v", got, want)
+	}
+}
+
+func TestCollectFilesGlobalGitignore(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, "main.go", "secret.go", "keep/secret.go", "scratch/tmp.go")
+	if err := os.WriteFile(filepath.Join(root, ".gitignore"), []byte("!keep/secret.go\n"), 0o644); err != nil {
+		t.Fatalf("write .gitignore: %v", err)
+	}
+	xdg := t.TempDir()
+	writeTree(t, xdg, "git/ignore")
+	if err := os.WriteFile(filepath.Join(xdg, "git", "ignore"), []byte("secret.go\nscratch/\n"), 0o644); err != nil {
+		t.Fatalf("write global ignore: %v", err)
+	}
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+
+	exts := map[string]struct{}{".go": {}}
+	files, _, err := CollectFiles(root, Options{Exts: exts, UseGitignore: true, SkipSubmodules: true, GlobalGitignore: true})
+	if err != nil {
+		t.Fatalf("CollectFiles error: %v", err)
+	}
+	if got, want := relPaths(files), []string{"keep/secret.go", "main.go"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("with global ignore got %v, want %v", got, want)
+	}
+
+	files, _, err = CollectFiles(root, Options{Exts: exts, UseGitignore: true, SkipSubmodules: true})
+	if err != nil {
+		t.Fatalf("CollectFiles error: %v", err)
+	}
+	if len(files) != 4 {
+		t.Fatalf("global ignore must be opt-in, got %v", relPaths(files))
+	}
+}
+
+func TestCollectFilesModifiedSince(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, "old.go", "recent.go", "sub/new.go")
+	now := time.Now()
+	old := now.Add(-72 * time.Hour)
+	if err := os.Chtimes(filepath.Join(root, "old.go"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	exts := map[string]struct{}{".go": {}}
+
+	since, err := ParseModifiedSince("24h", now)
+	if err != nil {
+		t.Fatalf("ParseModifiedSince error: %v", err)
+	}
+	files, _, err := CollectFiles(root, Options{Exts: exts, SkipSubmodules: true, ModifiedSince: since})
+	if err != nil {
+		t.Fatalf("CollectFiles error: %v", err)
+	}
+	var got []string
+	for _, f := range files {
+		got = append(got, f.RelPath)
+	}
+	if want := []string{"recent.go", "sub/new.go"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	files, _, err = CollectFiles(root, Options{Exts: exts, SkipSubmodules: true})
+	if err != nil || len(files) != 3 {
+		t.Fatalf("zero modifiedSince should keep every file, got %d (%v)", len(files), err)
+	}
+}
+
+func TestCollectFilesParallelHashingKeepsBudgetOrder(t *testing.T) {
+	root := t.TempDir()
+	var names []string
+	for i := 0; i < 50; i++ {
+		names = append(names, fmt.Sprintf("d%d/f%02d.go", i%3, i))
+	}
+	writeTree(t, root, names...)
+	if err := os.WriteFile(filepath.Join(root, "d0", "f00.go"), []byte("package x\n\nvar big = 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	exts := map[string]struct{}{".go": {}}
+	defer parallel.SetLimit(0)
+	collect := func(workers int) ([]FileInfo, int64) {
+		t.Helper()
+		parallel.SetLimit(workers)
+		files, total, err := CollectFiles(root, Options{Exts: exts, MaxBytes: 115, SkipSubmodules: true})
+		if err != nil {
+			t.Fatalf("CollectFiles error: %v", err)
+		}
+		return files, total
+	}
+	serial, serialTotal := collect(1)
+	par, parTotal := collect(8)
+	if !reflect.DeepEqual(serial, par) || serialTotal != parTotal {
+		t.Fatalf("parallel hashing changed the result:\n%v (%d)\n%v (%d)", relPaths(serial), serialTotal, relPaths(par), parTotal)
+	}
+	// d0/f00.go (23 bytes) and nine 10-byte files fit; the walk visits d0 first.
+	if len(par) != 10 || parTotal != 113 || par[0].SHA256Hex == "" {
+		t.Fatalf("got %v (total %d), want 10 files within the 115-byte budget", relPaths(par), parTotal)
+	}
+}
+
+func TestCollectFilesKeepRunsBeforeBudget(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, "a_test.go", "b.go")
+	exts := map[string]struct{}{".go": {}}
+	keep := func(f FileInfo) bool { return !strings.HasSuffix(f.RelPath, "_test.go") }
+	files, total, err := CollectFiles(root, Options{Exts: exts, MaxBytes: 10, Keep: keep})
+	if err != nil {
+		t.Fatalf("CollectFiles error: %v", err)
+	}
+	if got := relPaths(files); !reflect.DeepEqual(got, []string{"b.go"}) || total != 10 {
+		t.Fatalf("got %v (total %d), want [b.go] within the budget", got, total)
+	}
+}
+
+func TestWalkStopsOnceCandidatesFillBudget(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, "a.go", "b/c.go", "b/d.go", "e.go")
+	ws := &walkState{cfg: walkerConfig{src: root, maxBytes: 20, exts: map[string]struct{}{".go": {}}}, root: root}
+	if err := filepath.WalkDir(root, ws.visit); err != nil {
+		t.Fatalf("walk error: %v", err)
+	}
+	// Two 10-byte files fill the budget; the rest of the tree is not listed.
+	if got := relPaths(ws.candidates); !reflect.DeepEqual(got, []string{"a.go", "b/c.go"}) {
+		t.Fatalf("candidates = %v, want [a.go b/c.go]", got)
+	}
+}
+
+func TestParseModifiedSince(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	cases := map[string]time.Time{
+		"":                     {},
+		"36h":                  now.Add(-36 * time.Hour),
+		"7d":                   now.AddDate(0, 0, -7),
+		"2025-03-01T08:00:00Z": time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC),
+		"2025-03-01":           time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local),
+	}
+	for in, want := range cases {
+		got, err := ParseModifiedSince(in, now)
+		if err != nil || !got.Equal(want) {
+			t.Fatalf("ParseModifiedSince(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"yesterday", "-5h", "03/01/2025"} {
+		if _, err := ParseModifiedSince(bad, now); err == nil {
+			t.Fatalf("ParseModifiedSince(%q) should fail", bad)
+		}
+	}
+}
+
+func TestCollectFilesDedupContent(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, "a/stub.go", "b/stub.go", "c/stub.go")
+	for name, body := range map[string]string{"empty1.go": "", "empty2.go": "", "main.go": "package main\n"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(body), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	// Room for one stub and main.go: the duplicate stubs must not be charged.
+	exts := map[string]struct{}{".go": {}}
+	files, total, err := CollectFiles(root, Options{Exts: exts, MaxBytes: 23, DedupContent: true})
+	if err != nil {
+		t.Fatalf("CollectFiles error: %v", err)
+	}
+	want := []string{"a/stub.go", "empty1.go", "empty2.go", "main.go"}
+	if got := relPaths(files); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if want := []string{"b/stub.go", "c/stub.go"}; !reflect.DeepEqual(files[0].Aliases, want) {
+		t.Fatalf("aliases = %v, want %v", files[0].Aliases, want)
+	}
+	if files[1].Aliases != nil || files[2].Aliases != nil {
+		t.Fatalf("empty files were merged: %+v", files[1:3])
+	}
+	if total != 23 {
+		t.Fatalf("total = %d, want 23", total)
+	}
+}
+
+func TestCollectFilesNormalizesUnicodePaths(t *testing.T) {
+	root := t.TempDir()
+	// "café" decomposed (e + U+0301), as macOS reports it.
+	writeTree(t, root, "café/main.go", "b.go")
+
+	exts := map[string]struct{}{".go": {}}
+	files, _, err := CollectFiles(root, Options{Exts: exts, SkipSubmodules: true})
+	if err != nil {
+		t.Fatalf("CollectFiles error: %v", err)
+	}
+	want := []string{"b.go", "café/main.go"}
+	if got := relPaths(files); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if _, err := os.Stat(files[1].AbsPath); err != nil {
+		t.Fatalf("AbsPath must keep the on-disk spelling: %v", err)
+	}
+}
+
+func TestResolvePathFindsDecomposedNames(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, "caf\u0065\u0301/main.go")
+
+	got := ResolvePath(root, "caf\u00e9/main.go")
+	if want := filepath.Join(root, "caf\u0065\u0301", "main.go"); got != want {
+		t.Fatalf("ResolvePath = %q, want %q", got, want)
+	}
+	if _, err := os.Stat(got); err != nil {
+		t.Fatalf("resolved path does not exist: %v", err)
+	}
+}
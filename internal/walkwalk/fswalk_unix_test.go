@@ -0,0 +1,45 @@
+//go:build unix
+
+package walkwalk
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCollectFilesDedupHardlinks(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, "a/main.go", "other.go")
+	for _, alias := range []string{"b/main.go", "z.go"} {
+		dst := filepath.Join(root, filepath.FromSlash(alias))
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Link(filepath.Join(root, "a", "main.go"), dst); err != nil {
+			t.Skipf("hard links unsupported: %v", err)
+		}
+	}
+	exts := map[string]struct{}{".go": {}}
+
+	files, _, err := CollectFiles(root, Options{Exts: exts, SkipSubmodules: true, DedupHardlinks: true})
+	if err != nil {
+		t.Fatalf("CollectFiles error: %v", err)
+	}
+	var got []string
+	for _, f := range files {
+		got = append(got, f.RelPath)
+	}
+	if want := []string{"a/main.go", "other.go"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if want := []string{"b/main.go", "z.go"}; !reflect.DeepEqual(files[0].Aliases, want) {
+		t.Fatalf("aliases = %v, want %v", files[0].Aliases, want)
+	}
+
+	files, _, err = CollectFiles(root, Options{Exts: exts, SkipSubmodules: true})
+	if err != nil || len(files) != 4 {
+		t.Fatalf("without dedup every path is collected, got %d (%v)", len(files), err)
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package walkwalk
+
+import "io/fs"
+
+// inode identifies a file across hard links.
+type inode struct{ dev, ino uint64 }
+
+// inodeKey reports no inode where the platform does not expose one, so every
+// path is collected on its own.
+func inodeKey(fs.FileInfo) (inode, bool) { return inode{}, false }
@@ -0,0 +1,21 @@
+//go:build unix
+
+package walkwalk
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// inode identifies a file across hard links.
+type inode struct{ dev, ino uint64 }
+
+// inodeKey returns the device/inode pair of a file with more than one hard
+// link; files with a single link cannot alias anything.
+func inodeKey(info fs.FileInfo) (inode, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || st.Nlink < 2 {
+		return inode{}, false
+	}
+	return inode{dev: uint64(st.Dev), ino: uint64(st.Ino)}, true
+}
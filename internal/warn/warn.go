@@ -0,0 +1,102 @@
+// Package warn collects structured warnings for recoverable issues raised
+// while building a bundle (skipped files, degraded configs, oversize diffs,
+// truncations), so they can be reported instead of silently swallowed.
+package warn
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"sync"
+)
+
+// Warning kinds.
+const (
+	KindUnreadable = "unreadable"    // a file could not be read and was skipped
+	KindConfig     = "config"        // a config file was malformed and ignored
+	KindOversize   = "oversize-diff" // a diff exceeded -max-diff-bytes and was omitted
+	KindTruncated  = "truncated"     // content was cut to fit a size budget
+	KindEncoding   = "encoding"      // content was not UTF-8 and could not be transcoded
+	KindValidate   = "validate"      // a non-strict validation check failed
+	KindSuspicious = "suspicious"    // source that is likely wrong (e.g. require() in an ES module)
+)
+
+// Warning is a single recorded issue.
+type Warning struct {
+	Kind    string `json:"kind"`
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+// Collector accumulates warnings; it is safe for concurrent use and a nil
+// *Collector discards everything.
+type Collector struct {
+	mu   sync.Mutex
+	list []Warning
+}
+
+// Add records a warning with a formatted message.
+func (c *Collector) Add(kind, path, format string, args ...any) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.list = append(c.list, Warning{Kind: kind, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// List returns the recorded warnings sorted by (kind, path, message).
+func (c *Collector) List() []Warning {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	out := make([]Warning, len(c.list))
+	copy(out, c.list)
+	c.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Kind != out[j].Kind {
+			return out[i].Kind < out[j].Kind
+		}
+		if out[i].Path != out[j].Path {
+			return out[i].Path < out[j].Path
+		}
+		return out[i].Message < out[j].Message
+	})
+	return out
+}
+
+// Rewrite replaces every recorded warning with fn(w).
+func (c *Collector) Rewrite(fn func(Warning) Warning) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, w := range c.list {
+		c.list[i] = fn(w)
+	}
+}
+
+// Cause strips the *fs.PathError wrapper from err, so a warning reports
+// "permission denied" under its project-relative Path instead of embedding
+// the absolute filesystem path in warnings.json.
+func Cause(err error) error {
+	var pe *fs.PathError
+	if errors.As(err, &pe) {
+		return pe.Err
+	}
+	return err
+}
+
+var active *Collector
+
+// Use installs c as the collector that Add reports to (nil disables).
+func Use(c *Collector) { active = c }
+
+// Active returns the installed collector, or nil.
+func Active() *Collector { return active }
+
+// Add records a warning on the active collector, if any.
+func Add(kind, path, format string, args ...any) { active.Add(kind, path, format, args...) }
@@ -0,0 +1,22 @@
+package warn
+
+import "testing"
+
+func TestCollectorSortsAndNilIsNoop(t *testing.T) {
+	var nilc *Collector
+	nilc.Add(KindConfig, "x", "ignored")
+	if nilc.List() != nil {
+		t.Fatalf("nil collector should record nothing")
+	}
+
+	c := &Collector{}
+	Use(c)
+	defer Use(nil)
+	Add(KindUnreadable, "b.go", "skipped: %s", "denied")
+	Add(KindConfig, "tsconfig.json", "ignored")
+	Add(KindUnreadable, "a.go", "skipped")
+	got := c.List()
+	if len(got) != 3 || got[0].Kind != KindConfig || got[1].Path != "a.go" || got[2].Message != "skipped: denied" {
+		t.Fatalf("unexpected warnings: %#v", got)
+	}
+}
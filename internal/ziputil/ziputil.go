@@ -2,19 +2,28 @@ package ziputil
 
 import (
 	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"path/filepath"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // FixedZipTime ensures byte-for-byte reproducible archives (1980-01-01 UTC).
 var FixedZipTime = time.Unix(315532800, 0).UTC()
 
+// MaxComponentLen is the longest path component, in bytes, SanitizePath keeps
+// as-is (the NTFS and most POSIX filesystem limit).
+const MaxComponentLen = 255
+
 // SanitizePath normalizes ZIP entry paths (forward slashes, no drive, no leading '/'),
-// and removes '.' and '..' segments without escaping the root.
+// and removes '.' and '..' segments without escaping the root. Each remaining
+// component is made extractable on Windows: reserved device names gain a '_'
+// (CON.txt -> CON_.txt) and components over MaxComponentLen are shortened.
 func SanitizePath(p string) string {
 	s := filepath.ToSlash(p)
 	if len(s) > 1 && s[1] == ':' {
@@ -33,7 +42,7 @@ func SanitizePath(p string) string {
 			}
 			continue
 		}
-		stack = append(stack, part)
+		stack = append(stack, safeComponent(part))
 	}
 	s = strings.Join(stack, "/")
 	if s == "" {
@@ -42,6 +51,44 @@ func SanitizePath(p string) string {
 	return s
 }
 
+// reservedNames are Windows device names that cannot be used as a file base
+// name, with or without an extension.
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// safeComponent renames a reserved device base name and truncates an overlong
+// component to MaxComponentLen, keeping its extension and adding a short hash
+// of the original so distinct long names stay distinct.
+func safeComponent(part string) string {
+	base, rest := part, ""
+	if i := strings.IndexByte(part, '.'); i >= 0 {
+		base, rest = part[:i], part[i:]
+	}
+	if reservedNames[strings.ToUpper(strings.TrimRight(base, " "))] {
+		part = base + "_" + rest
+	}
+	if len(part) <= MaxComponentLen {
+		return part
+	}
+	sum := sha256.Sum256([]byte(part))
+	tag := "~" + hex.EncodeToString(sum[:])[:8]
+	ext := ""
+	if i := strings.LastIndexByte(part, '.'); i > 0 && len(part)-i <= 16 {
+		ext = part[i:]
+	}
+	keep := MaxComponentLen - len(tag) - len(ext)
+	// Back off to a rune boundary so the result stays valid UTF-8.
+	for keep > 0 && !utf8.RuneStart(part[keep]) {
+		keep--
+	}
+	return part[:keep] + tag + ext
+}
+
 // EnsureUniqueName returns a unique name by appending -1, -2, ... when needed.
 func EnsureUniqueName(name string, used map[string]struct{}) string {
 	if _, ok := used[name]; !ok {
@@ -61,8 +108,24 @@ func EnsureUniqueName(name string, used map[string]struct{}) string {
 	}
 }
 
+// EntryWriter is the part of *zip.Writer the Write helpers need: entries are
+// created one at a time from a header carrying the name, mode and fixed
+// timestamp. Non-ZIP bundle writers implement it too.
+type EntryWriter interface {
+	CreateHeader(fh *zip.FileHeader) (io.Writer, error)
+}
+
+// jsonCompact disables indentation in WriteJSON output.
+var jsonCompact bool
+
+// SetJSONCompact switches WriteJSON between two-space indented (default) and
+// compact output. Both forms are deterministic for the same input.
+func SetJSONCompact(compact bool) {
+	jsonCompact = compact
+}
+
 // WriteJSON writes a JSON-encoded value with fixed timestamp and mode.
-func WriteJSON(zw *zip.Writer, name string, v any) error {
+func WriteJSON(zw EntryWriter, name string, v any) error {
 	h := &zip.FileHeader{Name: SanitizePath(name), Method: zip.Deflate}
 	h.SetMode(0o644)
 	h.Modified = FixedZipTime
@@ -71,7 +134,9 @@ func WriteJSON(zw *zip.Writer, name string, v any) error {
 		return fmt.Errorf("create %s: %w", name, err)
 	}
 	enc := json.NewEncoder(w)
-	enc.SetIndent("", "  ")
+	if !jsonCompact {
+		enc.SetIndent("", "  ")
+	}
 	if err := enc.Encode(v); err != nil {
 		return fmt.Errorf("write %s: %w", name, err)
 	}
@@ -79,7 +144,7 @@ func WriteJSON(zw *zip.Writer, name string, v any) error {
 }
 
 // WriteText writes raw text (bytes) entry with fixed timestamp.
-func WriteText(zw *zip.Writer, name string, data []byte) error {
+func WriteText(zw EntryWriter, name string, data []byte) error {
 	h := &zip.FileHeader{Name: SanitizePath(name), Method: zip.Deflate}
 	h.SetMode(0o644)
 	h.Modified = FixedZipTime
@@ -94,12 +159,12 @@ func WriteText(zw *zip.Writer, name string, data []byte) error {
 }
 
 // WriteFile streams data bytes as a file entry with fixed timestamp.
-func WriteFile(zw *zip.Writer, name string, data []byte) error {
+func WriteFile(zw EntryWriter, name string, data []byte) error {
 	return WriteText(zw, name, data)
 }
 
 // CopyFromReader writes an entry from an io.Reader to avoid buffering whole files when needed.
-func CopyFromReader(zw *zip.Writer, name string, r io.Reader) error {
+func CopyFromReader(zw EntryWriter, name string, r io.Reader) error {
 	h := &zip.FileHeader{Name: SanitizePath(name), Method: zip.Deflate}
 	h.SetMode(0o644)
 	h.Modified = FixedZipTime
@@ -0,0 +1,110 @@
+package ziputil
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func writeJSONEntry(t *testing.T, v any) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	if err := WriteJSON(zw, "data.json", v); err != nil {
+		t.Fatalf("WriteJSON error: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("close zip: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	rc, err := zr.File[0].Open()
+	if err != nil {
+		t.Fatalf("open entry: %v", err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("read entry: %v", err)
+	}
+	return data
+}
+
+func TestWriteJSONCompact(t *testing.T) {
+	v := struct {
+		Module string   `json:"module"`
+		Files  []string `json:"files"`
+	}{Module: "m", Files: []string{"a.go", "b.go"}}
+
+	pretty := writeJSONEntry(t, v)
+	SetJSONCompact(true)
+	defer SetJSONCompact(false)
+	compact := writeJSONEntry(t, v)
+
+	if len(compact) >= len(pretty) {
+		t.Fatalf("compact (%d bytes) not smaller than pretty (%d bytes)", len(compact), len(pretty))
+	}
+	if !json.Valid(compact) {
+		t.Fatalf("compact output is not valid JSON: %s", compact)
+	}
+	if again := writeJSONEntry(t, v); !bytes.Equal(again, compact) {
+		t.Fatalf("compact output not deterministic: %s vs %s", again, compact)
+	}
+	if want := `{"module":"m","files":["a.go","b.go"]}` + "\n"; string(compact) != want {
+		t.Fatalf("compact = %q, want %q", compact, want)
+	}
+}
+
+func TestSanitizePathReservedNames(t *testing.T) {
+	cases := map[string]string{
+		"src/CON.txt":     "src/CON_.txt",
+		"nul":             "nul_",
+		"a/com1.tar.gz":   "a/com1_.tar.gz",
+		"aux/main.go":     "aux_/main.go",
+		"CONSOLE.txt":     "CONSOLE.txt",
+		"lpt10.c":         "lpt10.c",
+		"C:/x/PRN.h":      "x/PRN_.h",
+		"../../LPT3/a.go": "LPT3_/a.go",
+	}
+	for in, want := range cases {
+		if got := SanitizePath(in); got != want {
+			t.Errorf("SanitizePath(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	used := map[string]struct{}{"CON_.txt": {}}
+	if got := EnsureUniqueName(SanitizePath("CON.txt"), used); got != "CON_-1.txt" {
+		t.Fatalf("EnsureUniqueName = %q, want CON_-1.txt", got)
+	}
+}
+
+func TestSanitizePathLongComponents(t *testing.T) {
+	a := strings.Repeat("a", 300) + "1.java"
+	b := strings.Repeat("a", 300) + "2.java"
+	ga, gb := SanitizePath("dir/"+a), SanitizePath("dir/"+b)
+	for _, g := range []string{ga, gb} {
+		name := strings.TrimPrefix(g, "dir/")
+		if len(name) != MaxComponentLen {
+			t.Fatalf("component length = %d, want %d", len(name), MaxComponentLen)
+		}
+		if !strings.HasSuffix(name, ".java") {
+			t.Fatalf("extension lost: %q", name)
+		}
+	}
+	if ga == gb {
+		t.Fatalf("distinct long names collapsed to %q", ga)
+	}
+	if again := SanitizePath("dir/" + a); again != ga {
+		t.Fatalf("not deterministic: %q vs %q", again, ga)
+	}
+	long := strings.Repeat("é", 200) + ".txt"
+	if got := SanitizePath(long); !utf8.ValidString(got) || len(got) > MaxComponentLen {
+		t.Fatalf("multibyte truncation = %q (len %d)", got, len(got))
+	}
+}